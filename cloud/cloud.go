@@ -0,0 +1,122 @@
+// Package cloud defines the Azure clouds, which select the ARM endpoint of the generated configurations and the recordings,
+// the environment of the generated provider blocks and the default location of the testing resources.
+package cloud
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+)
+
+// EnvVar selects the cloud when it's not set in the project configuration, it's also used by the azapi and azurerm providers
+const EnvVar = "ARM_ENVIRONMENT"
+
+// Environment is an Azure cloud
+type Environment struct {
+	// Name is the environment of the azapi and azurerm providers, allowed values: 'public', 'china' and 'usgovernment'
+	Name string `yaml:"name,omitempty"`
+	// ResourceManagerEndpoint is the ARM endpoint, it's only set for the air-gapped clouds whose endpoints differ from the well-known ones
+	ResourceManagerEndpoint string `yaml:"resourceManagerEndpoint,omitempty"`
+	// Location is the default location of the testing resources, it must be valid in the cloud
+	Location string `yaml:"location,omitempty"`
+}
+
+var (
+	Public = Environment{
+		Name:                    "public",
+		ResourceManagerEndpoint: "https://management.azure.com",
+		Location:                "westeurope",
+	}
+	China = Environment{
+		Name:                    "china",
+		ResourceManagerEndpoint: "https://management.chinacloudapi.cn",
+		Location:                "chinanorth3",
+	}
+	USGovernment = Environment{
+		Name:                    "usgovernment",
+		ResourceManagerEndpoint: "https://management.usgovcloudapi.net",
+		Location:                "usgovvirginia",
+	}
+)
+
+// aliases are the names of the clouds used by the Azure CLI and the Azure SDKs
+var aliases = map[string]Environment{
+	"public":            Public,
+	"azurecloud":        Public,
+	"azurepubliccloud":  Public,
+	"china":             China,
+	"azurechinacloud":   China,
+	"usgovernment":      USGovernment,
+	"azureusgovernment": USGovernment,
+}
+
+var (
+	// currentMutex guards the selected cloud, which is read by the concurrent workers, e.g., the coverage report
+	currentMutex sync.RWMutex
+	current      = Public
+)
+
+// Current returns the selected cloud, default is the public cloud
+func Current() Environment {
+	currentMutex.RLock()
+	defer currentMutex.RUnlock()
+	return current
+}
+
+// Set selects the cloud, the unset fields are filled by the well-known cloud of the same name.
+// The cloud in the ARM_ENVIRONMENT environment variable is used if the name is not set.
+func Set(env Environment) error {
+	resolved, err := Resolve(env)
+	if err != nil {
+		return err
+	}
+	currentMutex.Lock()
+	defer currentMutex.Unlock()
+	current = resolved
+	return nil
+}
+
+// Resolve fills the unset fields by the well-known cloud of the same name, the name is normalized, e.g., AzureChinaCloud is resolved as china.
+// The cloud in the ARM_ENVIRONMENT environment variable is used if the name is not set.
+func Resolve(env Environment) (Environment, error) {
+	name := env.Name
+	if name == "" {
+		name = os.Getenv(EnvVar)
+	}
+	if name == "" {
+		name = Public.Name
+	}
+	known, ok := aliases[strings.ToLower(name)]
+	if !ok {
+		return Environment{}, fmt.Errorf("unknown cloud %q, allowed values: 'public', 'china' and 'usgovernment'", name)
+	}
+	out := known
+	if env.ResourceManagerEndpoint != "" {
+		u, err := url.Parse(env.ResourceManagerEndpoint)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return Environment{}, fmt.Errorf("resource manager endpoint %q is invalid, it must be an absolute URL, e.g., https://management.azure.com", env.ResourceManagerEndpoint)
+		}
+		out.ResourceManagerEndpoint = strings.TrimSuffix(env.ResourceManagerEndpoint, "/")
+	}
+	if env.Location != "" {
+		out.Location = env.Location
+	}
+	return out, nil
+}
+
+// IsCustom returns whether the ARM endpoint differs from the well-known one, e.g., in the air-gapped clouds
+func (e Environment) IsCustom() bool {
+	known, ok := aliases[strings.ToLower(e.Name)]
+	return ok && !strings.EqualFold(known.ResourceManagerEndpoint, e.ResourceManagerEndpoint)
+}
+
+// Host returns the host of the ARM endpoint, e.g., management.azure.com
+func (e Environment) Host() string {
+	u, err := url.Parse(e.ResourceManagerEndpoint)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,79 @@
+package cloud_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/azure/armstrong/cloud"
+)
+
+func Test_Resolve(t *testing.T) {
+	testcases := []struct {
+		name     string
+		env      cloud.Environment
+		envVar   string
+		expected cloud.Environment
+		isCustom bool
+		isError  bool
+	}{
+		{name: "default", expected: cloud.Public},
+		{name: "alias", env: cloud.Environment{Name: "AzureUSGovernment"}, expected: cloud.USGovernment},
+		{name: "environment variable", envVar: "china", expected: cloud.China},
+		{name: "name takes precedence", env: cloud.Environment{Name: "public"}, envVar: "china", expected: cloud.Public},
+		{
+			name:     "location",
+			env:      cloud.Environment{Name: "china", Location: "chinaeast2"},
+			expected: cloud.Environment{Name: "china", ResourceManagerEndpoint: "https://management.chinacloudapi.cn", Location: "chinaeast2"},
+		},
+		{
+			name:     "air-gapped",
+			env:      cloud.Environment{ResourceManagerEndpoint: "https://management.airgap.example/", Location: "airgapeast"},
+			expected: cloud.Environment{Name: "public", ResourceManagerEndpoint: "https://management.airgap.example", Location: "airgapeast"},
+			isCustom: true,
+		},
+		{name: "unknown cloud", env: cloud.Environment{Name: "german"}, isError: true},
+		{name: "invalid endpoint", env: cloud.Environment{ResourceManagerEndpoint: "management.airgap.example"}, isError: true},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(cloud.EnvVar, tc.envVar)
+			actual, err := cloud.Resolve(tc.env)
+			if tc.isError != (err != nil) {
+				t.Fatalf("expect error %v, but got %+v", tc.isError, err)
+			}
+			if tc.isError {
+				return
+			}
+			if actual != tc.expected {
+				t.Errorf("expect %+v, but got %+v", tc.expected, actual)
+			}
+			if actual.IsCustom() != tc.isCustom {
+				t.Errorf("expect custom %v, but got %v", tc.isCustom, actual.IsCustom())
+			}
+		})
+	}
+}
+
+func Test_SetConcurrent(t *testing.T) {
+	defer func() {
+		_ = cloud.Set(cloud.Public)
+	}()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			_ = cloud.Set(cloud.China)
+		}()
+		go func() {
+			defer wg.Done()
+			if env := cloud.Current(); env != cloud.Public && env != cloud.China {
+				t.Errorf("expect the public or china cloud, but got %+v", env)
+			}
+		}()
+	}
+	wg.Wait()
+	if cloud.Current() != cloud.China {
+		t.Errorf("expect the china cloud, but got %+v", cloud.Current())
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/azure/armstrong/knownerror"
+	"github.com/azure/armstrong/redact"
 	"github.com/azure/armstrong/report"
 	"github.com/azure/armstrong/tf"
 	"github.com/azure/armstrong/types"
@@ -18,21 +20,28 @@ import (
 )
 
 type CleanupCommand struct {
-	verbose    bool
-	workingDir string
+	verbose        bool
+	workingDir     string
+	redactPatterns stringSliceFlag
+	knownErrors    stringSliceFlag
+	terraform      terraformFlags
+	suppressions   []report.Suppression
 }
 
 func (c *CleanupCommand) flags() *flag.FlagSet {
 	fs := defaultFlagSet("cleanup")
 	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
 	fs.StringVar(&c.workingDir, "working-dir", "", "path to Terraform configuration files")
+	fs.Var(&c.redactPatterns, "redact-pattern", "regular expression of the values which should be redacted in the reports, it can be specified multiple times")
+	fs.Var(&c.knownErrors, "known-errors", "path to the .json file or directory of the known error rules, which are matched besides the built-in rules, it can be specified multiple times")
+	c.terraform.register(fs)
 	fs.Usage = func() { logrus.Error(c.Help()) }
 	return fs
 }
 
 func (c CleanupCommand) Help() string {
 	helpText := `
-Usage: armstrong cleanup [-v] [-working-dir <path to Terraform configuration files>]
+Usage: armstrong cleanup [-v] [-working-dir <path to Terraform configuration files>] [-redact-pattern <regular expression>] [-known-errors <path/dir to the known error rules>] [-terraform-flavor <terraform or tofu>] [-terraform-version <version constraint>] [-terraform-path <path to the executable>] [-plugin-mirror <path to the mirror directory>] [-dev-override <provider>=<directory>]
 ` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
 
 	return strings.TrimSpace(helpText)
@@ -44,10 +53,12 @@ func (c CleanupCommand) Synopsis() string {
 
 func (c CleanupCommand) Run(args []string) int {
 	f := c.flags()
-	if err := f.Parse(args); err != nil {
+	cfg, err := parseFlags(f, args)
+	if err != nil {
 		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
 		return 1
 	}
+	c.suppressions = cfg.Suppressions
 	if c.verbose {
 		log.SetOutput(os.Stdout)
 		logrus.SetLevel(logrus.DebugLevel)
@@ -75,17 +86,30 @@ func (c CleanupCommand) Execute() int {
 			return 1
 		}
 	}
-	terraform, err := tf.NewTerraform(wd, c.verbose)
+	redactor, err := redact.NewRedactor("", c.redactPatterns)
+	if err != nil {
+		logrus.Error(fmt.Sprintf("failed to create redactor: %+v", err))
+		return 1
+	}
+	knowledgeBase, err := knownerror.NewKnowledgeBase(c.knownErrors)
+	if err != nil {
+		logrus.Error(fmt.Sprintf("failed to load the known error rules: %+v", err))
+		return 1
+	}
+	terraform, err := tf.NewTerraform(wd, c.verbose, c.terraform.options())
 	if err != nil {
-		logrus.Fatalf("creating terraform executable: %+v", err)
+		logrus.Errorf("creating terraform executable: %+v", err)
+		return 1
 	}
 
 	state, err := terraform.Show()
 	if err != nil {
-		logrus.Fatalf("failed to get terraform state: %+v", err)
+		logrus.Errorf("failed to get terraform state: %+v", err)
+		return 1
 	}
 
 	passReport := tf.NewPassReportFromState(state)
+	passReport.TerraformVersion = terraform.Executable.String()
 	idAddressMap := tf.NewIdAddressFromState(state)
 
 	reportDir := fmt.Sprintf("armstrong_cleanup_reports_%s", time.Now().Format(time.DateTime))
@@ -94,7 +118,8 @@ func (c CleanupCommand) Execute() int {
 	reportDir = path.Join(wd, reportDir)
 	err = os.Mkdir(reportDir, 0755)
 	if err != nil {
-		logrus.Fatalf("failed to create report directory: %+v", err)
+		logrus.Errorf("failed to create report directory: %+v", err)
+		return 1
 	}
 
 	logrus.Infof("running terraform init...")
@@ -111,13 +136,23 @@ func (c CleanupCommand) Execute() int {
 			logrus.Errorf("failed to parse log.txt: %+v", err)
 		}
 
+		redactor.Collect(logs)
+
 		errorReport = tf.NewCleanupErrorReport(destroyErr, logs)
+		errorReport.TerraformVersion = terraform.Executable.String()
 		for i := range errorReport.Errors {
 			if address, ok := idAddressMap[errorReport.Errors[i].Id]; ok {
 				errorReport.Errors[i].Label = address
 			}
 		}
-		storeCleanupErrorReport(errorReport, reportDir)
+		report.SwaggerResolver{}.ResolveErrors(&errorReport, "DELETE")
+		knowledgeBase.ResolveErrors(&errorReport)
+		suppressions := report.NewSuppressions(report.LoadSuppressions(wd, c.suppressions))
+		suppressions.SuppressErrors(&errorReport)
+		if findings := suppressions.Findings(); len(findings) != 0 {
+			logrus.Infof("%d errors are suppressed:\n%s", len(findings), report.SuppressedFindingsMarkdown(findings))
+		}
+		storeCleanupErrorReport(errorReport, reportDir, redactor)
 
 		resources := make([]types.Resource, 0)
 		if state, err := terraform.Show(); err == nil && state != nil && state.Values != nil && state.Values.RootModule != nil && state.Values.RootModule.Resources != nil {
@@ -135,10 +170,10 @@ func (c CleanupCommand) Execute() int {
 			}
 		}
 		passReport.Resources = resources
-		storeCleanupReport(passReport, reportDir, partialPassedReportFileName)
+		storeCleanupReport(passReport, reportDir, partialPassedReportFileName, redactor)
 	} else {
 		logrus.Infof("all resources are cleaned up")
-		storeCleanupReport(passReport, reportDir, allPassedReportFileName)
+		storeCleanupReport(passReport, reportDir, allPassedReportFileName, redactor)
 	}
 
 	logrus.Infof("---------------- Summary ----------------")
@@ -150,9 +185,9 @@ func (c CleanupCommand) Execute() int {
 	return 0
 }
 
-func storeCleanupReport(passReport types.PassReport, reportDir string, reportName string) {
+func storeCleanupReport(passReport types.PassReport, reportDir string, reportName string, redactor *redact.Redactor) {
 	if len(passReport.Resources) != 0 {
-		err := os.WriteFile(path.Join(reportDir, reportName), []byte(report.CleanupMarkdownReport(passReport)), 0644)
+		err := os.WriteFile(path.Join(reportDir, reportName), []byte(redactor.String(report.CleanupMarkdownReport(passReport))), 0644)
 		if err != nil {
 			logrus.Errorf("failed to save passed markdown report to %s: %+v", reportName, err)
 		} else {
@@ -161,11 +196,11 @@ func storeCleanupReport(passReport types.PassReport, reportDir string, reportNam
 	}
 }
 
-func storeCleanupErrorReport(errorReport types.ErrorReport, reportDir string) {
+func storeCleanupErrorReport(errorReport types.ErrorReport, reportDir string, redactor *redact.Redactor) {
 	for _, r := range errorReport.Errors {
 		logrus.Warnf("found an error when deleting %s, address: %s\n", r.Type, r.Label)
 		markdownFilename := fmt.Sprintf("Error - %s_%s.md", strings.ReplaceAll(r.Type, "/", "_"), r.Label)
-		err := os.WriteFile(path.Join(reportDir, markdownFilename), []byte(report.CleanupErrorMarkdownReport(r, errorReport.Logs)), 0644)
+		err := os.WriteFile(path.Join(reportDir, markdownFilename), []byte(redactor.String(report.CleanupErrorMarkdownReport(r, errorReport.Logs, errorReport.TerraformVersion))), 0644)
 		if err != nil {
 			logrus.Errorf("failed to save markdown report to %s: %+v", markdownFilename, err)
 		} else {
@@ -0,0 +1,120 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/armstrong/config"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+type ConfigShowCommand struct {
+	workingDir string
+}
+
+func (c *ConfigShowCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("config show")
+	fs.StringVar(&c.workingDir, "working-dir", "", "path to the directory where the configuration file is searched from, default is current directory")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c ConfigShowCommand) Help() string {
+	helpText := `
+Usage: armstrong config show [-working-dir <path to the directory>]
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c ConfigShowCommand) Synopsis() string {
+	return "Show the effective settings of the project configuration file"
+}
+
+func (c ConfigShowCommand) Run(args []string) int {
+	f := c.flags()
+	if err := f.Parse(args); err != nil {
+		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c ConfigShowCommand) Execute() int {
+	wd, err := os.Getwd()
+	if err != nil {
+		logrus.Error(fmt.Sprintf("failed to get working directory: %+v", err))
+		return 1
+	}
+	if c.workingDir != "" {
+		wd, err = filepath.Abs(c.workingDir)
+		if err != nil {
+			logrus.Error(fmt.Sprintf("working directory is invalid: %+v", err))
+			return 1
+		}
+	}
+	cfg, err := config.Discover(wd)
+	if err != nil {
+		logrus.Error(fmt.Sprintf("failed to load the project configuration: %+v", err))
+		return 1
+	}
+	content, err := effectiveSettings(*cfg)
+	if err != nil {
+		logrus.Error(fmt.Sprintf("failed to show the project configuration: %+v", err))
+		return 1
+	}
+	fmt.Print(content)
+	return 0
+}
+
+// effectiveSettings returns the configuration file, its settings and the flag defaults of each command which are set by it, in YAML format
+func effectiveSettings(cfg config.Config) (string, error) {
+	header := fmt.Sprintf("# configuration file: %s\n", cfg.Path)
+	if cfg.Path == "" {
+		header = fmt.Sprintf("# no configuration file is found, the file names are: %s\n", strings.Join(config.FileNames, ", "))
+	}
+
+	commandFlags := make(map[string]map[string]interface{})
+	for name, fs := range commandFlagSets() {
+		flags := make(map[string]interface{})
+		for flagName, values := range cfg.FlagValues(name) {
+			if fs.Lookup(flagName) == nil {
+				continue
+			}
+			if len(values) == 1 {
+				flags[flagName] = values[0]
+			} else {
+				flags[flagName] = values
+			}
+		}
+		if len(flags) != 0 {
+			commandFlags[name] = flags
+		}
+	}
+	cfg.Commands = commandFlags
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	return header + string(data), nil
+}
+
+// commandFlagSets returns the flags of the commands which read the project configuration, keyed by the command names
+func commandFlagSets() map[string]*flag.FlagSet {
+	return map[string]*flag.FlagSet{
+		"generate": (&GenerateCommand{}).flags(),
+		"validate": (&ValidateCommand{}).flags(),
+		"test":     (&TestCommand{}).flags(),
+		"cleanup":  (&CleanupCommand{}).flags(),
+		"report":   (&ReportCommand{}).flags(),
+		"credscan": (&CredentialScanCommand{}).flags(),
+		"examples": (&ExamplesCommand{}).flags(),
+		"export":   (&ExportCommand{}).flags(),
+		"import":   (&ImportCommand{}).flags(),
+	}
+}
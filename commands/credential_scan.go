@@ -8,11 +8,16 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/credscan"
 	"github.com/azure/armstrong/hcl"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/sarif"
+	"github.com/azure/armstrong/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -22,22 +27,27 @@ type CredentialScanCommand struct {
 	swaggerRepoPath  string
 	swaggerIndexFile string
 	verbose          bool
+	fix              bool
+	fixLowConfidence bool
+	suppressions     []report.Suppression
 }
 
 func (c *CredentialScanCommand) flags() *flag.FlagSet {
-	fs := defaultFlagSet("test")
+	fs := defaultFlagSet("credscan")
 	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
 	fs.StringVar(&c.workingDir, "working-dir", "", "path to directory containing Terraform configuration files")
 	fs.StringVar(&c.outputDir, "output-dir", "", "path to directory to save output files, default to working-dir")
-	fs.StringVar(&c.swaggerRepoPath, "swagger-repo", "", "path to the swagger repo specification directory")
+	fs.StringVar(&c.swaggerRepoPath, "swagger-repo", "", "path to the swagger repo specification directory, or a resource provider directory in it which limits the index to the resource provider")
 	fs.StringVar(&c.swaggerIndexFile, "swagger-index-file", "", "path to the swagger index file, omit this will use the online swagger index file or locally build index")
+	fs.BoolVar(&c.fix, "fix", false, "whether rewrite the secrets into sensitive variables")
+	fs.BoolVar(&c.fixLowConfidence, "fix-low-confidence", false, "whether rewrite the low confidence secrets which are detected by heuristics, it's used with 'fix'")
 	fs.Usage = func() { logrus.Error(c.Help()) }
 	return fs
 }
 
 func (c CredentialScanCommand) Help() string {
 	helpText := `
-Usage: armstrong credscan [-v] [-working-dir <path to directory containing Terraform configuration files>] [-swagger-repo <path to the swagger repo specification directory>] [-swagger-index-file <path to the swagger index file>] [-output-dir <path to directory to save output files>]
+Usage: armstrong credscan [-v] [-working-dir <path to directory containing Terraform configuration files>] [-swagger-repo <path to the swagger repo specification directory or a resource provider directory in it>] [-swagger-index-file <path to the swagger index file>] [-output-dir <path to directory to save output files>] [-fix [-fix-low-confidence]]
 ` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
 
 	return strings.TrimSpace(helpText)
@@ -49,10 +59,12 @@ func (c CredentialScanCommand) Synopsis() string {
 
 func (c CredentialScanCommand) Run(args []string) int {
 	f := c.flags()
-	if err := f.Parse(args); err != nil {
+	cfg, err := parseFlags(f, args)
+	if err != nil {
 		logrus.Errorf("Error parsing command-line flags: %s", err)
 		return 1
 	}
+	c.suppressions = cfg.Suppressions
 	if c.verbose {
 		log.SetOutput(os.Stdout)
 		logrus.SetLevel(logrus.DebugLevel)
@@ -75,25 +87,12 @@ func (c CredentialScanCommand) Execute() int {
 		}
 	}
 	if c.swaggerRepoPath != "" {
-		c.swaggerRepoPath, err = filepath.Abs(c.swaggerRepoPath)
+		specDir, resourceProvider, err := coverage.SplitSpecDir(c.swaggerRepoPath)
 		if err != nil {
-			logrus.Errorf("swagger repo path %q is invalid: %+v", c.swaggerRepoPath, err)
+			logrus.Error(err)
 			return 1
 		}
-
-		if _, err := os.Stat(c.swaggerRepoPath); os.IsNotExist(err) {
-			logrus.Errorf("swagger repo path %q is invalid: path does not exist", c.swaggerRepoPath)
-			return 1
-		}
-
-		c.swaggerRepoPath = strings.TrimSuffix(c.swaggerRepoPath, "/")
-
-		if !strings.HasSuffix(c.swaggerRepoPath, "specification") {
-			logrus.Errorf("swagger repo path %q is invalid: must point to \"specification\", e.g., /home/projects/azure-rest-api-specs/specification", c.swaggerRepoPath)
-			return 1
-		}
-
-		c.swaggerRepoPath += "/"
+		c.swaggerRepoPath = filepath.Join(specDir, resourceProvider)
 	}
 	if c.swaggerIndexFile != "" {
 		c.swaggerIndexFile, err = filepath.Abs(c.swaggerIndexFile)
@@ -163,55 +162,86 @@ func (c CredentialScanCommand) Execute() int {
 
 	}
 
+	var fixer *credscan.Fixer
+	if c.fix {
+		fixer, err = credscan.NewFixer(wd)
+		if err != nil {
+			logrus.Errorf("failed to load tf files for fixing secrets: %+v", err)
+			return 1
+		}
+	}
+
+	// the suppressed secrets are neither reported nor fixed
+	suppressions := report.NewSuppressions(report.LoadSuppressions(wd, c.suppressions))
+	checkProviderSecret := func(azureProvider hcl.AzureProvider, propertyName, propertyValue string) []CredScanError {
+		return fixSecret(fixer, c.fixLowConfidence, suppressCredScanErrors(suppressions, checkAzureProviderSecret(azureProvider, propertyName, propertyValue, vars)), func() error {
+			address := []string{"provider", azureProvider.Type}
+			if azureProvider.Alias != "" {
+				address = append(address, azureProvider.Alias)
+			}
+			return fixer.FixAttribute(azureProvider.FileName, address, strings.Split(propertyName, "[")[0], propertyName, propertyValue)
+		})
+	}
+	checkResourceSecret := func(azapiResource hcl.AzapiResource, propertyName, propertyValue, confidence, reason string) []CredScanError {
+		return fixSecret(fixer, c.fixLowConfidence, suppressCredScanErrors(suppressions, checkSecret(azapiResource, propertyName, propertyValue, vars, confidence, reason)), func() error {
+			address := []string{azapiResource.ResourceName, azapiResource.Name}
+			if azapiResource.Kind == "data" {
+				address = append([]string{"data"}, address...)
+			}
+			return fixer.FixAttribute(azapiResource.FileName, address, "body", propertyName, propertyValue)
+		})
+	}
+
 	credScanErrors := make([]CredScanError, 0)
+	indexCommits := make(map[string]bool)
 
 	for _, azureProvider := range azureProviders {
 		if v := azureProvider.SubscriptionId; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "subscription_id", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "subscription_id", v)...)
 		}
 
 		if v := azureProvider.TenantId; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "tenant_id", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "tenant_id", v)...)
 		}
 
 		if v := azureProvider.AuxiliaryTenantIds; len(v) > 0 {
 			for i, tenant_id := range v {
-				credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, fmt.Sprintf("auxiliary_tenant_ids[%v]", i), tenant_id, vars)...)
+				credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, fmt.Sprintf("auxiliary_tenant_ids[%v]", i), tenant_id)...)
 			}
 		}
 
 		if v := azureProvider.AuxiliaryTenantIdsString; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "auxiliary_tenant_ids", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "auxiliary_tenant_ids", v)...)
 		}
 
 		if v := azureProvider.ClientId; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "client_id", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "client_id", v)...)
 		}
 
 		if v := azureProvider.ClientCertificate; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "client_certificate", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "client_certificate", v)...)
 		}
 
 		if v := azureProvider.ClientCertificatePassword; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "client_certificate_password", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "client_certificate_password", v)...)
 		}
 
 		if v := azureProvider.ClientSecret; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "client_secret", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "client_secret", v)...)
 		}
 
 		if v := azureProvider.OidcRequestToken; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "oidc_request_token", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "oidc_request_token", v)...)
 		}
 
 		if v := azureProvider.OidcToken; v != "" {
-			credScanErrors = append(credScanErrors, checkAzureProviderSecret(azureProvider, "oidc_token", v, vars)...)
+			credScanErrors = append(credScanErrors, checkProviderSecret(azureProvider, "oidc_token", v)...)
 		}
 
 	}
 
 	for _, azapiResource := range azapiResources {
-		logrus.Infof("scaning azapi_resource.%s(%s)", azapiResource.Name, azapiResource.Type)
+		logrus.Infof("scaning %s(%s)", azapiResource.Address(), azapiResource.Type)
 
 		if azapiResource.Body == "" {
 			continue
@@ -229,128 +259,115 @@ func (c CredentialScanCommand) Execute() int {
 			continue
 		}
 
-		mockedResourceId, apiVersion := coverage.MockResourceIDFromType(azapiResource.Type)
-		logrus.Infof("azapi_resource.%s(%s): mocked possible resource ID: %s, API version: %s", azapiResource.Name, azapiResource.Type, mockedResourceId, apiVersion)
-
-		var swaggerModel *coverage.SwaggerModel
-		if c.swaggerRepoPath != "" {
-			logrus.Infof("scan based on local swagger repo: %s", c.swaggerRepoPath)
-			swaggerModel, err = coverage.GetModelInfoFromLocalIndex(mockedResourceId, apiVersion, "PUT", c.swaggerRepoPath, c.swaggerIndexFile)
-			if err != nil {
-				credScanErr := makeCredScanError(
-					azapiResource,
-					fmt.Sprintf("fail to find swagger model from local swagger with possible resource ID(%s) API version(%s): %+v", mockedResourceId, apiVersion, err),
-					"",
-				)
-				credScanErrors = append(credScanErrors, credScanErr)
-				logrus.Error(credScanErr)
-
-				continue
-			}
-		} else {
-			swaggerModel, err = coverage.GetModelInfoFromIndex(mockedResourceId, apiVersion, "PUT", c.swaggerIndexFile)
-			if err != nil {
-				credScanErr := makeCredScanError(
-					azapiResource,
-					fmt.Sprintf("fail to find swagger model with possible resource ID(%s) API version(%s): %+v", mockedResourceId, apiVersion, err),
-					"",
-				)
-				credScanErrors = append(credScanErrors, credScanErr)
-				logrus.Error(credScanErr)
-
-				continue
-			}
+		model, indexCommit, err := c.requestModel(azapiResource)
+		if indexCommit != "" {
+			indexCommits[indexCommit] = true
 		}
-
-		if swaggerModel == nil {
-			credScanErr := makeCredScanError(
-				azapiResource,
-				fmt.Sprintf("unable to find swagger model with possible resource ID(%s) API version(%s)", mockedResourceId, apiVersion),
-				"",
-			)
+		if err != nil {
+			credScanErr := makeCredScanError(azapiResource, err.Error(), "")
 			credScanErrors = append(credScanErrors, credScanErr)
 			logrus.Error(credScanErr)
 
+			// the swagger model is not available, fall back to the heuristics
+			findings := credscan.Detect(body)
+			logrus.Infof("find possible secrets by heuristics for %s(%s): %+v", azapiResource.Address(), azapiResource.Type, findings)
+			for _, finding := range findings {
+				credScanErrors = append(credScanErrors, checkResourceSecret(azapiResource, finding.PropertyName, finding.Value, credscan.ConfidenceLow, finding.Reason)...)
+			}
 			continue
 		}
 
-		logrus.Infof("find swagger model for azapi_resource.%s(%s): %+v", azapiResource.Name, azapiResource.Type, *swaggerModel)
+		secrets := make(map[string]string)
+		model.CredScan(body, secrets)
 
-		model, err := coverage.Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
-		if err != nil {
-			credScanErr := makeCredScanError(
-				azapiResource,
-				fmt.Sprintf("failed to expand model: %+v", err),
-				"",
-			)
-			credScanErrors = append(credScanErrors, credScanErr)
-			logrus.Error(credScanErr)
+		logrus.Infof("find secrets for %s(%s): %+v", azapiResource.Address(), azapiResource.Type, secrets)
 
+		propertyNames := make([]string, 0)
+		for k := range secrets {
+			propertyNames = append(propertyNames, k)
+		}
+		sort.Strings(propertyNames)
+		for _, k := range propertyNames {
+			credScanErrors = append(credScanErrors, checkResourceSecret(azapiResource, k, secrets[k], credscan.ConfidenceHigh, "")...)
+		}
+	}
+
+	if fixer != nil {
+		if err := fixer.Save(); err != nil {
+			logrus.Errorf("failed to save the fixed tf files: %+v", err)
+			return 1
+		}
+	}
+
+	// the secrets are suppressed when they're checked, the errors which fail the scan are suppressed here
+	scanned := make([]CredScanError, 0)
+	for _, credScanErr := range credScanErrors {
+		if credScanErr.Confidence == "" {
+			scanned = append(scanned, suppressCredScanErrors(suppressions, []CredScanError{credScanErr})...)
 			continue
 		}
+		scanned = append(scanned, credScanErr)
+	}
 
-		secrets := make(map[string]string)
-		model.CredScan(body, secrets)
+	if err := storeCredScanErrors(outputDir, scanned, suppressions.Findings(), indexCommitList(indexCommits)); err != nil {
+		logrus.Errorf("failed to store the credential scan reports: %+v", err)
+		return 1
+	}
 
-		logrus.Infof("find secrets for azapi_resource.%s(%s): %+v", azapiResource.Name, azapiResource.Type, secrets)
+	return 0
+}
 
-		for k, v := range secrets {
-			if !strings.HasPrefix(v, "$") || strings.HasPrefix(v, "$local.") {
-				credScanErr := makeCredScanError(
-					azapiResource,
-					"cannot use plain text or 'local' for secret, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to hide the secret values",
-					k,
-				)
-				credScanErrors = append(credScanErrors, credScanErr)
-				logrus.Error(credScanErr)
+// requestModel returns the expanded swagger model of the request which sends the body of the azapi resource, and the commit of the index which finds the model
+func (c CredentialScanCommand) requestModel(azapiResource hcl.AzapiResource) (*coverage.Model, string, error) {
+	mockedResourceId, apiVersion := coverage.MockResourceIDFromType(azapiResource.Type)
+	if azapiResource.Action != "" {
+		mockedResourceId = fmt.Sprintf("%s/%s", mockedResourceId, azapiResource.Action)
+	}
+	methods := azapiResource.RequestMethods()
+	logrus.Infof("%s(%s): mocked possible resource ID: %s, API version: %s, methods: %v", azapiResource.Address(), azapiResource.Type, mockedResourceId, apiVersion, methods)
 
-				continue
+	var swaggerModel *coverage.SwaggerModel
+	var method string
+	var err error
+	for _, method = range methods {
+		if c.swaggerRepoPath != "" {
+			logrus.Infof("scan based on local swagger repo: %s", c.swaggerRepoPath)
+			swaggerModel, err = coverage.GetModelInfoFromLocalIndex(mockedResourceId, apiVersion, method, c.swaggerRepoPath, c.swaggerIndexFile)
+			if err != nil {
+				err = fmt.Errorf("fail to find swagger model from local swagger with possible resource ID(%s) API version(%s) method(%s): %+v", mockedResourceId, apiVersion, method, err)
 			}
-
-			if strings.HasPrefix(v, "$var.") {
-				varName := strings.TrimPrefix(v, "$var.")
-				varName = strings.Split(varName, ".")[0]
-				theVar, ok := vars[varName]
-				if !ok {
-					credScanErr := makeCredScanError(
-						azapiResource,
-						fmt.Sprintf("variable %q was not found, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName),
-						k,
-					)
-					credScanErrors = append(credScanErrors, credScanErr)
-					logrus.Error(credScanErr)
-
-					continue
-				}
-
-				if theVar.HasDefault {
-					credScanErr := makeCredScanError(
-						azapiResource,
-						fmt.Sprintf("variable %q (%v:%v) used in secret field but has a default value, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName, theVar.FileName, theVar.LineNumber),
-						k,
-					)
-					credScanErrors = append(credScanErrors, credScanErr)
-					logrus.Error(credScanErr)
-				}
-
-				if !theVar.IsSensitive {
-					credScanErr := makeCredScanError(
-						azapiResource,
-						fmt.Sprintf("variable %q (%v:%v) used in secret field but is not marked as sensitive, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName, theVar.FileName, theVar.LineNumber),
-						k,
-					)
-					credScanErrors = append(credScanErrors, credScanErr)
-					logrus.Error(credScanErr)
-				}
+		} else {
+			swaggerModel, err = coverage.GetModelInfoFromIndex(mockedResourceId, apiVersion, method, c.swaggerIndexFile)
+			if err != nil {
+				err = fmt.Errorf("fail to find swagger model with possible resource ID(%s) API version(%s) method(%s): %+v", mockedResourceId, apiVersion, method, err)
 			}
 		}
+		if err == nil && swaggerModel != nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	if swaggerModel == nil {
+		return nil, "", fmt.Errorf("unable to find swagger model with possible resource ID(%s) API version(%s) methods(%s)", mockedResourceId, apiVersion, strings.Join(methods, ", "))
 	}
 
-	storeCredScanErrors(outputDir, credScanErrors)
+	logrus.Infof("find swagger model for %s(%s) %s: %+v", azapiResource.Address(), azapiResource.Type, method, *swaggerModel)
 
-	return 0
+	model, err := coverage.Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
+	if err != nil {
+		return nil, swaggerModel.Commit, fmt.Errorf("failed to expand model: %+v", err)
+	}
+	return model, swaggerModel.Commit, nil
 }
 
+const (
+	azureRestApiSpecsRepoUri = "https://github.com/Azure/azure-rest-api-specs"
+	credScanSecretRuleId     = "armstrong-credscan/secret"
+	credScanFailureRuleId    = "armstrong-credscan/scan-error"
+)
+
 type CredScanError struct {
 	FileName     string `json:"file_name"`
 	Name         string `json:"name"`
@@ -358,13 +375,15 @@ type CredScanError struct {
 	PropertyName string `json:"property_name"`
 	ErrorMessage string `json:"error_message"`
 	LineNumber   int    `json:"line_number"`
+	// Confidence is high if the secret is detected by the swagger model, and low if it's detected by the heuristics
+	Confidence string `json:"confidence,omitempty"`
 }
 
 func makeCredScanError(azapiResource hcl.AzapiResource, errMessage, propertyName string) CredScanError {
 	result := CredScanError{
 		FileName:     azapiResource.FileName,
 		LineNumber:   azapiResource.LineNumber,
-		Name:         fmt.Sprintf("azapi_resource.%s", azapiResource.Name),
+		Name:         azapiResource.Address(),
 		Type:         azapiResource.Type,
 		ErrorMessage: errMessage,
 	}
@@ -383,6 +402,7 @@ func makeCredScanErrorForProvider(azureProvider hcl.AzureProvider, errMessage, p
 		Name:         azureProvider.Name(),
 		Type:         "provider",
 		ErrorMessage: errMessage,
+		Confidence:   credscan.ConfidenceHigh,
 	}
 
 	if propertyName != "" {
@@ -392,11 +412,56 @@ func makeCredScanErrorForProvider(azureProvider hcl.AzureProvider, errMessage, p
 	return result
 }
 
+// fixSecret fixes the secret when the fixer is specified, it returns the scan errors which are not fixed.
+// The low confidence secrets detected by the heuristics might be false positives, they're only fixed when fixLowConfidence is true.
+func fixSecret(fixer *credscan.Fixer, fixLowConfidence bool, credScanErrors []CredScanError, fix func() error) []CredScanError {
+	if fixer == nil || len(credScanErrors) == 0 {
+		return credScanErrors
+	}
+	if credScanErrors[0].Confidence == credscan.ConfidenceLow && !fixLowConfidence {
+		logrus.Infof("skip fixing %s of %s because it's detected by heuristics, use 'fix-low-confidence' option to fix it", credScanErrors[0].PropertyName, credScanErrors[0].Name)
+		return credScanErrors
+	}
+	if err := fix(); err != nil {
+		logrus.Warnf("failed to fix %s of %s: %+v", credScanErrors[0].PropertyName, credScanErrors[0].Name, err)
+		return credScanErrors
+	}
+	logrus.Infof("fixed %s of %s", credScanErrors[0].PropertyName, credScanErrors[0].Name)
+	return nil
+}
+
+// suppressCredScanErrors returns the scan errors which are not suppressed, the secrets and the errors which fail the scan are matched by their SARIF rule ids
+func suppressCredScanErrors(suppressions *report.Suppressions, credScanErrors []CredScanError) []CredScanError {
+	out := make([]CredScanError, 0)
+	for _, r := range credScanErrors {
+		rule := credScanSecretRuleId
+		if r.Confidence == "" {
+			rule = credScanFailureRuleId
+		}
+		if suppressions.Suppress(report.CredScanReportName, report.Finding{Rule: rule, File: r.FileName, ResourceType: r.Type, Property: r.PropertyName, Message: r.ErrorMessage}) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
 func (e CredScanError) Error() string {
 	return fmt.Sprintf("%s:%d %s(%s) --%s: %s", e.FileName, e.LineNumber, e.Name, e.Type, e.PropertyName, e.ErrorMessage)
 }
 
-func storeCredScanErrors(wd string, credScanErrors []CredScanError) {
+func indexCommitList(indexCommits map[string]bool) []string {
+	out := make([]string, 0)
+	for commit := range indexCommits {
+		out = append(out, commit)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// storeCredScanErrors writes the scan errors in markdown, json and SARIF formats, the commits of the swagger indexes which find the models are recorded.
+// It returns an error if the report directory can't be created, the failures of writing the reports are logged.
+func storeCredScanErrors(wd string, credScanErrors []CredScanError, suppressed []types.SuppressedFinding, indexCommits []string) error {
 	reportDir := fmt.Sprintf("armstrong_credscan_%s", time.Now().Format(time.Stamp))
 	reportDir = strings.ReplaceAll(reportDir, ":", "")
 	reportDir = strings.ReplaceAll(reportDir, " ", "_")
@@ -404,17 +469,25 @@ func storeCredScanErrors(wd string, credScanErrors []CredScanError) {
 
 	err := os.Mkdir(reportDir, 0755)
 	if err != nil {
-		logrus.Fatalf("error creating report dir %s: %+v", reportDir, err)
+		return fmt.Errorf("creating report dir %s: %+v", reportDir, err)
 	}
 
 	markdownFileName := "errors.md"
 	credScanErrorsMarkdown := `
-| File Name | Line Number | Name | Type | Property Name | Error Message |
-| --- | --- | --- | --- | --- | --- |
+| File Name | Line Number | Name | Type | Property Name | Confidence | Error Message |
+| --- | --- | --- | --- | --- | --- | --- |
 `
 	for _, r := range credScanErrors {
-		credScanErrorsMarkdown += fmt.Sprintf("| %s | %d | %s | %s | %s | %s |\n", r.FileName, r.LineNumber, r.Name, r.Type, r.PropertyName, r.ErrorMessage)
+		credScanErrorsMarkdown += fmt.Sprintf("| %s | %d | %s | %s | %s | %s | %s |\n", r.FileName, r.LineNumber, r.Name, r.Type, r.PropertyName, r.Confidence, r.ErrorMessage)
+	}
+	if len(indexCommits) != 0 {
+		links := make([]string, 0)
+		for _, commit := range indexCommits {
+			links = append(links, fmt.Sprintf("[%[1]s](%[2]s%[1]s)", commit, coverage.IndexCommitURL))
+		}
+		credScanErrorsMarkdown += fmt.Sprintf("\nThe swagger index is built on commit %s.\n", strings.Join(links, ", "))
 	}
+	credScanErrorsMarkdown += fmt.Sprintf("\n### Suppressed findings\n\n%s\n", report.SuppressedFindingsMarkdown(suppressed))
 
 	markdownFileName = path.Join(reportDir, markdownFileName)
 	err = os.WriteFile(markdownFileName, []byte(credScanErrorsMarkdown), 0644)
@@ -437,6 +510,104 @@ func storeCredScanErrors(wd string, credScanErrors []CredScanError) {
 	} else {
 		logrus.Infof("json report saved to %s", jsonFileName)
 	}
+
+	sarifFileName := path.Join(reportDir, "errors.sarif")
+	sarifContent, err := credScanSarifReport(credScanErrors, indexCommits).MarshalIndent()
+	if err != nil {
+		logrus.Errorf("failed to marshal sarif report: %+v", err)
+	}
+	err = os.WriteFile(sarifFileName, sarifContent, 0644)
+	if err != nil {
+		logrus.Errorf("failed to save sarif report to %s: %+v", sarifFileName, err)
+	} else {
+		logrus.Infof("sarif report saved to %s", sarifFileName)
+	}
+	return nil
+}
+
+// credScanSarifReport converts the scan errors to a SARIF log, the secrets detected by the swagger model are reported as errors,
+// the secrets detected by the heuristics are reported as warnings, and the errors which fail the scan are reported as notes.
+func credScanSarifReport(credScanErrors []CredScanError, indexCommits []string) sarif.Log {
+	rules := []sarif.Rule{
+		{
+			Id:               credScanSecretRuleId,
+			ShortDescription: &sarif.Message{Text: "The secret in the terraform configuration should be a reference to a sensitive variable without default value."},
+		},
+		{
+			Id:               credScanFailureRuleId,
+			ShortDescription: &sarif.Message{Text: "The terraform configuration can't be scanned."},
+		},
+	}
+	results := make([]sarif.Result, 0)
+	for _, r := range credScanErrors {
+		address := fmt.Sprintf("%s(%s)", r.Name, r.Type)
+		if r.PropertyName != "" {
+			address = fmt.Sprintf("%s %s", address, r.PropertyName)
+		}
+		result := sarif.Result{
+			RuleId:    credScanSecretRuleId,
+			Message:   sarif.Message{Text: fmt.Sprintf("%s: %s", address, r.ErrorMessage)},
+			Locations: []sarif.Location{sarif.NewLocation(r.FileName, r.LineNumber, 0)},
+		}
+		switch r.Confidence {
+		case credscan.ConfidenceHigh:
+			result.Level = sarif.LevelError
+		case credscan.ConfidenceLow:
+			result.Level = sarif.LevelWarning
+		default:
+			result.RuleId = credScanFailureRuleId
+			result.Level = sarif.LevelNote
+		}
+		results = append(results, result)
+	}
+	out := sarif.NewLog(rules, results)
+	for _, commit := range indexCommits {
+		out.Runs[0].VersionControlProvenance = append(out.Runs[0].VersionControlProvenance, sarif.VersionControlDetails{
+			RepositoryUri: azureRestApiSpecsRepoUri,
+			RevisionId:    commit,
+		})
+	}
+	return out
+}
+
+// checkSecret checks whether the secret value is a reference to a sensitive variable without default value,
+// the reason explains why the property is considered as a secret, it's empty if the property is marked as secret in the swagger.
+func checkSecret(azapiResource hcl.AzapiResource, propertyName, propertyValue string, vars map[string]hcl.Variable, confidence, reason string) []CredScanError {
+	credScanErrors := make([]CredScanError, 0)
+	report := func(errMessage string) {
+		if reason != "" {
+			errMessage = fmt.Sprintf("%s, %s", reason, errMessage)
+		}
+		credScanErr := makeCredScanError(azapiResource, errMessage, propertyName)
+		credScanErr.Confidence = confidence
+		credScanErrors = append(credScanErrors, credScanErr)
+		logrus.Error(credScanErr)
+	}
+
+	if !strings.HasPrefix(propertyValue, "$") || strings.HasPrefix(propertyValue, "$local.") {
+		report("cannot use plain text or 'local' for secret, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to hide the secret values")
+		return credScanErrors
+	}
+
+	if strings.HasPrefix(propertyValue, "$var.") {
+		varName := strings.TrimPrefix(propertyValue, "$var.")
+		varName = strings.Split(varName, ".")[0]
+		theVar, ok := vars[varName]
+		if !ok {
+			report(fmt.Sprintf("variable %q was not found, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName))
+			return credScanErrors
+		}
+
+		if theVar.HasDefault {
+			report(fmt.Sprintf("variable %q (%v:%v) used in secret field but has a default value, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName, theVar.FileName, theVar.LineNumber))
+		}
+
+		if !theVar.IsSensitive {
+			report(fmt.Sprintf("variable %q (%v:%v) used in secret field but is not marked as sensitive, please follow https://github.com/Azure/armstrong/blob/main/docs/guidance-for-api-test.md#4-q-i-have-some-sensitive-information-in-the-test-case-how-to-hide-it to set the variable for secret values", varName, theVar.FileName, theVar.LineNumber))
+		}
+	}
+
+	return credScanErrors
 }
 
 func checkAzureProviderSecret(azureProvider hcl.AzureProvider, propertyName, propertyValue string, vars map[string]hcl.Variable) []CredScanError {
@@ -0,0 +1,131 @@
+package commands
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/swagger"
+	"github.com/sirupsen/logrus"
+)
+
+type ExamplesCommand struct {
+	verbose       bool
+	workingDir    string
+	tracePath     string
+	swaggerPath   string
+	output        string
+	updateSwagger bool
+}
+
+func (c *ExamplesCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("examples")
+	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
+	fs.StringVar(&c.workingDir, "working-dir", "", "path to directory containing the 'traces' folder, it's used when the 'traces' is not specified")
+	fs.StringVar(&c.tracePath, "traces", "", "path to directory containing the traces of a successful test")
+	fs.StringVar(&c.swaggerPath, "swagger", "", "path to the .json swagger or the directory containing the swagger files")
+	fs.StringVar(&c.output, "output", "", "path to directory to save the examples, default to the 'examples' folder in the working directory")
+	fs.BoolVar(&c.updateSwagger, "update-swagger", false, "whether add the references to the generated examples in the 'x-ms-examples' of the operations in the swagger files")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c ExamplesCommand) Help() string {
+	helpText := `
+Usage: armstrong examples [-v] -swagger <path/dir to the swagger files> [-traces <dir to the traces>] [-working-dir <path to the working directory>] [-output <dir to the examples>] [-update-swagger]
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c ExamplesCommand) Synopsis() string {
+	return "Generate the x-ms-examples from the traces of a successful test"
+}
+
+func (c ExamplesCommand) Run(args []string) int {
+	f := c.flags()
+	if _, err := parseFlags(f, args); err != nil {
+		logrus.Errorf("Error parsing command-line flags: %s", err)
+		return 1
+	}
+	if c.verbose {
+		log.SetOutput(os.Stdout)
+		logrus.SetLevel(logrus.DebugLevel)
+		logrus.Infof("verbose mode enabled")
+	}
+	if c.swaggerPath == "" {
+		logrus.Error("'swagger' must be specified")
+		logrus.Error(c.Help())
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c ExamplesCommand) Execute() int {
+	wd, err := os.Getwd()
+	if err != nil {
+		logrus.Errorf("failed to get working directory: %+v", err)
+		return 1
+	}
+	if c.workingDir != "" {
+		wd, err = filepath.Abs(c.workingDir)
+		if err != nil {
+			logrus.Errorf("working directory is invalid: %+v", err)
+			return 1
+		}
+	}
+	traceDir := c.tracePath
+	if traceDir == "" {
+		traceDir = path.Join(wd, report.TraceLogDirName)
+	}
+	output := c.output
+	if output == "" {
+		output = path.Join(wd, "examples")
+	}
+	output, err = filepath.Abs(output)
+	if err != nil {
+		logrus.Errorf("output directory is invalid: %+v", err)
+		return 1
+	}
+
+	examples, err := swagger.NewExamplesFromTraces(traceDir, c.swaggerPath)
+	if err != nil {
+		logrus.Errorf("failed to generate examples from %s: %+v", traceDir, err)
+		return 1
+	}
+	logrus.Infof("found %d operation(s) in the traces", len(examples))
+	if len(examples) == 0 {
+		return 0
+	}
+
+	if err := os.MkdirAll(output, 0755); err != nil {
+		logrus.Errorf("error creating output dir %s: %+v", output, err)
+		return 1
+	}
+	for _, example := range examples {
+		content, err := example.MarshalIndent()
+		if err != nil {
+			logrus.Errorf("failed to marshal example of %s: %+v", example.OperationId, err)
+			return 1
+		}
+		exampleFile := path.Join(output, example.FileName())
+		if err := os.WriteFile(exampleFile, []byte(content), 0644); err != nil {
+			logrus.Errorf("failed to write %s: %+v", exampleFile, err)
+			return 1
+		}
+		logrus.Infof("example of %s is saved in %s", example.OperationId, exampleFile)
+
+		if c.updateSwagger {
+			if err := swagger.AddExampleRef(example, exampleFile); err != nil {
+				logrus.Errorf("failed to add the example reference to %s: %+v", example.SwaggerPath, err)
+				return 1
+			}
+			logrus.Infof("example reference is added to %s in %s", example.OperationId, example.SwaggerPath)
+		}
+	}
+	return 0
+}
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/armstrong/export"
+	"github.com/azure/armstrong/hcl"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	exportFormatHttp    = "http"
+	exportFormatPostman = "postman"
+)
+
+type ExportCommand struct {
+	verbose    bool
+	workingDir string
+	format     string
+	output     string
+}
+
+func (c *ExportCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("export")
+	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
+	fs.StringVar(&c.workingDir, "working-dir", "", "path to directory containing Terraform configuration files")
+	fs.StringVar(&c.format, "format", exportFormatHttp, "the format of the exported requests, allowed values: 'http'(REST client .http file) and 'postman'(Postman collection). Defaults to 'http'")
+	fs.StringVar(&c.output, "output", "", "path to the output file, default to 'armstrong.http' or 'armstrong.postman_collection.json' in the working directory")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c ExportCommand) Help() string {
+	helpText := `
+Usage: armstrong export [-v] [-working-dir <path to directory containing Terraform configuration files>] [-format http|postman] [-output <path to the output file>]
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c ExportCommand) Synopsis() string {
+	return "Export the azapi resources in given Terraform configuration as REST requests"
+}
+
+func (c ExportCommand) Run(args []string) int {
+	f := c.flags()
+	if _, err := parseFlags(f, args); err != nil {
+		logrus.Errorf("Error parsing command-line flags: %s", err)
+		return 1
+	}
+	if c.verbose {
+		log.SetOutput(os.Stdout)
+		logrus.SetLevel(logrus.DebugLevel)
+		logrus.Infof("verbose mode enabled")
+	}
+	if c.format != exportFormatHttp && c.format != exportFormatPostman {
+		logrus.Errorf("format %q is not supported, allowed values: %s, %s", c.format, exportFormatHttp, exportFormatPostman)
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c ExportCommand) Execute() int {
+	wd, err := os.Getwd()
+	if err != nil {
+		logrus.Errorf("failed to get working directory: %+v", err)
+		return 1
+	}
+	if c.workingDir != "" {
+		wd, err = filepath.Abs(c.workingDir)
+		if err != nil {
+			logrus.Errorf("working directory is invalid: %+v", err)
+			return 1
+		}
+	}
+
+	tfFiles, err := hcl.FindTfFiles(wd)
+	if err != nil {
+		logrus.Errorf("failed to find tf files for %q: %+v", wd, err)
+		return 1
+	}
+	if len(*tfFiles) == 0 {
+		logrus.Warnf("no tf file found in %q", wd)
+	}
+	logrus.Infof("find %v tf file(s) under %s", len(*tfFiles), wd)
+
+	azapiBlocks := make([]hcl.AzapiBlock, 0)
+	vars := make(map[string]hcl.Variable)
+	for _, tfFile := range *tfFiles {
+		f, errs := hcl.ParseHclFile(tfFile)
+		if errs != nil {
+			logrus.Errorf("failed to parse hcl file %q: %+v", tfFile, errs)
+			return 1
+		}
+
+		azapiBlocksInFile, errs := hcl.ParseAzapiBlocks(*f)
+		if errs != nil {
+			logrus.Errorf("failed to parse azapi blocks in %q: %+v", tfFile, errs)
+			return 1
+		}
+		azapiBlocks = append(azapiBlocks, *azapiBlocksInFile...)
+
+		varsInFile, errs := hcl.ParseVariables(*f)
+		if errs != nil {
+			logrus.Errorf("failed to parse variables in %q: %+v", tfFile, errs)
+			return 1
+		}
+		for name, v := range *varsInFile {
+			vars[name] = v
+		}
+	}
+	logrus.Infof("find %v azapi block(s) under %s", len(azapiBlocks), wd)
+
+	collection, err := export.NewCollection(filepath.Base(wd), azapiBlocks, vars)
+	if err != nil {
+		logrus.Errorf("failed to export requests: %+v", err)
+		return 1
+	}
+
+	var content string
+	output := c.output
+	switch c.format {
+	case exportFormatPostman:
+		if output == "" {
+			output = path.Join(wd, "armstrong.postman_collection.json")
+		}
+		content, err = export.PostmanCollection(*collection)
+		if err != nil {
+			logrus.Errorf("failed to build postman collection: %+v", err)
+			return 1
+		}
+	default:
+		if output == "" {
+			output = path.Join(wd, "armstrong.http")
+		}
+		content = export.HttpFile(*collection)
+	}
+
+	if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+		logrus.Errorf("failed to write %s: %+v", output, err)
+		return 1
+	}
+	logrus.Infof("%d requests are exported to %s", len(collection.Requests), output)
+	return 0
+}
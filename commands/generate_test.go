@@ -60,7 +60,7 @@ func runGenerateCommand(t *testing.T, args [][]string) {
 		}
 	}
 
-	terraform, err := tf.NewTerraform(wd, true)
+	terraform, err := tf.NewTerraform(wd, true, tf.Options{})
 	if err != nil {
 		t.Fatalf("[Error] error creating terraform executable: %+v\n", err)
 	}
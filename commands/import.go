@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"flag"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/armstrong/recording"
+	"github.com/azure/armstrong/redact"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/utils"
+	paltypes "github.com/ms-henglu/pal/types"
+	"github.com/sirupsen/logrus"
+)
+
+type ImportCommand struct {
+	verbose        bool
+	workingDir     string
+	recordingPath  string
+	harPath        string
+	swaggerPath    string
+	redactPatterns stringSliceFlag
+	suppressions   []report.Suppression
+}
+
+func (c *ImportCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("import")
+	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
+	fs.StringVar(&c.workingDir, "working-dir", "", "output path to the traces, the traces are saved in the 'traces' folder")
+	fs.StringVar(&c.recordingPath, "recording", "", "path or directory to the Azure SDK test-proxy recording files")
+	fs.StringVar(&c.harPath, "har", "", "path or directory to the HAR files")
+	fs.StringVar(&c.swaggerPath, "swagger", "", "path to the .json swagger which is being test, the swagger accuracy report and coverage report will be generated if it's specified")
+	fs.Var(&c.redactPatterns, "redact-pattern", "regular expression of the values which should be redacted in the traces, it can be specified multiple times")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c ImportCommand) Help() string {
+	helpText := `
+Usage: armstrong import [-v] [-recording <path/dir to the test-proxy recordings>] [-har <path/dir to the HAR files>] [-working-dir <output path to the traces>] [-swagger <path/dir to the swagger files>] [-redact-pattern <regular expression>]
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c ImportCommand) Synopsis() string {
+	return "Import the Azure SDK test-proxy recordings or HAR files as traces"
+}
+
+func (c ImportCommand) Run(args []string) int {
+	f := c.flags()
+	cfg, err := parseFlags(f, args)
+	if err != nil {
+		logrus.Errorf("Error parsing command-line flags: %s", err)
+		return 1
+	}
+	c.suppressions = cfg.Suppressions
+	if c.verbose {
+		log.SetOutput(os.Stdout)
+		logrus.SetLevel(logrus.DebugLevel)
+		logrus.Infof("verbose mode enabled")
+	}
+	if c.recordingPath == "" && c.harPath == "" {
+		logrus.Error("at least one of 'recording' and 'har' must be specified")
+		logrus.Error(c.Help())
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c ImportCommand) Execute() int {
+	wd, err := os.Getwd()
+	if err != nil {
+		logrus.Errorf("failed to get working directory: %+v", err)
+		return 1
+	}
+	if c.workingDir != "" {
+		wd, err = filepath.Abs(c.workingDir)
+		if err != nil {
+			logrus.Errorf("working directory is invalid: %+v", err)
+			return 1
+		}
+	}
+
+	redactor, err := redact.NewRedactor(c.swaggerPath, c.redactPatterns)
+	if err != nil {
+		logrus.Errorf("failed to create redactor: %+v", err)
+		return 1
+	}
+
+	traces := make([]paltypes.RequestTrace, 0)
+	for _, input := range []struct {
+		path      string
+		extension string
+	}{
+		{path: c.recordingPath, extension: ".json"},
+		{path: c.harPath, extension: ".har"},
+	} {
+		if input.path == "" {
+			continue
+		}
+		files, err := utils.ListFiles(input.path, input.extension, -1)
+		if err != nil {
+			logrus.Errorf("failed to list files in %s: %+v", input.path, err)
+			return 1
+		}
+		for _, file := range files {
+			logrus.Debugf("loading %s...", file)
+			tracesInFile, err := recording.LoadFile(file)
+			if err != nil {
+				logrus.Warnf("failed to load %s, it's skipped: %+v", file, err)
+				continue
+			}
+			traces = append(traces, tracesInFile...)
+		}
+		logrus.Infof("loaded %d file(s) from %s", len(files), input.path)
+	}
+	logrus.Infof("found %d requests", len(traces))
+
+	traceDir := path.Join(wd, "traces")
+	if err := os.MkdirAll(traceDir, 0755); err != nil {
+		logrus.Errorf("error creating trace dir %s: %+v", traceDir, err)
+		return 1
+	}
+	report.StoreOavTraffic(redactor.Traces(traces), traceDir)
+	logrus.Infof("traces are saved in %s", traceDir)
+
+	if c.swaggerPath != "" {
+		if _, err := report.StoreSwaggerReports(traceDir, c.swaggerPath, wd, report.NewSuppressions(report.LoadSuppressions(wd, c.suppressions)), logrus.StandardLogger()); err != nil {
+			return 1
+		}
+	}
+	return 0
+}
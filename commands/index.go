@@ -0,0 +1,186 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/sirupsen/logrus"
+)
+
+type IndexUpdateCommand struct{}
+
+func (c *IndexUpdateCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("index update")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c IndexUpdateCommand) Help() string {
+	helpText := `
+Usage: armstrong index update
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c IndexUpdateCommand) Synopsis() string {
+	return "Download the latest online swagger index into the index cache"
+}
+
+func (c IndexUpdateCommand) Run(args []string) int {
+	f := c.flags()
+	if _, err := parseFlags(f, args); err != nil {
+		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c IndexUpdateCommand) Execute() int {
+	cache, err := coverage.DefaultIndexCache()
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+	info, err := cache.Update()
+	if err != nil {
+		logrus.Errorf("failed to update the swagger index: %+v", err)
+		return 1
+	}
+	logrus.Infof("the swagger index of commit %s is saved to %s", info.Commit, info.Path)
+	return 0
+}
+
+type IndexBuildCommand struct {
+	swaggerRepoPath string
+}
+
+func (c *IndexBuildCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("index build")
+	fs.StringVar(&c.swaggerRepoPath, "swagger-repo", "", "path to the swagger repo specification directory, the index is keyed by the HEAD commit of the repo")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c IndexBuildCommand) Help() string {
+	helpText := `
+Usage: armstrong index build -swagger-repo <path to the swagger repo specification directory>
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c IndexBuildCommand) Synopsis() string {
+	return "Build the swagger index from the local swagger repo into the index cache"
+}
+
+func (c IndexBuildCommand) Run(args []string) int {
+	f := c.flags()
+	if _, err := parseFlags(f, args); err != nil {
+		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
+		return 1
+	}
+	if c.swaggerRepoPath == "" {
+		logrus.Error("swagger repo path is required")
+		logrus.Infof(c.Help())
+		return 1
+	}
+	return c.Execute()
+}
+
+func (c IndexBuildCommand) Execute() int {
+	cache, err := coverage.DefaultIndexCache()
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+	logrus.Infof("building index from local swagger %s, only the changed swagger files are parsed", c.swaggerRepoPath)
+	info, err := cache.Build(c.swaggerRepoPath)
+	if err != nil {
+		logrus.Errorf("failed to build the swagger index: %+v", err)
+		return 1
+	}
+	logrus.Infof("the swagger index of commit %s is saved to %s", info.Commit, info.Path)
+	return 0
+}
+
+type IndexShowCommand struct {
+	workingDir string
+}
+
+func (c *IndexShowCommand) flags() *flag.FlagSet {
+	fs := defaultFlagSet("index show")
+	fs.StringVar(&c.workingDir, "working-dir", "", "path to the directory where the configuration file is searched from, the pinned commit in it is shown")
+	fs.Usage = func() { logrus.Error(c.Help()) }
+	return fs
+}
+
+func (c IndexShowCommand) Help() string {
+	helpText := `
+Usage: armstrong index show [-working-dir <path to the directory>]
+` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c IndexShowCommand) Synopsis() string {
+	return "Show the swagger indexes in the index cache"
+}
+
+func (c IndexShowCommand) Run(args []string) int {
+	f := c.flags()
+	cfg, err := parseFlags(f, args)
+	if err != nil {
+		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
+		return 1
+	}
+	cache, err := coverage.DefaultIndexCache()
+	if err != nil {
+		logrus.Error(err)
+		return 1
+	}
+	infos, err := cache.List()
+	if err != nil {
+		logrus.Errorf("failed to list the swagger indexes in %s: %+v", cache.Dir, err)
+		return 1
+	}
+	fmt.Print(indexCacheContent(cache.Dir, infos, cfg.Swagger.IndexCommit))
+	return 0
+}
+
+// indexCacheContent describes the cached indexes, the current one, the pinned one and the stale ones are marked
+func indexCacheContent(dir string, infos []coverage.IndexInfo, pinnedCommit string) string {
+	lines := []string{fmt.Sprintf("index cache: %s", dir)}
+	if pinnedCommit != "" {
+		lines = append(lines, fmt.Sprintf("pinned commit: %s", pinnedCommit))
+	}
+	if len(infos) == 0 {
+		lines = append(lines, "no swagger index is cached, run `armstrong index update` or `armstrong index build` to add one")
+	}
+	pinnedFound := false
+	for _, info := range infos {
+		marks := make([]string, 0)
+		if info.Current {
+			marks = append(marks, "current")
+		}
+		if info.Commit == pinnedCommit {
+			marks = append(marks, "pinned")
+			pinnedFound = true
+		}
+		if info.IsStale() {
+			marks = append(marks, "stale")
+		}
+		line := fmt.Sprintf("- %s, updated at %s from %s", info.Commit, info.UpdatedAt.Local().Format(time.DateTime), info.Source)
+		if len(marks) != 0 {
+			line += fmt.Sprintf(" (%s)", strings.Join(marks, ", "))
+		}
+		lines = append(lines, line)
+	}
+	if pinnedCommit != "" && !pinnedFound {
+		lines = append(lines, fmt.Sprintf("the pinned commit %s is not cached, run `armstrong index build` with the swagger repo checked out at the commit", pinnedCommit))
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -3,17 +3,19 @@ package commands
 import (
 	"flag"
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
+	"github.com/azure/armstrong/cloud"
 	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/runner"
 	"github.com/sirupsen/logrus"
 )
 
 type ReportCommand struct {
-	workingDir  string
-	swaggerPath string
+	workingDir   string
+	swaggerPath  string
+	cloud        cloud.Environment
+	suppressions []report.Suppression
 }
 
 func (c *ReportCommand) flags() *flag.FlagSet {
@@ -38,10 +40,13 @@ func (c ReportCommand) Synopsis() string {
 
 func (c ReportCommand) Run(args []string) int {
 	f := c.flags()
-	if err := f.Parse(args); err != nil {
+	cfg, err := parseFlags(f, args)
+	if err != nil {
 		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
 		return 1
 	}
+	c.cloud = cfg.Cloud
+	c.suppressions = cfg.Suppressions
 
 	if c.swaggerPath == "" {
 		logrus.Error("swagger path is required")
@@ -52,24 +57,15 @@ func (c ReportCommand) Run(args []string) int {
 }
 
 func (c ReportCommand) Execute() int {
-	wd, err := os.Getwd()
+	_, err := runner.New(logrus.StandardLogger()).Report(runner.ReportOptions{
+		WorkingDir:   c.workingDir,
+		SwaggerPath:  c.swaggerPath,
+		Cloud:        c.cloud,
+		Suppressions: c.suppressions,
+	})
 	if err != nil {
-		logrus.Error(fmt.Sprintf("failed to get working directory: %+v", err))
+		logrus.Error(err)
 		return 1
 	}
-
-	if c.workingDir != "" {
-		wd, err = filepath.Abs(c.workingDir)
-		if err != nil {
-			logrus.Error(fmt.Sprintf("working directory is invalid: %+v", err))
-			return 1
-		}
-	}
-
-	if err := report.GenerateApiTestReports(wd, c.swaggerPath); err != nil {
-		logrus.Errorf("failed to generate swagger accuracy report: %+v", err)
-		return 1
-	}
-
 	return 0
 }
@@ -2,10 +2,21 @@ package commands
 
 import (
 	"flag"
+	"fmt"
 	"io"
+	"os"
+	"strconv"
 	"strings"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/tf"
 )
 
+// Version is the armstrong version shown in the reports, it's set by the main package
+var Version = "dev"
+
 func defaultFlagSet(cmdName string) *flag.FlagSet {
 	f := flag.NewFlagSet(cmdName, flag.ContinueOnError)
 	f.SetOutput(io.Discard)
@@ -27,3 +38,89 @@ func helpForFlags(fs *flag.FlagSet) string {
 
 	return buf.String()
 }
+
+// parseFlags parses the command line arguments, the flags which are not specified are set by the project configuration,
+// which is discovered from the working directory upward
+func parseFlags(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	dir := "."
+	if f := fs.Lookup("working-dir"); f != nil && f.Value.String() != "" {
+		dir = f.Value.String()
+	}
+	cfg, err := config.Discover(dir)
+	if err != nil {
+		return nil, fmt.Errorf("loading the project configuration: %+v", err)
+	}
+	if err := cloud.Set(cfg.Cloud); err != nil {
+		return nil, fmt.Errorf("selecting the cloud: %+v", err)
+	}
+	coverage.SetIndexCommit(cfg.Swagger.IndexCommit)
+	specified := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		specified[f.Name] = true
+	})
+	for name, values := range cfg.FlagValues(fs.Name()) {
+		if specified[name] || fs.Lookup(name) == nil {
+			continue
+		}
+		for _, value := range values {
+			if err := fs.Set(name, value); err != nil {
+				return nil, fmt.Errorf("setting -%s from %s: %+v", name, cfg.Path, err)
+			}
+		}
+	}
+	return cfg, nil
+}
+
+// commandLine returns the full command line which starts armstrong, the arguments which contain spaces are quoted
+func commandLine() string {
+	args := make([]string, 0)
+	for _, arg := range os.Args {
+		if strings.ContainsAny(arg, " \t\"") {
+			arg = strconv.Quote(arg)
+		}
+		args = append(args, arg)
+	}
+	return strings.Join(args, " ")
+}
+
+// stringSliceFlag is a flag which can be specified multiple times, e.g., -redact-pattern a -redact-pattern b
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// terraformFlags are the flags which configure the terraform executable and the provider installation, the environment variables are used when they're not specified
+type terraformFlags struct {
+	flavor       string
+	version      string
+	execPath     string
+	pluginMirror string
+	devOverrides stringSliceFlag
+}
+
+func (f *terraformFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.flavor, "terraform-flavor", "", "terraform or tofu, default is terraform, it can also be set by "+tf.FlavorEnvVar)
+	fs.StringVar(&f.version, "terraform-version", "", "version constraint of the terraform executable, e.g., 1.9.5 or \">= 1.5.0\", it can also be set by "+tf.VersionEnvVar)
+	fs.StringVar(&f.execPath, "terraform-path", "", "path to the terraform executable, it can also be set by "+tf.ExecPathEnvVar)
+	fs.StringVar(&f.pluginMirror, "plugin-mirror", "", "path to the filesystem mirror directory, the providers are installed from it instead of the registry, it can also be set by "+tf.PluginMirrorEnvVar)
+	fs.Var(&f.devOverrides, "dev-override", "locally built provider in the format of <provider>=<directory>, e.g., azapi=/path/to/azapi, it can be specified multiple times, it can also be set by "+tf.DevOverridesEnvVar)
+}
+
+func (f terraformFlags) options() tf.Options {
+	return tf.Options{
+		Flavor:       f.flavor,
+		Version:      f.version,
+		ExecPath:     f.execPath,
+		PluginMirror: f.pluginMirror,
+		DevOverrides: f.devOverrides,
+	}
+}
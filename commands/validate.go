@@ -15,19 +15,21 @@ import (
 type ValidateCommand struct {
 	verbose    bool
 	workingDir string
+	terraform  terraformFlags
 }
 
 func (c *ValidateCommand) flags() *flag.FlagSet {
 	fs := defaultFlagSet("validate")
 	fs.StringVar(&c.workingDir, "working-dir", "", "path to Terraform configuration files")
 	fs.BoolVar(&c.verbose, "v", false, "whether show terraform logs")
+	c.terraform.register(fs)
 	fs.Usage = func() { logrus.Error(c.Help()) }
 	return fs
 }
 
 func (c ValidateCommand) Help() string {
 	helpText := `
-Usage: armstrong validate [-v] [-working-dir <path to Terraform configuration files>]
+Usage: armstrong validate [-v] [-working-dir <path to Terraform configuration files>] [-terraform-flavor <terraform or tofu>] [-terraform-version <version constraint>] [-terraform-path <path to the executable>] [-plugin-mirror <path to the mirror directory>] [-dev-override <provider>=<directory>]
 ` + c.Synopsis() + "\n\n" + helpForFlags(c.flags())
 
 	return strings.TrimSpace(helpText)
@@ -39,7 +41,7 @@ func (c ValidateCommand) Synopsis() string {
 
 func (c ValidateCommand) Run(args []string) int {
 	f := c.flags()
-	if err := f.Parse(args); err != nil {
+	if _, err := parseFlags(f, args); err != nil {
 		logrus.Error(fmt.Sprintf("Error parsing command-line flags: %s", err))
 		return 1
 	}
@@ -64,9 +66,10 @@ func (c ValidateCommand) Execute() int {
 			return 1
 		}
 	}
-	terraform, err := tf.NewTerraform(wd, true)
+	terraform, err := tf.NewTerraform(wd, true, c.terraform.options())
 	if err != nil {
-		logrus.Fatalf("creating terraform executable: %+v\n", err)
+		logrus.Errorf("creating terraform executable: %+v", err)
+		return 1
 	}
 
 	logrus.Infof("running terraform init...")
@@ -75,7 +78,8 @@ func (c ValidateCommand) Execute() int {
 	logrus.Infof("running terraform plan to check the changes...")
 	plan, err := terraform.Plan()
 	if err != nil {
-		logrus.Fatalf("running terraform plan: %+v", err)
+		logrus.Errorf("running terraform plan: %+v", err)
+		return 1
 	}
 
 	_ = tf.GetChanges(plan)
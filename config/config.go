@@ -0,0 +1,291 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// FileNames are the names of the project configuration files, they're searched in this order in each directory
+var FileNames = []string{"armstrong.yaml", "armstrong.yml", "armstrong.hcl"}
+
+// pathFlags are the flags whose values are paths, the relative paths in the configuration file are relative to the directory of the file
+var pathFlags = map[string]bool{
+	"working-dir":        true,
+	"swagger":            true,
+	"swagger-repo":       true,
+	"swagger-index-file": true,
+	"terraform-path":     true,
+	"plugin-mirror":      true,
+	"known-errors":       true,
+	"output":             true,
+	"output-dir":         true,
+	"traces":             true,
+	"path":               true,
+	"arm-template":       true,
+	"readme":             true,
+	"recording":          true,
+	"har":                true,
+}
+
+// Config is the project configuration, it holds the defaults of the command flags and the settings which can't be specified by the flags.
+// The flags specified in the command line take precedence over the configuration.
+type Config struct {
+	// Path is the configuration file, it's empty if no configuration file is found
+	Path string `yaml:"-"`
+
+	Swagger   Swagger   `yaml:"swagger,omitempty"`
+	Terraform Terraform `yaml:"terraform,omitempty"`
+	Defaults  Defaults  `yaml:"defaults,omitempty"`
+	// Cloud selects the ARM endpoint, the provider environment and the default location, the ARM_ENVIRONMENT environment variable is used if it's not set
+	Cloud cloud.Environment `yaml:"cloud,omitempty"`
+	// Dependencies are the terraform configuration files which override the built-in dependencies, keyed by the ARM resource types, e.g., Microsoft.Network/virtualNetworks
+	Dependencies map[string]string `yaml:"dependencies,omitempty"`
+	// Suppressions are the findings which are not reported by the diff, error, coverage, credscan and accuracy reports, they're merged with the ones in ApiTestConfig.json
+	Suppressions []report.Suppression `yaml:"suppressions,omitempty"`
+	Thresholds   Thresholds           `yaml:"thresholds,omitempty"`
+	// Commands are the defaults of the command flags, keyed by the command names and the flag names, e.g., test: {destroy-after-test: true}
+	Commands map[string]map[string]interface{} `yaml:"commands,omitempty"`
+}
+
+// Swagger is the location of the swagger specs, it's used by the commands which support the -swagger, -swagger-repo and -swagger-index-file flags
+type Swagger struct {
+	Path      string `yaml:"path,omitempty"`
+	Repo      string `yaml:"repo,omitempty"`
+	IndexFile string `yaml:"indexFile,omitempty"`
+	// IndexCommit pins the commit of the swagger index in the index cache, which is used when no index file is specified
+	IndexCommit string `yaml:"indexCommit,omitempty"`
+}
+
+// Terraform is the terraform executable and the provider installation, it's used by the commands which support the terraform flags
+type Terraform struct {
+	Flavor       string   `yaml:"flavor,omitempty"`
+	Version      string   `yaml:"version,omitempty"`
+	Path         string   `yaml:"path,omitempty"`
+	PluginMirror string   `yaml:"pluginMirror,omitempty"`
+	DevOverrides []string `yaml:"devOverrides,omitempty"`
+}
+
+// Defaults are the defaults of the variables in the generated terraform configurations
+type Defaults struct {
+	// NamePrefix is the prefix of the resource_name variable, a random number is appended to it
+	NamePrefix string `yaml:"namePrefix,omitempty"`
+	Location   string `yaml:"location,omitempty"`
+}
+
+// Thresholds fail the test command when the results are worse than them, the unset thresholds are not checked
+type Thresholds struct {
+	// Coverage is the minimum percentage of the covered properties of each resource type
+	Coverage *float64 `yaml:"coverage,omitempty"`
+	// Errors is the maximum number of the errors when creating the testing resources
+	Errors *int `yaml:"errors,omitempty"`
+	// ApiIssues is the maximum number of the API issues
+	ApiIssues *int `yaml:"apiIssues,omitempty"`
+}
+
+// IsSet returns whether any threshold is set
+func (t Thresholds) IsSet() bool {
+	return t.Coverage != nil || t.Errors != nil || t.ApiIssues != nil
+}
+
+// Check returns the violations of the thresholds, it returns nil if all thresholds are met
+func (t Thresholds) Check(coverages []types.CoverageSummary, errorCount int, apiIssueCount int) []string {
+	var out []string
+	if t.Coverage != nil {
+		for _, c := range coverages {
+			percentage := 0.0
+			if c.TotalCount != 0 {
+				percentage = float64(c.CoveredCount) * 100 / float64(c.TotalCount)
+			}
+			if percentage < *t.Coverage {
+				out = append(out, fmt.Sprintf("the coverage of %s is %.1f%%, which is lower than %.1f%%", c.DisplayName, percentage, *t.Coverage))
+			}
+		}
+	}
+	if t.Errors != nil && errorCount > *t.Errors {
+		out = append(out, fmt.Sprintf("%d errors, which is more than %d", errorCount, *t.Errors))
+	}
+	if t.ApiIssues != nil && apiIssueCount > *t.ApiIssues {
+		out = append(out, fmt.Sprintf("%d API issues, which is more than %d", apiIssueCount, *t.ApiIssues))
+	}
+	return out
+}
+
+// Discover returns the configuration in the directory or its nearest parent directory up to the root of the git repo,
+// the parent directories are searched up to the filesystem root if it's not in a git repo. It returns an empty configuration if none is found.
+func Discover(dir string) (*Config, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	root := gitRoot(dir)
+	for {
+		for _, name := range FileNames {
+			filename := filepath.Join(dir, name)
+			if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+				return Load(filename)
+			}
+		}
+		parent := filepath.Dir(dir)
+		if dir == root || parent == dir {
+			return &Config{}, nil
+		}
+		dir = parent
+	}
+}
+
+// gitRoot returns the nearest directory which contains the .git folder or file, it returns empty string if it's not in a git repo
+func gitRoot(dir string) string {
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// Load returns the configuration in the YAML or HCL file, the relative paths are resolved against the directory of the file
+func Load(filename string) (*Config, error) {
+	filename, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(filename), ".hcl") {
+		data, err = hclToYaml(data, filename)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var config Config
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("parsing %s: %+v", filename, err)
+	}
+	config.Path = filename
+	config.resolvePaths(filepath.Dir(filename))
+	logrus.Debugf("loaded the configuration from %s", filename)
+	return &config, nil
+}
+
+func (c *Config) resolvePaths(dir string) {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+	c.Swagger.Path = resolve(c.Swagger.Path)
+	c.Swagger.Repo = resolve(c.Swagger.Repo)
+	c.Swagger.IndexFile = resolve(c.Swagger.IndexFile)
+	c.Terraform.Path = resolve(c.Terraform.Path)
+	c.Terraform.PluginMirror = resolve(c.Terraform.PluginMirror)
+	for i, devOverride := range c.Terraform.DevOverrides {
+		if parts := strings.SplitN(devOverride, "=", 2); len(parts) == 2 {
+			c.Terraform.DevOverrides[i] = parts[0] + "=" + resolve(parts[1])
+		}
+	}
+	for resourceType, filename := range c.Dependencies {
+		c.Dependencies[resourceType] = resolve(filename)
+	}
+	for _, flags := range c.Commands {
+		for name, value := range flags {
+			if !pathFlags[name] {
+				continue
+			}
+			switch v := value.(type) {
+			case string:
+				flags[name] = resolve(v)
+			case []interface{}:
+				for i := range v {
+					if s, ok := v[i].(string); ok {
+						v[i] = resolve(s)
+					}
+				}
+			}
+		}
+	}
+}
+
+// FlagValues returns the values of the flags of the command, the settings of the command take precedence over the shared settings.
+// The flags which can be specified multiple times have multiple values.
+func (c Config) FlagValues(command string) map[string][]string {
+	out := make(map[string][]string)
+	set := func(name string, values ...string) {
+		if len(values) != 0 && values[0] != "" {
+			out[name] = values
+		}
+	}
+	// the swagger path selects the input mode of the generate command, so it's only set by the settings of the generate command
+	if command != "generate" {
+		set("swagger", c.Swagger.Path)
+	}
+	set("swagger-repo", c.Swagger.Repo)
+	set("swagger-index-file", c.Swagger.IndexFile)
+	set("terraform-flavor", c.Terraform.Flavor)
+	set("terraform-version", c.Terraform.Version)
+	set("terraform-path", c.Terraform.Path)
+	set("plugin-mirror", c.Terraform.PluginMirror)
+	set("dev-override", c.Terraform.DevOverrides...)
+	for name, value := range c.Commands[command] {
+		switch v := value.(type) {
+		case []interface{}:
+			values := make([]string, 0)
+			for _, item := range v {
+				values = append(values, fmt.Sprintf("%v", item))
+			}
+			out[name] = values
+		case nil:
+			delete(out, name)
+		default:
+			out[name] = []string{fmt.Sprintf("%v", v)}
+		}
+	}
+	return out
+}
+
+// DependencyConfigs returns the content of the terraform configuration files which override the built-in dependencies, keyed by the ARM resource types
+func (c Config) DependencyConfigs() (map[string]string, error) {
+	out := make(map[string]string)
+	for resourceType, filename := range c.Dependencies {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return nil, fmt.Errorf("reading the dependency of %s: %+v", resourceType, err)
+		}
+		out[resourceType] = string(data)
+	}
+	return out, nil
+}
+
+// String returns the configuration in YAML format
+func (c Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// sortedKeys returns the keys of the map in alphabetical order
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,160 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/types"
+)
+
+func Test_Discover(t *testing.T) {
+	testcases := []struct {
+		Name string
+		Dir  string
+	}{
+		{
+			Name: "yaml in the parent directory",
+			Dir:  filepath.Join("testdata", "yaml", "project", "sub"),
+		},
+		{
+			Name: "hcl in the directory",
+			Dir:  filepath.Join("testdata", "hcl"),
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("testcase: %s", testcase.Name)
+		cfg, err := config.Discover(testcase.Dir)
+		if err != nil {
+			t.Fatalf("discovering the configuration: %+v", err)
+		}
+		if cfg.Path == "" {
+			t.Fatalf("expect the configuration file to be found")
+		}
+		dir := filepath.Dir(cfg.Path)
+
+		if expected := filepath.Join(dir, "specification", "account.json"); cfg.Swagger.Path != expected {
+			t.Errorf("expect swagger path %s, but got %s", expected, cfg.Swagger.Path)
+		}
+		if expected := filepath.Join(dir, "dependencies", "vnet.tf"); cfg.Dependencies["Microsoft.Network/virtualNetworks"] != expected {
+			t.Errorf("expect dependency %s, but got %s", expected, cfg.Dependencies["Microsoft.Network/virtualNetworks"])
+		}
+		if cfg.Defaults.NamePrefix != "armtest" || cfg.Defaults.Location != "eastus" {
+			t.Errorf("expect the defaults armtest and eastus, but got %+v", cfg.Defaults)
+		}
+		if cfg.Cloud.Name != "china" {
+			t.Errorf("expect the cloud china, but got %+v", cfg.Cloud)
+		}
+		if len(cfg.Suppressions) != 1 || cfg.Suppressions[0].Operation != "Accounts_Delete" {
+			t.Errorf("expect the suppression of Accounts_Delete, but got %+v", cfg.Suppressions)
+		}
+
+		expectedFlags := map[string]map[string][]string{
+			"test": {
+				"swagger":            {filepath.Join(dir, "specification", "account.json")},
+				"swagger-index-file": {filepath.Join(dir, "index.json")},
+				"terraform-flavor":   {"terraform"},
+				"dev-override":       {"azapi=" + filepath.Join(dir, "providers", "azapi")},
+				"destroy-after-test": {"true"},
+			},
+			"import": {
+				"swagger-index-file": {filepath.Join(dir, "index.json")},
+				"terraform-flavor":   {"tofu"},
+				"dev-override":       {"azapi=" + filepath.Join(dir, "providers", "azapi")},
+			},
+			"cleanup": {
+				"swagger":            {filepath.Join(dir, "specification", "account.json")},
+				"swagger-index-file": {filepath.Join(dir, "index.json")},
+				"terraform-flavor":   {"tofu"},
+				"dev-override":       {"azapi=" + filepath.Join(dir, "providers", "azapi")},
+				"redact-pattern":     {"secret-[0-9]+", "token-[a-z]+"},
+			},
+		}
+		for command, expected := range expectedFlags {
+			if actual := cfg.FlagValues(command); !reflect.DeepEqual(actual, expected) {
+				t.Errorf("expect the flags of %s to be %v, but got %v", command, expected, actual)
+			}
+		}
+		if _, ok := cfg.FlagValues("generate")["swagger"]; ok {
+			t.Errorf("expect the swagger path not to be set for the generate command")
+		}
+	}
+}
+
+func Test_Discover_NotFound(t *testing.T) {
+	cfg, err := config.Discover(os.TempDir())
+	if err != nil {
+		t.Fatalf("discovering the configuration: %+v", err)
+	}
+	if cfg.Path != "" {
+		t.Skipf("a configuration file exists in the parent directories of %s", os.TempDir())
+	}
+	if len(cfg.FlagValues("test")) != 0 {
+		t.Errorf("expect no flags, but got %v", cfg.FlagValues("test"))
+	}
+}
+
+func Test_Thresholds(t *testing.T) {
+	coverage, errors := 80.0, 0
+	thresholds := config.Thresholds{Coverage: &coverage, Errors: &errors}
+	violations := thresholds.Check([]types.CoverageSummary{
+		{DisplayName: "Microsoft.Automation/automationAccounts@2022-08-08", CoveredCount: 9, TotalCount: 10},
+		{DisplayName: "Microsoft.Automation/automationAccounts/runbooks@2022-08-08", CoveredCount: 1, TotalCount: 4},
+	}, 1, 3)
+	expected := []string{
+		"the coverage of Microsoft.Automation/automationAccounts/runbooks@2022-08-08 is 25.0%, which is lower than 80.0%",
+		"1 errors, which is more than 0",
+	}
+	if strings.Join(violations, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expect violations %v, but got %v", expected, violations)
+	}
+	if (config.Thresholds{}).IsSet() || len((config.Thresholds{}).Check(nil, 10, 10)) != 0 {
+		t.Errorf("expect the unset thresholds not to be checked")
+	}
+}
+
+func Test_Discover_StopAtGitRoot(t *testing.T) {
+	dir := t.TempDir()
+	project := filepath.Join(dir, "project")
+	sub := filepath.Join(project, "sub")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "armstrong.yaml"), []byte("defaults:\n  location: westus\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := config.Discover(sub)
+	if err != nil {
+		t.Fatalf("discovering the configuration: %+v", err)
+	}
+	if cfg.Path != filepath.Join(dir, "armstrong.yaml") {
+		t.Errorf("expect the configuration in the parent directories to be found outside a git repo, but got %s", cfg.Path)
+	}
+
+	if err := os.Mkdir(filepath.Join(project, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = config.Discover(sub)
+	if err != nil {
+		t.Fatalf("discovering the configuration: %+v", err)
+	}
+	if cfg.Path != "" {
+		t.Errorf("expect the configuration outside the git repo not to be found, but got %s", cfg.Path)
+	}
+
+	if err := os.WriteFile(filepath.Join(project, "armstrong.yaml"), []byte("defaults:\n  location: eastus\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = config.Discover(sub)
+	if err != nil {
+		t.Fatalf("discovering the configuration: %+v", err)
+	}
+	if cfg.Path != filepath.Join(project, "armstrong.yaml") {
+		t.Errorf("expect the configuration at the git root to be found, but got %s", cfg.Path)
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+	"gopkg.in/yaml.v3"
+)
+
+// hclToYaml converts the HCL configuration to YAML, so both formats share the same schema:
+// the attributes are converted to the fields, the blocks without labels are converted to the objects,
+// and the blocks with a label are converted to the objects keyed by the labels, e.g., commands "test" { ... }
+func hclToYaml(data []byte, filename string) ([]byte, error) {
+	file, diags := hclsyntax.ParseConfig(data, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+	body, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return nil, fmt.Errorf("parsing %s: unexpected body type %T", filename, file.Body)
+	}
+	out, err := hclBodyToMap(body)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %+v", filename, err)
+	}
+	return yaml.Marshal(out)
+}
+
+func hclBodyToMap(body *hclsyntax.Body) (map[string]interface{}, error) {
+	out := make(map[string]interface{})
+	for _, name := range sortedKeys(body.Attributes) {
+		attribute := body.Attributes[name]
+		value, diags := attribute.Expr.Value(nil)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		data, err := ctyjson.Marshal(value, value.Type())
+		if err != nil {
+			return nil, fmt.Errorf("attribute %s: %+v", name, err)
+		}
+		var v interface{}
+		if err := json.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("attribute %s: %+v", name, err)
+		}
+		out[name] = v
+	}
+	for _, block := range body.Blocks {
+		value, err := hclBodyToMap(block.Body)
+		if err != nil {
+			return nil, err
+		}
+		switch len(block.Labels) {
+		case 0:
+			out[block.Type] = value
+		case 1:
+			labeled, ok := out[block.Type].(map[string]interface{})
+			if !ok {
+				labeled = make(map[string]interface{})
+				out[block.Type] = labeled
+			}
+			labeled[block.Labels[0]] = value
+		default:
+			return nil, fmt.Errorf("block %s: at most one label is supported", block.Type)
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,49 @@
+swagger {
+  path      = "./specification/account.json"
+  indexFile = "./index.json"
+}
+
+terraform {
+  flavor       = "tofu"
+  devOverrides = ["azapi=./providers/azapi"]
+}
+
+defaults {
+  namePrefix = "armtest"
+  location   = "eastus"
+}
+
+cloud {
+  name = "china"
+}
+
+dependencies = {
+  "Microsoft.Network/virtualNetworks" = "./dependencies/vnet.tf"
+}
+
+suppressions = [
+  {
+    rule      = "OPERATION_NOT_TEST"
+    file      = "account.json"
+    operation = "Accounts_Delete"
+    reason    = "the delete operation is tested by the cleanup command"
+  },
+]
+
+thresholds {
+  coverage = 80
+  errors   = 0
+}
+
+commands "test" {
+  destroy-after-test = true
+  terraform-flavor   = "terraform"
+}
+
+commands "import" {
+  swagger = null
+}
+
+commands "cleanup" {
+  redact-pattern = ["secret-[0-9]+", "token-[a-z]+"]
+}
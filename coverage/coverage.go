@@ -23,7 +23,8 @@ type Model struct {
 	IsReadOnly              bool               `json:"IsReadOnly,omitempty"`
 	IsRequired              bool               `json:"IsRequired,omitempty"`
 	IsRoot                  bool               `json:"IsRoot,omitempty"`
-	IsSecret                bool               `json:"IsSecret,omitempty"` // related to x-ms-secret
+	IsSecret                bool               `json:"IsSecret,omitempty"`     // related to x-ms-secret
+	IsSuppressed            bool               `json:"IsSuppressed,omitempty"` // the uncovered property is suppressed, it's not counted like the read-only properties
 	Item                    *Model             `json:"Item,omitempty"`
 	ModelName               string             `json:"ModelName,omitempty"`
 	Properties              *map[string]*Model `json:"Properties,omitempty"`
@@ -32,7 +33,7 @@ type Model struct {
 	SourceFile              string             `json:"SourceFile,omitempty"`
 	TotalCount              int                `json:"TotalCount,omitempty"`
 	Type                    *string            `json:"Type,omitempty"`
-	Variants                *map[string]*Model `json:"Variants,omitempty"`    // variant model name is used as key, in case x-ms-discriminator-value is not available
+	Variants                *map[string]*Model `json:"Variants,omitempty"`    // variant model name is used as key, in case x-ms-discriminator-value is not available, the swagger file name is prefixed if the model name is used by another variant
 	VariantType             *string            `json:"VariantType,omitempty"` // the x-ms-discriminator-value of the variant model if exists, otherwise model name
 }
 
@@ -61,32 +62,12 @@ func (m *Model) CredScan(root interface{}, secrets map[string]string) {
 
 	case map[string]interface{}:
 		isMatchProperty := true
-		if m.Discriminator != nil && m.Variants != nil {
-		Loop:
-			for k, v := range value {
-				if k == *m.Discriminator {
-					if m.ModelName == v.(string) {
-						break
-					}
-					if m.VariantType != nil && *m.VariantType == v.(string) {
-						break
-					}
-					if variant, ok := (*m.Variants)[v.(string)]; ok {
-						isMatchProperty = false
-						variant.CredScan(value, secrets)
-
-						break
-					}
-					for _, variant := range *m.Variants {
-						if variant.VariantType != nil && *variant.VariantType == v.(string) {
-							isMatchProperty = false
-							variant.CredScan(value, secrets)
-
-							break Loop
-						}
-					}
-					logrus.Errorf("unexpected variant %s in %s", v.(string), m.Identifier)
-				}
+		if variantType, ok := m.discriminatorValue(value); ok {
+			if variant := m.findVariant(variantType); variant != nil {
+				isMatchProperty = false
+				variant.CredScan(value, secrets)
+			} else {
+				logrus.Errorf("unexpected variant %s in %s", variantType, m.Identifier)
 			}
 		}
 
@@ -119,6 +100,41 @@ func (m *Model) CredScan(root interface{}, secrets map[string]string) {
 	}
 }
 
+// discriminatorValue returns the discriminator value in the object if the model is polymorphic,
+// it returns false if the value is the model itself, which is matched by the model name or the variant type
+func (m *Model) discriminatorValue(value map[string]interface{}) (string, bool) {
+	if m.Discriminator == nil || m.Variants == nil {
+		return "", false
+	}
+	variantType, ok := value[*m.Discriminator].(string)
+	if !ok || m.ModelName == variantType || (m.VariantType != nil && *m.VariantType == variantType) {
+		return "", false
+	}
+	return variantType, true
+}
+
+// findVariant returns the variant whose model name or variant type is the discriminator value,
+// the variants of the variants are also searched in case of the multi-level polymorphism
+func (m *Model) findVariant(variantType string) *Model {
+	if m.Variants == nil {
+		return nil
+	}
+	if variant, ok := (*m.Variants)[variantType]; ok {
+		return variant
+	}
+	for _, variant := range *m.Variants {
+		if variant.VariantType != nil && *variant.VariantType == variantType {
+			return variant
+		}
+	}
+	for _, variant := range *m.Variants {
+		if found := variant.findVariant(variantType); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
 func (m *Model) MarkCovered(root interface{}) {
 	if root == nil || m == nil || m.IsReadOnly {
 		return
@@ -158,35 +174,12 @@ func (m *Model) MarkCovered(root interface{}) {
 	case map[string]interface{}:
 		// decide to match property or variant
 		isMatchProperty := true
-		if m.Discriminator != nil && m.Variants != nil {
-		Loop:
-			for k, v := range value {
-				if k == *m.Discriminator {
-					// if model name or variant type is matched, then we match the property
-					if m.ModelName == v.(string) {
-						break
-					}
-					if m.VariantType != nil && *m.VariantType == v.(string) {
-						break
-					}
-
-					// either the discriminator value hit the variant model name or variant type, we match the variant
-					if variant, ok := (*m.Variants)[v.(string)]; ok {
-						isMatchProperty = true
-						variant.MarkCovered(value)
-
-						break
-					}
-					for _, variant := range *m.Variants {
-						if variant.VariantType != nil && *variant.VariantType == v.(string) {
-							isMatchProperty = true
-							variant.MarkCovered(value)
-
-							break Loop
-						}
-					}
-					logrus.Errorf("unexpected variant %s in %s", v.(string), m.Identifier)
-				}
+		if variantType, ok := m.discriminatorValue(value); ok {
+			// either the discriminator value hit the variant model name or variant type, we match the variant
+			if variant := m.findVariant(variantType); variant != nil {
+				variant.MarkCovered(value)
+			} else {
+				logrus.Errorf("unexpected variant %s in %s", variantType, m.Identifier)
 			}
 		}
 
@@ -217,7 +210,7 @@ func (m *Model) MarkCovered(root interface{}) {
 }
 
 func (m *Model) CountCoverage() (int, int) {
-	if m == nil || m.IsReadOnly {
+	if m == nil || m.IsReadOnly || m.IsSuppressed {
 		return 0, 0
 	}
 
@@ -251,7 +244,7 @@ func (m *Model) CountCoverage() (int, int) {
 
 	if m.Properties != nil {
 		for _, v := range *m.Properties {
-			if v.IsReadOnly {
+			if v.IsReadOnly || v.IsSuppressed {
 				continue
 			}
 			if v.Item != nil && v.Item.IsReadOnly {
@@ -321,7 +314,7 @@ func (m *Model) CountCoverage() (int, int) {
 }
 
 func (m *Model) SplitCovered(covered, uncovered *[]string) {
-	if m == nil || m.IsReadOnly {
+	if m == nil || m.IsReadOnly || m.IsSuppressed {
 		return
 	}
 
@@ -0,0 +1,161 @@
+package coverage
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// {swaggerPath: the allOf references and the referenced swagger files of the swagger file}
+	swaggerReferencesCache, _ = lru.New[string, *swaggerReferences](1000)
+
+	// {swaggerPath: {parent definition: [child definitions]}}
+	definitionGraphCache, _ = lru.New[string, map[definitionRef][]definitionRef](10)
+)
+
+// definitionRef identifies a definition across the swagger files
+type definitionRef struct {
+	swaggerPath string
+	modelName   string
+}
+
+func newDefinitionRef(swaggerPath, modelName string) definitionRef {
+	return definitionRef{
+		swaggerPath: normalizeSwaggerPath(swaggerPath),
+		modelName:   modelName,
+	}
+}
+
+type swaggerReferences struct {
+	// allOfs are the parent definitions of the definitions in the swagger file, keyed by the definition names
+	allOfs map[string][]definitionRef
+	// files are the swagger files which are referenced by the swagger file
+	files []string
+}
+
+// getDefinitionGraph returns the allOf inheritance of the definitions, which maps the parent definitions to the child definitions.
+// The definitions are collected from the swagger files which are reachable from the swagger file by the references,
+// and from the local swagger files in the same directory, which might define the variants without being referenced.
+func getDefinitionGraph(swaggerPath string) (map[definitionRef][]definitionRef, error) {
+	swaggerPath = normalizeSwaggerPath(swaggerPath)
+	if graph, ok := definitionGraphCache.Get(swaggerPath); ok {
+		return graph, nil
+	}
+
+	queue := []string{swaggerPath}
+	if !isRemoteSwaggerPath(swaggerPath) {
+		siblings, err := os.ReadDir(filepath.Dir(swaggerPath))
+		if err != nil {
+			return nil, err
+		}
+		for _, sibling := range siblings {
+			if !sibling.IsDir() && strings.HasSuffix(sibling.Name(), ".json") {
+				queue = append(queue, filepath.Join(filepath.Dir(swaggerPath), sibling.Name()))
+			}
+		}
+	}
+
+	graph := make(map[definitionRef][]definitionRef)
+	visited := make(map[string]bool)
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		refs, err := getSwaggerReferences(current)
+		if err != nil {
+			if current == swaggerPath {
+				return nil, err
+			}
+			logrus.Warnf("failed to load swagger %s which is referenced by %s: %+v", current, swaggerPath, err)
+			continue
+		}
+		for modelName, parents := range refs.allOfs {
+			for _, parent := range parents {
+				graph[parent] = append(graph[parent], definitionRef{swaggerPath: current, modelName: modelName})
+			}
+		}
+		queue = append(queue, refs.files...)
+	}
+
+	for _, children := range graph {
+		sort.Slice(children, func(i, j int) bool {
+			if children[i].modelName != children[j].modelName {
+				return children[i].modelName < children[j].modelName
+			}
+			return children[i].swaggerPath < children[j].swaggerPath
+		})
+	}
+
+	definitionGraphCache.Add(swaggerPath, graph)
+	return graph, nil
+}
+
+func getSwaggerReferences(swaggerPath string) (*swaggerReferences, error) {
+	if refs, ok := swaggerReferencesCache.Get(swaggerPath); ok {
+		return refs, nil
+	}
+
+	doc, err := loadSwagger(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &swaggerReferences{
+		allOfs: make(map[string][]definitionRef),
+		files:  make([]string, 0),
+	}
+	for modelName, definition := range doc.Spec().Definitions {
+		for _, allOf := range definition.AllOf {
+			if allOf.Ref.String() == "" {
+				continue
+			}
+			parentName, parentPath := SchemaNamePathFromRef(swaggerPath, allOf.Ref)
+			out.allOfs[modelName] = append(out.allOfs[modelName], newDefinitionRef(parentPath, parentName))
+		}
+	}
+
+	files := make(map[string]bool)
+	for _, ref := range doc.Analyzer.AllRefs() {
+		refURL := ref.GetURL()
+		if refURL == nil || refURL.Path == "" || refURL.IsAbs() || !strings.HasSuffix(refURL.Path, ".json") {
+			continue
+		}
+		_, refPath := SchemaNamePathFromRef(swaggerPath, ref)
+		files[normalizeSwaggerPath(refPath)] = true
+	}
+	for file := range files {
+		out.files = append(out.files, file)
+	}
+	sort.Strings(out.files)
+
+	swaggerReferencesCache.Add(swaggerPath, out)
+	return out, nil
+}
+
+// normalizeSwaggerPath cleans the swagger path, so the same swagger file referenced by the different relative paths has the same key
+func normalizeSwaggerPath(swaggerPath string) string {
+	if isRemoteSwaggerPath(swaggerPath) {
+		u, err := url.Parse(swaggerPath)
+		if err != nil {
+			return swaggerPath
+		}
+		u.Path = path.Clean(u.Path)
+		return u.String()
+	}
+	return filepath.Clean(swaggerPath)
+}
+
+func isRemoteSwaggerPath(swaggerPath string) bool {
+	return strings.HasPrefix(swaggerPath, "http://") || strings.HasPrefix(swaggerPath, "https://")
+}
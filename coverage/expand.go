@@ -15,13 +15,8 @@ import (
 const msExtensionDiscriminator = "x-ms-discriminator-value"
 const msExtensionSecret = "x-ms-secret"
 
-var (
-	// {swaggerPath: doc Object}
-	swaggerCache, _ = lru.New[string, *loads.Document](30)
-
-	// {swaggerPath: {parentModelName: {childModelName: nil}}}
-	allOfTableCache, _ = lru.New[string, map[string]map[string]interface{}](10)
-)
+// {swaggerPath: doc Object}
+var swaggerCache, _ = lru.New[string, *loads.Document](30)
 
 func loadSwagger(swaggerPath string) (*loads.Document, error) {
 	if doc, ok := swaggerCache.Get(swaggerPath); ok {
@@ -36,40 +31,6 @@ func loadSwagger(swaggerPath string) (*loads.Document, error) {
 	return doc, nil
 }
 
-func getAllOfTable(swaggerPath string) (map[string]map[string]interface{}, error) {
-	if vt, ok := allOfTableCache.Get(swaggerPath); ok {
-		return vt, nil
-	}
-
-	doc, err := loadSwagger(swaggerPath)
-	if err != nil {
-		return nil, err
-	}
-	spec := doc.Spec()
-
-	allOfTable := map[string]map[string]interface{}{}
-	for k, v := range spec.Definitions {
-		if len(v.AllOf) > 0 {
-			for _, allOf := range v.AllOf {
-				if allOf.Ref.String() != "" {
-					modelName, absPath := SchemaNamePathFromRef(swaggerPath, allOf.Ref)
-					if absPath != swaggerPath {
-						continue
-					}
-
-					if _, ok := allOfTable[modelName]; !ok {
-						allOfTable[modelName] = map[string]interface{}{}
-					}
-					allOfTable[modelName][k] = nil
-				}
-			}
-		}
-	}
-
-	allOfTableCache.Add(swaggerPath, allOfTable)
-	return allOfTable, nil
-}
-
 func trimPath(path string) string {
 	if strings.Contains(path, "\\") {
 		return strings.ReplaceAll(path, "\\.\\", "\\")
@@ -96,24 +57,32 @@ func Expand(modelName, swaggerPath string) (*Model, error) {
 		return nil, fmt.Errorf("%s not found in the definition of %s", modelName, swaggerPath)
 	}
 
-	output := expandSchema(modelSchema, swaggerPath, modelName, "#", spec, map[string]interface{}{}, map[string]interface{}{})
+	// the variants are searched in the swagger files which are reachable from the model
+	graph, err := getDefinitionGraph(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	output := expandSchema(modelSchema, swaggerPath, modelName, "#", spec, graph, map[definitionRef]interface{}{}, map[definitionRef]interface{}{})
 
 	output.IsRoot = true
 
 	return output, nil
 }
 
-func expandSchema(input openapiSpec.Schema, swaggerPath, modelName, identifier string, root interface{}, resolvedDiscriminator map[string]interface{}, resolvedModel map[string]interface{}) *Model {
+func expandSchema(input openapiSpec.Schema, swaggerPath, modelName, identifier string, root interface{}, graph map[definitionRef][]definitionRef, resolvedDiscriminator map[definitionRef]interface{}, resolvedModel map[definitionRef]interface{}) *Model {
 	output := Model{
 		Identifier: identifier,
 		ModelName:  modelName,
 		SourceFile: swaggerPath,
 	}
 
-	if _, ok := resolvedModel[modelName]; ok {
+	// the models are identified by the swagger paths, the models of the same name might be defined in different swagger files
+	ref := newDefinitionRef(swaggerPath, modelName)
+	if _, ok := resolvedModel[ref]; ok {
 		return &output
 	}
-	resolvedModel[modelName] = nil
+	resolvedModel[ref] = nil
 
 	if len(input.Type) > 0 {
 		output.Type = &input.Type[0]
@@ -183,7 +152,7 @@ func expandSchema(input openapiSpec.Schema, swaggerPath, modelName, identifier s
 			refRoot = doc.Spec()
 		}
 
-		referenceModel := expandSchema(*resolved, refSwaggerPath, modelName, identifier, refRoot, resolvedDiscriminator, resolvedModel)
+		referenceModel := expandSchema(*resolved, refSwaggerPath, modelName, identifier, refRoot, graph, resolvedDiscriminator, resolvedModel)
 		if referenceModel.Properties != nil {
 			for k, v := range *referenceModel.Properties {
 				properties[k] = v
@@ -224,12 +193,12 @@ func expandSchema(input openapiSpec.Schema, swaggerPath, modelName, identifier s
 
 	// expand properties
 	for k, v := range input.Properties {
-		properties[k] = expandSchema(v, swaggerPath, fmt.Sprintf("%s.%s", modelName, k), fmt.Sprintf("%s.%s", identifier, k), root, resolvedDiscriminator, resolvedModel)
+		properties[k] = expandSchema(v, swaggerPath, fmt.Sprintf("%s.%s", modelName, k), fmt.Sprintf("%s.%s", identifier, k), root, graph, resolvedDiscriminator, resolvedModel)
 	}
 
 	// expand composition
 	for _, v := range input.AllOf {
-		allOf := expandSchema(v, swaggerPath, fmt.Sprintf("%s.allOf", modelName), identifier, root, resolvedDiscriminator, resolvedModel)
+		allOf := expandSchema(v, swaggerPath, fmt.Sprintf("%s.allOf", modelName), identifier, root, graph, resolvedDiscriminator, resolvedModel)
 		if allOf.Properties != nil {
 			for k, v := range *allOf.Properties {
 				properties[k] = v
@@ -274,48 +243,57 @@ func expandSchema(input openapiSpec.Schema, swaggerPath, modelName, identifier s
 
 	// expand items
 	if input.Items != nil {
-		item := expandSchema(*input.Items.Schema, swaggerPath, fmt.Sprintf("%s[]", modelName), fmt.Sprintf("%s[]", identifier), root, resolvedDiscriminator, resolvedModel)
+		item := expandSchema(*input.Items.Schema, swaggerPath, fmt.Sprintf("%s[]", modelName), fmt.Sprintf("%s[]", identifier), root, graph, resolvedDiscriminator, resolvedModel)
 		output.Item = item
 	}
 
-	delete(resolvedModel, modelName)
+	delete(resolvedModel, ref)
 
 	// expand variants
 	if input.Discriminator != "" || output.Discriminator != nil {
-		if _, hasResolvedDiscriminator := resolvedDiscriminator[modelName]; !hasResolvedDiscriminator {
-			allOfTable, err := getAllOfTable(swaggerPath)
-			if err != nil {
-				logrus.Panicf("get variant table %s: %+v", swaggerPath, err)
-			}
-
-			varSet, ok := allOfTable[modelName]
-			if ok {
-				resolvedDiscriminator[modelName] = nil
+		if _, hasResolvedDiscriminator := resolvedDiscriminator[ref]; !hasResolvedDiscriminator {
+			varSet := graph[ref]
+			if len(varSet) > 0 {
+				resolvedDiscriminator[ref] = nil
 				variants := map[string]*Model{}
+				visited := map[definitionRef]bool{}
 
-				// level order traverse to find all variants
+				// level order traverse to find all variants, the variants might be defined in the other swagger files
 				for len(varSet) > 0 {
-					tempVarSet := make(map[string]interface{})
-					for variantModelName := range varSet {
-						schema := root.(*openapiSpec.Swagger).Definitions[variantModelName]
-						variantName := variantModelName
+					tempVarSet := make([]definitionRef, 0)
+					for _, variantRef := range varSet {
+						if visited[variantRef] {
+							continue
+						}
+						visited[variantRef] = true
+
+						doc, err := loadSwagger(variantRef.swaggerPath)
+						if err != nil {
+							logrus.Panicf("load swagger %s: %+v", variantRef.swaggerPath, err)
+						}
+						variantRoot := doc.Spec()
+						schema := variantRoot.Definitions[variantRef.modelName]
+						variantName := variantRef.modelName
 						if variantNameRaw, ok := schema.Extensions[msExtensionDiscriminator]; ok && variantNameRaw != nil {
 							variantName = variantNameRaw.(string)
 						}
 
-						resolved := expandSchema(schema, swaggerPath, variantModelName, fmt.Sprintf("%s{%s}", identifier, variantName), root, resolvedDiscriminator, resolvedModel)
+						resolved := expandSchema(schema, variantRef.swaggerPath, variantRef.modelName, fmt.Sprintf("%s{%s}", identifier, variantName), variantRoot, graph, resolvedDiscriminator, resolvedModel)
 						resolved.VariantType = &variantName
-						// in case of https://github.com/Azure/azure-rest-api-specs/issues/25104, use modelName as key
-						variants[variantModelName] = resolved
-						if varVarSet, ok := allOfTable[variantModelName]; ok {
-							for v := range varVarSet {
-								tempVarSet[v] = nil
-							}
+						// in case of https://github.com/Azure/azure-rest-api-specs/issues/25104, use modelName as key,
+						// the variant of the same model name in another swagger file is keyed by the swagger file name and the model name
+						variantKey := variantRef.modelName
+						if _, ok := variants[variantKey]; ok {
+							variantKey = filepath.Base(variantRef.swaggerPath) + "#" + DefinitionPointer(variantRef.modelName)
+						}
+						if _, ok := variants[variantKey]; !ok {
+							variants[variantKey] = resolved
 						}
+						tempVarSet = append(tempVarSet, graph[variantRef]...)
 					}
 					varSet = tempVarSet
 				}
-				delete(resolvedDiscriminator, modelName)
+				delete(resolvedDiscriminator, ref)
 				if input.Discriminator != "" {
 					output.Discriminator = &input.Discriminator
 				}
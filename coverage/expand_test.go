@@ -109,6 +109,127 @@ func TestExpand_referToChildVariant(t *testing.T) {
 
 }
 
+func TestExpand_crossFileVariants(t *testing.T) {
+	swaggerPath := filepath.Join("testdata", "Microsoft.Pipeline", "stable", "2023-01-01")
+	swaggerModel, err := coverage.GetModelInfoFromLocalDir("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Pipeline/pipelines/test", swaggerPath, "PUT")
+	if err != nil || swaggerModel == nil {
+		t.Fatalf("expect the pipeline model, but got %+v, %+v", swaggerModel, err)
+	}
+
+	model, err := coverage.Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	activity := (*(*model.Properties)["properties"].Properties)["activities"].Item
+	if activity == nil || activity.Variants == nil {
+		t.Fatalf("expected activity variants not nil")
+	}
+	// the variants are defined in the referenced file and the sibling file, CopyActivity inherits ExecutionActivity
+	for _, variantName := range []string{"ControlActivity", "ExecutionActivity", "CopyActivity"} {
+		if _, ok := (*activity.Variants)[variantName]; !ok {
+			t.Fatalf("expected variant %s of activity, got %v", variantName, *activity.Variants)
+		}
+	}
+	copyActivity := (*activity.Variants)["CopyActivity"]
+	if copyActivity.VariantType == nil || *copyActivity.VariantType != "Copy" {
+		t.Fatalf("expected variantType Copy")
+	}
+	for _, property := range []string{"name", "type", "timeout", "source"} {
+		if _, ok := (*copyActivity.Properties)[property]; !ok {
+			t.Fatalf("expected property %s of CopyActivity", property)
+		}
+	}
+
+	// the parent is defined in common-types, the variants are defined in common-types and the swagger of the resource provider
+	password := (*(*copyActivity.Properties)["source"].Properties)["password"]
+	if password.Variants == nil || password.Discriminator == nil || *password.Discriminator != "type" {
+		t.Fatalf("expected password variants not nil")
+	}
+	for _, variantName := range []string{"SecureString", "KeyVaultSecretReference"} {
+		if _, ok := (*password.Variants)[variantName]; !ok {
+			t.Fatalf("expected variant %s of password, got %v", variantName, *password.Variants)
+		}
+	}
+
+	body := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"activities": []interface{}{
+				map[string]interface{}{
+					"name": "copy",
+					"type": "Copy",
+					"source": map[string]interface{}{
+						"password": map[string]interface{}{
+							"type":  "SecureString",
+							"value": "P@ssw0rd1234!",
+						},
+					},
+				},
+			},
+		},
+	}
+	model.MarkCovered(body)
+	model.CountCoverage()
+	if !copyActivity.IsAnyCovered || !(*password.Variants)["SecureString"].IsAnyCovered {
+		t.Fatalf("expected the Copy activity and the SecureString password are covered")
+	}
+	if (*activity.Variants)["ControlActivity"].IsAnyCovered {
+		t.Fatalf("expected the Container activity is not covered")
+	}
+
+	secrets := make(map[string]string)
+	model.CredScan(body, secrets)
+	if secrets["#.properties.activities[]{Copy}.source.password{SecureString}.value"] != "P@ssw0rd1234!" {
+		t.Fatalf("expected the secret in the SecureString password, got %v", secrets)
+	}
+}
+
+func TestExpand_sameNameVariants(t *testing.T) {
+	swaggerPath := filepath.Join("testdata", "Microsoft.Pipeline", "stable", "2023-01-01")
+	swaggerModel, err := coverage.GetModelInfoFromLocalDir("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Pipeline/pipelines/test", swaggerPath, "PUT")
+	if err != nil || swaggerModel == nil {
+		t.Fatalf("expect the pipeline model, but got %+v, %+v", swaggerModel, err)
+	}
+
+	model, err := coverage.Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ControlActivity is defined in both entityTypes/Activity.json and wait.json, the variants of both are kept
+	activity := (*(*model.Properties)["properties"].Properties)["activities"].Item
+	variants := make(map[string]*coverage.Model)
+	for key, variant := range *activity.Variants {
+		if variant.ModelName == "ControlActivity" {
+			variants[*variant.VariantType] = variant
+			if key != "ControlActivity" && key != "Activity.json#/definitions/ControlActivity" && key != "wait.json#/definitions/ControlActivity" {
+				t.Fatalf("expected the variant keyed by the model name or the swagger file name, got %s", key)
+			}
+		}
+	}
+	if len(variants) != 2 || variants["Container"] == nil || variants["Wait"] == nil {
+		t.Fatalf("expected the Container and Wait variants, got %v", variants)
+	}
+	if _, ok := (*variants["Wait"].Properties)["waitTimeInSeconds"]; !ok {
+		t.Fatalf("expected property waitTimeInSeconds of the Wait variant")
+	}
+
+	model.MarkCovered(map[string]interface{}{
+		"properties": map[string]interface{}{
+			"activities": []interface{}{
+				map[string]interface{}{
+					"name":              "wait",
+					"type":              "Wait",
+					"waitTimeInSeconds": 10,
+				},
+			},
+		},
+	})
+	if !variants["Wait"].IsAnyCovered || variants["Container"].IsAnyCovered {
+		t.Fatalf("expected only the Wait activity is covered")
+	}
+}
+
 // try to expand all PUT and POST models twice, and ensure result is the same
 // AZURE_REST_REPO_DIR="/home/test/go/src/github.com/azure/azure-rest-api-specs/specification" TEST_RESULT_FILE="/home/test/"
 func TestExpandAll(t *testing.T) {
@@ -2,41 +2,20 @@ package coverage
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
 	"strings"
 
-	"github.com/azure/armstrong/utils"
 	openapispec "github.com/go-openapi/spec"
 	"github.com/sirupsen/logrus"
 )
 
+// GetModelInfoFromLocalDir gets model info from the swagger file or the swagger files in the directory, the API paths are resolved by the path template trie of the swagger path,
+// which is built once and shared by the later lookups, the most specific API path is used if several ones match the resource id
 func GetModelInfoFromLocalDir(resourceId, swaggerPath string, method string) (*SwaggerModel, error) {
-	swaggerPath, err := filepath.Abs(swaggerPath)
+	trie, err := GetPathTemplateTrie(swaggerPath)
 	if err != nil {
 		return nil, err
 	}
-	file, err := os.Stat(swaggerPath)
-	if err != nil {
-		return nil, err
-	}
-	if !file.IsDir() {
-		return GetModelInfoFromLocalSpecFile(resourceId, swaggerPath, method)
-	}
-	files, err := utils.ListFiles(swaggerPath, ".json", 1)
-	if err != nil {
-		return nil, err
-	}
-	for _, filename := range files {
-		model, err := GetModelInfoFromLocalSpecFile(resourceId, filename, method)
-		if err != nil {
-			logrus.Warnf("failed to get model info from local spec file %v: %+v", filename, err)
-		}
-		if model != nil {
-			return model, nil
-		}
-	}
-	return nil, nil
+	return trie.Lookup(resourceId, method)
 }
 
 func GetModelInfoFromLocalSpecFile(resourceId, swaggerPath string, method string) (*SwaggerModel, error) {
@@ -55,63 +34,71 @@ func GetModelInfoFromLocalSpecFile(resourceId, swaggerPath string, method string
 			continue
 		}
 
-		var operation *openapispec.Operation
-		switch strings.ToUpper(method) {
-		case "GET":
-			operation = pathItem.Get
-		case "PUT":
-			operation = pathItem.Put
-		case "POST":
-			operation = pathItem.Post
-		case "DELETE":
-			operation = pathItem.Delete
-		case "OPTIONS":
-			operation = pathItem.Options
-		case "HEAD":
-			operation = pathItem.Head
-		case "PATCH":
-			operation = pathItem.Patch
-		default:
-			logrus.Warnf("unsupported method %v", method)
-		}
+		operation := pathItemOperation(pathItem, method)
 		if operation == nil {
 			// should not happen
-			logrus.Warnf("no PUT operation found for path %v", pathKey)
+			logrus.Warnf("no %s operation found for path %v", method, pathKey)
 			continue
 		}
 
-		var modelName string
-		for _, param := range operation.Parameters {
-			paramRef := param.Ref
-			if paramRef.String() != "" {
-				refParam, err := openapispec.ResolveParameterWithBase(nil, param.Ref, &openapispec.ExpandOptions{RelativeBase: swaggerPath})
-				if err != nil {
-					return nil, fmt.Errorf("resolve param ref %q: %+v", param.Ref.String(), err)
-				}
+		return modelFromOperation(swaggerPath, pathKey, operation)
+	}
+	return nil, nil
+}
 
-				// Update the param
-				param = *refParam
-			}
-			if param.In == "body" {
-				if paramRef.String() != "" {
-					modelName, swaggerPath = SchemaNamePathFromRef(swaggerPath, paramRef)
-				}
+// pathItemOperation returns the operation of the method in the path item, it returns nil if the method isn't supported
+func pathItemOperation(pathItem openapispec.PathItem, method string) *openapispec.Operation {
+	switch strings.ToUpper(method) {
+	case "GET":
+		return pathItem.Get
+	case "PUT":
+		return pathItem.Put
+	case "POST":
+		return pathItem.Post
+	case "DELETE":
+		return pathItem.Delete
+	case "OPTIONS":
+		return pathItem.Options
+	case "HEAD":
+		return pathItem.Head
+	case "PATCH":
+		return pathItem.Patch
+	}
+	return nil
+}
 
-				if param.Schema.Ref.String() != "" {
-					modelName, swaggerPath = SchemaNamePathFromRef(swaggerPath, param.Schema.Ref)
-				}
-				break
+// modelFromOperation returns the request body model of the operation
+func modelFromOperation(swaggerPath, pathKey string, operation *openapispec.Operation) (*SwaggerModel, error) {
+	var modelName string
+	for _, param := range operation.Parameters {
+		paramRef := param.Ref
+		if paramRef.String() != "" {
+			refParam, err := openapispec.ResolveParameterWithBase(nil, param.Ref, &openapispec.ExpandOptions{RelativeBase: swaggerPath})
+			if err != nil {
+				return nil, fmt.Errorf("resolve param ref %q: %+v", param.Ref.String(), err)
 			}
+
+			// Update the param
+			param = *refParam
 		}
+		if param.In == "body" {
+			if paramRef.String() != "" {
+				modelName, swaggerPath = SchemaNamePathFromRef(swaggerPath, paramRef)
+			}
 
-		return &SwaggerModel{
-			ApiPath:     pathKey,
-			ModelName:   modelName,
-			SwaggerPath: swaggerPath,
-			OperationID: operation.ID,
-		}, nil
+			if param.Schema.Ref.String() != "" {
+				modelName, swaggerPath = SchemaNamePathFromRef(swaggerPath, param.Schema.Ref)
+			}
+			break
+		}
 	}
-	return nil, nil
+
+	return &SwaggerModel{
+		ApiPath:     pathKey,
+		ModelName:   modelName,
+		SwaggerPath: swaggerPath,
+		OperationID: operation.ID,
+	}, nil
 }
 
 func IsPathKeyMatchWithResourceId(pathKey, resourceId string) bool {
@@ -120,7 +107,7 @@ func IsPathKeyMatchWithResourceId(pathKey, resourceId string) bool {
 	i := len(pathParts) - 1
 	j := len(resourceIdParts) - 1
 	for i >= 0 && j >= 0 {
-		if i == 0 && (strings.EqualFold(pathParts[i], "{resourceid}") || strings.EqualFold(pathParts[i], "{scope}") || strings.EqualFold(pathParts[i], "{resourceUri}")) {
+		if i == 0 && isScopeParameter(pathParts[i]) {
 			return true
 		}
 		if strings.EqualFold(pathParts[i], resourceIdParts[j]) ||
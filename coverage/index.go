@@ -11,7 +11,10 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/azure/armstrong/utils"
 	openapispec "github.com/go-openapi/spec"
 	"github.com/magodo/azure-rest-api-index/azidx"
 	"github.com/sirupsen/logrus"
@@ -20,89 +23,151 @@ import (
 const (
 	indexFileURL = "https://raw.githubusercontent.com/teowa/azure-rest-api-index-file/main/index.json.zip"
 	azureRepoURL = "https://raw.githubusercontent.com/Azure/azure-rest-api-specs/main/specification/"
+	// IndexCommitURL is the URL of the azure-rest-api-specs repo tree, the commit of the index is appended to it
+	IndexCommitURL = "https://github.com/Azure/azure-rest-api-specs/tree/"
 )
 
-var indexCache *azidx.Index
+var (
+	// indexMutex guards the loaded indexes, the indexes are loaded once and shared by the concurrent lookups
+	indexMutex sync.Mutex
+	// indexes are the loaded indexes, keyed by their sources, e.g., file:{path}, local:{swagger repo} and cache:{commit}
+	indexes = make(map[string]*azidx.Index)
+	// indexCommit pins the commit of the index in the cache, the current index is used if it's empty
+	indexCommit string
+)
+
+// SetIndexCommit pins the commit of the index in the cache which is used when no index file is specified, the current index is used if it's empty
+func SetIndexCommit(commit string) {
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+	indexCommit = commit
+}
 
+// GetIndexFromLocalDir builds the index from the local swagger repo, the swagger repo is the specification directory or a resource provider directory in it, which limits the index to the resource provider.
+// The index is built incrementally by the LocalIndexBuilder whose state is in the index cache, it's also saved to the index file if it's specified.
 func GetIndexFromLocalDir(swaggerRepo, indexFilePath string) (*azidx.Index, error) {
-	if indexCache != nil {
-		return indexCache, nil
+	specDir, resourceProvider, err := SplitSpecDir(swaggerRepo)
+	if err != nil {
+		return nil, err
+	}
+
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	key := "local:" + filepath.Join(specDir, resourceProvider)
+	if index, ok := indexes[key]; ok {
+		return index, nil
 	}
 
 	if indexFilePath != "" {
 		if _, err := os.Stat(indexFilePath); err == nil {
-			byteValue, _ := os.ReadFile(indexFilePath)
-
-			var index azidx.Index
-			if err := json.Unmarshal(byteValue, &index); err != nil {
-				return nil, fmt.Errorf("unmarshal index file: %+v", err)
+			index, err := readIndexFile(indexFilePath)
+			if err != nil {
+				return nil, err
 			}
-			indexCache = &index
+			indexes[key] = index
 
 			logrus.Infof("load index from cache file %s", indexFilePath)
 
-			return indexCache, nil
+			return index, nil
 		}
 	}
 
-	logrus.Infof("building index from from local swagger %s, it might take several minutes", swaggerRepo)
-	index, err := azidx.BuildIndex(swaggerRepo, "")
+	builder := LocalIndexBuilder{}
+	if cache, err := DefaultIndexCache(); err == nil {
+		builder = cache.LocalIndexBuilder(specDir)
+	}
+	logrus.Infof("building index from local swagger %s, only the changed swagger files are parsed", filepath.Join(specDir, resourceProvider))
+	index, err := builder.Build(specDir, resourceProvider)
 	if err != nil {
 		logrus.Error(fmt.Sprintf("failed to build index: %+v", err))
 		return nil, err
 	}
 	logrus.Infof("index successfully built on commit %+v", index.Commit)
 
-	indexCache = index
-
-	if indexFilePath != "" {
-		jsonBytes, err := json.Marshal(&index)
-		if err != nil {
-			logrus.Warningf("failed to marshal index: %+v", err)
-			return index, nil
-		}
-
-		err = os.WriteFile(indexFilePath, jsonBytes, 0644)
-		if err != nil {
-			logrus.Warningf("failed to write index cache file %s: %+v", indexFilePath, err)
-			return index, nil
-		}
-
-		logrus.Infof("index successfully saved to cache file %s", indexFilePath)
-	}
+	indexes[key] = index
+	writeIndexFile(indexFilePath, index)
 
 	return index, nil
 }
 
+// GetIndex returns the index in the index file if it exists, otherwise the index in the cache, the pinned commit or the current index is used.
+// The online index is downloaded into the cache if no index is cached, and it's also saved to the index file if it's specified.
 func GetIndex(indexFilePath string) (*azidx.Index, error) {
-	if indexCache != nil {
-		return indexCache, nil
+	indexMutex.Lock()
+	defer indexMutex.Unlock()
+
+	key := "cache:" + indexCommit
+	if indexFilePath != "" {
+		key = "file:" + indexFilePath
+	}
+	if index, ok := indexes[key]; ok {
+		return index, nil
 	}
 
 	if indexFilePath != "" {
 		if _, err := os.Stat(indexFilePath); err == nil {
-			byteValue, _ := os.ReadFile(indexFilePath)
-
-			var index azidx.Index
-			if err := json.Unmarshal(byteValue, &index); err != nil {
-				return nil, fmt.Errorf("unmarshal index file: %+v", err)
+			index, err := readIndexFile(indexFilePath)
+			if err != nil {
+				return nil, err
 			}
-			indexCache = &index
+			indexes[key] = index
 
 			logrus.Infof("load index from cache file %s", indexFilePath)
 
-			return indexCache, nil
+			return index, nil
 		}
 	}
 
-	resp, err := http.Get(indexFileURL)
+	cache, err := DefaultIndexCache()
 	if err != nil {
-		return nil, fmt.Errorf("get index file from %v: %+v", indexFileURL, err)
+		logrus.Warnf("the index cache is not available: %+v", err)
+	} else {
+		index, info, err := cache.Load(indexCommit)
+		if err != nil {
+			return nil, fmt.Errorf("loading index from cache %s: %+v", cache.Dir, err)
+		}
+		if index != nil {
+			logrus.Infof("load index based commit: %s%s", IndexCommitURL, index.Commit)
+			if info.IsStale() {
+				logrus.Warnf("the index of commit %s was updated at %s, it's stale, run `armstrong index update` to update it", info.Commit, info.UpdatedAt.Format(time.DateTime))
+			}
+			indexes[key] = index
+			writeIndexFile(indexFilePath, index)
+			return index, nil
+		}
+	}
+	if indexCommit != "" {
+		return nil, fmt.Errorf("the index of commit %s is not cached, run `armstrong index build` with the azure-rest-api-specs repo checked out at the commit", indexCommit)
 	}
 
-	logrus.Infof("downloading index file from %s", indexFileURL)
+	index, err := downloadIndex(indexFileURL)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("load index based commit: %s%s", IndexCommitURL, index.Commit)
+	if cache != nil {
+		if _, err := cache.Store(index, indexFileURL); err != nil {
+			logrus.Warnf("failed to save index to cache %s: %+v", cache.Dir, err)
+		}
+	}
+	indexes[key] = index
+	writeIndexFile(indexFilePath, index)
 
+	return index, nil
+}
+
+// downloadIndex downloads the zipped online index
+func downloadIndex(indexURL string) (*azidx.Index, error) {
+	logrus.Infof("downloading index file from %s", indexURL)
+	resp, err := http.Get(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("get index file from %v: %+v", indexURL, err)
+	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get index file from %v: unexpected status code %d", indexURL, resp.StatusCode)
+	}
 
 	b, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -133,27 +198,36 @@ func GetIndex(indexFilePath string) (*azidx.Index, error) {
 	if err := json.Unmarshal(unzippedIndexBytes, &index); err != nil {
 		return nil, fmt.Errorf("unmarshal index file: %+v", err)
 	}
-	indexCache = &index
-
-	logrus.Infof("load index based commit: https://github.com/Azure/azure-rest-api-specs/tree/%s", index.Commit)
-
-	if indexFilePath != "" {
-		jsonBytes, err := json.Marshal(&index)
-		if err != nil {
-			logrus.Warningf("failed to marshal index: %+v", err)
-			return indexCache, nil
-		}
-
-		err = os.WriteFile(indexFilePath, jsonBytes, 0644)
-		if err != nil {
-			logrus.Warningf("failed to write index cache file %s: %+v", indexFilePath, err)
-			return indexCache, nil
-		}
+	return &index, nil
+}
 
-		logrus.Infof("index successfully saved to cache file %s", indexFilePath)
+func readIndexFile(indexFilePath string) (*azidx.Index, error) {
+	byteValue, err := os.ReadFile(indexFilePath)
+	if err != nil {
+		return nil, fmt.Errorf("read index file: %+v", err)
 	}
+	var index azidx.Index
+	if err := json.Unmarshal(byteValue, &index); err != nil {
+		return nil, fmt.Errorf("unmarshal index file: %+v", err)
+	}
+	return &index, nil
+}
 
-	return indexCache, nil
+// writeIndexFile saves the index to the index file, it's skipped if the index file is not specified or already exists
+func writeIndexFile(indexFilePath string, index *azidx.Index) {
+	if indexFilePath == "" || utils.Exists(indexFilePath) {
+		return
+	}
+	jsonBytes, err := json.Marshal(index)
+	if err != nil {
+		logrus.Warningf("failed to marshal index: %+v", err)
+		return
+	}
+	if err := os.WriteFile(indexFilePath, jsonBytes, 0644); err != nil {
+		logrus.Warningf("failed to write index cache file %s: %+v", indexFilePath, err)
+		return
+	}
+	logrus.Infof("index successfully saved to cache file %s", indexFilePath)
 }
 
 type SwaggerModel struct {
@@ -161,17 +235,18 @@ type SwaggerModel struct {
 	ModelName   string
 	SwaggerPath string
 	OperationID string
+	// Commit is the commit of the azure-rest-api-specs repo which the index is built on, it's empty if the model is not found by an index
+	Commit string
 }
 
-// GetModelInfoFromIndex will try to download online index from https://github.com/teowa/azure-rest-api-index-file, and get model info from it
-// if the index is already downloaded as in {indexFilePath}, it will use the cached index
+// GetModelInfoFromIndex gets model info from the index, see GetIndex for how the index is loaded
 func GetModelInfoFromIndex(resourceId, apiVersion, method, indexFilePath string) (*SwaggerModel, error) {
 	index, err := GetIndex(indexFilePath)
 	if err != nil {
 		return nil, err
 	}
 
-	resourceURL := fmt.Sprintf("https://management.azure.com%s?api-version=%s", resourceId, apiVersion)
+	resourceURL := indexLookupURL(resourceId, apiVersion)
 	uRL, err := url.Parse(resourceURL)
 	if err != nil {
 		return nil, fmt.Errorf("parsing URL %s: %+v", resourceURL, err)
@@ -188,35 +263,24 @@ func GetModelInfoFromIndex(resourceId, apiVersion, method, indexFilePath string)
 	if model.ModelName == "" {
 		return nil, fmt.Errorf("PUT model not found for %s", ref.String())
 	}
+	model.Commit = index.Commit
 
 	return model, nil
 }
 
 // GetModelInfoFromLocalIndex tries to build index from local swagger repo and get model info from it
 func GetModelInfoFromLocalIndex(resourceId, apiVersion, method, swaggerRepo, indexCacheFile string) (*SwaggerModel, error) {
-	swaggerRepo, err := filepath.Abs(swaggerRepo)
+	specDir, _, err := SplitSpecDir(swaggerRepo)
 	if err != nil {
-		return nil, fmt.Errorf("swagger repo path %q is invalid: %+v", swaggerRepo, err)
-	}
-
-	if _, err := os.Stat(swaggerRepo); os.IsNotExist(err) {
-		return nil, fmt.Errorf("swagger repo path %q is invalid: path does not exist", swaggerRepo)
-	}
-
-	swaggerRepo = strings.TrimSuffix(swaggerRepo, "/")
-
-	if !strings.HasSuffix(swaggerRepo, "specification") {
-		return nil, fmt.Errorf("swagger repo path %q is invalid: must point to \"specification\", e.g., /home/projects/azure-rest-api-specs/specification", swaggerRepo)
+		return nil, err
 	}
 
-	swaggerRepo += "/"
-
 	index, err := GetIndexFromLocalDir(swaggerRepo, indexCacheFile)
 	if err != nil {
 		return nil, fmt.Errorf("build index from local dir %s: %+v", swaggerRepo, err)
 	}
 
-	resourceURL := fmt.Sprintf("https://management.azure.com%s?api-version=%s", resourceId, apiVersion)
+	resourceURL := indexLookupURL(resourceId, apiVersion)
 	uRL, err := url.Parse(resourceURL)
 	if err != nil {
 		return nil, fmt.Errorf("parsing URL %s: %+v", resourceURL, err)
@@ -226,13 +290,14 @@ func GetModelInfoFromLocalIndex(resourceId, apiVersion, method, swaggerRepo, ind
 		return nil, fmt.Errorf("lookup %s URL %s in index: %+v", method, resourceURL, err)
 	}
 
-	model, err := GetModelInfoFromIndexRef(openapispec.Ref{Ref: *ref}, swaggerRepo)
+	model, err := GetModelInfoFromIndexRef(openapispec.Ref{Ref: *ref}, specDir+"/")
 	if err != nil {
 		return nil, fmt.Errorf("get model %s: %+v", ref, err)
 	}
 	if model.ModelName == "" {
 		return nil, fmt.Errorf("PUT model not found for %s", ref.String())
 	}
+	model.Commit = index.Commit
 
 	return model, nil
 }
@@ -294,6 +359,12 @@ func readZipFile(zf *zip.File) ([]byte, error) {
 	return io.ReadAll(f)
 }
 
+// indexLookupURL returns the URL of the request which is looked up in the index. The index matches the path and the api-version only,
+// so the requests to all the clouds are matched, and the host is a placeholder.
+func indexLookupURL(requestPath string, apiVersion string) string {
+	return fmt.Sprintf("https://management.azure.com%s?api-version=%s", requestPath, apiVersion)
+}
+
 func MockResourceIDFromType(azapiResourceType string) (string, string) {
 	const (
 		managementGroupId = "/providers/Microsoft.Management"
@@ -0,0 +1,579 @@
+package coverage
+
+import (
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-openapi/jsonpointer"
+	"github.com/go-openapi/jsonreference"
+	"github.com/go-openapi/loads"
+	"github.com/magodo/azure-rest-api-index/azidx"
+	"github.com/magodo/azure-rest-api-index/azidx/specpath"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultIndexDedup is the same as the default deduplication file of the azidx.BuildIndex
+//
+//go:embed index_dedup.json
+var defaultIndexDedup []byte
+
+// localIndexStateVersion is increased when the parsed operations change, the state of the other versions is discarded
+const localIndexStateVersion = 1
+
+// LocalIndexBuilder builds the swagger index from the local swagger repo incrementally.
+// The operations parsed from each swagger file are saved in the state file with the hash of the file, and only the changed files are parsed again.
+type LocalIndexBuilder struct {
+	// StateFile saves the parsed swagger files, the index is built from scratch if it's empty
+	StateFile string
+	// DedupFile picks the operations which are defined in multiple swagger files, it's in the same format as the one of the azidx.BuildIndex,
+	// and the default one of the azidx.BuildIndex is used if it's empty
+	DedupFile string
+}
+
+type localIndexState struct {
+	Version int                       `json:"version"`
+	Files   map[string]localIndexFile `json:"files"`
+}
+
+type localIndexFile struct {
+	Size       int64                 `json:"size"`
+	ModTime    time.Time             `json:"modTime"`
+	Hash       string                `json:"hash"`
+	Operations []localIndexOperation `json:"operations,omitempty"`
+}
+
+type localIndexOperation struct {
+	azidx.OpLocator
+	PathPattern azidx.PathPatternStr `json:"pathPattern"`
+	Ref         string               `json:"ref"`
+}
+
+// LocalIndexBuilder returns the builder whose state is saved in the cache, the state is keyed by the specification directory
+func (c IndexCache) LocalIndexBuilder(specDir string) LocalIndexBuilder {
+	specDir, _ = filepath.Abs(specDir)
+	hash := sha256.Sum256([]byte(filepath.Clean(specDir)))
+	return LocalIndexBuilder{
+		StateFile: filepath.Join(c.Dir, "local", hex.EncodeToString(hash[:8])+".json"),
+	}
+}
+
+// SplitSpecDir splits the path to the specification directory or a resource provider directory in it,
+// e.g., /specs/specification/compute is split as /specs/specification and compute
+func SplitSpecDir(path string) (string, string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("swagger repo path %q is invalid: %+v", path, err)
+	}
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", "", fmt.Errorf("swagger repo path %q is invalid: path does not exist", path)
+	}
+	if filepath.Base(path) == "specification" {
+		return path, "", nil
+	}
+	if filepath.Base(filepath.Dir(path)) == "specification" {
+		return filepath.Dir(path), filepath.Base(path), nil
+	}
+	return "", "", fmt.Errorf("swagger repo path %q is invalid: must point to \"specification\" or a resource provider directory in it, e.g., /home/projects/azure-rest-api-specs/specification or /home/projects/azure-rest-api-specs/specification/compute", path)
+}
+
+// Build builds the index of the specification directory, the index is limited to the resource provider directory if it's specified, e.g., compute.
+// The commit of the index is the HEAD commit of the swagger repo.
+func (b LocalIndexBuilder) Build(specDir string, resourceProvider string) (*azidx.Index, error) {
+	specDir, err := filepath.Abs(specDir)
+	if err != nil {
+		return nil, fmt.Errorf("specification directory %q is invalid: %+v", specDir, err)
+	}
+	rootDir := specDir
+	if resourceProvider != "" {
+		rootDir = filepath.Join(specDir, resourceProvider)
+	}
+
+	deduplicator, err := b.deduplicator()
+	if err != nil {
+		return nil, err
+	}
+
+	specs, err := collectLocalSpecs(rootDir)
+	if err != nil {
+		return nil, fmt.Errorf("collecting specs in %s: %+v", rootDir, err)
+	}
+
+	state := b.loadState()
+	files := make(map[string]localIndexFile, len(specs))
+	changed := make([]string, 0)
+	for _, spec := range specs {
+		relPath, err := filepath.Rel(specDir, spec)
+		if err != nil {
+			return nil, err
+		}
+		relPath = filepath.ToSlash(relPath)
+		file, ok, err := unchangedLocalSpec(spec, state.Files[relPath])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			files[relPath] = file
+			continue
+		}
+		changed = append(changed, relPath)
+	}
+
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	errs := make([]error, 0)
+	sem := make(chan struct{}, runtime.NumCPU())
+	for _, relPath := range changed {
+		wg.Add(1)
+		go func(relPath string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			file, err := parseLocalSpec(specDir, relPath)
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("parsing spec %s: %+v", relPath, err))
+				return
+			}
+			files[relPath] = *file
+		}(relPath)
+	}
+	wg.Wait()
+	if len(errs) != 0 {
+		return nil, errs[0]
+	}
+	logrus.Infof("%d swagger files are parsed, %d unchanged swagger files are loaded from the index state", len(changed), len(specs)-len(changed))
+
+	// the files of the other resource providers are kept, so they're not parsed again by the other builds
+	for relPath := range state.Files {
+		if resourceProvider == "" || strings.HasPrefix(relPath, resourceProvider+"/") {
+			delete(state.Files, relPath)
+		}
+	}
+	for relPath, file := range files {
+		state.Files[relPath] = file
+	}
+	if err := b.saveState(state); err != nil {
+		logrus.Warnf("failed to save the index state to %s: %+v", b.StateFile, err)
+	}
+
+	ops, err := mergeOperations(files, deduplicator)
+	if err != nil {
+		return nil, err
+	}
+	return &azidx.Index{
+		Commit:            gitHeadCommit(specDir),
+		ResourceProviders: layerOperations(ops),
+	}, nil
+}
+
+func (b LocalIndexBuilder) deduplicator() (azidx.Deduplicator, error) {
+	data := defaultIndexDedup
+	if b.DedupFile != "" {
+		var err error
+		if data, err = os.ReadFile(b.DedupFile); err != nil {
+			return nil, fmt.Errorf("reading dedup file %s: %+v", b.DedupFile, err)
+		}
+	}
+	var records azidx.DeduplicateRecords
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("unmarshal dedup file %s: %+v", b.DedupFile, err)
+	}
+	deduplicator, err := records.ToDeduplicator()
+	if err != nil {
+		return nil, fmt.Errorf("converting dedup file %s: %+v", b.DedupFile, err)
+	}
+	return deduplicator, nil
+}
+
+func (b LocalIndexBuilder) loadState() localIndexState {
+	state := localIndexState{
+		Version: localIndexStateVersion,
+		Files:   map[string]localIndexFile{},
+	}
+	if b.StateFile == "" {
+		return state
+	}
+	data, err := os.ReadFile(b.StateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logrus.Warnf("failed to read the index state %s, the index is built from scratch: %+v", b.StateFile, err)
+		}
+		return state
+	}
+	var saved localIndexState
+	if err := json.Unmarshal(data, &saved); err != nil {
+		logrus.Warnf("failed to unmarshal the index state %s, the index is built from scratch: %+v", b.StateFile, err)
+		return state
+	}
+	if saved.Version != localIndexStateVersion || saved.Files == nil {
+		return state
+	}
+	return saved
+}
+
+func (b LocalIndexBuilder) saveState(state localIndexState) error {
+	if b.StateFile == "" {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(b.StateFile), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(b.StateFile, data)
+}
+
+// collectLocalSpecs collects the swagger files listed in the readme.md of each resource provider, it's the same as the azidx.BuildIndex
+func collectLocalSpecs(rootDir string) ([]string, error) {
+	specs := make(map[string]bool)
+	err := filepath.WalkDir(rootDir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if strings.EqualFold(d.Name(), "data-plane") || strings.EqualFold(d.Name(), "examples") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Name() != "readme.md" {
+			return nil
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return fmt.Errorf("reading file %s: %v", p, err)
+		}
+		l, err := azidx.SpecListFromReadmeMD(content)
+		if err != nil {
+			return fmt.Errorf("retrieving spec list from %s: %v", p, err)
+		}
+		for _, relPath := range l {
+			specs[filepath.Join(filepath.Dir(p), relPath)] = true
+		}
+		return filepath.SkipDir
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0, len(specs))
+	for spec := range specs {
+		out = append(out, spec)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// unchangedLocalSpec returns whether the swagger file is unchanged since it's parsed, the hash is only computed when the size or the modification time changes
+func unchangedLocalSpec(spec string, file localIndexFile) (localIndexFile, bool, error) {
+	if file.Hash == "" {
+		return file, false, nil
+	}
+	stat, err := os.Stat(spec)
+	if err != nil {
+		return file, false, fmt.Errorf("reading file %s: %v", spec, err)
+	}
+	if stat.Size() == file.Size && stat.ModTime().Equal(file.ModTime) {
+		return file, true, nil
+	}
+	hash, err := fileHash(spec)
+	if err != nil {
+		return file, false, err
+	}
+	if hash != file.Hash {
+		return file, false, nil
+	}
+	file.Size = stat.Size()
+	file.ModTime = stat.ModTime()
+	return file, true, nil
+}
+
+func fileHash(filename string) (string, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("reading file %s: %v", filename, err)
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// parseLocalSpec parses the operations in the swagger file, the operations are located in the same way as the azidx.BuildIndex
+func parseLocalSpec(specDir, relPath string) (*localIndexFile, error) {
+	p := filepath.Join(specDir, filepath.FromSlash(relPath))
+	stat, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := fileHash(p)
+	if err != nil {
+		return nil, err
+	}
+	out := &localIndexFile{
+		Size:       stat.Size(),
+		ModTime:    stat.ModTime(),
+		Hash:       hash,
+		Operations: make([]localIndexOperation, 0),
+	}
+
+	doc, err := loads.Spec(p)
+	if err != nil {
+		return nil, fmt.Errorf("loading spec: %v", err)
+	}
+	swagger := doc.Spec()
+	if swagger.Paths == nil || len(swagger.Paths.Paths) == 0 {
+		return out, nil
+	}
+	if swagger.Info == nil || swagger.Info.Version == "" {
+		return nil, fmt.Errorf(`spec has no "Info.Version"`)
+	}
+	pinfo, err := specpath.SpecPathInfo(filepath.FromSlash(relPath))
+	if err != nil {
+		return nil, fmt.Errorf("new spec path info: %v", err)
+	}
+
+	seen := make(map[azidx.OpLocator]map[azidx.PathPatternStr]bool)
+	for path, pathItem := range swagger.Paths.Paths {
+		for _, opKind := range azidx.PossibleOperationKinds {
+			if azidx.PathItemOperation(pathItem, opKind) == nil {
+				continue
+			}
+			pathPatterns, err := azidx.ParsePathPatternFromSwagger(p, swagger, path, opKind)
+			if err != nil {
+				return nil, fmt.Errorf("parsing path pattern for %s (%s): %v", path, opKind, err)
+			}
+			for _, pathPattern := range pathPatterns {
+				opLoc, ok := operationLocator(pathPattern, pinfo, swagger.Info.Version, opKind)
+				if !ok {
+					continue
+				}
+				pathPatternStr := azidx.PathPatternStr(strings.ToUpper(pathPattern.String()))
+				if seen[opLoc] == nil {
+					seen[opLoc] = make(map[azidx.PathPatternStr]bool)
+				}
+				if seen[opLoc][pathPatternStr] {
+					return nil, fmt.Errorf("operation locator %#v for path pattern %s is duplicated", opLoc, pathPatternStr)
+				}
+				seen[opLoc][pathPatternStr] = true
+				out.Operations = append(out.Operations, localIndexOperation{
+					OpLocator:   opLoc,
+					PathPattern: pathPatternStr,
+					Ref:         relPath + "#/paths/" + jsonpointer.Escape(path) + "/" + strings.ToLower(string(opKind)),
+				})
+			}
+		}
+	}
+	sort.Slice(out.Operations, func(i, j int) bool {
+		return out.Operations[i].Ref+string(out.Operations[i].PathPattern) < out.Operations[j].Ref+string(out.Operations[j].PathPattern)
+	})
+	return out, nil
+}
+
+// operationLocator identifies the resource provider, the resource type and the action of the API path, it returns false for the too generic API paths
+func operationLocator(pathPattern azidx.PathPattern, pinfo *specpath.Info, version string, opKind azidx.OperationKind) (azidx.OpLocator, bool) {
+	segments := pathPattern.Segments
+	providerIdx := -1
+	for i := len(segments) - 1; i >= 0; i-- {
+		if strings.EqualFold(segments[i].FixedName, "providers") {
+			providerIdx = i
+			break
+		}
+	}
+
+	var rp, act string
+	var rpIsGlob bool
+	var nextIdx int
+	if providerIdx == -1 || len(segments) == providerIdx+1 {
+		// the API paths without the provider segment are only allowed in the Microsoft.Resources
+		if !strings.EqualFold(pinfo.ResourceProviderMS, azidx.ResourceRP) {
+			return azidx.OpLocator{}, false
+		}
+		rp = azidx.ResourceRP
+	} else {
+		rp = segments[providerIdx+1].FixedName
+		rpIsGlob = segments[providerIdx+1].IsParameter
+		nextIdx = providerIdx + 2
+	}
+
+	// ignore the API paths which have only one multi-segmented parameter, or whose provider and all the following segments are parameterized
+	if len(segments) == 1 {
+		return azidx.OpLocator{}, false
+	}
+	if rpIsGlob {
+		allParameterized := true
+		for _, seg := range segments[nextIdx:] {
+			allParameterized = allParameterized && seg.IsParameter
+		}
+		if allParameterized {
+			return azidx.OpLocator{}, false
+		}
+	}
+
+	lastIdx := len(segments)
+	if len(segments[nextIdx:])%2 == 1 {
+		lastIdx--
+		seg := segments[len(segments)-1]
+		switch {
+		case seg.IsParameter && seg.IsMulti:
+			return azidx.OpLocator{}, false
+		case seg.IsParameter:
+			act = azidx.Wildcard
+		default:
+			act = seg.FixedName
+		}
+	}
+
+	rts := make([]string, 0)
+	for i := nextIdx; i < lastIdx; i += 2 {
+		seg := segments[i]
+		switch {
+		case seg.IsParameter && seg.IsMulti:
+			continue
+		case seg.IsParameter:
+			rts = append(rts, azidx.Wildcard)
+		default:
+			rts = append(rts, seg.FixedName)
+		}
+	}
+
+	opLoc := azidx.OpLocator{
+		RP:      strings.ToUpper(rp),
+		Version: version,
+		RT:      strings.ToUpper("/" + strings.Join(rts, "/")),
+		ACT:     strings.ToUpper(act),
+		Method:  opKind,
+	}
+	if rpIsGlob {
+		opLoc.RP = azidx.Wildcard
+	}
+	return opLoc, true
+}
+
+// mergeOperations merges the operations of the swagger files, the duplicated operations are resolved in the same way as the azidx.BuildIndex:
+// the one whose swagger path matches the resource provider and the api-version is used, otherwise the deduplicator picks one, uses any of them or ignores them.
+// The first one ordered by the swagger path is used if they're still not resolved.
+func mergeOperations(files map[string]localIndexFile, deduplicator azidx.Deduplicator) (azidx.FlattenOpIndex, error) {
+	type dupKey struct {
+		azidx.OpLocator
+		azidx.PathPatternStr
+	}
+	candidates := make(map[dupKey][]string)
+	for _, file := range files {
+		for _, op := range file.Operations {
+			key := dupKey{OpLocator: op.OpLocator, PathPatternStr: op.PathPattern}
+			candidates[key] = append(candidates[key], op.Ref)
+		}
+	}
+
+	out := azidx.FlattenOpIndex{}
+	for key, refs := range candidates {
+		sort.Strings(refs)
+		picked, ok, err := dedupOperation(key.OpLocator, key.PathPatternStr, refs, deduplicator)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if out[key.OpLocator] == nil {
+			out[key.OpLocator] = azidx.OperationRefs{}
+		}
+		out[key.OpLocator][key.PathPatternStr] = jsonreference.MustCreateRef(picked)
+	}
+	return out, nil
+}
+
+// dedupOperation returns the reference of the operation which is defined by the references, it returns false if the operation is ignored by the deduplicator
+func dedupOperation(loc azidx.OpLocator, pathPattern azidx.PathPatternStr, refs []string, deduplicator azidx.Deduplicator) (string, bool, error) {
+	if len(refs) == 1 {
+		return refs[0], true, nil
+	}
+
+	matched := make([]string, 0)
+	for _, ref := range refs {
+		pinfo, err := specpath.SpecPathInfo(filepath.FromSlash(strings.Split(ref, "#")[0]))
+		if err != nil {
+			return "", false, fmt.Errorf("new spec path info: %v", err)
+		}
+		if strings.EqualFold(pinfo.ResourceProviderMS, loc.RP) && strings.EqualFold(pinfo.Version, loc.Version) &&
+			pinfo.IsPreview == strings.HasSuffix(loc.Version, "preview") {
+			matched = append(matched, ref)
+		}
+	}
+	if len(matched) == 1 {
+		return matched[0], true, nil
+	}
+
+	var dedupOp *azidx.DedupOp
+	matcherName := ""
+	for matcher, op := range deduplicator {
+		op := op
+		if !matcher.Match(loc, string(pathPattern)) {
+			continue
+		}
+		if dedupOp != nil {
+			return "", false, fmt.Errorf("duplicate matchers in the deduplicator match %+v %s: %s vs %s", loc, pathPattern, matcherName, matcher.Name)
+		}
+		dedupOp, matcherName = &op, matcher.Name
+	}
+	switch {
+	case dedupOp == nil:
+		logrus.Debugf("duplicate definitions of %+v %s: %v, %s is used", loc, pathPattern, refs, refs[0])
+	case dedupOp.Picker != nil:
+		picked := make([]string, 0)
+		for _, ref := range refs {
+			if dedupOp.Picker.Match(jsonreference.MustCreateRef(ref)) {
+				picked = append(picked, ref)
+			}
+		}
+		if len(picked) == 1 {
+			return picked[0], true, nil
+		}
+		logrus.Debugf("dedup matcher %s picked %d of the duplicate definitions of %+v %s: %v, %s is used", matcherName, len(picked), loc, pathPattern, refs, refs[0])
+	case dedupOp.Ignore:
+		return "", false, nil
+	}
+	return refs[0], true, nil
+}
+
+// layerOperations turns the flattened operations into the layered index, it's the same as the azidx.BuildIndex
+func layerOperations(ops azidx.FlattenOpIndex) azidx.ResourceProviders {
+	rps := azidx.ResourceProviders{}
+	for loc, refs := range ops {
+		if rps[loc.RP] == nil {
+			rps[loc.RP] = azidx.APIVersions{}
+		}
+		if rps[loc.RP][loc.Version] == nil {
+			rps[loc.RP][loc.Version] = azidx.APIMethods{}
+		}
+		if rps[loc.RP][loc.Version][loc.Method] == nil {
+			rps[loc.RP][loc.Version][loc.Method] = azidx.ResourceTypes{}
+		}
+		info := rps[loc.RP][loc.Version][loc.Method][loc.RT]
+		if info == nil {
+			info = &azidx.OperationInfo{}
+			rps[loc.RP][loc.Version][loc.Method][loc.RT] = info
+		}
+		if loc.ACT == "" {
+			info.OperationRefs = refs
+			continue
+		}
+		if info.Actions == nil {
+			info.Actions = map[string]azidx.OperationRefs{}
+		}
+		info.Actions[loc.ACT] = refs
+	}
+	return rps
+}
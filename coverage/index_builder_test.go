@@ -0,0 +1,215 @@
+package coverage_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+func writeLocalSpec(t *testing.T, specDir, rp, namespace string, resourceTypes ...string) {
+	dir := filepath.Join(specDir, rp, "resource-manager")
+	versionDir := filepath.Join(dir, namespace, "stable", "2023-01-01")
+	if err := os.MkdirAll(versionDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	readme := fmt.Sprintf("### Tag: package-2023-01\n\n```yaml $(tag) == 'package-2023-01'\ninput-file:\n  - %s/stable/2023-01-01/%s.json\n```\n", namespace, rp)
+	if err := os.WriteFile(filepath.Join(dir, "readme.md"), []byte(readme), 0644); err != nil {
+		t.Fatal(err)
+	}
+	paths := make([]string, 0)
+	for _, resourceType := range resourceTypes {
+		paths = append(paths, fmt.Sprintf(`"/subscriptions/{subscriptionId}/providers/%s/%s/{name}": {
+      "put": {
+        "operationId": "%s_CreateOrUpdate",
+        "parameters": [
+          {"name": "subscriptionId", "in": "path", "required": true, "type": "string"},
+          {"name": "name", "in": "path", "required": true, "type": "string"}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }`, namespace, resourceType, resourceType))
+	}
+	swagger := fmt.Sprintf(`{
+  "swagger": "2.0",
+  "info": {"title": "%s", "version": "2023-01-01"},
+  "paths": {
+    %s
+  }
+}`, rp, strings.Join(paths, ",\n    "))
+	if err := os.WriteFile(filepath.Join(versionDir, rp+".json"), []byte(swagger), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func lookupLocalIndex(index *azidx.Index, namespace, resourceType string) error {
+	uRL, err := url.Parse(fmt.Sprintf("https://management.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/providers/%s/%s/test?api-version=2023-01-01", namespace, resourceType))
+	if err != nil {
+		return err
+	}
+	_, err = index.Lookup("PUT", *uRL)
+	return err
+}
+
+func TestLocalIndexBuilder(t *testing.T) {
+	specDir := filepath.Join(t.TempDir(), "specification")
+	writeLocalSpec(t, specDir, "foo", "Microsoft.Foo", "foos")
+	writeLocalSpec(t, specDir, "bar", "Microsoft.Bar", "bars")
+	builder := coverage.LocalIndexBuilder{StateFile: filepath.Join(t.TempDir(), "state.json")}
+
+	index, err := builder.Build(specDir, "")
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Foo", "foos"); err != nil {
+		t.Fatalf("expect foos in the index, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Bar", "bars"); err != nil {
+		t.Fatalf("expect bars in the index, but got %+v", err)
+	}
+	if _, err := os.Stat(builder.StateFile); err != nil {
+		t.Fatalf("expect the state file is saved, but got %+v", err)
+	}
+
+	// the changed swagger file is parsed again
+	writeLocalSpec(t, specDir, "foo", "Microsoft.Foo", "foos", "widgets")
+	index, err = builder.Build(specDir, "")
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Foo", "widgets"); err != nil {
+		t.Fatalf("expect the added widgets in the index, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Bar", "bars"); err != nil {
+		t.Fatalf("expect the unchanged bars in the index, but got %+v", err)
+	}
+
+	// the index limited to a resource provider doesn't parse the other ones
+	if err := os.WriteFile(filepath.Join(specDir, "bar", "resource-manager", "Microsoft.Bar", "stable", "2023-01-01", "bar.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	index, err = builder.Build(specDir, "foo")
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Foo", "widgets"); err != nil {
+		t.Fatalf("expect widgets in the index, but got %+v", err)
+	}
+	if err := lookupLocalIndex(index, "Microsoft.Bar", "bars"); err == nil {
+		t.Fatalf("expect bars not in the index limited to foo")
+	}
+	if _, err = builder.Build(specDir, ""); err == nil {
+		t.Fatalf("expect an error for the invalid swagger file")
+	}
+}
+
+func TestSplitSpecDir(t *testing.T) {
+	specDir := filepath.Join(t.TempDir(), "specification")
+	if err := os.MkdirAll(filepath.Join(specDir, "foo"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, rp, err := coverage.SplitSpecDir(specDir + string(os.PathSeparator))
+	if err != nil || dir != specDir || rp != "" {
+		t.Fatalf("expect %s without resource provider, but got %s, %s, %+v", specDir, dir, rp, err)
+	}
+	dir, rp, err = coverage.SplitSpecDir(filepath.Join(specDir, "foo"))
+	if err != nil || dir != specDir || rp != "foo" {
+		t.Fatalf("expect %s and foo, but got %s, %s, %+v", specDir, dir, rp, err)
+	}
+	if _, _, err = coverage.SplitSpecDir(filepath.Dir(specDir)); err == nil {
+		t.Fatalf("expect an error for the directory which isn't the specification directory")
+	}
+	if _, _, err = coverage.SplitSpecDir(filepath.Join(specDir, "bar")); err == nil {
+		t.Fatalf("expect an error for the directory which doesn't exist")
+	}
+}
+
+func writeDuplicateSpecs(t *testing.T, specDir, rp, namespace string, files map[string]string) {
+	dir := filepath.Join(specDir, rp, "resource-manager")
+	inputs := make([]string, 0)
+	for file, version := range files {
+		inputs = append(inputs, "  - "+file)
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		swagger := fmt.Sprintf(`{
+  "swagger": "2.0",
+  "info": {"title": "%s", "version": "%s"},
+  "paths": {
+    "/subscriptions/{subscriptionId}/providers/%s/widgets/{name}": {
+      "get": {
+        "operationId": "Widgets_Get",
+        "parameters": [
+          {"name": "subscriptionId", "in": "path", "required": true, "type": "string"},
+          {"name": "name", "in": "path", "required": true, "type": "string"}
+        ],
+        "responses": {"200": {"description": "OK"}}
+      }
+    }
+  }
+}`, rp, version, namespace)
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(swagger), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	readme := fmt.Sprintf("### Tag: package-2023-01\n\n```yaml $(tag) == 'package-2023-01'\ninput-file:\n%s\n```\n", strings.Join(inputs, "\n"))
+	if err := os.WriteFile(filepath.Join(dir, "readme.md"), []byte(readme), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLocalIndexBuilder_SameAsBuildIndex(t *testing.T) {
+	specDir := filepath.Join(t.TempDir(), "specification")
+	// resolved by the swagger path which matches the api-version
+	writeDuplicateSpecs(t, specDir, "foo", "Microsoft.Foo", map[string]string{
+		"Microsoft.Foo/stable/2023-01-01/foo.json":    "2023-01-01",
+		"Microsoft.Foo/stable/2022-01-01/legacy.json": "2023-01-01",
+	})
+	// resolved by the picker of the dedup file
+	writeDuplicateSpecs(t, specDir, "bar", "Microsoft.Bar", map[string]string{
+		"Microsoft.Bar/stable/2023-01-01/bar.json":     "2023-01-01",
+		"Microsoft.Bar/stable/2023-01-01/widgets.json": "2023-01-01",
+	})
+	// ignored by the dedup file
+	writeDuplicateSpecs(t, specDir, "baz", "Microsoft.Baz", map[string]string{
+		"Microsoft.Baz/stable/2023-01-01/baz.json":     "2023-01-01",
+		"Microsoft.Baz/stable/2023-01-01/widgets.json": "2023-01-01",
+	})
+	dedupFile := filepath.Join(t.TempDir(), "dedup.json")
+	dedup := `{
+  "bar": {"matcher": {"rp": "MICROSOFT.BAR"}, "picker": {"spec_path": "widgets.json$"}},
+  "baz": {"matcher": {"rp": "MICROSOFT.BAZ"}, "ignore": true}
+}`
+	if err := os.WriteFile(dedupFile, []byte(dedup), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := azidx.BuildIndex(specDir, dedupFile)
+	if err != nil {
+		t.Fatalf("building the index by azidx: %+v", err)
+	}
+	actual, err := coverage.LocalIndexBuilder{DedupFile: dedupFile}.Build(specDir, "")
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	expectedJson, _ := json.Marshal(expected.ResourceProviders)
+	actualJson, _ := json.Marshal(actual.ResourceProviders)
+	if string(actualJson) != string(expectedJson) {
+		t.Fatalf("expect the same operations as azidx.BuildIndex:\n%s\nbut got:\n%s", expectedJson, actualJson)
+	}
+	for _, expected := range []string{"foo/resource-manager/Microsoft.Foo/stable/2023-01-01/foo.json", "Microsoft.Bar/stable/2023-01-01/widgets.json"} {
+		if !strings.Contains(string(actualJson), expected) {
+			t.Errorf("expect %s in the index, but got %s", expected, actualJson)
+		}
+	}
+	if strings.Contains(string(actualJson), "MICROSOFT.BAZ") {
+		t.Errorf("expect the ignored operations not in the index, but got %s", actualJson)
+	}
+}
@@ -0,0 +1,221 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+const (
+	// IndexMaxAge is the age after which the cached index is stale, the stale index is still used until it's updated
+	IndexMaxAge = 7 * 24 * time.Hour
+
+	indexCurrentFileName = "current"
+	indexInfoSuffix      = ".info.json"
+)
+
+var commitPattern = regexp.MustCompile(`^[0-9a-zA-Z._-]+$`)
+
+// IndexInfo describes an index in the cache
+type IndexInfo struct {
+	// Commit is the commit of the azure-rest-api-specs repo which the index is built on
+	Commit string `json:"commit"`
+	// Source is the URL which the index is downloaded from, or the specification directory which the index is built from
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	// Path is the index file, it can be used as the -swagger-index-file option
+	Path string `json:"-"`
+	// Current is whether the index is used when no commit is pinned
+	Current bool `json:"-"`
+}
+
+// IsStale returns whether the index is older than IndexMaxAge
+func (i IndexInfo) IsStale() bool {
+	return time.Since(i.UpdatedAt) > IndexMaxAge
+}
+
+// IndexCache is the cache of the swagger indexes, the indexes are keyed by the commits of the azure-rest-api-specs repo.
+// The files are replaced atomically, so the cache can be shared by the concurrent runs.
+type IndexCache struct {
+	Dir string
+}
+
+// DefaultIndexCache returns the index cache in the armstrong cache directory, next to the cached terraform executables
+func DefaultIndexCache() (*IndexCache, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("error finding the user cache directory: %w", err)
+	}
+	return &IndexCache{Dir: filepath.Join(cacheDir, "armstrong", "index")}, nil
+}
+
+// Info returns the index of the commit, the current index is returned if the commit is empty, it returns nil if the index is not cached
+func (c IndexCache) Info(commit string) (*IndexInfo, error) {
+	current, err := c.current()
+	if err != nil {
+		return nil, err
+	}
+	if commit == "" {
+		commit = current
+	}
+	if commit == "" {
+		return nil, nil
+	}
+	if !commitPattern.MatchString(commit) {
+		return nil, fmt.Errorf("commit %q is invalid", commit)
+	}
+	data, err := os.ReadFile(filepath.Join(c.Dir, commit+indexInfoSuffix))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var info IndexInfo
+	if err := json.Unmarshal(data, &info); err != nil {
+		return nil, fmt.Errorf("unmarshal the index info of %s: %+v", commit, err)
+	}
+	info.Path = filepath.Join(c.Dir, commit+".json")
+	info.Current = commit == current
+	return &info, nil
+}
+
+// List returns the cached indexes, the latest updated one is the first
+func (c IndexCache) List() ([]IndexInfo, error) {
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	out := make([]IndexInfo, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), indexInfoSuffix) {
+			continue
+		}
+		info, err := c.Info(strings.TrimSuffix(entry.Name(), indexInfoSuffix))
+		if err != nil {
+			return nil, err
+		}
+		if info != nil {
+			out = append(out, *info)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].UpdatedAt.After(out[j].UpdatedAt)
+	})
+	return out, nil
+}
+
+// Load returns the index of the commit, the current index is returned if the commit is empty, it returns nil if the index is not cached
+func (c IndexCache) Load(commit string) (*azidx.Index, *IndexInfo, error) {
+	info, err := c.Info(commit)
+	if err != nil || info == nil {
+		return nil, nil, err
+	}
+	index, err := readIndexFile(info.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return index, info, nil
+}
+
+// Store saves the index and makes it the current one
+func (c IndexCache) Store(index *azidx.Index, source string) (*IndexInfo, error) {
+	if index == nil || index.Commit == "" || !commitPattern.MatchString(index.Commit) {
+		return nil, fmt.Errorf("the index doesn't have a valid commit")
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating index cache dir %q: %w", c.Dir, err)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, fmt.Errorf("marshal index: %+v", err)
+	}
+	info := IndexInfo{
+		Commit:    index.Commit,
+		Source:    source,
+		UpdatedAt: time.Now().UTC(),
+		Path:      filepath.Join(c.Dir, index.Commit+".json"),
+		Current:   true,
+	}
+	infoData, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshal index info: %+v", err)
+	}
+	if err := writeFileAtomic(info.Path, data); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(c.Dir, index.Commit+indexInfoSuffix), infoData); err != nil {
+		return nil, err
+	}
+	if err := writeFileAtomic(filepath.Join(c.Dir, indexCurrentFileName), []byte(index.Commit)); err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+// Update downloads the latest online index into the cache
+func (c IndexCache) Update() (*IndexInfo, error) {
+	index, err := downloadIndex(indexFileURL)
+	if err != nil {
+		return nil, err
+	}
+	return c.Store(index, indexFileURL)
+}
+
+// Build builds the index from the specification directory of the azure-rest-api-specs repo into the cache, the commit is the HEAD commit of the repo.
+// Only the swagger files which are changed since the last build are parsed.
+func (c IndexCache) Build(specDir string) (*IndexInfo, error) {
+	specDir, resourceProvider, err := SplitSpecDir(specDir)
+	if err != nil {
+		return nil, err
+	}
+	if resourceProvider != "" {
+		return nil, fmt.Errorf("the index in the cache must cover all the resource providers, specify the specification directory %s instead", specDir)
+	}
+	index, err := c.LocalIndexBuilder(specDir).Build(specDir, "")
+	if err != nil {
+		return nil, fmt.Errorf("building index from %s: %+v", specDir, err)
+	}
+	if index.Commit == "" {
+		return nil, fmt.Errorf("%s is not in a git repository, the index can't be keyed by the commit", specDir)
+	}
+	return c.Store(index, specDir)
+}
+
+func (c IndexCache) current() (string, error) {
+	data, err := os.ReadFile(filepath.Join(c.Dir, indexCurrentFileName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// writeFileAtomic writes the file by renaming a temporary file, so the readers never see a partially written file
+func writeFileAtomic(filename string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(filename), filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filename)
+}
@@ -0,0 +1,85 @@
+package coverage_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/magodo/azure-rest-api-index/azidx"
+)
+
+func TestIndexCache(t *testing.T) {
+	cache := coverage.IndexCache{Dir: t.TempDir()}
+
+	info, err := cache.Info("")
+	if err != nil || info != nil {
+		t.Fatalf("expect no index in the empty cache, but got %+v, %+v", info, err)
+	}
+
+	var wg sync.WaitGroup
+	for _, commit := range []string{"1111111", "2222222", "1111111"} {
+		wg.Add(1)
+		go func(commit string) {
+			defer wg.Done()
+			if _, err := cache.Store(&azidx.Index{Commit: commit}, "https://example.com/index.json.zip"); err != nil {
+				t.Errorf("expect no error when storing %s, but got %+v", commit, err)
+			}
+		}(commit)
+	}
+	wg.Wait()
+	if _, err := cache.Store(&azidx.Index{Commit: "2222222"}, "/specs/specification"); err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+
+	infos, err := cache.List()
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	if len(infos) != 2 || infos[0].Commit != "2222222" || !infos[0].Current || infos[1].Current {
+		t.Fatalf("expect 2 indexes and 2222222 is the current one, but got %+v", infos)
+	}
+
+	index, info, err := cache.Load("")
+	if err != nil || index == nil || index.Commit != "2222222" || info.Source != "/specs/specification" {
+		t.Fatalf("expect the current index 2222222, but got %+v, %+v, %+v", index, info, err)
+	}
+	index, _, err = cache.Load("1111111")
+	if err != nil || index == nil || index.Commit != "1111111" {
+		t.Fatalf("expect the index 1111111, but got %+v, %+v", index, err)
+	}
+	index, _, err = cache.Load("3333333")
+	if err != nil || index != nil {
+		t.Fatalf("expect no index of 3333333, but got %+v, %+v", index, err)
+	}
+	if _, _, err = cache.Load("../1111111"); err == nil {
+		t.Fatalf("expect an error for the invalid commit")
+	}
+	if _, err = cache.Store(&azidx.Index{}, "/specs/specification"); err == nil {
+		t.Fatalf("expect an error for the index without commit")
+	}
+}
+
+func TestIndexInfo_IsStale(t *testing.T) {
+	if (coverage.IndexInfo{UpdatedAt: time.Now()}).IsStale() {
+		t.Errorf("expect the index updated now is not stale")
+	}
+	if !(coverage.IndexInfo{UpdatedAt: time.Now().Add(-coverage.IndexMaxAge - time.Hour)}).IsStale() {
+		t.Errorf("expect the index updated before the max age is stale")
+	}
+}
+
+func TestGetIndex_PinnedCommitNotCached(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("LocalAppData", dir)
+	coverage.SetIndexCommit("0000000")
+	defer coverage.SetIndexCommit("")
+
+	_, err := coverage.GetIndex("")
+	if err == nil || !strings.Contains(err.Error(), "is not cached") {
+		t.Fatalf("expect the pinned commit is not cached, but got %+v", err)
+	}
+}
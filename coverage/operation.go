@@ -0,0 +1,278 @@
+package coverage
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/azure/armstrong/utils"
+	openapispec "github.com/go-openapi/spec"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	githubSpecsURL   = "https://github.com/Azure/azure-rest-api-specs/blob/"
+	githubRawSpecURL = "https://raw.githubusercontent.com/Azure/azure-rest-api-specs/"
+)
+
+// {swaggerPath@commit: content}
+var specContentCache, _ = lru.New[string, []byte](30)
+
+// SwaggerOperation is the operation in the swagger spec which serves a request
+type SwaggerOperation struct {
+	OperationID string
+	ApiPath     string
+	Method      string
+	// SwaggerPath is the local path or the online URL of the swagger file which defines the operation
+	SwaggerPath string
+	// Pointer is the JSON pointer of the operation in the swagger file, e.g., /paths/~1subscriptions~1{subscriptionId}/get
+	Pointer string
+	// ModelName and ModelSwaggerPath are the request body model of the operation, they're empty if the operation has no request body
+	ModelName        string
+	ModelSwaggerPath string
+	// Commit is the commit of the azure-rest-api-specs repo which the online index is built on, it's empty for the local swagger files
+	Commit string
+}
+
+// GetOperationFromLocalDir returns the operation which serves the request in the local swagger file or directory, it returns nil if not found
+func GetOperationFromLocalDir(requestPath, method, swaggerPath string) (*SwaggerOperation, error) {
+	swaggerPath, err := filepath.Abs(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Stat(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{swaggerPath}
+	if file.IsDir() {
+		files, err = utils.ListFiles(swaggerPath, ".json", 1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for _, filename := range files {
+		model, err := GetModelInfoFromLocalSpecFile(requestPath, filename, method)
+		if err != nil {
+			logrus.Warnf("failed to get model info from local spec file %v: %+v", filename, err)
+		}
+		if model == nil {
+			continue
+		}
+		return &SwaggerOperation{
+			OperationID:      model.OperationID,
+			ApiPath:          model.ApiPath,
+			Method:           strings.ToUpper(method),
+			SwaggerPath:      filename,
+			Pointer:          operationPointer(model.ApiPath, method),
+			ModelName:        model.ModelName,
+			ModelSwaggerPath: model.SwaggerPath,
+		}, nil
+	}
+	return nil, nil
+}
+
+// GetOperationFromIndex returns the operation which serves the request in the online index, the swagger files are from the commit of the index
+func GetOperationFromIndex(requestPath, apiVersion, method, indexFilePath string) (*SwaggerOperation, error) {
+	index, err := GetIndex(indexFilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	resourceURL := indexLookupURL(requestPath, apiVersion)
+	uRL, err := url.Parse(resourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("parsing URL %s: %+v", resourceURL, err)
+	}
+	ref, err := index.Lookup(method, *uRL)
+	if err != nil {
+		return nil, fmt.Errorf("lookup %s URL %s in index: %+v", method, resourceURL, err)
+	}
+
+	model, err := GetModelInfoFromIndexRef(openapispec.Ref{Ref: *ref}, azureRepoURL)
+	if err != nil {
+		return nil, fmt.Errorf("get model %s: %+v", ref, err)
+	}
+
+	out := &SwaggerOperation{
+		ApiPath:          model.ApiPath,
+		Method:           strings.ToUpper(method),
+		SwaggerPath:      azureRepoURL + filepath.ToSlash(ref.GetURL().Path),
+		Pointer:          ref.GetPointer().String(),
+		ModelName:        model.ModelName,
+		ModelSwaggerPath: model.SwaggerPath,
+		Commit:           index.Commit,
+	}
+
+	content, err := specContent(out.SwaggerPath, out.Commit)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshal %s: %+v", out.SwaggerPath, err)
+	}
+	for _, token := range ref.GetPointer().DecodedTokens() {
+		m, ok := doc.(map[string]interface{})
+		if !ok {
+			doc = nil
+			break
+		}
+		doc = m[token]
+	}
+	if operation, ok := doc.(map[string]interface{}); ok {
+		out.OperationID, _ = operation["operationId"].(string)
+	}
+	return out, nil
+}
+
+// SwaggerPermalink returns the link to the value referenced by the JSON pointer in the swagger file, with the line range, e.g.,
+// https://github.com/Azure/azure-rest-api-specs/blob/{commit}/specification/compute/resource-manager/Microsoft.Compute/stable/2020-06-01/compute.json#L9065-L9101
+// The online swagger files are linked at the commit, the local swagger files are linked at the HEAD commit of their git repo,
+// or by their local paths if they're not in the azure-rest-api-specs repo or they have uncommitted changes.
+func SwaggerPermalink(swaggerPath, commit, pointer string) (string, error) {
+	relativePath := ""
+	if strings.HasPrefix(swaggerPath, azureRepoURL) {
+		relativePath = strings.TrimPrefix(swaggerPath, azureRepoURL)
+	} else if index := strings.LastIndex(filepath.ToSlash(swaggerPath), "/specification/"); index != -1 {
+		relativePath = filepath.ToSlash(swaggerPath)[index+len("/specification/"):]
+		commit = ""
+		if !gitFileChanged(swaggerPath) {
+			commit = gitHeadCommit(filepath.Dir(swaggerPath))
+		}
+	}
+
+	content, err := specContent(swaggerPath, commit)
+	if err != nil {
+		return "", err
+	}
+	start, end := utils.JsonPointerRange(content, pointer)
+	if start == 0 {
+		return "", fmt.Errorf("%s is not found in %s", pointer, swaggerPath)
+	}
+	lines := fmt.Sprintf("#L%d-L%d", start, end)
+	if start == end {
+		lines = fmt.Sprintf("#L%d", start)
+	}
+
+	if relativePath == "" || commit == "" {
+		return swaggerPath + lines, nil
+	}
+	return githubSpecsURL + commit + "/specification/" + relativePath + lines, nil
+}
+
+// specContent returns the content of the swagger file, the online swagger file is downloaded at the commit if it's specified
+func specContent(swaggerPath, commit string) ([]byte, error) {
+	key := swaggerPath + "@" + commit
+	if content, ok := specContentCache.Get(key); ok {
+		return content, nil
+	}
+
+	var content []byte
+	if strings.HasPrefix(swaggerPath, "https://") {
+		fileURL := swaggerPath
+		if commit != "" && strings.HasPrefix(swaggerPath, azureRepoURL) {
+			fileURL = githubRawSpecURL + commit + "/specification/" + strings.TrimPrefix(swaggerPath, azureRepoURL)
+		}
+		resp, err := http.Get(fileURL)
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %+v", fileURL, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("downloading %s: unexpected status code %d", fileURL, resp.StatusCode)
+		}
+		content, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("downloading %s: %+v", fileURL, err)
+		}
+	} else {
+		var err error
+		content, err = os.ReadFile(swaggerPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	specContentCache.Add(key, content)
+	return content, nil
+}
+
+// gitHeadCommit returns the HEAD commit of the git repo which contains the directory, it returns empty string if it's not in a git repo
+func gitHeadCommit(dir string) string {
+	output, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		logrus.Debugf("failed to get the git commit of %s: %+v", dir, err)
+		return ""
+	}
+	return strings.TrimSpace(string(output))
+}
+
+// gitFileChanged returns whether the file has uncommitted changes or is not tracked, so the lines at the HEAD commit might be different
+func gitFileChanged(filename string) bool {
+	output, err := exec.Command("git", "-C", filepath.Dir(filename), "status", "--porcelain", "--", filepath.Base(filename)).Output()
+	if err != nil {
+		logrus.Debugf("failed to get the git status of %s: %+v", filename, err)
+		return true
+	}
+	return strings.TrimSpace(string(output)) != ""
+}
+
+func operationPointer(apiPath, method string) string {
+	return "/paths/" + escapePointerToken(apiPath) + "/" + strings.ToLower(method)
+}
+
+// PropertyLocation returns the swagger file and the JSON pointer where the property at the path is declared, e.g., ["properties", "sku", "name"],
+// it returns the location of the deepest property which is found along the path, and empty strings if none is found.
+func (m *Model) PropertyLocation(path []string) (string, string) {
+	swaggerPath, pointer := "", ""
+	current := m
+	base := ""
+	if isDefinitionName(m.ModelName) {
+		base = DefinitionPointer(m.ModelName)
+	}
+	for _, key := range path {
+		if current.Item != nil {
+			if isDefinitionName(current.Item.ModelName) {
+				base = DefinitionPointer(current.Item.ModelName)
+			} else {
+				base += "/items"
+			}
+			current = current.Item
+		}
+		if current.Properties == nil || base == "" {
+			break
+		}
+		child, ok := (*current.Properties)[key]
+		if !ok || child == nil {
+			break
+		}
+		swaggerPath, pointer = child.SourceFile, base+"/properties/"+escapePointerToken(key)
+		base = pointer
+		if isDefinitionName(child.ModelName) {
+			base = DefinitionPointer(child.ModelName)
+		}
+		current = child
+	}
+	return swaggerPath, pointer
+}
+
+// isDefinitionName returns whether the model name is a definition name, the inline models are named like Parent.property and Parent[]
+func isDefinitionName(modelName string) bool {
+	return modelName != "" && !strings.ContainsAny(modelName, ".[{")
+}
+
+// DefinitionPointer returns the JSON pointer of the definition in the swagger file, e.g., /definitions/VirtualMachine
+func DefinitionPointer(modelName string) string {
+	return "/definitions/" + escapePointerToken(modelName)
+}
+
+func escapePointerToken(token string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1")
+}
@@ -5,82 +5,160 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"runtime"
 	"strings"
+	"sync"
 
 	"github.com/ms-henglu/pal/formatter"
 	"github.com/sirupsen/logrus"
 )
 
+// NewOperationPropertiesCoverageReport builds the coverage report of the request bodies in the traces, the traces are resolved to the swagger models in parallel.
+// The API paths are resolved by the path template trie of the swagger path, see GetModelInfoFromLocalDir.
 func NewOperationPropertiesCoverageReport(traceDir string, swaggerPath string) (*CoverageReport, error) {
 	files, err := os.ReadDir(traceDir)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := GetPathTemplateTrie(swaggerPath); err != nil {
+		return nil, err
+	}
 	report := &CoverageReport{
 		Coverages: make(map[string]*CoverageItem),
 	}
 
+	traceFiles := make([]string, 0)
 	for _, file := range files {
-		if !strings.HasSuffix(file.Name(), ".json") {
-			continue
+		if strings.HasSuffix(file.Name(), ".json") {
+			traceFiles = append(traceFiles, file.Name())
 		}
+	}
 
-		data, err := os.ReadFile(path.Join(traceDir, file.Name()))
-		if err != nil {
-			logrus.Warnf("failed to read file %s: %+v", file.Name(), err)
-			continue
-		}
+	// the traces are resolved in parallel, the results are kept in the order of the trace files
+	traces := make([]*traceModel, len(traceFiles))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, filename := range traceFiles {
+		wg.Add(1)
+		go func(i int, filename string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			traces[i] = resolveTrace(path.Join(traceDir, filename), swaggerPath)
+		}(i, filename)
+	}
+	wg.Wait()
 
-		var trace formatter.OavTraffic
-		if err := json.Unmarshal(data, &trace); err != nil {
-			logrus.Warnf("failed to unmarshal file %s: %+v", file.Name(), err)
+	// the models are expanded in parallel, each API path is expanded once
+	expanded := make(map[string]*Model)
+	expanding := make(map[string]bool)
+	var lock sync.Mutex
+	for _, trace := range traces {
+		if trace == nil || trace.model.ModelName == "" {
 			continue
 		}
-
-		swaggerModel, err := GetModelInfoFromLocalDir(removeQueryParameters(trace.LiveRequest.Url), swaggerPath, trace.LiveRequest.Method)
-		if err != nil {
-			logrus.Warnf("failed to get model info from local dir: %+v", err)
+		index := trace.index()
+		if expanding[index] {
 			continue
 		}
+		expanding[index] = true
+		wg.Add(1)
+		go func(index string, swaggerModel *SwaggerModel) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			model, err := Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
+			if err != nil {
+				logrus.Warnf("failed to expand model %s property: %+v", swaggerModel.ModelName, err)
+				return
+			}
+			lock.Lock()
+			defer lock.Unlock()
+			expanded[index] = model
+		}(index, trace.model)
+	}
+	wg.Wait()
 
-		if swaggerModel == nil {
-			// the API is not in the swagger file, usually it's an API that out of the testing scope
+	for _, trace := range traces {
+		if trace == nil {
 			continue
 		}
-
-		index := fmt.Sprintf("%s-%s", trace.LiveRequest.Method, swaggerModel.ApiPath)
-		if swaggerModel.ModelName == "" {
+		index := trace.index()
+		if trace.model.ModelName == "" {
 			// the API has no request body, mark it as fully covered
 			report.Coverages[index] = &CoverageItem{
-				ApiPath:     swaggerModel.ApiPath,
-				DisplayName: swaggerModel.OperationID,
+				ApiPath:     trace.model.ApiPath,
+				DisplayName: trace.model.OperationID,
 				Model: &Model{
 					IsFullyCovered: true,
 				},
 			}
 			continue
 		}
-
+		if expanded[index] == nil {
+			continue
+		}
 		if _, ok := report.Coverages[index]; !ok {
-			expanded, err := Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
-			if err != nil {
-				logrus.Warnf("failed to expand model %s property: %+v", swaggerModel.ModelName, err)
-				continue
-			}
 			report.Coverages[index] = &CoverageItem{
-				ApiPath:     swaggerModel.ApiPath,
-				DisplayName: swaggerModel.OperationID,
-				Model:       expanded,
+				ApiPath:     trace.model.ApiPath,
+				DisplayName: trace.model.OperationID,
+				Model:       expanded[index],
 			}
 		}
-
-		report.Coverages[index].Model.MarkCovered(trace.LiveRequest.Body)
-		report.Coverages[index].Model.CountCoverage()
+		report.Coverages[index].Model.MarkCovered(trace.body)
+	}
+	for index := range expanded {
+		if item, ok := report.Coverages[index]; ok && item.Model == expanded[index] {
+			item.Model.CountCoverage()
+		}
 	}
 
 	return report, nil
 }
 
+// traceModel is the request body of a trace and the swagger model which it's resolved to
+type traceModel struct {
+	method string
+	body   interface{}
+	model  *SwaggerModel
+}
+
+func (t traceModel) index() string {
+	return fmt.Sprintf("%s-%s", t.method, t.model.ApiPath)
+}
+
+// resolveTrace reads the trace file and resolves its swagger model, it returns nil if the trace is invalid or the API is not in the swagger
+func resolveTrace(filename string, swaggerPath string) *traceModel {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		logrus.Warnf("failed to read file %s: %+v", path.Base(filename), err)
+		return nil
+	}
+
+	var trace formatter.OavTraffic
+	if err := json.Unmarshal(data, &trace); err != nil {
+		logrus.Warnf("failed to unmarshal file %s: %+v", path.Base(filename), err)
+		return nil
+	}
+
+	swaggerModel, err := GetModelInfoFromLocalDir(removeQueryParameters(trace.LiveRequest.Url), swaggerPath, trace.LiveRequest.Method)
+	if err != nil {
+		logrus.Warnf("failed to get model info from local dir: %+v", err)
+		return nil
+	}
+
+	if swaggerModel == nil {
+		// the API is not in the swagger file, usually it's an API that out of the testing scope
+		return nil
+	}
+
+	return &traceModel{
+		method: trace.LiveRequest.Method,
+		body:   trace.LiveRequest.Body,
+		model:  swaggerModel,
+	}
+}
+
 func removeQueryParameters(url string) string {
 	return strings.Split(url, "?")[0]
 }
@@ -0,0 +1,111 @@
+package coverage_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/coverage"
+)
+
+func Test_GetOperationFromLocalDir(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get working directory error: %+v", err)
+	}
+	swaggerPath := path.Join(wd, "testdata", "Microsoft.Automation", "stable", "2022-08-08")
+	resourceId := "/subscriptions/12345678-1234-9876-4563-123456789012/resourceGroups/test-resources/providers/Microsoft.Automation/automationAccounts/test-automation-account"
+
+	testcases := []struct {
+		Method      string
+		OperationID string
+		Pointer     string
+		ModelName   string
+	}{
+		{
+			Method:      "PUT",
+			OperationID: "AutomationAccount_CreateOrUpdate",
+			Pointer:     "/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Automation~1automationAccounts~1{automationAccountName}/put",
+			ModelName:   "AutomationAccountCreateOrUpdateParameters",
+		},
+		{
+			Method:      "GET",
+			OperationID: "AutomationAccount_Get",
+			Pointer:     "/paths/~1subscriptions~1{subscriptionId}~1resourceGroups~1{resourceGroupName}~1providers~1Microsoft.Automation~1automationAccounts~1{automationAccountName}/get",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("testcase: %+v", testcase.Method)
+		actual, err := coverage.GetOperationFromLocalDir(resourceId, testcase.Method, swaggerPath)
+		if err != nil {
+			t.Fatalf("get operation from local dir error: %+v", err)
+		}
+		if actual == nil {
+			t.Fatalf("expected operation %s, got nil", testcase.OperationID)
+		}
+		if actual.OperationID != testcase.OperationID {
+			t.Fatalf("expected operationId %s, got %s", testcase.OperationID, actual.OperationID)
+		}
+		if actual.Pointer != testcase.Pointer {
+			t.Fatalf("expected pointer %s, got %s", testcase.Pointer, actual.Pointer)
+		}
+		if actual.ModelName != testcase.ModelName {
+			t.Fatalf("expected modelName %s, got %s", testcase.ModelName, actual.ModelName)
+		}
+
+		permalink, err := coverage.SwaggerPermalink(actual.SwaggerPath, "", actual.Pointer)
+		if err != nil {
+			t.Fatalf("build swagger permalink error: %+v", err)
+		}
+		if !strings.HasPrefix(permalink, path.Join(swaggerPath, "account.json")+"#L") {
+			t.Fatalf("expected the permalink to the local swagger file, got %s", permalink)
+		}
+	}
+}
+
+func Test_PropertyLocation(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get working directory error: %+v", err)
+	}
+	swaggerPath := path.Join(wd, "testdata", "Microsoft.Automation", "stable", "2022-08-08", "account.json")
+	model, err := coverage.Expand("AutomationAccountCreateOrUpdateParameters", swaggerPath)
+	if err != nil {
+		t.Fatalf("expand model error: %+v", err)
+	}
+
+	testcases := []struct {
+		Path     []string
+		Expected string
+	}{
+		{
+			Path:     []string{"location"},
+			Expected: "/definitions/AutomationAccountCreateOrUpdateParameters/properties/location",
+		},
+		{
+			Path:     []string{"properties", "sku", "name"},
+			Expected: "/definitions/Sku/properties/name",
+		},
+		{
+			Path:     []string{"properties", "sku", "unknown"},
+			Expected: "/definitions/AutomationAccountCreateOrUpdateProperties/properties/sku",
+		},
+		{
+			Path:     []string{"unknown"},
+			Expected: "",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("testcase: %+v", testcase.Path)
+		actualSwaggerPath, actual := model.PropertyLocation(testcase.Path)
+		if actual != testcase.Expected {
+			t.Fatalf("expected pointer %s, got %s", testcase.Expected, actual)
+		}
+		if actual != "" && normarlizePath(actualSwaggerPath) != normarlizePath(swaggerPath) {
+			t.Fatalf("expected swaggerPath %s, got %s", swaggerPath, actualSwaggerPath)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package coverage
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/azure/armstrong/utils"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	// pathTemplateTriesMutex guards the built tries, the tries are built once and shared by the concurrent lookups
+	pathTemplateTriesMutex sync.Mutex
+	// {absolute swaggerPath: trie}, the same as the swaggerCache, the least recently used tries are evicted
+	pathTemplateTries, _ = lru.New[string, *PathTemplateTrie](10)
+)
+
+// PathTemplateTrie resolves the resource ids to the API paths of a swagger file or the swagger files in a directory.
+// The API paths are matched from the last segment, the same as IsPathKeyMatchWithResourceId:
+// a fixed segment matches the segment case-insensitively, a parameter segment matches any segment,
+// and the first segment matches one or more segments if it's a scope parameter, e.g., {resourceId}, {scope} or {resourceUri}.
+type PathTemplateTrie struct {
+	root *pathTrieNode
+
+	modelsMutex sync.Mutex
+	// models are the resolved models, keyed by the methods and the API paths
+	models map[string]*SwaggerModel
+}
+
+type pathTrieNode struct {
+	// fixed are the children of the fixed segments, keyed by the lower cased segments
+	fixed map[string]*pathTrieNode
+	// parameter is the child of the parameter segments
+	parameter *pathTrieNode
+	// templates are the API paths which end at the node
+	templates []*pathTemplate
+	// scopeTemplates are the API paths which end at the node with a scope parameter
+	scopeTemplates []*pathTemplate
+}
+
+type pathTemplate struct {
+	apiPath     string
+	swaggerPath string
+	// methods are the upper cased methods of the operations under the API path
+	methods map[string]bool
+	// fixedCount is the number of the fixed segments, the API path with more fixed segments is more specific
+	fixedCount int
+	// order is the order of the swagger files and the API paths, it breaks the tie of the equally specific API paths
+	order int
+}
+
+// GetPathTemplateTrie returns the trie of the swagger path, it's built at the first call and rebuilt after it's evicted from the cache
+func GetPathTemplateTrie(swaggerPath string) (*PathTemplateTrie, error) {
+	swaggerPath, err := filepath.Abs(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pathTemplateTriesMutex.Lock()
+	defer pathTemplateTriesMutex.Unlock()
+
+	if trie, ok := pathTemplateTries.Get(swaggerPath); ok {
+		return trie, nil
+	}
+	trie, err := NewPathTemplateTrie(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+	pathTemplateTries.Add(swaggerPath, trie)
+	return trie, nil
+}
+
+// NewPathTemplateTrie builds the trie of the API paths in the swagger file or the swagger files in the directory
+func NewPathTemplateTrie(swaggerPath string) (*PathTemplateTrie, error) {
+	swaggerPath, err := filepath.Abs(swaggerPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(swaggerPath); err != nil {
+		return nil, err
+	}
+	files, err := utils.ListFiles(swaggerPath, ".json", 1)
+	if err != nil {
+		return nil, err
+	}
+
+	trie := &PathTemplateTrie{
+		root:   &pathTrieNode{},
+		models: make(map[string]*SwaggerModel),
+	}
+	order := 0
+	for _, filename := range files {
+		doc, err := loadSwagger(filename)
+		if err != nil {
+			logrus.Warnf("failed to load local spec file %v: %+v", filename, err)
+			continue
+		}
+		paths := doc.Spec().Paths
+		if paths == nil {
+			continue
+		}
+		apiPaths := make([]string, 0, len(paths.Paths))
+		for apiPath := range paths.Paths {
+			apiPaths = append(apiPaths, apiPath)
+		}
+		sort.Strings(apiPaths)
+		for _, apiPath := range apiPaths {
+			methods := make(map[string]bool)
+			for _, method := range []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH"} {
+				if pathItemOperation(paths.Paths[apiPath], method) != nil {
+					methods[method] = true
+				}
+			}
+			trie.insert(&pathTemplate{
+				apiPath:     apiPath,
+				swaggerPath: filename,
+				methods:     methods,
+				order:       order,
+			})
+			order++
+		}
+	}
+	return trie, nil
+}
+
+func (t *PathTemplateTrie) insert(template *pathTemplate) {
+	segments := strings.Split(strings.Trim(template.apiPath, "/"), "/")
+	node := t.root
+	for i := len(segments) - 1; i >= 0; i-- {
+		segment := segments[i]
+		if i == 0 && isScopeParameter(segment) {
+			node.scopeTemplates = append(node.scopeTemplates, template)
+			return
+		}
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if node.parameter == nil {
+				node.parameter = &pathTrieNode{}
+			}
+			node = node.parameter
+			continue
+		}
+		template.fixedCount++
+		key := strings.ToLower(segment)
+		if node.fixed == nil {
+			node.fixed = make(map[string]*pathTrieNode)
+		}
+		if node.fixed[key] == nil {
+			node.fixed[key] = &pathTrieNode{}
+		}
+		node = node.fixed[key]
+	}
+	node.templates = append(node.templates, template)
+}
+
+// Match returns the API path which matches the resource id and has the operation of the method, it returns empty if none matches.
+// If several API paths match, the one with more fixed segments is used, then the one without the scope parameter, then the first one ordered by the swagger files and the API paths.
+func (t *PathTemplateTrie) Match(resourceId, method string) (string, string) {
+	segments := strings.Split(strings.Trim(resourceId, "/"), "/")
+	method = strings.ToUpper(method)
+
+	var best *pathTemplate
+	bestIsScope := false
+	consider := func(templates []*pathTemplate, isScope bool) {
+		for _, template := range templates {
+			if !template.methods[method] {
+				continue
+			}
+			switch {
+			case best == nil,
+				template.fixedCount > best.fixedCount,
+				template.fixedCount == best.fixedCount && bestIsScope && !isScope,
+				template.fixedCount == best.fixedCount && bestIsScope == isScope && template.order < best.order:
+				best = template
+				bestIsScope = isScope
+			}
+		}
+	}
+
+	var walk func(node *pathTrieNode, j int)
+	walk = func(node *pathTrieNode, j int) {
+		if j < 0 {
+			consider(node.templates, false)
+			return
+		}
+		consider(node.scopeTemplates, true)
+		if child, ok := node.fixed[strings.ToLower(segments[j])]; ok {
+			walk(child, j-1)
+		}
+		if node.parameter != nil {
+			walk(node.parameter, j-1)
+		}
+	}
+	walk(t.root, len(segments)-1)
+
+	if best == nil {
+		return "", ""
+	}
+	return best.apiPath, best.swaggerPath
+}
+
+// Lookup returns the model of the API path which matches the resource id, see Match for how the API path is matched, it returns nil if none matches
+func (t *PathTemplateTrie) Lookup(resourceId, method string) (*SwaggerModel, error) {
+	apiPath, swaggerPath := t.Match(resourceId, method)
+	if apiPath == "" {
+		return nil, nil
+	}
+
+	key := strings.ToUpper(method) + " " + swaggerPath + "#" + apiPath
+	t.modelsMutex.Lock()
+	model, ok := t.models[key]
+	t.modelsMutex.Unlock()
+	if !ok {
+		doc, err := loadSwagger(swaggerPath)
+		if err != nil {
+			return nil, err
+		}
+		model, err = modelFromOperation(swaggerPath, apiPath, pathItemOperation(doc.Spec().Paths.Paths[apiPath], method))
+		if err != nil {
+			return nil, err
+		}
+		t.modelsMutex.Lock()
+		t.models[key] = model
+		t.modelsMutex.Unlock()
+	}
+
+	out := *model
+	return &out, nil
+}
+
+func isScopeParameter(segment string) bool {
+	return strings.EqualFold(segment, "{resourceId}") || strings.EqualFold(segment, "{scope}") || strings.EqualFold(segment, "{resourceUri}")
+}
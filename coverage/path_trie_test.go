@@ -0,0 +1,129 @@
+package coverage_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/armstrong/coverage"
+)
+
+func TestPathTemplateTrie_Match(t *testing.T) {
+	swagger := `{
+  "swagger": "2.0",
+  "info": {"title": "foo", "version": "2023-01-01"},
+  "paths": {
+    "/{scope}/providers/Microsoft.Foo/foos/{name}": {"put": {"responses": {}}, "get": {"responses": {}}},
+    "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/foos/{name}": {"put": {"responses": {}}},
+    "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/foos/default": {"put": {"responses": {}}},
+    "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/{resourceType}/{name}": {"put": {"responses": {}}}
+  }
+}`
+	swaggerPath := filepath.Join(t.TempDir(), "foo.json")
+	if err := os.WriteFile(swaggerPath, []byte(swagger), 0644); err != nil {
+		t.Fatal(err)
+	}
+	trie, err := coverage.NewPathTemplateTrie(swaggerPath)
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+
+	testcases := []struct {
+		ResourceId string
+		Method     string
+		Expected   string
+	}{
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Foo/foos/test",
+			Method:     "PUT",
+			Expected:   "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/foos/{name}",
+		},
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/microsoft.foo/FOOS/default",
+			Method:     "put",
+			Expected:   "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/foos/default",
+		},
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Foo/bars/test",
+			Method:     "PUT",
+			Expected:   "/subscriptions/{subscriptionId}/providers/Microsoft.Foo/{resourceType}/{name}",
+		},
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Foo/foos/test",
+			Method:     "GET",
+			Expected:   "/{scope}/providers/Microsoft.Foo/foos/{name}",
+		},
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Foo/foos/test",
+			Method:     "PUT",
+			Expected:   "/{scope}/providers/Microsoft.Foo/foos/{name}",
+		},
+		{
+			ResourceId: "/providers/Microsoft.Foo/foos/test",
+			Method:     "PUT",
+			Expected:   "",
+		},
+		{
+			ResourceId: "/subscriptions/00000000-0000-0000-0000-000000000000/providers/Microsoft.Foo/foos/test",
+			Method:     "DELETE",
+			Expected:   "",
+		},
+	}
+	for _, testcase := range testcases {
+		t.Logf("testcase: %s %s", testcase.Method, testcase.ResourceId)
+		actual, actualSwaggerPath := trie.Match(testcase.ResourceId, testcase.Method)
+		if actual != testcase.Expected {
+			t.Fatalf("expected %q, got %q", testcase.Expected, actual)
+		}
+		if actual != "" && actualSwaggerPath != swaggerPath {
+			t.Fatalf("expected swagger path %s, got %s", swaggerPath, actualSwaggerPath)
+		}
+	}
+}
+
+func TestNewOperationPropertiesCoverageReport(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("get working directory error: %+v", err)
+	}
+	swaggerPath := path.Join(wd, "testdata", "Microsoft.Automation", "stable", "2022-08-08")
+	traceDir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		trace := map[string]interface{}{
+			"liveRequest": map[string]interface{}{
+				"method": "PUT",
+				"url":    fmt.Sprintf("/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Automation/automationAccounts/test%d?api-version=2022-08-08", i),
+				"body": map[string]interface{}{
+					"location": "westeurope",
+					"properties": map[string]interface{}{
+						"sku": map[string]interface{}{
+							"name": "Basic",
+						},
+					},
+				},
+			},
+		}
+		data, err := json.Marshal(trace)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(traceDir, fmt.Sprintf("trace-%d.json", i)), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := coverage.NewOperationPropertiesCoverageReport(traceDir, swaggerPath)
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	item, ok := report.Coverages["PUT-/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Automation/automationAccounts/{automationAccountName}"]
+	if len(report.Coverages) != 1 || !ok {
+		t.Fatalf("expect the coverage of the automation account, but got %+v", report.Coverages)
+	}
+	if item.Model.CoveredCount == 0 || item.Model.CoveredCount >= item.Model.TotalCount {
+		t.Fatalf("expect the automation account is partially covered, but got %d/%d", item.Model.CoveredCount, item.Model.TotalCount)
+	}
+}
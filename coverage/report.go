@@ -2,6 +2,7 @@ package coverage
 
 import (
 	"fmt"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -13,6 +14,8 @@ const isBoolEnumDisplayed = false
 
 type CoverageReport struct {
 	Coverages map[string]*CoverageItem
+	// IndexCommit is the commit of the azure-rest-api-specs repo which the index is built on, it's empty if the index is not used
+	IndexCommit string
 }
 
 type CoverageItem struct {
@@ -38,6 +41,7 @@ func (c *CoverageReport) AddCoverageFromState(resourceId, resourceType string, j
 			return fmt.Errorf("error find the path for %s from index: %+v", resourceId, err)
 		}
 		swaggerModel = swaggerModelFromIndex
+		c.IndexCommit = swaggerModel.Commit
 	}
 
 	logrus.Infof("matched API path: %s; modelSwawggerPath: %s\n", swaggerModel.ApiPath, swaggerModel.SwaggerPath)
@@ -60,13 +64,61 @@ func (c *CoverageReport) AddCoverageFromState(resourceId, resourceType string, j
 	return nil
 }
 
+// Suppress marks the uncovered properties which are suppressed, they're not counted or reported, then counts the coverage again.
+// The property paths are relative to the root models, e.g., properties.networkAcls.ipRules[].value, the variant names are omitted.
+func (c *CoverageReport) Suppress(isSuppressed func(item *CoverageItem, model *Model, property string) bool) {
+	for _, item := range c.Coverages {
+		if item == nil || item.Model == nil {
+			continue
+		}
+		item.Model.suppress(func(model *Model, property string) bool {
+			return isSuppressed(item, model, property)
+		})
+		item.Model.CountCoverage()
+	}
+}
+
+func (m *Model) suppress(isSuppressed func(model *Model, property string) bool) {
+	if m == nil || m.IsReadOnly {
+		return
+	}
+	if m.Properties != nil {
+		for _, v := range *m.Properties {
+			if v == nil || v.IsReadOnly || v.IsSuppressed {
+				continue
+			}
+			if !v.IsAnyCovered && isSuppressed(v, propertyPath(v.Identifier)) {
+				v.IsSuppressed = true
+				continue
+			}
+			v.suppress(isSuppressed)
+		}
+	}
+	if m.Item != nil {
+		m.Item.suppress(isSuppressed)
+	}
+	if m.Variants != nil {
+		for _, v := range *m.Variants {
+			v.suppress(isSuppressed)
+		}
+	}
+}
+
+var variantPattern = regexp.MustCompile(`\{[^}]*\}`)
+
+// propertyPath converts the identifier to the property path, e.g., #{Variant}.properties.rules[].name to properties.rules[].name
+func propertyPath(identifier string) string {
+	identifier = variantPattern.ReplaceAllString(identifier, "")
+	return strings.TrimPrefix(strings.TrimPrefix(identifier, "#"), ".")
+}
+
 func (c *CoverageReport) MarkdownContent() string {
 	template := `
 ### Coverage Status:
 
 #### Summary
 
-${coverage_summary}
+${coverage_summary}${index_commit}
 
 #### Details
 
@@ -92,6 +144,11 @@ ${coverage_details}
 	}
 
 	content := strings.ReplaceAll(template, "${coverage_summary}", summary)
+	indexCommit := ""
+	if c.IndexCommit != "" {
+		indexCommit = fmt.Sprintf("The swagger index is built on commit [%[1]s](%[2]s%[1]s).\n", c.IndexCommit, IndexCommitURL)
+	}
+	content = strings.ReplaceAll(content, "${index_commit}", indexCommit)
 
 	var coverages []string
 	count := 0
@@ -190,7 +247,7 @@ func getReport(displayName string, model *Model) []string {
 
 	if model.Properties != nil {
 		for k, v := range *model.Properties {
-			if v.IsReadOnly {
+			if v.IsReadOnly || v.IsSuppressed {
 				continue
 			}
 
@@ -0,0 +1,465 @@
+package credscan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+const (
+	// VariablesFileName is the file where the new variables are added
+	VariablesFileName = "variables.tf"
+	// TfvarsExampleFileName is the file which lists the variables that need values
+	TfvarsExampleFileName = "terraform.tfvars.example"
+)
+
+var (
+	indexRegex      = regexp.MustCompile(`\[\d+\]`)
+	arrayIndexRegex = regexp.MustCompile(`\[\d*\]`)
+	variantRegex    = regexp.MustCompile(`\{[^}]*\}`)
+)
+
+// Fixer rewrites the secrets in the terraform configuration files into references to sensitive variables without default values:
+// 1. The plain text values are replaced with `var.<name>` and the variables are added to `variables.tf`.
+// 2. The locals which are used as secrets are replaced with `var.<name>`, the locals must be literals.
+// 3. The variables which have default values or are not marked as sensitive are patched.
+// The changes are kept in memory until Save is called.
+type Fixer struct {
+	workingDir string
+	files      map[string]*hclwrite.File
+	changed    map[string]bool
+	// the variables defined in the configuration files
+	variables map[string]bool
+	// the variables created for the plain text values, the same value is always replaced with the same variable
+	secretVariables map[string]string
+	// the variables which need values, they're listed in the tfvars example file
+	sensitiveVariables map[string]bool
+}
+
+// NewFixer returns a fixer for the terraform configuration files in the working directory
+func NewFixer(workingDir string) (*Fixer, error) {
+	entries, err := os.ReadDir(workingDir)
+	if err != nil {
+		return nil, err
+	}
+	f := &Fixer{
+		workingDir:         workingDir,
+		files:              make(map[string]*hclwrite.File),
+		changed:            make(map[string]bool),
+		variables:          make(map[string]bool),
+		secretVariables:    make(map[string]string),
+		sensitiveVariables: make(map[string]bool),
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tf") {
+			continue
+		}
+		fileName := workingDir + "/" + entry.Name()
+		src, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, err
+		}
+		file, diags := hclwrite.ParseConfig(src, fileName, hcl.InitialPos)
+		if diags.HasErrors() {
+			return nil, fmt.Errorf("failed to parse %s: %s", fileName, diags.Error())
+		}
+		f.files[fileName] = file
+		for _, block := range file.Body().Blocks() {
+			if block.Type() == "variable" && len(block.Labels()) == 1 {
+				f.variables[block.Labels()[0]] = true
+			}
+		}
+	}
+	return f, nil
+}
+
+// FixAttribute fixes the secret in the attribute of the block, the block is identified by the file and its address,
+// e.g., ["azapi_resource", "test"], ["data", "azapi_resource_action", "test"] and ["provider", "azurerm", "<alias>"].
+// The property name is the path to the secret reported by the scan, it's used to name the new variable.
+// The value is the mocked value of the secret, e.g., a plain text, `$local.<name>` or `$var.<name>`.
+func (f *Fixer) FixAttribute(fileName string, address []string, attributeName, propertyName, value string) error {
+	switch {
+	case strings.HasPrefix(value, "$var."):
+		return f.FixVariable(strings.Split(strings.TrimPrefix(value, "$var."), ".")[0])
+	case strings.HasPrefix(value, "$local."):
+		return f.fixLocal(strings.TrimPrefix(value, "$local."))
+	}
+
+	block := f.findBlock(fileName, address)
+	if block == nil {
+		return fmt.Errorf("block %s is not found in %s", strings.Join(address, "."), fileName)
+	}
+	attr := block.Body().GetAttribute(attributeName)
+	if attr == nil {
+		return fmt.Errorf("attribute %q is not found in %s", attributeName, strings.Join(address, "."))
+	}
+
+	varName, ok := f.secretVariables[value]
+	if !ok {
+		varName = f.uniqueVariableName(variableName(address, propertyName))
+	}
+	tokens, replaced, err := replaceSecret(attr.Expr().BuildTokens(nil), propertyName, value, varName)
+	if err != nil {
+		return fmt.Errorf("parsing %s.%s: %+v", strings.Join(address, "."), attributeName, err)
+	}
+	if !replaced {
+		return fmt.Errorf("the value of %s is not a string literal in %s.%s", propertyName, strings.Join(address, "."), attributeName)
+	}
+	block.Body().SetAttributeRaw(attributeName, tokens)
+	f.changed[fileName] = true
+	f.secretVariables[value] = varName
+	return f.FixVariable(varName)
+}
+
+// FixVariable adds the variable if it doesn't exist, otherwise removes its default value and marks it as sensitive
+func (f *Fixer) FixVariable(name string) error {
+	f.sensitiveVariables[name] = true
+	for _, fileName := range f.fileNames() {
+		for _, block := range f.files[fileName].Body().Blocks() {
+			if block.Type() != "variable" || len(block.Labels()) != 1 || block.Labels()[0] != name {
+				continue
+			}
+			body := block.Body()
+			if body.GetAttribute("default") != nil {
+				body.RemoveAttribute("default")
+				f.changed[fileName] = true
+			}
+			if sensitive := body.GetAttribute("sensitive"); sensitive == nil || strings.TrimSpace(string(sensitive.Expr().BuildTokens(nil).Bytes())) != "true" {
+				body.SetAttributeValue("sensitive", cty.True)
+				f.changed[fileName] = true
+			}
+			return nil
+		}
+	}
+
+	fileName := f.workingDir + "/" + VariablesFileName
+	file, ok := f.files[fileName]
+	if !ok {
+		file = hclwrite.NewEmptyFile()
+		f.files[fileName] = file
+	}
+	if len(file.Body().Attributes()) != 0 || len(file.Body().Blocks()) != 0 {
+		file.Body().AppendNewline()
+	}
+	body := file.Body().AppendNewBlock("variable", []string{name}).Body()
+	body.SetAttributeTraversal("type", hcl.Traversal{hcl.TraverseRoot{Name: "string"}})
+	body.SetAttributeValue("sensitive", cty.True)
+	f.variables[name] = true
+	f.changed[fileName] = true
+	return nil
+}
+
+// Save writes the changed configuration files and the tfvars example file which lists the variables that need values
+func (f *Fixer) Save() error {
+	for _, fileName := range f.fileNames() {
+		if !f.changed[fileName] {
+			continue
+		}
+		if err := os.WriteFile(fileName, hclwrite.Format(f.files[fileName].Bytes()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %+v", fileName, err)
+		}
+		logrus.Infof("secrets are fixed in %s", fileName)
+	}
+	if len(f.sensitiveVariables) == 0 {
+		return nil
+	}
+
+	exampleFileName := filepath.Join(f.workingDir, TfvarsExampleFileName)
+	example := hclwrite.NewEmptyFile()
+	if src, err := os.ReadFile(exampleFileName); err == nil {
+		file, diags := hclwrite.ParseConfig(src, exampleFileName, hcl.InitialPos)
+		if diags.HasErrors() {
+			return fmt.Errorf("failed to parse %s: %s", exampleFileName, diags.Error())
+		}
+		example = file
+	} else {
+		example.Body().AppendUnstructuredTokens(hclwrite.Tokens{
+			{
+				Type:  hclsyntax.TokenComment,
+				Bytes: []byte("# Copy this file to terraform.tfvars and fill in the values, or set them by TF_VAR_<name> environment variables.\n# Don't commit the file which contains the values.\n"),
+			},
+		})
+	}
+	names := make([]string, 0)
+	for name := range f.sensitiveVariables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if example.Body().GetAttribute(name) == nil {
+			example.Body().SetAttributeValue(name, cty.StringVal(""))
+		}
+	}
+	if err := os.WriteFile(exampleFileName, hclwrite.Format(example.Bytes()), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %+v", exampleFileName, err)
+	}
+	logrus.Infof("the variables which need values are listed in %s", exampleFileName)
+	return nil
+}
+
+// fixLocal replaces the local value with a reference to the variable of the same name, only the literal locals can be fixed
+func (f *Fixer) fixLocal(name string) error {
+	if strings.Contains(name, ".") {
+		return fmt.Errorf("local.%s is not a literal, please fix it manually", name)
+	}
+	for _, fileName := range f.fileNames() {
+		for _, block := range f.files[fileName].Body().Blocks() {
+			if block.Type() != "locals" {
+				continue
+			}
+			attr := block.Body().GetAttribute(name)
+			if attr == nil {
+				continue
+			}
+			if len(attr.Expr().Variables()) != 0 {
+				return fmt.Errorf("local.%s is not a literal, please fix it manually", name)
+			}
+			varName := f.uniqueVariableName(name)
+			block.Body().SetAttributeTraversal(name, hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: varName}})
+			f.changed[fileName] = true
+			return f.FixVariable(varName)
+		}
+	}
+	return fmt.Errorf("local.%s is not found", name)
+}
+
+func (f *Fixer) findBlock(fileName string, address []string) *hclwrite.Block {
+	file, ok := f.files[fileName]
+	if !ok {
+		return nil
+	}
+	for _, block := range file.Body().Blocks() {
+		blockAddress := block.Labels()
+		switch block.Type() {
+		case "data", "provider":
+			blockAddress = append([]string{block.Type()}, blockAddress...)
+		case "resource":
+		default:
+			continue
+		}
+		if block.Type() == "provider" {
+			if alias := block.Body().GetAttribute("alias"); alias != nil {
+				blockAddress = append(blockAddress, strings.Trim(strings.TrimSpace(string(alias.Expr().BuildTokens(nil).Bytes())), `"`))
+			}
+		}
+		if strings.Join(blockAddress, ".") == strings.Join(address, ".") {
+			return block
+		}
+	}
+	return nil
+}
+
+func (f *Fixer) uniqueVariableName(name string) string {
+	out := name
+	for i := 1; f.variables[out]; i++ {
+		out = fmt.Sprintf("%s_%d", name, i)
+	}
+	return out
+}
+
+func (f *Fixer) fileNames() []string {
+	out := make([]string, 0)
+	for fileName := range f.files {
+		out = append(out, fileName)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// variableName returns the name of the variable for the secret, e.g., the adminPassword of azapi_resource.vm is named vm_admin_password
+func variableName(address []string, propertyName string) string {
+	parts := strings.Split(indexRegex.ReplaceAllString(propertyName, ""), ".")
+	words := splitWords(parts[len(parts)-1])
+	prefix := ""
+	switch {
+	case len(address) == 3 && address[0] == "provider":
+		prefix = address[2]
+	case len(address) >= 2 && address[0] != "provider":
+		prefix = address[len(address)-1]
+	}
+	if prefix != "" {
+		words = append(splitWords(prefix), words...)
+	}
+	return strings.Join(words, "_")
+}
+
+// replaceSecret replaces the secret value of the property in the expression tokens with the reference to the variable, it supports:
+// 1. The string literal in the object, e.g., { key = "secret" } is replaced with { key = var.name }, the same as in jsonencode({...}).
+// 2. The string in the JSON heredoc, e.g., "key": "secret" is replaced with "key": ${jsonencode(var.name)}.
+// Only the values at the property path are replaced, the path is matched from the end because the paths reported by the swagger models
+// start with the model names, e.g., VirtualMachine.properties.osProfile.adminPassword.
+func replaceSecret(tokens hclwrite.Tokens, propertyName, value, varName string) (hclwrite.Tokens, bool, error) {
+	// the heredoc needs a newline after the closing marker
+	src := append(tokens.Bytes(), '\n')
+	expr, diags := hclsyntax.ParseExpression(src, "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, false, diags
+	}
+	locations := make([]secretLocation, 0)
+	findSecret(src, expr, propertyPath(propertyName), nil, value, &locations)
+	if len(locations) == 0 {
+		return tokens, false, nil
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		return locations[i].start > locations[j].start
+	})
+	out := append([]byte{}, src...)
+	for _, location := range locations {
+		replacement := "var." + varName
+		if location.json {
+			replacement = fmt.Sprintf("${jsonencode(var.%s)}", varName)
+		}
+		out = append(out[:location.start], append([]byte(replacement), out[location.end:]...)...)
+	}
+
+	file, diags := hclwrite.ParseConfig(append([]byte("value ="), out...), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return nil, false, diags
+	}
+	return file.Body().GetAttribute("value").Expr().BuildTokens(nil), true, nil
+}
+
+// secretLocation is the byte range of the secret in the expression, json is true if the secret is a string in the JSON heredoc
+type secretLocation struct {
+	start int
+	end   int
+	json  bool
+}
+
+// findSecret finds the string values which equal the secret and whose paths match the property path
+func findSecret(src []byte, expr hclsyntax.Expression, property []string, path []string, value string, out *[]secretLocation) {
+	switch e := expr.(type) {
+	case *hclsyntax.ObjectConsExpr:
+		for _, item := range e.Items {
+			key, diags := item.KeyExpr.Value(nil)
+			if diags.HasErrors() || key.Type() != cty.String || !key.IsKnown() {
+				continue
+			}
+			findSecret(src, item.ValueExpr, property, append(path[:len(path):len(path)], key.AsString()), value, out)
+		}
+	case *hclsyntax.TupleConsExpr:
+		for i, item := range e.Exprs {
+			findSecret(src, item, property, append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i)), value, out)
+		}
+	case *hclsyntax.FunctionCallExpr:
+		if e.Name == "jsonencode" && len(e.Args) == 1 {
+			findSecret(src, e.Args[0], property, path, value, out)
+		}
+	case *hclsyntax.TemplateExpr:
+		if len(e.Variables()) != 0 {
+			return
+		}
+		v, diags := e.Value(nil)
+		if diags.HasErrors() || v.Type() != cty.String {
+			return
+		}
+		if v.AsString() == value {
+			if matchPropertyPath(property, path) {
+				*out = append(*out, secretLocation{start: e.SrcRange.Start.Byte, end: e.SrcRange.End.Byte})
+			}
+			return
+		}
+		// the JSON in the heredoc, the heredoc is parsed as the literals of the lines and the offsets of the JSON values are the same as in the source
+		if len(e.Parts) == 0 {
+			return
+		}
+		start, end := e.Parts[0].Range().Start.Byte, e.Parts[len(e.Parts)-1].Range().End.Byte
+		findJSONSecret(src[start:end], start, property, path, value, out)
+	}
+}
+
+// findJSONSecret finds the JSON strings which equal the secret and whose paths match the property path, the offset is the position of the JSON in the expression
+func findJSONSecret(data []byte, offset int, property []string, path []string, value string, out *[]secretLocation) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	var walk func(path []string) error
+	walk = func(path []string) error {
+		start := skipJSONSeparators(data, int(decoder.InputOffset()))
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		switch v := token.(type) {
+		case json.Delim:
+			switch v {
+			case '{':
+				for decoder.More() {
+					key, err := decoder.Token()
+					if err != nil {
+						return err
+					}
+					if err := walk(append(path[:len(path):len(path)], fmt.Sprintf("%v", key))); err != nil {
+						return err
+					}
+				}
+			case '[':
+				for i := 0; decoder.More(); i++ {
+					if err := walk(append(path[:len(path):len(path)], fmt.Sprintf("[%d]", i))); err != nil {
+						return err
+					}
+				}
+			}
+			_, err := decoder.Token()
+			return err
+		case string:
+			if v == value && matchPropertyPath(property, path) {
+				*out = append(*out, secretLocation{start: offset + start, end: offset + int(decoder.InputOffset()), json: true})
+			}
+		}
+		return nil
+	}
+	if err := walk(path); err != nil {
+		logrus.Debugf("the string is not a JSON: %+v", err)
+	}
+}
+
+// skipJSONSeparators returns the offset of the next JSON value, the whitespaces, commas and colons are skipped
+func skipJSONSeparators(data []byte, offset int) int {
+	for offset < len(data) && strings.ContainsRune(" \t\r\n,:", rune(data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// propertyPath splits the property name into the keys and the array indexes,
+// e.g., properties.appSettings[0].value is split into [properties appSettings [0] value], the variant names like {Copy} are removed.
+func propertyPath(propertyName string) []string {
+	propertyName = variantRegex.ReplaceAllString(propertyName, "")
+	out := make([]string, 0)
+	for _, part := range strings.Split(propertyName, ".") {
+		name := part
+		if index := strings.Index(part, "["); index != -1 {
+			name = part[:index]
+		}
+		if name != "" {
+			out = append(out, name)
+		}
+		out = append(out, arrayIndexRegex.FindAllString(part, -1)...)
+	}
+	return out
+}
+
+// matchPropertyPath returns whether the path of the value is the suffix of the property path, the [] in the property path matches any array index
+func matchPropertyPath(property []string, path []string) bool {
+	if len(path) > len(property) {
+		return false
+	}
+	property = property[len(property)-len(path):]
+	for i := range path {
+		if property[i] != path[i] && !(property[i] == "[]" && strings.HasPrefix(path[i], "[")) {
+			return false
+		}
+	}
+	return true
+}
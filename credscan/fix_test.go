@@ -0,0 +1,80 @@
+package credscan_test
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/credscan"
+)
+
+func Test_Fixer(t *testing.T) {
+	wd := t.TempDir()
+	src, err := os.ReadFile(filepath.Join("testdata", "fix", "main.tf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainFile := wd + "/main.tf"
+	if err := os.WriteFile(mainFile, src, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fixer, err := credscan.NewFixer(wd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fixes := []struct {
+		Address       []string
+		AttributeName string
+		PropertyName  string
+		Value         string
+	}{
+		{Address: []string{"provider", "azurerm"}, AttributeName: "client_secret", PropertyName: "client_secret", Value: "plain-client-secret"},
+		{Address: []string{"azapi_resource", "vm"}, AttributeName: "body", PropertyName: "VirtualMachine.properties.osProfile.adminPassword", Value: "P@ssw0rd1234!"},
+		{Address: []string{"azapi_resource", "json"}, AttributeName: "body", PropertyName: "properties.siteConfig.appSettings[0].value", Value: "json-secret-value"},
+		{Address: []string{"azapi_resource", "vm"}, AttributeName: "body", PropertyName: "properties.key", Value: "$local.key"},
+		{Address: []string{"azapi_resource", "vm"}, AttributeName: "body", PropertyName: "properties.token", Value: "$var.token"},
+	}
+	for _, fix := range fixes {
+		if err := fixer.FixAttribute(mainFile, fix.Address, fix.AttributeName, fix.PropertyName, fix.Value); err != nil {
+			t.Fatalf("failed to fix %s: %+v", fix.PropertyName, err)
+		}
+	}
+	if err := fixer.FixAttribute(mainFile, []string{"azapi_resource", "vm"}, "body", "properties.missing", "not-in-config"); err == nil {
+		t.Errorf("expected error for the value which is not in the configuration")
+	}
+	if err := fixer.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, _ := os.ReadFile(mainFile)
+	for _, expected := range []string{
+		`client_secret = var.client_secret`,
+		`adminPassword = var.vm_admin_password`,
+		`customData    = "prefix-P@ssw0rd1234!"`,
+		`description = "P@ssw0rd1234!"`,
+		`{"name": "key", "value": ${jsonencode(var.json_value)}}`,
+		`key = var.key`,
+		"variable \"token\" {\n  type      = string\n  sensitive = true\n}",
+	} {
+		if !strings.Contains(string(actual), expected) {
+			t.Errorf("expected %q in main.tf, got:\n%s", expected, actual)
+		}
+	}
+
+	variables, _ := os.ReadFile(filepath.Join(wd, credscan.VariablesFileName))
+	for _, name := range []string{"client_secret", "vm_admin_password", "json_value", "key"} {
+		if !strings.Contains(string(variables), "variable \""+name+"\" {\n  type      = string\n  sensitive = true\n}") {
+			t.Errorf("expected variable %q in variables.tf, got:\n%s", name, variables)
+		}
+	}
+
+	example, _ := os.ReadFile(filepath.Join(wd, credscan.TfvarsExampleFileName))
+	for _, name := range []string{"client_secret", "json_value", "key", "token", "vm_admin_password"} {
+		if !regexp.MustCompile(`(?m)^` + name + `\s+= ""$`).Match(example) {
+			t.Errorf("expected variable %q in the tfvars example, got:\n%s", name, example)
+		}
+	}
+}
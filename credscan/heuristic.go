@@ -0,0 +1,192 @@
+package credscan
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	// ConfidenceHigh is used for the secrets detected by the `x-ms-secret` in the swagger model
+	ConfidenceHigh = "high"
+	// ConfidenceLow is used for the secrets detected by the heuristics, they might be false positives
+	ConfidenceLow = "low"
+)
+
+// Finding is a property which might contain a secret
+type Finding struct {
+	// PropertyName is the path to the property, e.g., properties.osProfile.adminPassword
+	PropertyName string
+	Value        string
+	Reason       string
+}
+
+// the words in the property names which usually mean the property contains a secret
+var secretNameWords = []string{"password", "passwd", "secret", "connectionstring"}
+
+// the last words in the property names which usually mean the property contains a secret, e.g., primaryKey and sasToken
+var secretNameSuffixes = []string{"key", "keys", "token", "sas", "pwd"}
+
+// the last words in the property names which usually mean the property only refers to a secret, e.g., keyVaultSecretId
+var nonSecretNameSuffixes = []string{"id", "ids", "uri", "url", "name", "names", "type", "kind", "version", "source", "expiry", "expiration", "time", "enabled", "policy", "profile", "settings", "length", "reference"}
+
+var valuePatterns = []struct {
+	regex  *regexp.Regexp
+	reason string
+}{
+	{
+		regex:  regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----`),
+		reason: "the value looks like a PEM block",
+	},
+	{
+		regex:  regexp.MustCompile(`^[A-Za-z0-9+/]{86}==$`),
+		reason: "the value looks like a storage account key",
+	},
+	{
+		regex:  regexp.MustCompile(`(^|[?&])sig=[A-Za-z0-9%+/=]{16,}`),
+		reason: "the value looks like a SAS token",
+	},
+	{
+		regex:  regexp.MustCompile(`(?i)(^|;)\s*(AccountKey|SharedAccessKey|Password|Pwd)=[^;]+`),
+		reason: "the value looks like a connection string with credentials",
+	},
+}
+
+const (
+	entropyMinLength = 32
+	entropyThreshold = 4.5
+)
+
+// Detect returns the properties which might contain secrets in the body, it's used when the swagger model is not available.
+// The properties are detected by the property names, the known credential formats and the entropy of the values.
+func Detect(body interface{}) []Finding {
+	out := make([]Finding, 0)
+	detect(body, "", "", &out)
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].PropertyName < out[j].PropertyName
+	})
+	return out
+}
+
+func detect(input interface{}, name string, propertyName string, out *[]Finding) {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			path := key
+			if propertyName != "" {
+				path = propertyName + "." + key
+			}
+			detect(value, key, path, out)
+		}
+	case []interface{}:
+		for i, item := range v {
+			detect(item, name, fmt.Sprintf("%s[%d]", propertyName, i), out)
+		}
+	case string:
+		if v == "" {
+			return
+		}
+		if IsSecretName(name) {
+			*out = append(*out, Finding{
+				PropertyName: propertyName,
+				Value:        v,
+				Reason:       fmt.Sprintf("the property name %q looks like a secret", name),
+			})
+			return
+		}
+		if reason := SecretValueReason(v); reason != "" {
+			*out = append(*out, Finding{
+				PropertyName: propertyName,
+				Value:        v,
+				Reason:       reason,
+			})
+		}
+	}
+}
+
+// IsSecretName returns whether the property name looks like a secret, e.g., adminPassword, primaryKey and connectionString
+func IsSecretName(name string) bool {
+	words := splitWords(name)
+	if len(words) == 0 {
+		return false
+	}
+	last := words[len(words)-1]
+	for _, suffix := range nonSecretNameSuffixes {
+		if last == suffix {
+			return false
+		}
+	}
+	lowerName := strings.ToLower(name)
+	for _, word := range secretNameWords {
+		if strings.Contains(lowerName, word) {
+			return true
+		}
+	}
+	for _, suffix := range secretNameSuffixes {
+		if last == suffix {
+			return true
+		}
+	}
+	return false
+}
+
+// SecretValueReason returns why the value looks like a secret, it returns empty string if the value doesn't look like a secret
+func SecretValueReason(value string) string {
+	// the references like $var.password are checked by the property names
+	if strings.HasPrefix(value, "$") {
+		return ""
+	}
+	for _, pattern := range valuePatterns {
+		if pattern.regex.MatchString(value) {
+			return pattern.reason
+		}
+	}
+	if len(value) >= entropyMinLength && !strings.ContainsAny(value, " :.") && !strings.HasPrefix(value, "/") && entropy(value) >= entropyThreshold {
+		return "the value has high entropy"
+	}
+	return ""
+}
+
+// entropy returns the Shannon entropy of the value in bits per character
+func entropy(value string) float64 {
+	counts := make(map[rune]int)
+	total := 0
+	for _, r := range value {
+		counts[r]++
+		total++
+	}
+	out := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(total)
+		out -= p * math.Log2(p)
+	}
+	return out
+}
+
+// splitWords splits the camel case or snake case name into lower case words, e.g., sasToken is split into [sas token]
+func splitWords(name string) []string {
+	words := make([]string, 0)
+	current := make([]rune, 0)
+	runes := []rune(name)
+	for i, r := range runes {
+		if r == '_' || r == '-' || r == '.' {
+			if len(current) != 0 {
+				words = append(words, strings.ToLower(string(current)))
+				current = current[:0]
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && len(current) != 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+			words = append(words, strings.ToLower(string(current)))
+			current = current[:0]
+		}
+		current = append(current, r)
+	}
+	if len(current) != 0 {
+		words = append(words, strings.ToLower(string(current)))
+	}
+	return words
+}
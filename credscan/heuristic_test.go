@@ -0,0 +1,91 @@
+package credscan_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/azure/armstrong/credscan"
+)
+
+func Test_IsSecretName(t *testing.T) {
+	testcases := []struct {
+		Name     string
+		Expected bool
+	}{
+		{Name: "adminPassword", Expected: true},
+		{Name: "primaryKey", Expected: true},
+		{Name: "sasToken", Expected: true},
+		{Name: "connectionString", Expected: true},
+		{Name: "client_secret", Expected: true},
+		{Name: "SAS", Expected: true},
+		{Name: "keyName", Expected: false},
+		{Name: "keyVaultSecretId", Expected: false},
+		{Name: "passwordProfile", Expected: false},
+		{Name: "location", Expected: false},
+		{Name: "monkey", Expected: false},
+	}
+	for _, testcase := range testcases {
+		if actual := credscan.IsSecretName(testcase.Name); actual != testcase.Expected {
+			t.Errorf("expected %v for %s, got %v", testcase.Expected, testcase.Name, actual)
+		}
+	}
+}
+
+func Test_Detect(t *testing.T) {
+	input := `{
+  "location": "westeurope",
+  "properties": {
+    "osProfile": {
+      "adminUsername": "adminuser",
+      "adminPassword": "$var.admin_password"
+    },
+    "keyName": "primary",
+    "storageAccountId": "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Storage/storageAccounts/sa",
+    "settings": [
+      {
+        "value": "DefaultEndpointsProtocol=https;AccountName=example;AccountKey=abc123;EndpointSuffix=core.windows.net"
+      },
+      {
+        "value": "sv=2021-06-08&ss=b&srt=sco&sp=rl&se=2024-01-01T00:00:00Z&sig=N2NmMDk4ZDI3YjE0YzA1YjAzNzQ2"
+      }
+    ],
+    "certificate": "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----",
+    "storageKey": "$local.key",
+    "description": "qX7vR2mK9pL4wN8zT1yB6cF3hJ5dG0sAeUoI",
+    "accessKey": "BwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyAhIiMkJSYnKCkqKywtLi8wMTIzNDU2Nzg5Ojs8PT4/QEFCQ0RFRg=="
+  }
+}`
+	var body interface{}
+	if err := json.Unmarshal([]byte(input), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	findings := credscan.Detect(body)
+	actual := make(map[string]string)
+	for _, finding := range findings {
+		actual[finding.PropertyName] = finding.Reason
+	}
+	expected := map[string]string{
+		"properties.accessKey":               `the property name "accessKey" looks like a secret`,
+		"properties.certificate":             "the value looks like a PEM block",
+		"properties.description":             "the value has high entropy",
+		"properties.osProfile.adminPassword": `the property name "adminPassword" looks like a secret`,
+		"properties.settings[0].value":       "the value looks like a connection string with credentials",
+		"properties.settings[1].value":       "the value looks like a SAS token",
+		"properties.storageKey":              `the property name "storageKey" looks like a secret`,
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func Test_DetectStorageKey(t *testing.T) {
+	body := map[string]interface{}{
+		"value": "BwgJCgsMDQ4PEBESExQVFhcYGRobHB0eHyAhIiMkJSYnKCkqKywtLi8wMTIzNDU2Nzg5Ojs8PT4/QEFCQ0RFRg==",
+	}
+	findings := credscan.Detect(body)
+	if len(findings) != 1 || findings[0].Reason != "the value looks like a storage account key" {
+		t.Errorf("expected a storage account key, got %+v", findings)
+	}
+}
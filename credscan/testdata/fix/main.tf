@@ -0,0 +1,39 @@
+provider "azurerm" {
+  features {}
+  client_secret = "plain-client-secret"
+}
+
+variable "token" {
+  type    = string
+  default = "abc"
+}
+
+locals {
+  key = "local-secret-key"
+}
+
+resource "azapi_resource" "vm" {
+  type = "Microsoft.Compute/virtualMachines@2023-03-01"
+  body = {
+    properties = {
+      description = "P@ssw0rd1234!"
+      osProfile = {
+        adminPassword = "P@ssw0rd1234!"
+        customData    = "prefix-P@ssw0rd1234!"
+      }
+    }
+  }
+}
+
+resource "azapi_resource" "json" {
+  type = "Microsoft.Web/sites@2022-03-01"
+  body = <<BODY
+{
+  "properties": {
+    "siteConfig": {
+      "appSettings": [{"name": "key", "value": "json-secret-value"}]
+    }
+  }
+}
+BODY
+}
@@ -0,0 +1,335 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/hcl"
+	"github.com/azure/armstrong/utils"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+)
+
+const (
+	HostVariable  = "host"
+	TokenVariable = "token"
+)
+
+// Collection is an ordered list of REST requests converted from the azapi blocks
+type Collection struct {
+	Name      string
+	Requests  []Request
+	Variables []Variable
+}
+
+type Request struct {
+	Name        string
+	Description string
+	Method      string
+	// Path is the request path with the `{{variable}}` placeholders, e.g., /subscriptions/{{subscription_id}}/resourceGroups/rg
+	Path       string
+	ApiVersion string
+	// Body is the indented JSON payload, it's empty if the request has no body
+	Body string
+}
+
+// Url returns the request url which uses the host variable, e.g., {{host}}/subscriptions/xxx?api-version=2021-01-01
+func (r Request) Url() string {
+	out := fmt.Sprintf("{{%s}}%s", HostVariable, r.Path)
+	if r.ApiVersion != "" {
+		out += "?api-version=" + r.ApiVersion
+	}
+	return out
+}
+
+type Variable struct {
+	Name  string
+	Value string
+}
+
+// NewCollection converts the azapi blocks to REST requests, the blocks are ordered by their dependencies.
+// The references to other azapi blocks are replaced by the computed values, e.g., `azapi_resource.test.id` is replaced by the resource id,
+// the other references like `var.location` are kept as collection variables.
+func NewCollection(name string, blocks []hcl.AzapiBlock, variables map[string]hcl.Variable) (*Collection, error) {
+	ordered, err := sortBlocks(blocks)
+	if err != nil {
+		return nil, err
+	}
+
+	b := collectionBuilder{
+		values:    make(map[string]map[string]string),
+		variables: make(map[string]string),
+		inputs:    variables,
+	}
+	requests := make([]Request, 0)
+	deletes := make([]Request, 0)
+	for _, block := range ordered {
+		blockRequests, err := b.requests(block)
+		if err != nil {
+			return nil, fmt.Errorf("converting %s: %+v", block.Address(), err)
+		}
+		for _, request := range blockRequests {
+			if request.Method == http.MethodDelete {
+				deletes = append([]Request{request}, deletes...)
+				continue
+			}
+			requests = append(requests, request)
+		}
+	}
+
+	out := Collection{
+		Name:     name,
+		Requests: append(requests, deletes...),
+		Variables: []Variable{
+			{Name: HostVariable, Value: cloud.Current().ResourceManagerEndpoint},
+			{Name: TokenVariable},
+		},
+	}
+	variableNames := make([]string, 0)
+	for name := range b.variables {
+		variableNames = append(variableNames, name)
+	}
+	sort.Strings(variableNames)
+	for _, name := range variableNames {
+		out.Variables = append(out.Variables, Variable{Name: name, Value: b.variables[name]})
+	}
+	return &out, nil
+}
+
+type collectionBuilder struct {
+	// the evaluated attributes of the converted blocks, the key is the block address
+	values map[string]map[string]string
+	// the collection variables
+	variables map[string]string
+	inputs    map[string]hcl.Variable
+}
+
+func (b *collectionBuilder) requests(block hcl.AzapiBlock) ([]Request, error) {
+	values := make(map[string]string)
+	for _, name := range []string{"type", "parent_id", "name", "resource_id", "action", "method"} {
+		value, err := b.stringAttribute(block, name)
+		if err != nil {
+			return nil, err
+		}
+		values[name] = value
+	}
+	resourceType, apiVersion, _ := strings.Cut(values["type"], "@")
+	if values["parent_id"] == "" && values["resource_id"] == "" && values["type"] != "" {
+		// the parent_id defaults to the subscription
+		values["parent_id"] = "/subscriptions/" + b.resolve("data.azapi_client_config.current.subscription_id").AsString()
+	}
+	resourceId := values["resource_id"]
+	if resourceId == "" && values["parent_id"] != "" {
+		resourceId = utils.ResourceIdOf(values["parent_id"], resourceType, values["name"])
+	}
+	values["id"] = resourceId
+	if block.ResourceName == "azapi_resource_action" {
+		values["id"] = fmt.Sprintf("%s/%s", strings.TrimSuffix(resourceId, "/"), values["action"])
+	}
+	b.values[block.Address()] = values
+
+	body, err := b.body(block)
+	if err != nil {
+		return nil, err
+	}
+
+	request := Request{
+		Name:       fmt.Sprintf("%s %s", http.MethodGet, block.Address()),
+		Method:     http.MethodGet,
+		Path:       resourceId,
+		ApiVersion: apiVersion,
+	}
+	out := make([]Request, 0)
+	switch {
+	case block.Kind == "resource" && block.ResourceName == "azapi_resource":
+		put := request
+		put.Name = fmt.Sprintf("%s %s", http.MethodPut, block.Address())
+		put.Method = http.MethodPut
+		put.Body = body
+		del := request
+		del.Name = fmt.Sprintf("%s %s", http.MethodDelete, block.Address())
+		del.Method = http.MethodDelete
+		out = append(out, put, request, del)
+	case block.Kind == "resource" && block.ResourceName == "azapi_update_resource":
+		put := request
+		put.Name = fmt.Sprintf("%s %s", http.MethodPut, block.Address())
+		put.Description = "the body should be merged into the response of the previous GET request"
+		put.Method = http.MethodPut
+		put.Body = body
+		out = append(out, request, put)
+	case block.ResourceName == "azapi_resource_action":
+		method := values["method"]
+		if method == "" {
+			method = http.MethodPost
+		}
+		request.Name = fmt.Sprintf("%s %s", method, block.Address())
+		request.Method = method
+		request.Path = values["id"]
+		request.Body = body
+		out = append(out, request)
+	case block.Kind == "data" && block.ResourceName == "azapi_resource":
+		out = append(out, request)
+	case block.Kind == "data" && block.ResourceName == "azapi_resource_list":
+		request.Path = strings.TrimSuffix(utils.ResourceIdOf(values["parent_id"], resourceType, ""), "/")
+		out = append(out, request)
+	case block.Kind == "data" && (block.ResourceName == "azapi_resource_id" || block.ResourceName == "azapi_client_config"):
+		// no request is needed, the values are used by other blocks
+	default:
+		logrus.Warnf("%s is not supported, it's skipped", block.Address())
+	}
+	return out, nil
+}
+
+// body returns the indented JSON payload, the top-level fields like `location` and `tags` are merged into the payload
+func (b *collectionBuilder) body(block hcl.AzapiBlock) (string, error) {
+	payload := make(map[string]interface{})
+	value, errs := block.EvaluateAttribute("body", b.resolve)
+	if len(errs) != 0 {
+		return "", fmt.Errorf("evaluating body: %+v", errs)
+	}
+	if value != nil && !value.IsNull() {
+		raw := ""
+		if value.Type() == cty.String {
+			raw = value.AsString()
+		} else {
+			encoded, err := stdlib.JSONEncode(*value)
+			if err != nil {
+				return "", fmt.Errorf("encoding body: %+v", err)
+			}
+			raw = encoded.AsString()
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			return "", fmt.Errorf("unmarshalling body: %+v", err)
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			payload = m
+		}
+	}
+
+	for _, name := range []string{"location", "tags"} {
+		value, errs := block.EvaluateAttribute(name, b.resolve)
+		if len(errs) != 0 {
+			return "", fmt.Errorf("evaluating %s: %+v", name, errs)
+		}
+		if value == nil || value.IsNull() || payload[name] != nil {
+			continue
+		}
+		encoded, err := stdlib.JSONEncode(*value)
+		if err != nil {
+			return "", fmt.Errorf("encoding %s: %+v", name, err)
+		}
+		var v interface{}
+		_ = json.Unmarshal([]byte(encoded.AsString()), &v)
+		payload[name] = v
+	}
+
+	if len(payload) == 0 {
+		return "", nil
+	}
+	out, err := json.MarshalIndent(payload, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (b *collectionBuilder) stringAttribute(block hcl.AzapiBlock, name string) (string, error) {
+	value, errs := block.EvaluateAttribute(name, b.resolve)
+	if len(errs) != 0 {
+		return "", fmt.Errorf("evaluating %s: %+v", name, errs)
+	}
+	if value == nil || value.IsNull() || value.Type() != cty.String {
+		return "", nil
+	}
+	return value.AsString(), nil
+}
+
+// resolve returns the value of the reference, the attributes of the converted blocks are returned directly,
+// the other references are converted to the collection variables, e.g., `var.location` is converted to `{{location}}`
+func (b *collectionBuilder) resolve(reference string) cty.Value {
+	if index := strings.LastIndex(reference, "."); index != -1 {
+		if values, ok := b.values[reference[:index]]; ok {
+			if value := values[reference[index+1:]]; value != "" {
+				return cty.StringVal(value)
+			}
+		}
+	}
+
+	name := reference
+	defaultValue := ""
+	switch {
+	case strings.HasPrefix(reference, "var."):
+		name = strings.TrimPrefix(reference, "var.")
+		if variable, ok := b.inputs[name]; ok && variable.Default != cty.NilVal && !variable.IsSensitive {
+			defaultValue = stringValue(variable.Default)
+		}
+	case strings.HasPrefix(reference, "data.azapi_client_config."):
+		name = reference[strings.LastIndex(reference, ".")+1:]
+	}
+	name = strings.ReplaceAll(name, ".", "_")
+	b.variables[name] = defaultValue
+	return cty.StringVal(fmt.Sprintf("{{%s}}", name))
+}
+
+func stringValue(value cty.Value) string {
+	if value.IsNull() || !value.IsKnown() {
+		return ""
+	}
+	if value.Type() == cty.String {
+		return value.AsString()
+	}
+	encoded, err := stdlib.JSONEncode(value)
+	if err != nil {
+		return ""
+	}
+	return encoded.AsString()
+}
+
+// sortBlocks sorts the blocks topologically by their dependencies, the blocks without dependencies between them keep their original order
+func sortBlocks(blocks []hcl.AzapiBlock) ([]hcl.AzapiBlock, error) {
+	blockMap := make(map[string]bool)
+	for _, block := range blocks {
+		blockMap[block.Address()] = true
+	}
+
+	out := make([]hcl.AzapiBlock, 0)
+	added := make(map[string]bool)
+	for len(out) != len(blocks) {
+		progressed := false
+		for _, block := range blocks {
+			if added[block.Address()] {
+				continue
+			}
+			ready := true
+			for _, dependency := range block.Dependencies {
+				if blockMap[dependency] && !added[dependency] {
+					ready = false
+					break
+				}
+			}
+			if !ready {
+				continue
+			}
+			added[block.Address()] = true
+			out = append(out, block)
+			progressed = true
+			break
+		}
+		if !progressed {
+			remaining := make([]string, 0)
+			for _, block := range blocks {
+				if !added[block.Address()] {
+					remaining = append(remaining, block.Address())
+				}
+			}
+			return nil, fmt.Errorf("found dependency cycle among %s", strings.Join(remaining, ", "))
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,132 @@
+package export_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/export"
+	"github.com/azure/armstrong/hcl"
+)
+
+func loadCollection(t *testing.T) *export.Collection {
+	f, errs := hcl.ParseHclFile("testdata/main.tf")
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	blocks, errs := hcl.ParseAzapiBlocks(*f)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	vars, errs := hcl.ParseVariables(*f)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+	collection, err := export.NewCollection("test", *blocks, *vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collection
+}
+
+func Test_NewCollection(t *testing.T) {
+	collection := loadCollection(t)
+
+	const (
+		resourceGroupId     = "/subscriptions/{{subscription_id}}/resourceGroups/{{resource_name}}"
+		automationAccountId = resourceGroupId + "/providers/Microsoft.Automation/automationAccounts/{{resource_name}}"
+	)
+	expected := []string{
+		"PUT " + resourceGroupId,
+		"GET " + resourceGroupId,
+		"PUT " + automationAccountId,
+		"GET " + automationAccountId,
+		"POST " + automationAccountId + "/listKeys",
+		"GET " + resourceGroupId + "/providers/Microsoft.Automation/automationAccounts",
+		"DELETE " + automationAccountId,
+		"DELETE " + resourceGroupId,
+	}
+	actual := make([]string, 0)
+	for _, request := range collection.Requests {
+		actual = append(actual, request.Method+" "+request.Path)
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("expected requests %v, got %v", expected, actual)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal([]byte(collection.Requests[0].Body), &body); err != nil {
+		t.Fatalf("invalid body: %+v", err)
+	}
+	expectedBody := map[string]interface{}{
+		"location": "{{location}}",
+		"tags": map[string]interface{}{
+			"password": "{{admin_password}}",
+		},
+	}
+	if !reflect.DeepEqual(body, expectedBody) {
+		t.Fatalf("expected body %v, got %v", expectedBody, body)
+	}
+
+	variables := make(map[string]string)
+	for _, v := range collection.Variables {
+		variables[v.Name] = v.Value
+	}
+	expectedVariables := map[string]string{
+		"host":            cloud.Current().ResourceManagerEndpoint,
+		"token":           "",
+		"subscription_id": "",
+		"resource_name":   "acctest0001",
+		"location":        "westeurope",
+		"admin_password":  "",
+	}
+	if !reflect.DeepEqual(variables, expectedVariables) {
+		t.Fatalf("expected variables %v, got %v", expectedVariables, variables)
+	}
+}
+
+func Test_HttpFile(t *testing.T) {
+	content := export.HttpFile(*loadCollection(t))
+	for _, expected := range []string{
+		"@host = https://management.azure.com",
+		"@location = westeurope",
+		"### PUT azapi_resource.resourceGroup\nPUT {{host}}/subscriptions/{{subscription_id}}/resourceGroups/{{resource_name}}?api-version=2020-06-01\nAuthorization: Bearer {{token}}\nContent-Type: application/json\n",
+		"### POST azapi_resource_action.listKeys\nPOST {{host}}/subscriptions/{{subscription_id}}/resourceGroups/{{resource_name}}/providers/Microsoft.Automation/automationAccounts/{{resource_name}}/listKeys?api-version=2022-08-08\n",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %q in http file:\n%s", expected, content)
+		}
+	}
+}
+
+func Test_PostmanCollection(t *testing.T) {
+	content, err := export.PostmanCollection(*loadCollection(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var collection struct {
+		Item []struct {
+			Name    string `json:"name"`
+			Request struct {
+				Method string `json:"method"`
+				Url    struct {
+					Raw string `json:"raw"`
+				} `json:"url"`
+			} `json:"request"`
+		} `json:"item"`
+		Variable []struct {
+			Key string `json:"key"`
+		} `json:"variable"`
+	}
+	if err := json.Unmarshal([]byte(content), &collection); err != nil {
+		t.Fatalf("invalid postman collection: %+v", err)
+	}
+	if len(collection.Item) != 8 {
+		t.Fatalf("expected 8 items, got %d", len(collection.Item))
+	}
+	if item := collection.Item[0]; item.Request.Method != "PUT" || item.Request.Url.Raw != "{{host}}/subscriptions/{{subscription_id}}/resourceGroups/{{resource_name}}?api-version=2020-06-01" {
+		t.Fatalf("unexpected first item: %+v", item)
+	}
+}
@@ -0,0 +1,31 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HttpFile returns the content of the `.http` file which can be used by the REST clients like VS Code REST Client and JetBrains HTTP Client
+func HttpFile(collection Collection) string {
+	lines := make([]string, 0)
+	for _, variable := range collection.Variables {
+		lines = append(lines, strings.TrimSpace(fmt.Sprintf("@%s = %s", variable.Name, variable.Value)))
+	}
+	lines = append(lines, "")
+
+	for _, request := range collection.Requests {
+		lines = append(lines, fmt.Sprintf("### %s", request.Name))
+		if request.Description != "" {
+			lines = append(lines, fmt.Sprintf("# %s", request.Description))
+		}
+		lines = append(lines,
+			fmt.Sprintf("%s %s", request.Method, request.Url()),
+			fmt.Sprintf("Authorization: Bearer {{%s}}", TokenVariable),
+		)
+		if request.Body != "" {
+			lines = append(lines, "Content-Type: application/json", "", request.Body)
+		}
+		lines = append(lines, "")
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,117 @@
+package export
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+type postmanCollection struct {
+	Info     postmanInfo       `json:"info"`
+	Item     []postmanItem     `json:"item"`
+	Auth     postmanAuth       `json:"auth"`
+	Variable []postmanKeyValue `json:"variable"`
+}
+
+type postmanInfo struct {
+	Name   string `json:"name"`
+	Schema string `json:"schema"`
+}
+
+type postmanItem struct {
+	Name    string         `json:"name"`
+	Request postmanRequest `json:"request"`
+}
+
+type postmanRequest struct {
+	Method      string            `json:"method"`
+	Header      []postmanKeyValue `json:"header"`
+	Url         postmanUrl        `json:"url"`
+	Body        *postmanBody      `json:"body,omitempty"`
+	Description string            `json:"description,omitempty"`
+}
+
+type postmanUrl struct {
+	Raw   string            `json:"raw"`
+	Host  []string          `json:"host"`
+	Path  []string          `json:"path"`
+	Query []postmanKeyValue `json:"query,omitempty"`
+}
+
+type postmanBody struct {
+	Mode    string `json:"mode"`
+	Raw     string `json:"raw"`
+	Options struct {
+		Raw struct {
+			Language string `json:"language"`
+		} `json:"raw"`
+	} `json:"options"`
+}
+
+type postmanAuth struct {
+	Type   string            `json:"type"`
+	Bearer []postmanKeyValue `json:"bearer"`
+}
+
+type postmanKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type,omitempty"`
+}
+
+// PostmanCollection returns the content of the Postman collection in v2.1 format, the requests are run in order by the collection runner
+func PostmanCollection(collection Collection) (string, error) {
+	out := postmanCollection{
+		Info: postmanInfo{
+			Name:   collection.Name,
+			Schema: postmanSchema,
+		},
+		Item: make([]postmanItem, 0),
+		Auth: postmanAuth{
+			Type: "bearer",
+			Bearer: []postmanKeyValue{
+				{Key: "token", Value: "{{" + TokenVariable + "}}", Type: "string"},
+			},
+		},
+		Variable: make([]postmanKeyValue, 0),
+	}
+	for _, variable := range collection.Variables {
+		out.Variable = append(out.Variable, postmanKeyValue{Key: variable.Name, Value: variable.Value})
+	}
+
+	for _, request := range collection.Requests {
+		item := postmanItem{
+			Name: request.Name,
+			Request: postmanRequest{
+				Method:      request.Method,
+				Header:      make([]postmanKeyValue, 0),
+				Description: request.Description,
+				Url: postmanUrl{
+					Raw:  request.Url(),
+					Host: []string{"{{" + HostVariable + "}}"},
+					Path: strings.Split(strings.Trim(request.Path, "/"), "/"),
+				},
+			},
+		}
+		if request.ApiVersion != "" {
+			item.Request.Url.Query = []postmanKeyValue{{Key: "api-version", Value: request.ApiVersion}}
+		}
+		if request.Body != "" {
+			item.Request.Header = append(item.Request.Header, postmanKeyValue{Key: "Content-Type", Value: "application/json"})
+			body := postmanBody{
+				Mode: "raw",
+				Raw:  request.Body,
+			}
+			body.Options.Raw.Language = "json"
+			item.Request.Body = &body
+		}
+		out.Item = append(out.Item, item)
+	}
+
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,50 @@
+variable "resource_name" {
+  type    = string
+  default = "acctest0001"
+}
+
+variable "location" {
+  type    = string
+  default = "westeurope"
+}
+
+variable "admin_password" {
+  type      = string
+  default   = "secret"
+  sensitive = true
+}
+
+resource "azapi_resource" "automationAccount" {
+  type      = "Microsoft.Automation/automationAccounts@2022-08-08"
+  parent_id = azapi_resource.resourceGroup.id
+  name      = var.resource_name
+  location  = var.location
+  body = {
+    properties = {
+      sku = {
+        name = "Basic"
+      }
+    }
+  }
+}
+
+resource "azapi_resource" "resourceGroup" {
+  type     = "Microsoft.Resources/resourceGroups@2020-06-01"
+  name     = var.resource_name
+  location = var.location
+  tags = {
+    password = var.admin_password
+  }
+}
+
+resource "azapi_resource_action" "listKeys" {
+  type        = "Microsoft.Automation/automationAccounts@2022-08-08"
+  resource_id = azapi_resource.automationAccount.id
+  action      = "listKeys"
+}
+
+data "azapi_resource_list" "listAutomationAccounts" {
+  type       = "Microsoft.Automation/automationAccounts@2022-08-08"
+  parent_id  = azapi_resource.resourceGroup.id
+  depends_on = [azapi_resource.automationAccount]
+}
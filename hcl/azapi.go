@@ -0,0 +1,138 @@
+package hcl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/sirupsen/logrus"
+	"github.com/zclconf/go-cty/cty"
+)
+
+var AzapiBlockSchema = hcl.BodySchema{
+	Blocks: []hcl.BlockHeaderSchema{
+		{
+			Type:       "resource",
+			LabelNames: []string{"type", "name"},
+		},
+		{
+			Type:       "data",
+			LabelNames: []string{"type", "name"},
+		},
+	},
+}
+
+// AzapiBlock is a resource or data block of the azapi provider, e.g., azapi_resource, azapi_update_resource and azapi_resource_action
+type AzapiBlock struct {
+	Kind         string // resource or data
+	ResourceName string // azapi_resource, azapi_update_resource, azapi_resource_action, etc.
+	Name         string
+	Attributes   hcl.Attributes
+	// addresses of the blocks referenced by this block, e.g., azapi_resource.test or data.azapi_resource.test
+	Dependencies []string
+	FileName     string
+	LineNumber   int
+}
+
+func (b AzapiBlock) Address() string {
+	if b.Kind == "data" {
+		return fmt.Sprintf("data.%s.%s", b.ResourceName, b.Name)
+	}
+	return fmt.Sprintf("%s.%s", b.ResourceName, b.Name)
+}
+
+// EvaluateAttribute evaluates the attribute, the references in the expression are replaced by the values returned by the resolve function,
+// e.g., `var.location` is resolved by calling resolve("var.location"). It returns nil if the attribute is not specified.
+func (b AzapiBlock) EvaluateAttribute(name string, resolve func(reference string) cty.Value) (*cty.Value, []error) {
+	attr, ok := b.Attributes[name]
+	if !ok {
+		return nil, nil
+	}
+	ctx := &hcl.EvalContext{
+		Functions: evalContext.Functions,
+		Variables: resolveVariables(attr.Expr.Variables(), resolve),
+	}
+	v, diags := attr.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return nil, diags.Errs()
+	}
+	return &v, nil
+}
+
+// ParseAzapiBlocks returns all the resource and data blocks of the azapi provider in the given file,
+// the errors of the other blocks, e.g., the resource blocks without names, are logged and the blocks are skipped
+func ParseAzapiBlocks(f hcl.File) (*[]AzapiBlock, []error) {
+	content, _, diags := f.Body.PartialContent(&AzapiBlockSchema)
+	if diags.HasErrors() {
+		logrus.Error(diags)
+	}
+
+	results := make([]AzapiBlock, 0)
+	for _, block := range content.Blocks {
+		if len(block.Labels) != 2 || !strings.HasPrefix(block.Labels[0], "azapi_") {
+			continue
+		}
+
+		attrs, diags := block.Body.JustAttributes()
+		if diags.HasErrors() {
+			diags := skipJustAttributesDiags(diags)
+			if diags.HasErrors() {
+				return nil, diags.Errs()
+			}
+		}
+
+		dependencies := make([]string, 0)
+		dependencyMap := make(map[string]bool)
+		for _, attr := range attrs {
+			for _, traversal := range attr.Expr.Variables() {
+				address := blockAddress(traversal)
+				if address == "" || dependencyMap[address] {
+					continue
+				}
+				dependencyMap[address] = true
+				dependencies = append(dependencies, address)
+			}
+		}
+
+		results = append(results, AzapiBlock{
+			Kind:         block.Type,
+			ResourceName: block.Labels[0],
+			Name:         block.Labels[1],
+			Attributes:   attrs,
+			Dependencies: dependencies,
+			FileName:     block.DefRange.Filename,
+			LineNumber:   block.DefRange.Start.Line,
+		})
+	}
+
+	return &results, nil
+}
+
+// blockAddress returns the address of the resource or data block referenced by the traversal, it returns empty string if it's not a block reference
+func blockAddress(traversal hcl.Traversal) string {
+	names := make([]string, 0)
+	for _, step := range traversal {
+		switch v := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, v.Name)
+		case hcl.TraverseAttr:
+			names = append(names, v.Name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	switch names[0] {
+	case "var", "local", "each", "count", "path", "terraform", "self", "module":
+		return ""
+	case "data":
+		if len(names) < 3 {
+			return ""
+		}
+		return strings.Join(names[:3], ".")
+	}
+	if len(names) < 2 {
+		return ""
+	}
+	return strings.Join(names[:2], ".")
+}
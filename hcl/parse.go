@@ -2,6 +2,7 @@ package hcl
 
 import (
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
@@ -14,6 +15,9 @@ import (
 	"github.com/zclconf/go-cty/cty/function/stdlib"
 )
 
+// ResourceBlockSchema is the schema of the resource blocks.
+//
+// Deprecated: use AzapiBlockSchema and ParseAzapiBlocks, which parse both the resource and data blocks.
 var ResourceBlockSchema = hcl.BodySchema{
 	Blocks: []hcl.BlockHeaderSchema{
 		{
@@ -87,16 +91,45 @@ func (p AzureProvider) Name() string {
 }
 
 type AzapiResource struct {
-	Name       string
-	Type       string
-	Body       string
-	FileName   string
-	LineNumber int
+	Kind         string // resource or data
+	ResourceName string // azapi_resource, azapi_update_resource, azapi_resource_action, etc.
+	Name         string
+	Type         string
+	Body         string
+	Action       string
+	Method       string
+	FileName     string
+	LineNumber   int
+}
+
+func (r AzapiResource) Address() string {
+	if r.Kind == "data" {
+		return fmt.Sprintf("data.%s.%s", r.ResourceName, r.Name)
+	}
+	return fmt.Sprintf("%s.%s", r.ResourceName, r.Name)
+}
+
+// RequestMethods returns the HTTP methods of the requests which send the body, the first method whose model is found in the swagger should be used
+func (r AzapiResource) RequestMethods() []string {
+	switch r.ResourceName {
+	case "azapi_resource_action":
+		if r.Method != "" {
+			return []string{strings.ToUpper(r.Method)}
+		}
+		return []string{http.MethodPost}
+	case "azapi_update_resource":
+		return []string{http.MethodPatch, http.MethodPut}
+	}
+	if r.Kind == "data" {
+		return []string{http.MethodGet}
+	}
+	return []string{http.MethodPut}
 }
 
 type Variable struct {
 	Name        string
 	HasDefault  bool
+	Default     cty.Value
 	FileName    string
 	LineNumber  int
 	IsSensitive bool
@@ -105,11 +138,21 @@ type Variable struct {
 // mockVariables returns a map of variables that are used in the given traversals.
 // The mocked variables are prefixed with the given prefix and of  type string.
 func mockVariables(traversals []hcl.Traversal) map[string]cty.Value {
+	return resolveVariables(traversals, mockReference)
+}
+
+// mockReference returns the mocked value of the reference, e.g., `$var.location` for `var.location`
+func mockReference(reference string) cty.Value {
 	const variablePrefix = "$"
 
+	return cty.StringVal(variablePrefix + reference)
+}
+
+// resolveVariables returns a map of variables that are used in the given traversals, the value of each reference is returned by the resolve function.
+func resolveVariables(traversals []hcl.Traversal, resolve func(reference string) cty.Value) map[string]cty.Value {
 	result := make(map[string]cty.Value)
 	for _, traversal := range traversals {
-		mockedVariable := mockVariable(traversal, 0, variablePrefix)
+		mockedVariable := mockVariable(traversal, 0, "", resolve)
 		result = mergeKeys(result, mockedVariable)
 	}
 
@@ -133,7 +176,7 @@ func mergeKeys(left, right map[string]cty.Value) map[string]cty.Value {
 
 // one hcl.Traversal corresponds to one reference
 // e.g.,[{{} azapi_resource testdata/test.tf:121,18-32} {{} networkInterface testdata/test.tf:121,32-49} {{} id testdata/test.tf:121,49-52}]
-func mockVariable(steps hcl.Traversal, index int, placeholder string) map[string]cty.Value {
+func mockVariable(steps hcl.Traversal, index int, reference string, resolve func(reference string) cty.Value) map[string]cty.Value {
 	if index >= len(steps) {
 		return map[string]cty.Value{}
 	}
@@ -143,14 +186,14 @@ func mockVariable(steps hcl.Traversal, index int, placeholder string) map[string
 
 	switch stepValue := step.(type) {
 	case hcl.TraverseRoot:
-		placeholder += stepValue.Name
-		result[stepValue.Name] = cty.ObjectVal(mockVariable(steps, index+1, placeholder))
+		reference += stepValue.Name
+		result[stepValue.Name] = cty.ObjectVal(mockVariable(steps, index+1, reference, resolve))
 	case hcl.TraverseAttr:
-		placeholder += "." + stepValue.Name
+		reference += "." + stepValue.Name
 		if index < len(steps)-1 {
-			result[stepValue.Name] = cty.ObjectVal(mockVariable(steps, index+1, placeholder))
+			result[stepValue.Name] = cty.ObjectVal(mockVariable(steps, index+1, reference, resolve))
 		} else {
-			result[stepValue.Name] = cty.StringVal(placeholder)
+			result[stepValue.Name] = resolve(reference)
 		}
 	}
 	return result
@@ -200,64 +243,66 @@ func ParseHclFile(path string) (*hcl.File, []error) {
 	return f, nil
 }
 
+// ParseAzapiResource returns the azapi resources and data sources which have the `type` attribute, e.g., azapi_resource, azapi_update_resource and azapi_resource_action
 func ParseAzapiResource(f hcl.File) (*[]AzapiResource, []error) {
-	content, _, diags := f.Body.PartialContent(&ResourceBlockSchema)
-	if diags.HasErrors() {
-		logrus.Error(diags)
+	blocks, errs := ParseAzapiBlocks(f)
+	if errs != nil {
+		return nil, errs
 	}
 
 	results := make([]AzapiResource, 0)
-	for _, block := range content.Blocks {
-		if block.Type == "resource" && len(block.Labels) > 1 && block.Labels[0] == "azapi_resource" {
-
-			attrs, diags := block.Body.JustAttributes()
-			if diags.HasErrors() {
-				diags := skipJustAttributesDiags(diags)
-				if diags.HasErrors() {
-					return nil, diags.Errs()
-				}
-			}
+	for _, block := range *blocks {
+		r := AzapiResource{
+			Kind:         block.Kind,
+			ResourceName: block.ResourceName,
+			Name:         block.Name,
+			FileName:     block.FileName,
+			LineNumber:   block.LineNumber,
+		}
 
-			resourceTypeRaw, ok := attrs["type"]
-			if !ok {
-				return nil, []error{fmt.Errorf("resource type is not specified for azapi_resource.%s", block.Labels[1])}
+		resourceType, errs := block.EvaluateAttribute("type", mockReference)
+		if errs != nil {
+			return nil, errs
+		}
+		if resourceType == nil {
+			if block.Kind == "resource" && block.ResourceName == "azapi_resource" {
+				return nil, []error{fmt.Errorf("resource type is not specified for %s", r.Address())}
 			}
+			// the blocks like azapi_client_config don't send requests with the resource type
+			continue
+		}
+		r.Type = resourceType.AsString()
 
-			resourceType, errs := mockExpression(resourceTypeRaw.Expr)
+		for name, value := range map[string]*string{"action": &r.Action, "method": &r.Method} {
+			v, errs := block.EvaluateAttribute(name, mockReference)
 			if errs != nil {
 				return nil, errs
 			}
-
-			r := AzapiResource{
-				Name:       block.Labels[1],
-				Type:       resourceType.AsString(),
-				FileName:   block.DefRange.Filename,
-				LineNumber: block.DefRange.Start.Line,
+			if v != nil && v.Type() == cty.String {
+				*value = v.AsString()
 			}
+		}
 
-			if p := attrs["body"]; p != nil {
-				body, errs := mockExpression(p.Expr)
-				if errs != nil {
-					return nil, errs
+		body, errs := block.EvaluateAttribute("body", mockReference)
+		if errs != nil {
+			return nil, errs
+		}
+		if body != nil {
+			if body.Type() == cty.String {
+				r.Body = body.AsString()
+			} else {
+				logrus.Debugf("jsonencode for %s with dynamic schema body: %+v", r.Address(), body)
+				// azapi dynamic schema is used
+				v, err := stdlib.JSONEncode(*body)
+				if err != nil {
+					return nil, []error{fmt.Errorf("jsonencode for azapi dynamic schema: %+v", err)}
 				}
 
-				if body.Type() == cty.String {
-					r.Body = body.AsString()
-				} else {
-					logrus.Debugf("jsonencode for azapi_resource %s with dynamic schema body: %+v", r.Name, body)
-					// azapi dynamic schema is used
-					v, err := stdlib.JSONEncode(*body)
-					if err != nil {
-						errs = append(errs, fmt.Errorf("jsonencode for azapi dynamic schema: %+v", err))
-						return nil, errs
-					}
-
-					r.Body = v.AsString()
-				}
+				r.Body = v.AsString()
 			}
-
-			results = append(results, r)
 		}
+
+		results = append(results, r)
 	}
 
 	return &results, nil
@@ -280,8 +325,12 @@ func ParseVariables(f hcl.File) (*map[string]Variable, []error) {
 		}
 
 		var hasDefault bool
+		defaultValue := cty.NilVal
 		if p := attrs["default"]; p != nil {
 			hasDefault = true
+			if value, diags := p.Expr.Value(nil); !diags.HasErrors() {
+				defaultValue = value
+			}
 		}
 
 		var isSensitive bool
@@ -299,6 +348,7 @@ func ParseVariables(f hcl.File) (*map[string]Variable, []error) {
 			LineNumber:  block.DefRange.Start.Line,
 			IsSensitive: isSensitive,
 			HasDefault:  hasDefault,
+			Default:     defaultValue,
 		}
 	}
 
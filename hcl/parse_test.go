@@ -1,6 +1,9 @@
 package hcl_test
 
 import (
+	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/azure/armstrong/hcl"
@@ -31,6 +34,48 @@ func TestParseAzapiResource(t *testing.T) {
 	}
 }
 
+func TestParseAzapiResource_allKinds(t *testing.T) {
+	f, errs := hcl.ParseHclFile("testdata/test3.tf")
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	azapiResources, errs := hcl.ParseAzapiResource(*f)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	expected := map[string]struct {
+		Action  string
+		Methods []string
+		HasBody bool
+	}{
+		"azapi_update_resource.account":       {Methods: []string{"PATCH", "PUT"}, HasBody: true},
+		"azapi_resource_action.regenerate":    {Action: "agentRegistrationInformation/regenerateKey", Methods: []string{"POST"}, HasBody: true},
+		"data.azapi_resource_action.listKeys": {Action: "listKeys", Methods: []string{"POST"}},
+		"data.azapi_resource.account":         {Methods: []string{"GET"}},
+	}
+	if len(*azapiResources) != len(expected) {
+		t.Fatalf("expected %d azapi resources, got %d", len(expected), len(*azapiResources))
+	}
+	for _, ar := range *azapiResources {
+		e, ok := expected[ar.Address()]
+		if !ok {
+			t.Errorf("unexpected azapi resource %s", ar.Address())
+			continue
+		}
+		if ar.Action != e.Action {
+			t.Errorf("expected action %q for %s, got %q", e.Action, ar.Address(), ar.Action)
+		}
+		if !reflect.DeepEqual(ar.RequestMethods(), e.Methods) {
+			t.Errorf("expected methods %v for %s, got %v", e.Methods, ar.Address(), ar.RequestMethods())
+		}
+		if (ar.Body != "") != e.HasBody {
+			t.Errorf("expected body presence %v for %s, got %q", e.HasBody, ar.Address(), ar.Body)
+		}
+	}
+}
+
 func TestParseVariable(t *testing.T) {
 	testFileDir := "testdata/"
 
@@ -80,3 +125,55 @@ func TestParseAzureProvider(t *testing.T) {
 		}
 	}
 }
+
+func TestParseAzapiBlocks(t *testing.T) {
+	f, errs := hcl.ParseHclFile("testdata/test.tf")
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	blocks, errs := hcl.ParseAzapiBlocks(*f)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	for _, block := range *blocks {
+		if block.Address() != "azapi_resource.subnet" {
+			continue
+		}
+		if len(block.Dependencies) != 1 || block.Dependencies[0] != "azapi_resource.virtualNetwork" {
+			t.Fatalf("expected dependencies [azapi_resource.virtualNetwork], got %v", block.Dependencies)
+		}
+		return
+	}
+	t.Fatalf("azapi_resource.subnet is not found")
+}
+
+func TestParseAzapiResource_InvalidOtherBlocks(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "main.tf")
+	config := `
+resource "azurerm_resource_group" {
+  name = "invalid"
+}
+
+resource "azapi_resource" "test" {
+  type = "Microsoft.Resources/resourceGroups@2021-04-01"
+  name = "acctest"
+}
+`
+	if err := os.WriteFile(filename, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, errs := hcl.ParseHclFile(filename)
+	if errs != nil {
+		t.Fatal(errs)
+	}
+
+	resources, errs := hcl.ParseAzapiResource(*f)
+	if errs != nil {
+		t.Fatalf("expect the invalid block not related to azapi is skipped, but got %+v", errs)
+	}
+	if len(*resources) != 1 || (*resources)[0].Address() != "azapi_resource.test" {
+		t.Fatalf("expect azapi_resource.test, but got %+v", *resources)
+	}
+}
@@ -0,0 +1,39 @@
+variable "admin_password" {
+  type      = string
+  sensitive = true
+}
+
+data "azapi_client_config" "current" {}
+
+resource "azapi_update_resource" "account" {
+  type      = "Microsoft.Automation/automationAccounts@2022-08-08"
+  parent_id = "/subscriptions/${data.azapi_client_config.current.subscription_id}/resourceGroups/example"
+  name      = "example"
+  body = {
+    properties = {
+      publicNetworkAccess = false
+    }
+  }
+}
+
+resource "azapi_resource_action" "regenerate" {
+  type        = "Microsoft.Automation/automationAccounts@2022-08-08"
+  resource_id = azapi_update_resource.account.id
+  action      = "agentRegistrationInformation/regenerateKey"
+  body = {
+    keyName = "primary"
+  }
+}
+
+data "azapi_resource_action" "listKeys" {
+  type        = "Microsoft.Automation/automationAccounts@2022-08-08"
+  resource_id = azapi_update_resource.account.id
+  action      = "listKeys"
+  method      = "post"
+}
+
+data "azapi_resource" "account" {
+  type      = "Microsoft.Automation/automationAccounts@2022-08-08"
+  parent_id = "/subscriptions/${data.azapi_client_config.current.subscription_id}/resourceGroups/example"
+  name      = "example"
+}
@@ -0,0 +1,226 @@
+package knownerror
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+	"github.com/azure/armstrong/utils"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed rules.json
+var builtinRules []byte
+
+// Rule describes a known error and its fix, the error matches the rule if any of its error codes or its message matches the rule.
+// The placeholders in the cause, fix and patch are replaced with the details of the failed resource:
+// ${address} is the resource address, ${resource_type} is the ARM resource type and ${namespace} is the resource provider namespace.
+type Rule struct {
+	Name string `json:"name"`
+	// Codes are the error codes which match the rule, they're compared case-insensitively
+	Codes []string `json:"codes"`
+	// MessagePattern is the regular expression which matches the error message
+	MessagePattern string `json:"messagePattern"`
+	// ResourceTypes limits the rule to the resource types, e.g., Microsoft.Purview/accounts, the rule applies to all resource types if it's empty
+	ResourceTypes []string `json:"resourceTypes"`
+	Cause         string   `json:"cause"`
+	Fix           string   `json:"fix"`
+	Patch         string   `json:"patch"`
+	Reference     string   `json:"reference"`
+
+	messageRegex *regexp.Regexp
+}
+
+// KnowledgeBase matches the errors against the known errors, the user defined rules take precedence over the built-in rules
+type KnowledgeBase struct {
+	rules []Rule
+}
+
+// NewKnowledgeBase returns the knowledge base with the built-in rules and the rules in the files, the paths can be the JSON files or the directories which contain them
+func NewKnowledgeBase(paths []string) (*KnowledgeBase, error) {
+	kb := &KnowledgeBase{
+		rules: make([]Rule, 0),
+	}
+	for _, path := range paths {
+		files := []string{path}
+		if info, err := os.Stat(path); err != nil {
+			return nil, err
+		} else if info.IsDir() {
+			files, err = utils.ListFiles(path, ".json", 1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			if err := kb.add(data); err != nil {
+				return nil, fmt.Errorf("loading known error rules from %s: %+v", filepath.Base(file), err)
+			}
+		}
+	}
+	if err := kb.add(builtinRules); err != nil {
+		return nil, fmt.Errorf("loading built-in known error rules: %+v", err)
+	}
+	return kb, nil
+}
+
+// Rules returns the rules in the order they're matched
+func (kb *KnowledgeBase) Rules() []Rule {
+	return kb.rules
+}
+
+func (kb *KnowledgeBase) add(data []byte) error {
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return err
+	}
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return fmt.Errorf("the rule name is required")
+		}
+		if len(rule.Codes) == 0 && rule.MessagePattern == "" {
+			return fmt.Errorf("rule %s: either codes or messagePattern is required", rule.Name)
+		}
+		if rule.MessagePattern != "" {
+			regex, err := regexp.Compile(rule.MessagePattern)
+			if err != nil {
+				return fmt.Errorf("rule %s: invalid messagePattern: %+v", rule.Name, err)
+			}
+			rule.messageRegex = regex
+		}
+		kb.rules = append(kb.rules, rule)
+	}
+	return nil
+}
+
+// ResolveErrors fills the likely causes and fixes of the errors, the error codes and messages of the failed requests in the logs are matched as well
+func (kb *KnowledgeBase) ResolveErrors(errorReport *types.ErrorReport) {
+	for i, e := range errorReport.Errors {
+		codes := make([]string, 0)
+		if e.Code != "" {
+			codes = append(codes, e.Code)
+		}
+		messages := []string{e.Message}
+		for _, trace := range report.FailedRequests(e.Id, errorReport.Logs) {
+			if trace.Response == nil {
+				continue
+			}
+			responseCodes, responseMessages := responseErrors(trace.Response.Body)
+			codes = append(codes, responseCodes...)
+			messages = append(messages, responseMessages...)
+		}
+
+		address := report.ErrorAddress(e)
+		resourceType := strings.Split(e.Type, "@")[0]
+		fixes := kb.Match(resourceType, codes, messages)
+		for j := range fixes {
+			fixes[j] = fillPlaceholders(fixes[j], address, resourceType)
+		}
+		if len(fixes) != 0 {
+			logrus.Debugf("found %d known fixes for %s", len(fixes), address)
+		}
+		errorReport.Errors[i].Fixes = fixes
+	}
+}
+
+// Match returns the fixes of the rules which match any of the error codes or messages, the placeholders are not replaced
+func (kb *KnowledgeBase) Match(resourceType string, codes []string, messages []string) []types.KnownFix {
+	out := make([]types.KnownFix, 0)
+	for _, rule := range kb.rules {
+		if !rule.appliesTo(resourceType) || !rule.matches(codes, messages) {
+			continue
+		}
+		out = append(out, types.KnownFix{
+			Rule:      rule.Name,
+			Cause:     rule.Cause,
+			Fix:       rule.Fix,
+			Patch:     rule.Patch,
+			Reference: rule.Reference,
+		})
+	}
+	return out
+}
+
+func (r Rule) appliesTo(resourceType string) bool {
+	if len(r.ResourceTypes) == 0 {
+		return true
+	}
+	for _, t := range r.ResourceTypes {
+		if strings.EqualFold(t, resourceType) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r Rule) matches(codes []string, messages []string) bool {
+	for _, code := range codes {
+		for _, c := range r.Codes {
+			if strings.EqualFold(c, code) {
+				return true
+			}
+		}
+	}
+	if r.messageRegex != nil {
+		for _, message := range messages {
+			if r.messageRegex.MatchString(message) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fillPlaceholders(fix types.KnownFix, address string, resourceType string) types.KnownFix {
+	namespace := strings.Split(resourceType, "/")[0]
+	replacer := strings.NewReplacer("${address}", address, "${resource_type}", resourceType, "${namespace}", namespace)
+	fix.Cause = replacer.Replace(fix.Cause)
+	fix.Fix = replacer.Replace(fix.Fix)
+	fix.Patch = replacer.Replace(fix.Patch)
+	return fix
+}
+
+type armError struct {
+	Code    string     `json:"code"`
+	Message string     `json:"message"`
+	Details []armError `json:"details"`
+}
+
+// responseErrors returns the error codes and messages in the ARM error response, including the nested details
+func responseErrors(body string) ([]string, []string) {
+	var response struct {
+		armError
+		Error *armError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &response); err != nil {
+		return nil, nil
+	}
+	root := response.armError
+	if response.Error != nil {
+		root = *response.Error
+	}
+	codes, messages := make([]string, 0), make([]string, 0)
+	var walk func(e armError)
+	walk = func(e armError) {
+		if e.Code != "" {
+			codes = append(codes, e.Code)
+		}
+		if e.Message != "" {
+			messages = append(messages, e.Message)
+		}
+		for _, detail := range e.Details {
+			walk(detail)
+		}
+	}
+	walk(root)
+	return codes, messages
+}
@@ -0,0 +1,166 @@
+package knownerror_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/knownerror"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+	paltypes "github.com/ms-henglu/pal/types"
+)
+
+const accountId = "/subscriptions/000/resourceGroups/rg/providers/Microsoft.Purview/accounts/test"
+
+func Test_ResolveErrors(t *testing.T) {
+	kb, err := knownerror.NewKnowledgeBase([]string{"./testdata"})
+	if err != nil {
+		t.Fatalf("loading the known error rules: %+v", err)
+	}
+	if kb.Rules()[0].Name != "PurviewManagedEventHubConflict" {
+		t.Fatalf("expect the user defined rules to be matched first, but got %s", kb.Rules()[0].Name)
+	}
+
+	testcases := []struct {
+		Name     string
+		Error    types.Error
+		Logs     []paltypes.RequestTrace
+		Expected []string
+	}{
+		{
+			Name: "code in the nested details of the response",
+			Error: types.Error{
+				Id:      accountId,
+				Type:    "Microsoft.Purview/accounts@2021-12-01",
+				Label:   "account",
+				Message: "unexpected status 409",
+			},
+			Logs: []paltypes.RequestTrace{
+				{
+					Url:        accountId + "?api-version=2021-12-01",
+					Method:     "PUT",
+					StatusCode: 409,
+					Response: &paltypes.HttpResponse{
+						Body: `{"error":{"code":"Conflict","message":"failed","details":[{"code":"MissingSubscriptionRegistration","message":"The subscription is not registered."}]}}`,
+					},
+				},
+			},
+			Expected: []string{"MissingSubscriptionRegistration"},
+		},
+		{
+			Name: "message in the error",
+			Error: types.Error{
+				Id:      accountId,
+				Type:    "Microsoft.Purview/accounts@2021-12-01",
+				Address: "azapi_resource.account",
+				Code:    "1001",
+				Message: `{"error": {"code": "1001", "message": "IdentityUrl is required"}}`,
+			},
+			Expected: []string{"IdentityRequired"},
+		},
+		{
+			Name: "user defined rule of the resource type",
+			Error: types.Error{
+				Id:      accountId + "/kafkaConfigurations/test",
+				Type:    "Microsoft.Purview/accounts/kafkaConfigurations@2021-12-01",
+				Label:   "kafkaConfiguration",
+				Message: "The managed event hub is enabled.",
+			},
+			Expected: []string{"PurviewManagedEventHubConflict"},
+		},
+		{
+			Name: "user defined rule of other resource types",
+			Error: types.Error{
+				Id:      accountId,
+				Type:    "Microsoft.Purview/accounts@2021-12-01",
+				Label:   "account",
+				Message: "The managed event hub is enabled.",
+			},
+			Expected: []string{},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("testcase: %s", testcase.Name)
+		errorReport := types.ErrorReport{Errors: []types.Error{testcase.Error}, Logs: testcase.Logs}
+		kb.ResolveErrors(&errorReport)
+		fixes := errorReport.Errors[0].Fixes
+		actual := make([]string, 0)
+		for _, fix := range fixes {
+			actual = append(actual, fix.Rule)
+		}
+		if strings.Join(actual, ",") != strings.Join(testcase.Expected, ",") {
+			t.Fatalf("expect rules %v, but got %v", testcase.Expected, actual)
+		}
+	}
+}
+
+func Test_KnownFixesInReport(t *testing.T) {
+	kb, err := knownerror.NewKnowledgeBase(nil)
+	if err != nil {
+		t.Fatalf("loading the known error rules: %+v", err)
+	}
+	errorReport := types.ErrorReport{
+		Errors: []types.Error{
+			{
+				Id:      accountId,
+				Type:    "Microsoft.Purview/accounts@2021-12-01",
+				Label:   "account",
+				Code:    "MissingSubscriptionRegistration",
+				Message: "The subscription is not registered to use namespace 'Microsoft.Purview'.",
+			},
+		},
+	}
+	kb.ResolveErrors(&errorReport)
+
+	content := report.ErrorMarkdownReport(errorReport.Errors[0], nil, "terraform v1.9.5")
+	for _, expected := range []string{
+		"### Likely cause and fix",
+		"`az provider register --namespace Microsoft.Purview`",
+		`resource_id = "/subscriptions/${data.azapi_client_config.current.subscription_id}/providers/Microsoft.Purview"`,
+		"# add the following config to azapi_resource.account",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expect %s in the report, but got:\n%s", expected, content)
+		}
+	}
+
+	content = report.ErrorMarkdownReport(types.Error{Type: "Microsoft.Purview/accounts@2021-12-01"}, nil, "terraform v1.9.5")
+	if strings.Contains(content, "Likely cause and fix") || strings.Contains(content, "${known_fixes}") {
+		t.Errorf("expect no known fix section in the report, but got:\n%s", content)
+	}
+}
+
+func Test_CapacityNotAvailable(t *testing.T) {
+	kb, err := knownerror.NewKnowledgeBase(nil)
+	if err != nil {
+		t.Fatalf("loading the known error rules: %+v", err)
+	}
+	testcases := []struct {
+		Message  string
+		Expected bool
+	}{
+		{Message: "Operation could not be completed as it results in exceeding approved Total Regional Cores quota.", Expected: true},
+		{Message: "The quota was exceeded for the subscription.", Expected: true},
+		{Message: "The SKU Standard_D2s_v3 is not available in location 'westus'.", Expected: true},
+		{Message: "The property 'quotaId' is invalid."},
+		{Message: "The disk pool quota settings are not supported in this api-version."},
+	}
+	for _, testcase := range testcases {
+		actual := false
+		for _, fix := range kb.Match("Microsoft.Compute/virtualMachines", nil, []string{testcase.Message}) {
+			if fix.Rule == "CapacityNotAvailable" {
+				actual = true
+			}
+		}
+		if actual != testcase.Expected {
+			t.Errorf("expect %v for %q, but got %v", testcase.Expected, testcase.Message, actual)
+		}
+	}
+}
+
+func Test_NewKnowledgeBase_InvalidRule(t *testing.T) {
+	if _, err := knownerror.NewKnowledgeBase([]string{"./testdata/not-exist.json"}); err == nil {
+		t.Fatalf("expect error when the rule file doesn't exist")
+	}
+}
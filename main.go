@@ -13,6 +13,7 @@ import (
 func main() {
 	logrus.SetLevel(logrus.InfoLevel)
 	log.SetOutput(io.Discard)
+	commands.Version = VersionString()
 
 	c := &cli.CLI{
 		Name:       "armstrong",
@@ -40,6 +41,27 @@ func main() {
 		"credscan": func() (cli.Command, error) {
 			return &commands.CredentialScanCommand{}, nil
 		},
+		"examples": func() (cli.Command, error) {
+			return &commands.ExamplesCommand{}, nil
+		},
+		"export": func() (cli.Command, error) {
+			return &commands.ExportCommand{}, nil
+		},
+		"import": func() (cli.Command, error) {
+			return &commands.ImportCommand{}, nil
+		},
+		"config show": func() (cli.Command, error) {
+			return &commands.ConfigShowCommand{}, nil
+		},
+		"index update": func() (cli.Command, error) {
+			return &commands.IndexUpdateCommand{}, nil
+		},
+		"index build": func() (cli.Command, error) {
+			return &commands.IndexBuildCommand{}, nil
+		},
+		"index show": func() (cli.Command, error) {
+			return &commands.IndexShowCommand{}, nil
+		},
 	}
 
 	exitStatus, err := c.Run()
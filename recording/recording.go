@@ -0,0 +1,244 @@
+package recording
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/ms-henglu/pal/types"
+)
+
+// SanitizedHost returns the host which replaces the sanitized host in the recordings, it's the ARM endpoint of the selected cloud
+func SanitizedHost() string {
+	return cloud.Current().Host()
+}
+
+// the values which are used by the test-proxy sanitizers and other tools to replace the secrets
+var sanitizedValues = []string{"sanitized", "redacted"}
+
+// the headers which are removed from the traces because they contain credentials
+var removedHeaders = []string{"authorization", "cookie", "set-cookie", "x-ms-authorization-auxiliary"}
+
+// LoadFile loads the request traces from an Azure SDK test-proxy recording or a HAR file, the format is detected by the content
+func LoadFile(filepath string) ([]types.RequestTrace, error) {
+	data, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	var content map[string]json.RawMessage
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("parsing %s: %+v", filepath, err)
+	}
+	switch {
+	case content["Entries"] != nil:
+		return LoadTestProxyRecording(data)
+	case content["log"] != nil:
+		return LoadHar(data)
+	}
+	return nil, fmt.Errorf("%s is neither a test-proxy recording nor a HAR file", filepath)
+}
+
+type testProxyRecording struct {
+	Entries []struct {
+		RequestUri      string                 `json:"RequestUri"`
+		RequestMethod   string                 `json:"RequestMethod"`
+		RequestHeaders  map[string]interface{} `json:"RequestHeaders"`
+		RequestBody     interface{}            `json:"RequestBody"`
+		StatusCode      int                    `json:"StatusCode"`
+		ResponseHeaders map[string]interface{} `json:"ResponseHeaders"`
+		ResponseBody    interface{}            `json:"ResponseBody"`
+	} `json:"Entries"`
+}
+
+// LoadTestProxyRecording loads the request traces from an Azure SDK test-proxy recording
+func LoadTestProxyRecording(data []byte) ([]types.RequestTrace, error) {
+	var recording testProxyRecording
+	if err := json.Unmarshal(data, &recording); err != nil {
+		return nil, err
+	}
+	out := make([]types.RequestTrace, 0)
+	for _, entry := range recording.Entries {
+		out = append(out, Sanitize(types.RequestTrace{
+			Url:        entry.RequestUri,
+			Method:     strings.ToUpper(entry.RequestMethod),
+			StatusCode: entry.StatusCode,
+			Request: &types.HttpRequest{
+				Headers: recordingHeaders(entry.RequestHeaders),
+				Body:    recordingBody(entry.RequestBody),
+			},
+			Response: &types.HttpResponse{
+				Headers: recordingHeaders(entry.ResponseHeaders),
+				Body:    recordingBody(entry.ResponseBody),
+			},
+		}))
+	}
+	return out, nil
+}
+
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			StartedDateTime time.Time `json:"startedDateTime"`
+			Request         struct {
+				Method   string      `json:"method"`
+				Url      string      `json:"url"`
+				Headers  []harHeader `json:"headers"`
+				PostData *struct {
+					Text string `json:"text"`
+				} `json:"postData"`
+			} `json:"request"`
+			Response struct {
+				Status  int         `json:"status"`
+				Headers []harHeader `json:"headers"`
+				Content struct {
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// LoadHar loads the request traces from a HAR file, e.g., the network log exported from the browser or fiddler
+func LoadHar(data []byte) ([]types.RequestTrace, error) {
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, err
+	}
+	out := make([]types.RequestTrace, 0)
+	for _, entry := range har.Log.Entries {
+		requestBody := ""
+		if entry.Request.PostData != nil {
+			requestBody = entry.Request.PostData.Text
+		}
+		responseBody := entry.Response.Content.Text
+		if entry.Response.Content.Encoding == "base64" {
+			if decoded, err := base64.StdEncoding.DecodeString(responseBody); err == nil {
+				responseBody = string(decoded)
+			}
+		}
+		out = append(out, Sanitize(types.RequestTrace{
+			Url:        entry.Request.Url,
+			Method:     strings.ToUpper(entry.Request.Method),
+			StatusCode: entry.Response.Status,
+			TimeStamp:  entry.StartedDateTime,
+			Request: &types.HttpRequest{
+				Headers: harHeaders(entry.Request.Headers),
+				Body:    requestBody,
+			},
+			Response: &types.HttpResponse{
+				Headers: harHeaders(entry.Response.Headers),
+				Body:    responseBody,
+			},
+		}))
+	}
+	return out, nil
+}
+
+// Sanitize normalizes the trace, the url only contains the path and query, the sanitized host is replaced with the ARM endpoint of the selected cloud,
+// and the headers which contain credentials are removed.
+func Sanitize(trace types.RequestTrace) types.RequestTrace {
+	if u, err := url.Parse(trace.Url); err == nil {
+		if u.Host == "" || isSanitized(u.Hostname()) {
+			u.Host = SanitizedHost()
+		}
+		// keep the same format as the traces collected from the terraform logs, the host is stored separately
+		trace.Url = u.RequestURI()
+		trace.Host = u.Host
+	}
+	if trace.Method == "" {
+		trace.Method = http.MethodGet
+	}
+	if trace.Request != nil {
+		trace.Request.Headers = sanitizeHeaders(trace.Request.Headers)
+	}
+	if trace.Response != nil {
+		trace.Response.Headers = sanitizeHeaders(trace.Response.Headers)
+	}
+	return trace
+}
+
+func isSanitized(input string) bool {
+	input = strings.ToLower(input)
+	for _, value := range sanitizedValues {
+		if strings.Contains(input, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func sanitizeHeaders(headers map[string]string) map[string]string {
+	out := make(map[string]string)
+	for key, value := range headers {
+		removed := false
+		for _, header := range removedHeaders {
+			if strings.EqualFold(key, header) {
+				removed = true
+				break
+			}
+		}
+		if !removed {
+			out[key] = value
+		}
+	}
+	return out
+}
+
+// recordingHeaders converts the recording headers, the value could be a string or an array of strings
+func recordingHeaders(input map[string]interface{}) map[string]string {
+	out := make(map[string]string)
+	for key, value := range input {
+		switch v := value.(type) {
+		case string:
+			out[key] = v
+		case []interface{}:
+			values := make([]string, 0)
+			for _, item := range v {
+				values = append(values, fmt.Sprintf("%v", item))
+			}
+			out[key] = strings.Join(values, ", ")
+		default:
+			out[key] = fmt.Sprintf("%v", v)
+		}
+	}
+	return out
+}
+
+// recordingBody converts the recording body, the body could be a JSON value or a string which contains the JSON payload
+func recordingBody(input interface{}) string {
+	switch v := input.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	data, err := json.Marshal(input)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func harHeaders(input []harHeader) map[string]string {
+	out := make(map[string]string)
+	for _, header := range input {
+		if existing, ok := out[header.Name]; ok {
+			out[header.Name] = existing + ", " + header.Value
+			continue
+		}
+		out[header.Name] = header.Value
+	}
+	return out
+}
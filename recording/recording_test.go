@@ -0,0 +1,134 @@
+package recording_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/recording"
+	"github.com/ms-henglu/pal/types"
+)
+
+func Test_LoadTestProxyRecording(t *testing.T) {
+	traces, err := recording.LoadFile("testdata/recording.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 {
+		t.Fatalf("expected 2 traces, got %d", len(traces))
+	}
+
+	put := traces[0]
+	if put.Method != http.MethodPut || put.StatusCode != 201 {
+		t.Errorf("unexpected method or status code: %s %d", put.Method, put.StatusCode)
+	}
+	expectedUrl := "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa?api-version=2022-08-08"
+	if put.Url != expectedUrl {
+		t.Errorf("expected url %s, got %s", expectedUrl, put.Url)
+	}
+	if put.Host != recording.SanitizedHost() {
+		t.Errorf("expected host %s, got %s", recording.SanitizedHost(), put.Host)
+	}
+	if _, ok := put.Request.Headers["Authorization"]; ok {
+		t.Errorf("expected the authorization header to be removed")
+	}
+	if _, ok := put.Response.Headers["Set-Cookie"]; ok {
+		t.Errorf("expected the set-cookie header to be removed")
+	}
+	if v := put.Request.Headers["Accept"]; v != "application/json" {
+		t.Errorf("expected accept header application/json, got %s", v)
+	}
+	if expected := `{"location":"westus","properties":{"sku":{"name":"Basic"}}}`; put.Request.Body != expected {
+		t.Errorf("expected request body %s, got %s", expected, put.Request.Body)
+	}
+
+	del := traces[1]
+	if del.Request.Body != "" {
+		t.Errorf("expected empty request body, got %s", del.Request.Body)
+	}
+	if expected := `{"status":"Succeeded"}`; del.Response.Body != expected {
+		t.Errorf("expected response body %s, got %s", expected, del.Response.Body)
+	}
+}
+
+func Test_LoadHar(t *testing.T) {
+	traces, err := recording.LoadFile("testdata/network.har")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 1 {
+		t.Fatalf("expected 1 trace, got %d", len(traces))
+	}
+
+	trace := traces[0]
+	if trace.Method != http.MethodPost || trace.StatusCode != 200 {
+		t.Errorf("unexpected method or status code: %s %d", trace.Method, trace.StatusCode)
+	}
+	if _, ok := trace.Request.Headers["Authorization"]; ok {
+		t.Errorf("expected the authorization header to be removed")
+	}
+	if trace.Request.Body != "{}" {
+		t.Errorf("expected request body {}, got %s", trace.Request.Body)
+	}
+	if expected := `{"keys":[]}`; trace.Response.Body != expected {
+		t.Errorf("expected response body %s, got %s", expected, trace.Response.Body)
+	}
+	if trace.TimeStamp.IsZero() {
+		t.Errorf("expected the timestamp to be loaded")
+	}
+}
+
+func Test_LoadFileUnknownFormat(t *testing.T) {
+	if _, err := recording.LoadFile("testdata/unknown.json"); err == nil {
+		t.Errorf("expected an error for the unknown format")
+	}
+}
+
+func Test_Sanitize(t *testing.T) {
+	testcases := []struct {
+		Url          string
+		ExpectedUrl  string
+		ExpectedHost string
+	}{
+		{
+			Url:          "https://management.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test?api-version=2021-04-01",
+			ExpectedUrl:  "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test?api-version=2021-04-01",
+			ExpectedHost: "management.azure.com",
+		},
+		{
+			Url:          "https://Sanitized.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/providers",
+			ExpectedUrl:  "/subscriptions/00000000-0000-0000-0000-000000000000/providers",
+			ExpectedHost: recording.SanitizedHost(),
+		},
+		{
+			Url:          "https://management.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Web/sites/test/hostNameBindings/foo%2Fbar?api-version=2022-03-01",
+			ExpectedUrl:  "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/test/providers/Microsoft.Web/sites/test/hostNameBindings/foo%2Fbar?api-version=2022-03-01",
+			ExpectedHost: "management.azure.com",
+		},
+	}
+	for _, testcase := range testcases {
+		trace := recording.Sanitize(types.RequestTrace{Url: testcase.Url})
+		if trace.Url != testcase.ExpectedUrl {
+			t.Errorf("expected url %s, got %s", testcase.ExpectedUrl, trace.Url)
+		}
+		if trace.Host != testcase.ExpectedHost {
+			t.Errorf("expected host %s, got %s", testcase.ExpectedHost, trace.Host)
+		}
+		if trace.Method != http.MethodGet {
+			t.Errorf("expected method %s, got %s", http.MethodGet, trace.Method)
+		}
+	}
+}
+
+func Test_SanitizeSovereignCloud(t *testing.T) {
+	if err := cloud.Set(cloud.China); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		_ = cloud.Set(cloud.Public)
+	}()
+	trace := recording.Sanitize(types.RequestTrace{Url: "https://Sanitized.azure.com/subscriptions/00000000-0000-0000-0000-000000000000/providers"})
+	if trace.Host != "management.chinacloudapi.cn" {
+		t.Errorf("expected the host of the china cloud, got %s", trace.Host)
+	}
+}
@@ -0,0 +1,368 @@
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/credscan"
+	paltypes "github.com/ms-henglu/pal/types"
+	"github.com/sirupsen/logrus"
+)
+
+// RedactedValue is used to replace the secrets in the traces and reports
+const RedactedValue = "REDACTED"
+
+// the secrets shorter than this are only redacted in the properties where they're found, the short values are likely to appear in other places
+const minSecretLength = 8
+
+// the headers whose values are redacted, they contain credentials
+var deniedHeaders = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"x-ms-authorization-auxiliary",
+	"ocp-apim-subscription-key",
+	"x-functions-key",
+	"x-api-key",
+}
+
+var guidRegex = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+
+// Redactor redacts the secrets and identifiers in the traces and reports before they're written, the secrets are detected by:
+// 1. The `x-ms-secret` properties in the request models, if the swagger is specified.
+// 2. The headers which contain credentials, e.g., Authorization.
+// 3. The property names and values which look like secrets, e.g., the keys returned by listKeys.
+// 4. The user specified regular expressions.
+// The GUIDs like subscription ids and tenant ids are replaced consistently, so the traces still correlate with each other.
+// The values of the properties which are detected only by the property names are redacted only as the values of the same properties,
+// because they could be ordinary words which appear in other places. The Redactor is safe for concurrent use.
+type Redactor struct {
+	swaggerPath string
+	patterns    []*regexp.Regexp
+
+	mutex sync.Mutex
+	// the secret values which are replaced in any content
+	secrets map[string]bool
+	// secretsReplacer replaces the secrets, it's rebuilt when it's nil, i.e., new secrets are collected
+	secretsReplacer *strings.Replacer
+	// the secret values which are only replaced as the values of the properties, keyed by the property names
+	properties map[string]map[string]bool
+	// propertyRegexes match the values of the properties, keyed by the property names, they're rebuilt when new values are collected
+	propertyRegexes map[string]*regexp.Regexp
+	// the GUIDs and their replacements
+	guids        map[string]string
+	replacements map[string]bool
+	models       map[string]*coverage.Model
+}
+
+// NewRedactor returns a redactor, the swagger path is optional, it's used to find the `x-ms-secret` properties in the request bodies
+func NewRedactor(swaggerPath string, patterns []string) (*Redactor, error) {
+	r := &Redactor{
+		swaggerPath:     swaggerPath,
+		patterns:        make([]*regexp.Regexp, 0),
+		secrets:         make(map[string]bool),
+		properties:      make(map[string]map[string]bool),
+		propertyRegexes: make(map[string]*regexp.Regexp),
+		guids:           make(map[string]string),
+		replacements:    make(map[string]bool),
+		models:          make(map[string]*coverage.Model),
+	}
+	for _, pattern := range patterns {
+		regex, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redact pattern %q: %+v", pattern, err)
+		}
+		r.patterns = append(r.patterns, regex)
+	}
+	return r, nil
+}
+
+// Collect collects the secrets in the traces, so the secrets are redacted in the content rendered from the traces, e.g., the markdown reports
+func (r *Redactor) Collect(traces []paltypes.RequestTrace) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collect(traces)
+}
+
+func (r *Redactor) collect(traces []paltypes.RequestTrace) {
+	for _, trace := range traces {
+		if trace.Request != nil {
+			r.collectHeaders(trace.Request.Headers)
+			r.collectBody(trace.Request.Body)
+			for _, secret := range r.modelSecrets(trace) {
+				r.addSecret(secret)
+			}
+		}
+		if trace.Response != nil {
+			r.collectHeaders(trace.Response.Headers)
+			r.collectBody(trace.Response.Body)
+		}
+	}
+}
+
+// Traces returns the redacted copies of the traces, the secrets in all traces are collected first,
+// so a secret returned by one request is also redacted in the other requests.
+func (r *Redactor) Traces(traces []paltypes.RequestTrace) []paltypes.RequestTrace {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.collect(traces)
+	out := make([]paltypes.RequestTrace, 0)
+	for _, trace := range traces {
+		out = append(out, r.trace(trace))
+	}
+	return out
+}
+
+// String redacts the collected secrets, the user specified patterns, the known credential formats and the GUIDs in the content
+func (r *Redactor) String(input string) string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.string(input)
+}
+
+// Value returns the redacted copy of the decoded JSON value, the name is the property name of the value, it's used to detect the secret properties
+func (r *Redactor) Value(name string, input interface{}) interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.value(name, input)
+}
+
+func (r *Redactor) string(input string) string {
+	if r.secretsReplacer == nil {
+		secrets := make([]string, 0)
+		for secret := range r.secrets {
+			secrets = append(secrets, secret)
+		}
+		// replace the longer secrets first, in case a secret contains another one
+		sort.Slice(secrets, func(i, j int) bool {
+			return len(secrets[i]) > len(secrets[j])
+		})
+		pairs := make([]string, 0)
+		for _, secret := range secrets {
+			pairs = append(pairs, secret, RedactedValue)
+		}
+		r.secretsReplacer = strings.NewReplacer(pairs...)
+	}
+	input = r.secretsReplacer.Replace(input)
+	// the property secrets are only replaced in the JSON content, e.g., "adminPassword": "secret"
+	for name := range r.properties {
+		input = r.propertyRegex(name).ReplaceAllString(input, "${1}"+RedactedValue+`"`)
+	}
+	for _, pattern := range r.patterns {
+		input = pattern.ReplaceAllString(input, RedactedValue)
+	}
+	return guidRegex.ReplaceAllStringFunc(input, r.guid)
+}
+
+// propertyRegex returns the regular expression which matches the secret values of the property, it's compiled once for the collected values
+func (r *Redactor) propertyRegex(name string) *regexp.Regexp {
+	if regex, ok := r.propertyRegexes[name]; ok {
+		return regex
+	}
+	values := make([]string, 0)
+	for value := range r.properties[name] {
+		values = append(values, value)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for i := range values {
+		values[i] = regexp.QuoteMeta(jsonString(values[i]))
+	}
+	regex := regexp.MustCompile(`("` + regexp.QuoteMeta(jsonString(name)) + `"\s*:\s*")(?:` + strings.Join(values, "|") + `)"`)
+	r.propertyRegexes[name] = regex
+	return regex
+}
+
+func (r *Redactor) trace(trace paltypes.RequestTrace) paltypes.RequestTrace {
+	trace.Url = r.string(trace.Url)
+	if trace.Request != nil {
+		trace.Request = &paltypes.HttpRequest{
+			Headers: r.headers(trace.Request.Headers),
+			Body:    r.body(trace.Request.Body),
+		}
+	}
+	if trace.Response != nil {
+		trace.Response = &paltypes.HttpResponse{
+			Headers: r.headers(trace.Response.Headers),
+			Body:    r.body(trace.Response.Body),
+		}
+	}
+	return trace
+}
+
+func (r *Redactor) headers(input map[string]string) map[string]string {
+	out := make(map[string]string)
+	for key, value := range input {
+		if isDeniedHeader(key) {
+			out[key] = RedactedValue
+			continue
+		}
+		out[key] = r.string(value)
+	}
+	return out
+}
+
+func (r *Redactor) body(input string) string {
+	var body interface{}
+	if err := json.Unmarshal([]byte(input), &body); err != nil || body == nil {
+		return r.string(input)
+	}
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(r.value("", body)); err != nil {
+		return r.string(input)
+	}
+	return r.string(strings.TrimSuffix(buf.String(), "\n"))
+}
+
+// value replaces the values of the properties which look like secrets
+func (r *Redactor) value(name string, input interface{}) interface{} {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for key, value := range v {
+			out[key] = r.value(key, value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0)
+		for _, item := range v {
+			out = append(out, r.value(name, item))
+		}
+		return out
+	case string:
+		if v != "" && (credscan.IsSecretName(name) || credscan.SecretValueReason(v) != "" || r.secrets[v]) {
+			return RedactedValue
+		}
+		return r.string(v)
+	}
+	return input
+}
+
+func (r *Redactor) collectHeaders(headers map[string]string) {
+	for key, value := range headers {
+		if isDeniedHeader(key) {
+			r.addSecret(value)
+		}
+	}
+}
+
+func (r *Redactor) collectBody(input string) {
+	var body interface{}
+	if err := json.Unmarshal([]byte(input), &body); err != nil {
+		return
+	}
+	for _, finding := range credscan.Detect(body) {
+		if credscan.SecretValueReason(finding.Value) != "" {
+			r.addSecret(finding.Value)
+			continue
+		}
+		// the value is detected by the property name, it's only redacted as the value of the same property
+		name := finding.PropertyName[strings.LastIndex(finding.PropertyName, ".")+1:]
+		if index := strings.Index(name, "["); index != -1 {
+			name = name[:index]
+		}
+		if r.properties[name] == nil {
+			r.properties[name] = make(map[string]bool)
+		}
+		if !r.properties[name][finding.Value] {
+			r.properties[name][finding.Value] = true
+			delete(r.propertyRegexes, name)
+		}
+	}
+}
+
+// modelSecrets returns the values of the `x-ms-secret` properties in the request body
+func (r *Redactor) modelSecrets(trace paltypes.RequestTrace) []string {
+	if r.swaggerPath == "" || trace.Request == nil || trace.Request.Body == "" {
+		return nil
+	}
+	var body interface{}
+	if err := json.Unmarshal([]byte(trace.Request.Body), &body); err != nil {
+		return nil
+	}
+	requestUrl, err := url.Parse(trace.Url)
+	if err != nil {
+		return nil
+	}
+
+	key := fmt.Sprintf("%s-%s", trace.Method, requestUrl.Path)
+	model, ok := r.models[key]
+	if !ok {
+		swaggerModel, err := coverage.GetModelInfoFromLocalDir(requestUrl.Path, r.swaggerPath, trace.Method)
+		if err != nil {
+			logrus.Debugf("failed to get model info of %s: %+v", key, err)
+		}
+		if swaggerModel != nil && swaggerModel.ModelName != "" {
+			model, err = coverage.Expand(swaggerModel.ModelName, swaggerModel.SwaggerPath)
+			if err != nil {
+				logrus.Debugf("failed to expand model %s: %+v", swaggerModel.ModelName, err)
+			}
+		}
+		r.models[key] = model
+	}
+	if model == nil {
+		return nil
+	}
+
+	secrets := make(map[string]string)
+	model.CredScan(body, secrets)
+	out := make([]string, 0)
+	for _, value := range secrets {
+		out = append(out, value)
+	}
+	return out
+}
+
+func (r *Redactor) addSecret(value string) {
+	if len(value) >= minSecretLength && value != RedactedValue && !r.secrets[value] {
+		r.secrets[value] = true
+		r.secretsReplacer = nil
+	}
+}
+
+// guid returns the replacement of the GUID, the same GUID is always replaced with the same value
+func (r *Redactor) guid(input string) string {
+	key := strings.ToLower(input)
+	if key == "00000000-0000-0000-0000-000000000000" || r.replacements[key] {
+		return input
+	}
+	if v, ok := r.guids[key]; ok {
+		return v
+	}
+	v := fmt.Sprintf("00000000-0000-0000-0000-%012d", len(r.guids)+1)
+	r.guids[key] = v
+	r.replacements[v] = true
+	return v
+}
+
+// jsonString returns the string escaped in the JSON format without the quotes
+func jsonString(input string) string {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(input); err != nil {
+		return input
+	}
+	out := strings.TrimSuffix(buf.String(), "\n")
+	return out[1 : len(out)-1]
+}
+
+func isDeniedHeader(name string) bool {
+	for _, header := range deniedHeaders {
+		if strings.EqualFold(name, header) {
+			return true
+		}
+	}
+	return false
+}
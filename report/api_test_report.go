@@ -2,6 +2,7 @@ package report
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
@@ -11,7 +12,10 @@ import (
 	"strings"
 
 	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/types"
 	"github.com/azure/armstrong/utils"
+	"github.com/ms-henglu/pal/formatter"
+	paltypes "github.com/ms-henglu/pal/types"
 	"github.com/sirupsen/logrus"
 )
 
@@ -29,6 +33,15 @@ type ApiTestReport struct {
 	Errors                  []ErrorItem           `json:"errors"`
 }
 
+// ApiTestReports are the reports generated from the traces of the test cases
+type ApiTestReports struct {
+	// Accuracy is the swagger accuracy report without the suppressed errors
+	Accuracy ApiTestReport
+	// Coverage is the operation properties coverage report
+	Coverage   coverage.CoverageReport
+	Suppressed []types.SuppressedFinding
+}
+
 type UnCoveredOperations struct {
 	Spec         string   `json:"spec"`
 	OperationIds []string `json:"operationIds"`
@@ -47,25 +60,24 @@ type ApiTestConfig struct {
 	SuppressionList []Suppression `json:"suppressionList"`
 }
 
-type Suppression struct {
-	Code      string `json:"rule"`
-	File      string `json:"file"`
-	Operation string `json:"operation"`
-	Reason    string `json:"reason"`
-}
-
-func OavValidateTraffic(traceDir string, swaggerPath string, outputDir string) (*ApiTestReport, error) {
+// OavValidateTraffic validates the traces against the swagger by oav, the suppressed errors and uncovered operations are removed from the report
+func OavValidateTraffic(traceDir string, swaggerPath string, outputDir string, suppressions *Suppressions) (*ApiTestReport, error) {
 	htmlReportFilePath := path.Join(outputDir, fmt.Sprintf("%s.html", ApiTestReportFileName))
 	jsonReportFilePath := path.Join(outputDir, fmt.Sprintf("%s.json", ApiTestReportFileName))
 
 	logrus.Debugf("oav validate-traffic %s %s --report %s --jsonReport %s", traceDir, swaggerPath, htmlReportFilePath, jsonReportFilePath)
 	cmd := exec.Command("oav", "validate-traffic", traceDir, swaggerPath, "--report", htmlReportFilePath, "--jsonReport", jsonReportFilePath)
-	if err := cmd.Run(); err != nil {
-		logrus.Warnf("oav validates-traffic: %+v", err)
+	// oav exits with non-zero code when the traces don't match the swagger, so its error is only returned when the report is not generated
+	oavErr := cmd.Run()
+	if oavErr != nil {
+		logrus.Warnf("oav validates-traffic: %+v", oavErr)
 	}
 
 	contentBytes, err := os.ReadFile(jsonReportFilePath)
 	if err != nil {
+		if oavErr != nil {
+			return nil, fmt.Errorf("oav validate-traffic: %+v, error when opening file(%s): %+v", oavErr, jsonReportFilePath, err)
+		}
 		return nil, fmt.Errorf("error when opening file(%s): %+v", jsonReportFilePath, err)
 	}
 
@@ -87,34 +99,124 @@ func OavValidateTraffic(traceDir string, swaggerPath string, outputDir string) (
 
 	errors := make([]ErrorItem, 0)
 	for _, v := range errorMap {
+		if suppressions.Suppress(AccuracyReportName, Finding{Rule: v.ErrorCode, File: v.Spec, Operation: v.OperationId, Message: v.ErrorMessage}) {
+			continue
+		}
 		errors = append(errors, v)
 	}
 
 	payload.Errors = errors
 
+	unCoveredOperationsList := make([]UnCoveredOperations, 0)
+	for _, operationsItem := range payload.UnCoveredOperationsList {
+		operationIds := make([]string, 0)
+		for _, id := range operationsItem.OperationIds {
+			if suppressions.Suppress(AccuracyReportName, Finding{Rule: "OPERATION_NOT_TEST", File: operationsItem.Spec, Operation: id, Message: fmt.Sprintf("%s operation is not test", id)}) {
+				continue
+			}
+			operationIds = append(operationIds, id)
+		}
+		if len(operationIds) != 0 {
+			unCoveredOperationsList = append(unCoveredOperationsList, UnCoveredOperations{Spec: operationsItem.Spec, OperationIds: operationIds})
+		}
+	}
+	payload.UnCoveredOperationsList = unCoveredOperationsList
+
+	sarifReportFilePath := path.Join(outputDir, fmt.Sprintf("%s.sarif", ApiTestReportFileName))
+	sarifContent, err := ApiTestSarifReport(*payload).MarshalIndent()
+	if err != nil {
+		logrus.Warnf("failed to marshal sarif report: %+v", err)
+	} else if err := os.WriteFile(sarifReportFilePath, sarifContent, 0644); err != nil {
+		logrus.Warnf("error when writing file(%s): %+v", sarifReportFilePath, err)
+	}
+
 	return payload, nil
 }
 
-func GenerateApiTestReports(wd string, swaggerPath string) error {
+// Logger receives the progress messages of the reports, both *logrus.Logger and *logrus.Entry implement it
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// StoreSwaggerReports generates the swagger accuracy report and the operation properties coverage report from the traces, the suppressed findings are not reported.
+// It returns the swagger accuracy report, and the error if either report fails to be generated, the coverage report is still generated if the accuracy report fails.
+func StoreSwaggerReports(traceDir string, swaggerPath string, reportDir string, suppressions *Suppressions, logger Logger) (*ApiTestReport, error) {
+	logger.Infof("generating swagger accuracy report...")
+	accuracy, accuracyErr := OavValidateTraffic(traceDir, swaggerPath, reportDir, suppressions)
+	if accuracyErr != nil {
+		accuracyErr = fmt.Errorf("generating swagger accuracy report: %+v", accuracyErr)
+		logger.Errorf("%+v", accuracyErr)
+	}
+
+	logger.Infof("generating operation properties coverage report...")
+	covReport, err := coverage.NewOperationPropertiesCoverageReport(traceDir, swaggerPath)
+	if err != nil {
+		err = fmt.Errorf("generating operation properties coverage report: %+v", err)
+		logger.Errorf("%+v", err)
+		return accuracy, errors.Join(accuracyErr, err)
+	}
+	suppressions.SuppressCoverage(covReport, true)
+	reportContent := covReport.MarkdownContent()
+	outputPath := path.Join(reportDir, CoverageReportFileName)
+	if err := os.WriteFile(outputPath, []byte(reportContent), 0644); err != nil {
+		logger.Warnf("failed to save operation properties coverage report to %s: %+v", CoverageReportFileName, err)
+	} else {
+		logger.Infof("operation properties coverage report saved to %s", CoverageReportFileName)
+	}
+	return accuracy, accuracyErr
+}
+
+// StoreOavTraffic writes the traces in the oav traffic format, it returns the written file names in the same order as the traces
+func StoreOavTraffic(traces []paltypes.RequestTrace, output string) []string {
+	written := make([]string, 0)
+	format := formatter.OavTrafficFormatter{}
+	files, err := os.ReadDir(output)
+	if err != nil {
+		logrus.Warnf("failed to read trace output directory: %v", err)
+	}
+	index := len(files)
+	for _, t := range traces {
+		out := format.Format(t)
+		index = index + 1
+		outputPath := path.Join(output, fmt.Sprintf("trace-%d.json", index))
+		if err := os.WriteFile(outputPath, []byte(out), 0644); err != nil {
+			logrus.Warnf("failed to write file: %v", err)
+			written = append(written, "")
+		} else {
+			logrus.Debugf("trace saved to %s", outputPath)
+			written = append(written, path.Base(outputPath))
+		}
+	}
+	return written
+}
+
+// GenerateApiTestReports generates the swagger accuracy reports of the test cases in the working directory, the suppressions are merged with the ones in ApiTestConfig.json
+func GenerateApiTestReports(wd string, swaggerPath string, suppressions []Suppression) (*ApiTestReports, error) {
 	testReportPath := path.Join(wd, TestReportDirName)
 	traceLogPath := path.Join(testReportPath, TraceLogDirName)
 	swaggerPath, _ = filepath.Abs(swaggerPath)
 
 	logrus.Infof("copying trace files to %s...", traceLogPath)
 	if err := mergeApiTestTraceFiles(wd, traceLogPath); err != nil {
-		return fmt.Errorf("[ERROR] failed to merge trace files: %+v", err)
+		return nil, fmt.Errorf("[ERROR] failed to merge trace files: %+v", err)
 	}
 
+	matcher := NewSuppressions(LoadSuppressions(wd, suppressions))
+
 	logrus.Infof("validating traces...")
-	report, err := OavValidateTraffic(traceLogPath, swaggerPath, testReportPath)
+	report, err := OavValidateTraffic(traceLogPath, swaggerPath, testReportPath, matcher)
 	if err != nil {
-		return fmt.Errorf("[ERROR] failed to retrieve oav report: %+v", err)
+		return nil, fmt.Errorf("[ERROR] failed to retrieve oav report: %+v", err)
 	}
 
 	opCovReport, err := coverage.NewOperationPropertiesCoverageReport(traceLogPath, swaggerPath)
 	if err != nil {
 		logrus.Warnf("[ERROR] failed to generate operation properties coverage report: %+v", err)
+		opCovReport = &coverage.CoverageReport{}
 	}
+	matcher.SuppressCoverage(opCovReport, true)
 
 	logrus.Infof("generating markdown report...")
 
@@ -125,11 +227,15 @@ func GenerateApiTestReports(wd string, swaggerPath string) error {
 		logrus.Infof("markdown report saved to %s", path.Join(testReportPath, CoverageReportFileName))
 	}
 
-	if err = generateApiTestMarkdownReport(*report, *opCovReport, swaggerPath, testReportPath, path.Join(wd, ApiTestConfigFileName)); err != nil {
-		return fmt.Errorf("[ERROR] failed to generate markdown report: %+v", err)
+	if err = generateApiTestMarkdownReport(*report, *opCovReport, swaggerPath, testReportPath, matcher); err != nil {
+		return nil, fmt.Errorf("[ERROR] failed to generate markdown report: %+v", err)
 	}
 
-	return nil
+	return &ApiTestReports{
+		Accuracy:   *report,
+		Coverage:   *opCovReport,
+		Suppressed: matcher.Findings(),
+	}, nil
 }
 
 func mergeApiTestTraceFiles(wd string, traceLogPath string) error {
@@ -161,36 +267,8 @@ func mergeApiTestTraceFiles(wd string, traceLogPath string) error {
 	return nil
 }
 
-func isSuppressedInApiTest(suppressionList []Suppression, rule string, filePath string, operation string) bool {
-	segments := strings.Split(filepath.ToSlash(filePath), "/")
-	file := segments[len(segments)-1]
-
-	for _, suppression := range suppressionList {
-		if strings.EqualFold(suppression.Code, rule) && strings.EqualFold(suppression.File, file) && (strings.EqualFold(suppression.Code, "SWAGGER_NOT_TEST") || strings.EqualFold(suppression.Operation, operation)) {
-			return true
-		}
-	}
-
-	return false
-}
-
-func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.CoverageReport, swaggerPath string, testReportPath string, apiTestConfigFilePath string) error {
-	var config ApiTestConfig
-
-	if utils.Exists(apiTestConfigFilePath) {
-		contentBytes, err := os.ReadFile(apiTestConfigFilePath)
-		if err != nil {
-			logrus.Errorf("error when opening file(%s): %+v", apiTestConfigFilePath, err)
-		}
-
-		err = json.Unmarshal(contentBytes, &config)
-		if err != nil {
-			logrus.Errorf("error during Unmarshal() for file(%s): %+v", apiTestConfigFilePath, err)
-		}
-	} else {
-		logrus.Debugf("no config file found")
-	}
-
+// generateApiTestMarkdownReport writes the markdown report of the accuracy errors which are not suppressed, the suppressed findings are listed in the appendix
+func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.CoverageReport, swaggerPath string, testReportPath string, suppressions *Suppressions) error {
 	mdTitle := "## API TEST ERROR REPORT<br>\n|Rule|Message|\n|---|---|"
 	mdTable := make([]string, 0)
 
@@ -208,7 +286,7 @@ func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.Co
 	for _, v := range swaggerFiles {
 		v = strings.ReplaceAll(v, "\\", "/")
 		if _, exists := testedMap[v]; !exists {
-			if !isSuppressedInApiTest(config.SuppressionList, "SWAGGER_NOT_TEST", v, "") {
+			if !suppressions.Suppress(AccuracyReportName, Finding{Rule: "SWAGGER_NOT_TEST", File: v, Message: "No operations in swagger is test"}) {
 				mdTable = append(mdTable, fmt.Sprintf("|[SWAGGER_NOT_TEST](about:blank)|**message**: No operations in swagger is test.<br>**location**: %s", v[strings.Index(v, "/specification/"):]))
 			}
 		}
@@ -216,9 +294,7 @@ func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.Co
 
 	for _, operationsItem := range result.UnCoveredOperationsList {
 		for _, id := range operationsItem.OperationIds {
-			if !isSuppressedInApiTest(config.SuppressionList, "OPERATION_NOT_TEST", operationsItem.Spec, id) {
-				mdTable = append(mdTable, fmt.Sprintf("|[OPERATION_NOT_TEST](about:blank)|**message**: **%s** opeartion is not test.<br>**opeartion**: %s<br>**location**: %s", id, id, operationsItem.Spec[strings.Index(operationsItem.Spec, "/specification/"):]))
-			}
+			mdTable = append(mdTable, fmt.Sprintf("|[OPERATION_NOT_TEST](about:blank)|**message**: **%s** opeartion is not test.<br>**opeartion**: %s<br>**location**: %s", id, id, operationsItem.Spec[strings.Index(operationsItem.Spec, "/specification/"):]))
 		}
 	}
 
@@ -229,9 +305,7 @@ func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.Co
 			location = normalizedPath[subIndex:]
 		}
 
-		if !isSuppressedInApiTest(config.SuppressionList, errItem.ErrorCode, errItem.Spec, errItem.OperationId) {
-			mdTable = append(mdTable, fmt.Sprintf("|[%s](%s)|**message**: %s.<br>**opeartion**: %s<br>**location**: %s", errItem.ErrorCode, errItem.ErrorLink, errItem.ErrorMessage, errItem.OperationId, location))
-		}
+		mdTable = append(mdTable, fmt.Sprintf("|[%s](%s)|**message**: %s.<br>**opeartion**: %s<br>**location**: %s", errItem.ErrorCode, errItem.ErrorLink, errItem.ErrorMessage, errItem.OperationId, location))
 	}
 
 	sort.Strings(mdTable)
@@ -240,6 +314,8 @@ func generateApiTestMarkdownReport(result ApiTestReport, opCovReport coverage.Co
 
 	mdContent += opCovReport.MarkdownContentCompact()
 
+	mdContent += "\n## Suppressed Findings\n" + SuppressedFindingsMarkdown(suppressions.Findings()) + "\n"
+
 	mdReportFilePath := path.Join(testReportPath, fmt.Sprintf("%s.md", ApiTestReportFileName))
 	if err := os.WriteFile(mdReportFilePath, []byte(mdContent), 0644); err != nil {
 		return fmt.Errorf("error when writing file(%s): %+v", mdReportFilePath, err)
@@ -0,0 +1,29 @@
+package report_test
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/report"
+	"github.com/sirupsen/logrus"
+)
+
+func Test_StoreSwaggerReports_Error(t *testing.T) {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+
+	reportDir := t.TempDir()
+	accuracy, err := report.StoreSwaggerReports(t.TempDir(), filepath.Join(reportDir, "notfound.json"), reportDir, report.NewSuppressions(nil), logger)
+	if err == nil || accuracy != nil {
+		t.Fatalf("expect the error of the swagger accuracy report, but got %+v, %+v", accuracy, err)
+	}
+	if !strings.Contains(err.Error(), "generating swagger accuracy report") {
+		t.Errorf("expect the error of the swagger accuracy report, but got %+v", err)
+	}
+	if !strings.Contains(out.String(), "generating swagger accuracy report...") {
+		t.Errorf("expect the progress messages in the given logger, but got %s", out.String())
+	}
+}
@@ -0,0 +1,79 @@
+package report
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/azure/armstrong/sarif"
+	"github.com/azure/armstrong/utils"
+)
+
+// matches the line position in the schema path, e.g., compute.json#L9065:10 and compute.json#L9065-L9101
+var linePositionRegex = regexp.MustCompile(`^L(\d+)(?::(\d+))?`)
+
+// ApiTestSarifReport converts the swagger accuracy errors to a SARIF log, each error is located at the swagger file and line which defines the schema
+func ApiTestSarifReport(result ApiTestReport) sarif.Log {
+	rules := make([]sarif.Rule, 0)
+	ruleMap := make(map[string]bool)
+	results := make([]sarif.Result, 0)
+	for _, errItem := range result.Errors {
+		if !ruleMap[errItem.ErrorCode] {
+			ruleMap[errItem.ErrorCode] = true
+			rules = append(rules, sarif.Rule{
+				Id:      errItem.ErrorCode,
+				HelpUri: errItem.ErrorLink,
+			})
+		}
+
+		file, line, column := SchemaLocation(errItem.SchemaPathWithPosition, errItem.Spec)
+		message := errItem.ErrorMessage
+		if errItem.OperationId != "" {
+			message = fmt.Sprintf("%s, operation: %s", message, errItem.OperationId)
+		}
+		r := sarif.Result{
+			RuleId:  errItem.ErrorCode,
+			Level:   sarif.LevelError,
+			Message: sarif.Message{Text: message},
+		}
+		if file != "" {
+			r.Locations = []sarif.Location{sarif.NewLocation(file, line, column)}
+		}
+		results = append(results, r)
+	}
+	return sarif.NewLog(rules, results)
+}
+
+// SchemaLocation returns the swagger file, line and column of the schema path reported by oav, the schema path could be:
+// 1. A file with line position, e.g., /specification/compute/.../compute.json#L9065:10
+// 2. A file with JSON pointer, e.g., /specification/compute/.../compute.json#/definitions/VirtualMachine
+// 3. A github url of the file, e.g., https://github.com/Azure/azure-rest-api-specs/blob/main/specification/compute/.../compute.json#L9065
+// The spec is the local swagger file of the operation, it's used when the schema path is not a local file.
+func SchemaLocation(schemaPathWithPosition string, spec string) (string, int, int) {
+	file, position, _ := strings.Cut(schemaPathWithPosition, "#")
+	if file == "" {
+		file = spec
+	}
+	if !utils.Exists(file) && spec != "" {
+		normalizedFile := filepath.ToSlash(file)
+		if index := strings.Index(normalizedFile, "specification/"); index != -1 && strings.HasSuffix(filepath.ToSlash(spec), normalizedFile[index:]) {
+			file = spec
+		}
+	}
+
+	if matches := linePositionRegex.FindStringSubmatch(position); matches != nil {
+		line, _ := strconv.Atoi(matches[1])
+		column, _ := strconv.Atoi(matches[2])
+		return file, line, column
+	}
+
+	if strings.HasPrefix(position, "/") {
+		if data, err := os.ReadFile(file); err == nil {
+			return file, utils.JsonPointerLine(data, position), 0
+		}
+	}
+	return file, 0, 0
+}
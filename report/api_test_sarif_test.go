@@ -0,0 +1,55 @@
+package report_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/armstrong/report"
+)
+
+func Test_SchemaLocation(t *testing.T) {
+	spec, _ := filepath.Abs(filepath.Join("testdata", "swagger.json"))
+	testcases := []struct {
+		SchemaPath string
+		Spec       string
+		File       string
+		Line       int
+		Column     int
+	}{
+		{
+			SchemaPath: spec + "#L4:5",
+			File:       spec,
+			Line:       4,
+			Column:     5,
+		},
+		{
+			SchemaPath: spec + "#L6-L8",
+			File:       spec,
+			Line:       6,
+		},
+		{
+			SchemaPath: spec + "#/definitions/Account/properties/name",
+			File:       spec,
+			Line:       6,
+		},
+		{
+			SchemaPath: "https://github.com/Azure/azure-rest-api-specs/blob/main/specification/report/testdata/swagger.json#L4",
+			Spec:       "/root/specification/report/testdata/swagger.json",
+			File:       "/root/specification/report/testdata/swagger.json",
+			Line:       4,
+		},
+		{
+			SchemaPath: "",
+			Spec:       spec,
+			File:       spec,
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("testcase: %s", testcase.SchemaPath)
+		file, line, column := report.SchemaLocation(testcase.SchemaPath, testcase.Spec)
+		if file != testcase.File || line != testcase.Line || column != testcase.Column {
+			t.Errorf("expect %s:%d:%d, but got %s:%d:%d", testcase.File, testcase.Line, testcase.Column, file, line, column)
+		}
+	}
+}
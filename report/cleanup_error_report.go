@@ -11,13 +11,16 @@ import (
 //go:embed cleanup_error_report.md
 var cleanupErrorReportTemplate string
 
-func CleanupErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace) string {
+func CleanupErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace, terraformVersion string) string {
 	parts := strings.Split(report.Type, "@")
 	resourceType := ""
 	apiVersion := ""
 	if len(parts) == 2 {
 		resourceType = parts[0]
 		apiVersion = parts[1]
+	} else {
+		// the resources other than azapi are reported with their terraform resource types
+		resourceType = report.Type
 	}
 	requestTraces := CleanupAllRequestTracesContent(report.Id, logs)
 	content := cleanupErrorReportTemplate
@@ -25,6 +28,11 @@ func CleanupErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace
 	content = strings.ReplaceAll(content, "${api_version}", apiVersion)
 	content = strings.ReplaceAll(content, "${request_traces}", requestTraces)
 	content = strings.ReplaceAll(content, "${error_message}", report.Message)
+	content = strings.ReplaceAll(content, "${terraform_version}", terraformVersion)
+	content = strings.ReplaceAll(content, "${operation_id}", detailOrTodo(report.OperationId, "VirtualMachines_Get"))
+	content = strings.ReplaceAll(content, "${swagger_permalink}", detailOrTodo(report.Permalink, permalinkExample))
+	content = strings.ReplaceAll(content, "${error_code}", detailOrTodo(report.Code, ""))
+	content = strings.ReplaceAll(content, "${known_fixes}", KnownFixesMarkdown(report.Fixes))
 	return content
 }
 
@@ -21,6 +21,7 @@ func CleanupMarkdownReport(passReport types.PassReport) string {
 
 	content := cleanupReportTemplate
 	content = strings.ReplaceAll(content, "${resource_type}", strings.Join(resourceTypes, "\n"))
+	content = strings.ReplaceAll(content, "${terraform_version}", passReport.TerraformVersion)
 
 	return content
 }
@@ -20,7 +20,7 @@ func DiffMarkdownReport(report types.Diff, logs []paltypes.RequestTrace) string
 		apiVersion = parts[1]
 	}
 
-	operationId := "TODO\ne.g., VirtualMachines_Get"
+	operationId := detailOrTodo(report.OperationId, "VirtualMachines_Get")
 
 	diffDescription := DiffMessageDescription(report.Change)
 	diffJson := DiffMessageMarkdown(report.Change)
@@ -39,6 +39,7 @@ func DiffMarkdownReport(report types.Diff, logs []paltypes.RequestTrace) string
 	content = strings.ReplaceAll(content, "${resource_type}", resourceType)
 	content = strings.ReplaceAll(content, "${api_version}", apiVersion)
 	content = strings.ReplaceAll(content, "${operation_id}", operationId)
+	content = strings.ReplaceAll(content, "${swagger_permalink}", detailOrTodo(report.Permalink, permalinkExample))
 	content = strings.ReplaceAll(content, "${error_code_in_title}", strings.Join(errCodes, " && "))
 	content = strings.ReplaceAll(content, "${error_code_in_block}", strings.Join(errCodes, "\n"))
 	content = strings.ReplaceAll(content, "${request_traces}", requestTraces)
@@ -11,13 +11,16 @@ import (
 //go:embed error_report.md
 var errorReportTemplate string
 
-func ErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace) string {
+func ErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace, terraformVersion string) string {
 	parts := strings.Split(report.Type, "@")
 	resourceType := ""
 	apiVersion := ""
 	if len(parts) == 2 {
 		resourceType = parts[0]
 		apiVersion = parts[1]
+	} else {
+		// the resources other than azapi are reported with their terraform resource types
+		resourceType = report.Type
 	}
 	requestTraces := AllRequestTracesContent(report.Id, logs)
 	content := errorReportTemplate
@@ -25,6 +28,11 @@ func ErrorMarkdownReport(report types.Error, logs []paltypes.RequestTrace) strin
 	content = strings.ReplaceAll(content, "${api_version}", apiVersion)
 	content = strings.ReplaceAll(content, "${request_traces}", requestTraces)
 	content = strings.ReplaceAll(content, "${error_message}", report.Message)
+	content = strings.ReplaceAll(content, "${terraform_version}", terraformVersion)
+	content = strings.ReplaceAll(content, "${operation_id}", detailOrTodo(report.OperationId, "VirtualMachines_Get"))
+	content = strings.ReplaceAll(content, "${swagger_permalink}", detailOrTodo(report.Permalink, permalinkExample))
+	content = strings.ReplaceAll(content, "${error_code}", detailOrTodo(report.Code, ""))
+	content = strings.ReplaceAll(content, "${known_fixes}", KnownFixesMarkdown(report.Fixes))
 	return content
 }
 
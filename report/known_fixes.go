@@ -0,0 +1,26 @@
+package report
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/azure/armstrong/types"
+)
+
+// KnownFixesMarkdown returns the "Likely cause and fix" section of the error report, it returns empty string if there's no known fix
+func KnownFixesMarkdown(fixes []types.KnownFix) string {
+	if len(fixes) == 0 {
+		return ""
+	}
+	content := "### Likely cause and fix\n\n"
+	for _, fix := range fixes {
+		content += fmt.Sprintf("**%s**: %s\n\n%s\n\n", fix.Rule, fix.Cause, fix.Fix)
+		if fix.Patch != "" {
+			content += fmt.Sprintf("```hcl\n%s\n```\n\n", strings.TrimSpace(fix.Patch))
+		}
+		if fix.Reference != "" {
+			content += fmt.Sprintf("See [the example](%s) for more details.\n\n", fix.Reference)
+		}
+	}
+	return content
+}
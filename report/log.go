@@ -12,6 +12,25 @@ func IsUrlMatchWithId(url string, id string) bool {
 	return strings.HasPrefix(url, id+"?")
 }
 
+// FailedRequests returns the failed requests of the resource and its child resources in the logs, the ids are compared case-insensitively
+func FailedRequests(id string, logs []paltypes.RequestTrace) []paltypes.RequestTrace {
+	out := make([]paltypes.RequestTrace, 0)
+	if id == "" {
+		return out
+	}
+	id = strings.ToLower(id)
+	for _, trace := range logs {
+		if trace.StatusCode < 400 {
+			continue
+		}
+		url := strings.ToLower(trace.Url)
+		if IsUrlMatchWithId(url, id) || strings.HasPrefix(url, id+"/") {
+			out = append(out, trace)
+		}
+	}
+	return out
+}
+
 func RequestTraceToString(r paltypes.RequestTrace) string {
 	return fmt.Sprintf(`%s %s
 Status Code: %d
@@ -20,6 +20,7 @@ func PassedMarkdownReport(passReport types.PassReport, coverageReport coverage.C
 
 	content := passedReportTemplate
 	content = strings.ReplaceAll(content, "${resource_type}", strings.Join(resourceTypes, "\n"))
+	content = strings.ReplaceAll(content, "${terraform_version}", passReport.TerraformVersion)
 
 	content += coverageReport.MarkdownContent()
 
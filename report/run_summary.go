@@ -0,0 +1,244 @@
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/types"
+	"github.com/gomarkdown/markdown"
+	"github.com/gomarkdown/markdown/html"
+	"github.com/gomarkdown/markdown/parser"
+)
+
+const (
+	RunSummaryMarkdownFileName = "index.md"
+	RunSummaryHtmlFileName     = "index.html"
+)
+
+//go:embed run_summary.md
+var runSummaryTemplate string
+
+// the status with the higher priority is shown when a resource has multiple statuses, e.g., it's created but the plan shows differences
+var statusPriority = map[string]int{
+	types.StatusPassed: 0,
+	types.StatusDiff:   1,
+	types.StatusFailed: 2,
+}
+
+// ErrorAddress returns the address of the resource which causes the error, the errors parsed from the logs are reported by their labels
+func ErrorAddress(e types.Error) string {
+	if e.Address != "" {
+		return e.Address
+	}
+	return fmt.Sprintf("azapi_resource.%s", e.Label)
+}
+
+// NewResourceSummaries returns the status of each resource address, the reports are keyed by the resource addresses
+func NewResourceSummaries(passReport types.PassReport, errorReport types.ErrorReport, diffReport types.DiffReport, reports map[string][]string) []types.ResourceSummary {
+	byAddress := make(map[string]*types.ResourceSummary)
+	add := func(summary types.ResourceSummary) {
+		existing, ok := byAddress[summary.Address]
+		if !ok {
+			byAddress[summary.Address] = &summary
+			return
+		}
+		if statusPriority[summary.Status] > statusPriority[existing.Status] {
+			existing.Status = summary.Status
+		}
+		if existing.Id == "" {
+			existing.Id = summary.Id
+		}
+		if existing.Type == "" {
+			existing.Type = summary.Type
+		}
+	}
+	for _, r := range passReport.Resources {
+		add(types.ResourceSummary{Id: r.Id, Address: r.Address, Type: r.Type, Status: types.StatusPassed})
+	}
+	for _, e := range errorReport.Errors {
+		add(types.ResourceSummary{Id: e.Id, Address: ErrorAddress(e), Type: e.Type, Status: types.StatusFailed})
+	}
+	for _, d := range diffReport.Diffs {
+		add(types.ResourceSummary{Id: d.Id, Address: d.Address, Type: d.Type, Status: types.StatusDiff})
+	}
+
+	out := make([]types.ResourceSummary, 0)
+	for address, summary := range byAddress {
+		summary.Reports = reports[address]
+		out = append(out, *summary)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Address < out[j].Address
+	})
+	return out
+}
+
+// LinkTraces assigns the trace files to the resources by the request URLs, the trace is assigned to the resource whose ID is the longest prefix of the URL path,
+// so the requests of the child resources are not assigned to the parent resources.
+func LinkTraces(resources []types.ResourceSummary, traceFiles []string, traceUrls []string) {
+	for i, traceUrl := range traceUrls {
+		if i >= len(traceFiles) || traceFiles[i] == "" {
+			continue
+		}
+		requestPath := strings.ToLower(traceUrl)
+		if u, err := url.Parse(traceUrl); err == nil {
+			requestPath = strings.ToLower(u.Path)
+		}
+		matched := -1
+		for j, r := range resources {
+			id := strings.ToLower(r.Id)
+			if id == "" || (requestPath != id && !strings.HasPrefix(requestPath, id+"/")) {
+				continue
+			}
+			if matched == -1 || len(id) > len(resources[matched].Id) {
+				matched = j
+			}
+		}
+		if matched != -1 {
+			resources[matched].Traces = append(resources[matched].Traces, traceFiles[i])
+		}
+	}
+}
+
+// NewCoverageSummaries returns the covered and total property counts of each API path in the coverage report
+func NewCoverageSummaries(coverageReport coverage.CoverageReport) []types.CoverageSummary {
+	out := make([]types.CoverageSummary, 0)
+	for _, item := range coverageReport.Coverages {
+		if item == nil || item.Model == nil {
+			continue
+		}
+		out = append(out, types.CoverageSummary{
+			DisplayName:  item.DisplayName,
+			ApiPath:      item.ApiPath,
+			CoveredCount: item.Model.RootCoveredCount,
+			TotalCount:   item.Model.RootTotalCount,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].DisplayName < out[j].DisplayName
+	})
+	return out
+}
+
+// ListArtifacts returns the files in the report directory, the files in the sub-directories are summarized by their directories
+func ListArtifacts(reportDir string) ([]string, error) {
+	entries, err := os.ReadDir(reportDir)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]string, 0)
+	for _, entry := range entries {
+		if entry.Name() == RunSummaryMarkdownFileName || entry.Name() == RunSummaryHtmlFileName {
+			continue
+		}
+		if entry.IsDir() {
+			count := 0
+			_ = filepath.Walk(filepath.Join(reportDir, entry.Name()), func(_ string, info os.FileInfo, err error) error {
+				if err == nil && !info.IsDir() {
+					count++
+				}
+				return nil
+			})
+			out = append(out, fmt.Sprintf("%s/ (%d files)", entry.Name(), count))
+			continue
+		}
+		out = append(out, entry.Name())
+	}
+	return out, nil
+}
+
+func RunSummaryMarkdownReport(summary types.RunSummary) string {
+	phases := make([]string, 0)
+	for _, phase := range summary.Phases {
+		phases = append(phases, fmt.Sprintf("|%s|%s|%s|", phase.Name, phase.Status, tableCell(phase.Detail)))
+	}
+
+	counts := make(map[string]int)
+	resources := make([]string, 0)
+	for _, r := range summary.Resources {
+		counts[r.Status]++
+		reports := make([]string, 0)
+		for _, file := range r.Reports {
+			reports = append(reports, markdownLink(file, file))
+		}
+		traces := make([]string, 0)
+		for _, file := range r.Traces {
+			traces = append(traces, markdownLink(strings.TrimSuffix(filepath.Base(file), ".json"), file))
+		}
+		resources = append(resources, fmt.Sprintf("|%s|%s|%s|%s|%s|", r.Address, r.Type, r.Status, strings.Join(reports, "<br>"), strings.Join(traces, " ")))
+	}
+	resourceTotals := fmt.Sprintf("%d resources in total, %d passed, %d failed, %d with API issues.",
+		len(summary.Resources), counts[types.StatusPassed], counts[types.StatusFailed], counts[types.StatusDiff])
+
+	coverages := "No coverage data."
+	if len(summary.Coverages) != 0 {
+		lines := []string{"|Resource type|Covered properties|Total properties|Coverage|", "|---|---|---|---|"}
+		for _, c := range summary.Coverages {
+			percentage := 0.0
+			if c.TotalCount != 0 {
+				percentage = float64(c.CoveredCount) * 100 / float64(c.TotalCount)
+			}
+			lines = append(lines, fmt.Sprintf("|%s|%d|%d|%.1f%%|", c.DisplayName, c.CoveredCount, c.TotalCount, percentage))
+		}
+		coverages = strings.Join(lines, "\n")
+	}
+	if summary.SwaggerIndexCommit != "" {
+		coverages = fmt.Sprintf("The swagger index is built on commit [%[1]s](%[2]s%[1]s).\n\n%[3]s", summary.SwaggerIndexCommit, coverage.IndexCommitURL, coverages)
+	}
+
+	artifacts := make([]string, 0)
+	for _, artifact := range summary.Artifacts {
+		if strings.Contains(artifact, "/ (") {
+			// the summarized directory
+			artifacts = append(artifacts, fmt.Sprintf("- %s", artifact))
+			continue
+		}
+		artifacts = append(artifacts, fmt.Sprintf("- %s", markdownLink(artifact, artifact)))
+	}
+
+	content := runSummaryTemplate
+	content = strings.ReplaceAll(content, "${command}", summary.CommandLine)
+	content = strings.ReplaceAll(content, "${tool_version}", summary.ToolVersion)
+	content = strings.ReplaceAll(content, "${terraform_version}", summary.TerraformVersion)
+	content = strings.ReplaceAll(content, "${start_time}", summary.StartTime.Format(time.RFC3339))
+	content = strings.ReplaceAll(content, "${duration}", summary.EndTime.Sub(summary.StartTime).Round(time.Second).String())
+	content = strings.ReplaceAll(content, "${phases}", strings.Join(phases, "\n"))
+	content = strings.ReplaceAll(content, "${resource_totals}", resourceTotals)
+	content = strings.ReplaceAll(content, "${resources}", strings.Join(resources, "\n"))
+	content = strings.ReplaceAll(content, "${coverage}", coverages)
+	content = strings.ReplaceAll(content, "${artifacts}", strings.Join(artifacts, "\n"))
+	content = strings.ReplaceAll(content, "${suppressed_findings}", SuppressedFindingsMarkdown(summary.Suppressed))
+	return content
+}
+
+// RunSummaryHtmlReport renders the markdown summary as a standalone html page
+func RunSummaryHtmlReport(markdownContent string) string {
+	p := parser.NewWithExtensions(parser.CommonExtensions)
+	renderer := html.NewRenderer(html.RendererOptions{
+		Title: "Armstrong Test Run Summary",
+		Flags: html.CommonFlags | html.CompletePage,
+	})
+	return string(markdown.ToHTML([]byte(markdownContent), p, renderer))
+}
+
+// markdownLink returns the link to the relative path, the spaces in the file names are escaped
+func markdownLink(text string, relativePath string) string {
+	segments := strings.Split(filepath.ToSlash(relativePath), "/")
+	for i := range segments {
+		segments[i] = url.PathEscape(segments[i])
+	}
+	return fmt.Sprintf("[%s](%s)", text, strings.Join(segments, "/"))
+}
+
+// tableCell escapes the content in the markdown table cell
+func tableCell(input string) string {
+	lines := strings.Split(strings.TrimSpace(input), "\n")
+	return strings.ReplaceAll(strings.Join(lines, "<br>"), "|", "\\|")
+}
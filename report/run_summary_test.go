@@ -0,0 +1,110 @@
+package report_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+)
+
+func Test_RunSummary(t *testing.T) {
+	const (
+		groupId   = "/subscriptions/000/resourceGroups/rg"
+		accountId = "/subscriptions/000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/test"
+	)
+	passReport := types.PassReport{
+		Resources: []types.Resource{
+			{Id: groupId, Type: "Microsoft.Resources/resourceGroups@2021-04-01", Address: "azapi_resource.resourceGroup"},
+			{Id: accountId, Type: "Microsoft.Automation/automationAccounts@2022-08-08", Address: "azapi_resource.automationAccount"},
+		},
+	}
+	errorReport := types.ErrorReport{
+		Errors: []types.Error{
+			{Type: "Microsoft.Automation/automationAccounts/runbooks@2022-08-08", Label: "runbook"},
+		},
+	}
+	diffReport := types.DiffReport{
+		Diffs: []types.Diff{
+			{Id: accountId, Type: "Microsoft.Automation/automationAccounts@2022-08-08", Address: "azapi_resource.automationAccount"},
+		},
+	}
+	resources := report.NewResourceSummaries(passReport, errorReport, diffReport, map[string][]string{
+		"azapi_resource.automationAccount": {"Error - Microsoft.Automation_automationAccounts@2022-08-08_automationAccount.md"},
+	})
+	if len(resources) != 3 {
+		t.Fatalf("expect 3 resources, but got %v", resources)
+	}
+	statuses := make(map[string]string)
+	for _, r := range resources {
+		statuses[r.Address] = r.Status
+	}
+	expectedStatuses := map[string]string{
+		"azapi_resource.resourceGroup":     types.StatusPassed,
+		"azapi_resource.automationAccount": types.StatusDiff,
+		"azapi_resource.runbook":           types.StatusFailed,
+	}
+	for address, status := range expectedStatuses {
+		if statuses[address] != status {
+			t.Errorf("expect %s to be %s, but got %s", address, status, statuses[address])
+		}
+	}
+
+	report.LinkTraces(resources, []string{"traces/trace-1.json", "traces/trace-2.json", "traces/trace-3.json"}, []string{
+		"https://management.azure.com" + groupId + "?api-version=2021-04-01",
+		"https://management.azure.com" + accountId + "?api-version=2022-08-08",
+		"https://management.azure.com" + strings.ToUpper(accountId) + "/listKeys?api-version=2022-08-08",
+	})
+	for _, r := range resources {
+		switch r.Address {
+		case "azapi_resource.resourceGroup":
+			if len(r.Traces) != 1 || r.Traces[0] != "traces/trace-1.json" {
+				t.Errorf("expect only the resource group trace linked to %s, but got %v", r.Address, r.Traces)
+			}
+		case "azapi_resource.automationAccount":
+			if len(r.Traces) != 2 {
+				t.Errorf("expect 2 traces linked to %s, but got %v", r.Address, r.Traces)
+			}
+		}
+	}
+
+	startTime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	content := report.RunSummaryMarkdownReport(types.RunSummary{
+		CommandLine:      "armstrong test -v",
+		ToolVersion:      "0.17.0",
+		TerraformVersion: "terraform v1.9.5",
+		StartTime:        startTime,
+		EndTime:          startTime.Add(90 * time.Second),
+		Phases: []types.PhaseSummary{
+			{Name: "apply", Status: types.StatusFailed, Detail: "1 errors"},
+		},
+		Resources: resources,
+		Coverages: []types.CoverageSummary{
+			{DisplayName: "Microsoft.Automation/automationAccounts@2022-08-08", CoveredCount: 3, TotalCount: 4},
+		},
+		SwaggerIndexCommit: "0123456789abcdef",
+		Artifacts:          []string{"Onboard Terraform - partial_passed_report.md", "traces/ (3 files)"},
+	})
+	for _, expected := range []string{
+		"`armstrong test -v`",
+		"Duration: 1m30s",
+		"|apply|failed|1 errors|",
+		"3 resources in total, 1 passed, 1 failed, 1 with API issues.",
+		"(Error%20-%20Microsoft.Automation_automationAccounts@2022-08-08_automationAccount.md)",
+		"[trace-1](traces/trace-1.json)",
+		"|Microsoft.Automation/automationAccounts@2022-08-08|3|4|75.0%|",
+		"commit [0123456789abcdef](https://github.com/Azure/azure-rest-api-specs/tree/0123456789abcdef)",
+		"- [Onboard Terraform - partial_passed_report.md](Onboard%20Terraform%20-%20partial_passed_report.md)",
+		"- traces/ (3 files)",
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expect %s in the summary, but got:\n%s", expected, content)
+		}
+	}
+
+	html := report.RunSummaryHtmlReport(content)
+	if !strings.Contains(html, "<table>") || !strings.Contains(html, "<title>Armstrong Test Run Summary</title>") {
+		t.Errorf("expect a complete html page with tables, but got:\n%s", html)
+	}
+}
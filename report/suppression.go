@@ -0,0 +1,438 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/types"
+	"github.com/azure/armstrong/utils"
+	"github.com/sirupsen/logrus"
+)
+
+// the reports which the findings belong to
+const (
+	DiffReportName     = "diff"
+	ErrorReportName    = "error"
+	CoverageReportName = "coverage"
+	CredScanReportName = "credscan"
+	AccuracyReportName = "accuracy"
+)
+
+const (
+	// PropertyNotCoveredRule is the rule of the properties which are not covered by the tests
+	PropertyNotCoveredRule = "PROPERTY_NOT_COVERED"
+	// SuppressionDateFormat is the format of the expiry dates of the suppressions
+	SuppressionDateFormat = "2006-01-02"
+)
+
+// Suppression suppresses the findings which match all of its patterns, the empty patterns match any finding.
+// The patterns are globs, e.g., Microsoft.Network/*, or regular expressions enclosed in slashes, e.g., /^properties\.(tags|sku)/, they're matched case-insensitively.
+type Suppression struct {
+	Code         string `json:"rule" yaml:"rule"`
+	File         string `json:"file" yaml:"file"`
+	Operation    string `json:"operation" yaml:"operation,omitempty"`
+	ResourceType string `json:"resourceType,omitempty" yaml:"resourceType,omitempty"`
+	// Property is the path of the property, e.g., properties.networkAcls.ipRules[].value, the array items are represented by []
+	Property string `json:"property,omitempty" yaml:"property,omitempty"`
+	// Reason is mandatory, the suppressions without reasons are ignored
+	Reason string `json:"reason" yaml:"reason,omitempty"`
+	// Expires is the last date when the suppression takes effect in the format of YYYY-MM-DD, the suppression never expires if it's empty
+	Expires string `json:"expires,omitempty" yaml:"expires,omitempty"`
+}
+
+// Finding is a problem found by a report, the fields which don't apply to the report are empty
+type Finding struct {
+	Rule         string
+	File         string
+	Operation    string
+	ResourceType string
+	Property     string
+	Message      string
+}
+
+// Suppressions matches the findings against the suppressions and records the suppressed findings, the nil value suppresses nothing
+type Suppressions struct {
+	items      []suppressionMatcher
+	suppressed []types.SuppressedFinding
+}
+
+type suppressionMatcher struct {
+	Suppression
+	patterns []fieldPattern
+}
+
+type fieldPattern struct {
+	pattern *pattern
+	value   func(Finding) []string
+}
+
+type pattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// NewSuppressions returns the matcher of the suppressions, the suppressions without patterns or reasons, with invalid patterns or which are expired are ignored with warnings
+func NewSuppressions(suppressions []Suppression) *Suppressions {
+	return newSuppressions(suppressions, time.Now())
+}
+
+func newSuppressions(suppressions []Suppression, now time.Time) *Suppressions {
+	out := &Suppressions{}
+	today := now.Format(SuppressionDateFormat)
+	for _, s := range suppressions {
+		if s.Code == "" && s.File == "" && s.Operation == "" && s.ResourceType == "" && s.Property == "" {
+			logrus.Warnf("suppression is ignored: at least one of rule, file, operation, resourceType and property is required, reason: %s", s.Reason)
+			continue
+		}
+		if strings.TrimSpace(s.Reason) == "" {
+			logrus.Warnf("suppression %s is ignored: the reason is required", s)
+			continue
+		}
+		if s.Expires != "" {
+			if _, err := time.Parse(SuppressionDateFormat, s.Expires); err != nil {
+				logrus.Warnf("suppression %s is ignored: the expiry date must be in the format of YYYY-MM-DD: %+v", s, err)
+				continue
+			}
+			// the dates in the same format are compared as strings
+			if s.Expires < today {
+				logrus.Warnf("suppression %s is ignored: it expired on %s", s, s.Expires)
+				continue
+			}
+		}
+		matcher := suppressionMatcher{Suppression: s}
+		fields := []struct {
+			input string
+			value func(Finding) []string
+		}{
+			{s.Code, func(f Finding) []string { return []string{f.Rule} }},
+			{s.File, func(f Finding) []string {
+				file := filepath.ToSlash(f.File)
+				return []string{file, path.Base(file)}
+			}},
+			{s.Operation, func(f Finding) []string { return []string{f.Operation} }},
+			{s.ResourceType, func(f Finding) []string {
+				return []string{f.ResourceType, strings.Split(f.ResourceType, "@")[0]}
+			}},
+			{s.Property, func(f Finding) []string { return []string{f.Property} }},
+		}
+		valid := true
+		for _, field := range fields {
+			p, err := newPattern(field.input)
+			if err != nil {
+				logrus.Warnf("suppression %s is ignored: %+v", s, err)
+				valid = false
+				break
+			}
+			if p != nil {
+				matcher.patterns = append(matcher.patterns, fieldPattern{pattern: p, value: field.value})
+			}
+		}
+		if valid {
+			out.items = append(out.items, matcher)
+		}
+	}
+	return out
+}
+
+// newPattern returns nil if the input is empty
+func newPattern(input string) (*pattern, error) {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil, nil
+	}
+	if len(input) > 1 && strings.HasPrefix(input, "/") && strings.HasSuffix(input, "/") {
+		regex, err := regexp.Compile("(?i)" + input[1:len(input)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %+v", input, err)
+		}
+		return &pattern{regex: regex}, nil
+	}
+	// the array items in the property paths are represented by [], which is not a character class
+	glob := strings.ReplaceAll(strings.ToLower(input), "[]", `\[\]`)
+	if _, err := path.Match(glob, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob %s: %+v", input, err)
+	}
+	return &pattern{glob: glob}, nil
+}
+
+// match returns whether any of the non-empty values matches the pattern
+func (p pattern) match(values []string) bool {
+	for _, value := range values {
+		if value == "" {
+			continue
+		}
+		if p.regex != nil {
+			if p.regex.MatchString(value) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(p.glob, strings.ToLower(value)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m suppressionMatcher) match(finding Finding) bool {
+	for _, p := range m.patterns {
+		if !p.pattern.match(p.value(finding)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Suppress returns whether the finding of the report is suppressed, the suppressed finding is recorded with the reason of the first matched suppression
+func (s *Suppressions) Suppress(reportName string, finding Finding) bool {
+	if s == nil {
+		return false
+	}
+	for _, item := range s.items {
+		if !item.match(finding) {
+			continue
+		}
+		logrus.Debugf("%s finding %s of %s is suppressed: %s", reportName, finding.Rule, findingTarget(finding), item.Reason)
+		s.suppressed = append(s.suppressed, types.SuppressedFinding{
+			Report:  reportName,
+			Rule:    finding.Rule,
+			Target:  findingTarget(finding),
+			Message: finding.Message,
+			Reason:  item.Reason,
+			Expires: item.Expires,
+		})
+		return true
+	}
+	return false
+}
+
+// Findings returns the suppressed findings in the order of matching
+func (s *Suppressions) Findings() []types.SuppressedFinding {
+	if s == nil {
+		return nil
+	}
+	return s.suppressed
+}
+
+// SuppressErrors removes the suppressed errors from the error report, the errors are matched by their error codes, resource types and operation ids
+func (s *Suppressions) SuppressErrors(errorReport *types.ErrorReport) {
+	if s == nil {
+		return
+	}
+	errors := make([]types.Error, 0)
+	for _, e := range errorReport.Errors {
+		if s.Suppress(ErrorReportName, Finding{Rule: e.Code, ResourceType: e.Type, Operation: e.OperationId, Message: e.Message}) {
+			continue
+		}
+		errors = append(errors, e)
+	}
+	errorReport.Errors = errors
+}
+
+// SuppressDiffs removes the suppressed properties from the differences, the expected values of them are replaced with the returned values,
+// and the differences without unsuppressed properties are removed from the diff report.
+func (s *Suppressions) SuppressDiffs(diffReport *types.DiffReport) {
+	if s == nil {
+		return
+	}
+	diffs := make([]types.Diff, 0)
+	for _, d := range diffReport.Diffs {
+		var got, expect interface{}
+		if json.Unmarshal([]byte(d.Change.Before), &got) != nil || json.Unmarshal([]byte(d.Change.After), &expect) != nil {
+			diffs = append(diffs, d)
+			continue
+		}
+		suppressed := false
+		expect, _ = suppressDiff(got, expect, "", func(property string, got interface{}, expect interface{}) bool {
+			finding := Finding{
+				Rule:         "ROUNDTRIP_INCONSISTENT_PROPERTY",
+				ResourceType: d.Type,
+				Operation:    d.OperationId,
+				Property:     property,
+				Message:      fmt.Sprintf("expect %s, but got %s", jsonString(expect), jsonString(got)),
+			}
+			if got == nil {
+				finding.Rule = "ROUNDTRIP_MISSING_PROPERTY"
+				finding.Message = fmt.Sprintf("%s is not returned from response", jsonString(expect))
+			}
+			if s.Suppress(DiffReportName, finding) {
+				suppressed = true
+				return true
+			}
+			return false
+		})
+		if !suppressed {
+			diffs = append(diffs, d)
+			continue
+		}
+		if firstDiffPath(got, expect) == nil {
+			continue
+		}
+		after, err := json.Marshal(expect)
+		if err != nil {
+			logrus.Warnf("marshaling the suppressed configuration of %s: %+v", d.Address, err)
+			diffs = append(diffs, d)
+			continue
+		}
+		d.Change.After = string(after)
+		diffs = append(diffs, d)
+	}
+	diffReport.Diffs = diffs
+}
+
+// suppressDiff returns the expected value whose suppressed properties are replaced with the returned values, and whether the expected value should be removed,
+// because the suppressed property is not returned. The array items are represented by [] in the property paths.
+func suppressDiff(got interface{}, expect interface{}, property string, isSuppressed func(property string, got interface{}, expect interface{}) bool) (interface{}, bool) {
+	switch expectValue := expect.(type) {
+	case map[string]interface{}:
+		if gotMap, ok := got.(map[string]interface{}); ok {
+			for _, key := range sortedKeys(expectValue) {
+				value, remove := suppressDiff(gotMap[key], expectValue[key], joinProperty(property, key), isSuppressed)
+				if remove {
+					delete(expectValue, key)
+				} else {
+					expectValue[key] = value
+				}
+			}
+			return expectValue, false
+		}
+	case []interface{}:
+		if gotArray, ok := got.([]interface{}); ok && len(gotArray) == len(expectValue) {
+			for i := range expectValue {
+				// the array items can't be removed, the suppressed missing items are replaced with null
+				expectValue[i], _ = suppressDiff(gotArray[i], expectValue[i], property+"[]", isSuppressed)
+			}
+			return expectValue, false
+		}
+	}
+	if jsonEqual(got, expect) || !isSuppressed(property, got, expect) {
+		return expect, false
+	}
+	return got, got == nil
+}
+
+// SuppressCoverage removes the suppressed properties which are not covered from the coverage report and counts the coverage again,
+// the coverage items are the resource types if byOperation is false, otherwise they're the operation ids.
+func (s *Suppressions) SuppressCoverage(coverageReport *coverage.CoverageReport, byOperation bool) {
+	if s == nil || coverageReport == nil {
+		return
+	}
+	coverageReport.Suppress(func(item *coverage.CoverageItem, model *coverage.Model, property string) bool {
+		finding := Finding{
+			Rule:     PropertyNotCoveredRule,
+			File:     model.SourceFile,
+			Property: property,
+			Message:  fmt.Sprintf("%s is not covered", property),
+		}
+		if byOperation {
+			finding.Operation = item.DisplayName
+		} else {
+			finding.ResourceType = item.DisplayName
+		}
+		return s.Suppress(CoverageReportName, finding)
+	})
+}
+
+// legacySuppressionReason is the reason of the suppressions in ApiTestConfig.json which have no reasons, the reason wasn't required by its format
+const legacySuppressionReason = "suppressed in " + ApiTestConfigFileName
+
+// LoadSuppressions returns the suppressions in ApiTestConfig.json of the working directory and the given suppressions.
+// The suppressions in ApiTestConfig.json are exempt from the mandatory reasons, because its format didn't require them.
+func LoadSuppressions(wd string, suppressions []Suppression) []Suppression {
+	out := make([]Suppression, 0)
+	apiTestConfigFilePath := filepath.Join(wd, ApiTestConfigFileName)
+	if utils.Exists(apiTestConfigFilePath) {
+		var config ApiTestConfig
+		contentBytes, err := os.ReadFile(apiTestConfigFilePath)
+		if err != nil {
+			logrus.Errorf("error when opening file(%s): %+v", apiTestConfigFilePath, err)
+		} else if err := json.Unmarshal(contentBytes, &config); err != nil {
+			logrus.Errorf("error during Unmarshal() for file(%s): %+v", apiTestConfigFilePath, err)
+		}
+		for _, s := range config.SuppressionList {
+			if strings.TrimSpace(s.Reason) == "" {
+				s.Reason = legacySuppressionReason
+			}
+			out = append(out, s)
+		}
+	} else {
+		logrus.Debugf("no config file found")
+	}
+	return append(out, suppressions...)
+}
+
+// SuppressedFindingsMarkdown returns the table of the suppressed findings, it returns "No suppressed findings." if there's none
+func SuppressedFindingsMarkdown(findings []types.SuppressedFinding) string {
+	if len(findings) == 0 {
+		return "No suppressed findings."
+	}
+	rows := make([]string, 0)
+	for _, f := range findings {
+		expires := f.Expires
+		if expires == "" {
+			expires = "never"
+		}
+		rows = append(rows, fmt.Sprintf("|%s|%s|%s|%s|%s|%s|", f.Report, tableCell(f.Rule), tableCell(f.Target), tableCell(f.Message), tableCell(f.Reason), expires))
+	}
+	sort.Strings(rows)
+	return strings.Join(append([]string{"|Report|Rule|Target|Message|Reason|Expires|", "|---|---|---|---|---|---|"}, rows...), "\n")
+}
+
+func (s Suppression) String() string {
+	fields := make([]string, 0)
+	for _, field := range []struct{ name, value string }{
+		{"rule", s.Code},
+		{"file", s.File},
+		{"operation", s.Operation},
+		{"resourceType", s.ResourceType},
+		{"property", s.Property},
+	} {
+		if field.value != "" {
+			fields = append(fields, fmt.Sprintf("%s=%s", field.name, field.value))
+		}
+	}
+	return fmt.Sprintf("{%s}", strings.Join(fields, ", "))
+}
+
+// findingTarget returns the resource type, the operation, the property and the file of the finding, which are not empty
+func findingTarget(finding Finding) string {
+	parts := make([]string, 0)
+	for _, part := range []string{finding.ResourceType, finding.Operation, finding.Property, finding.File} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func joinProperty(property, key string) string {
+	if property == "" {
+		return key
+	}
+	return property + "." + key
+}
+
+func jsonString(input interface{}) string {
+	data, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Sprintf("%v", input)
+	}
+	return string(data)
+}
+
+func sortedKeys(input map[string]interface{}) []string {
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,187 @@
+package report_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+)
+
+func Test_SuppressionsMatch(t *testing.T) {
+	suppressions := report.NewSuppressions([]report.Suppression{
+		{Code: "ROUNDTRIP_*", ResourceType: "Microsoft.Automation/*", Property: "properties.sku.*", Reason: "the sku is normalized by the service"},
+		{Code: "InvalidParameter", Operation: "/^automationaccount_(create|update)/", Reason: "tracked by the service team"},
+		{Code: "OPERATION_NOT_TEST", File: "account.json", Reason: "without reason", Expires: "2000-01-01"},
+		{Code: "OPERATION_NOT_TEST", File: "runbook.json"},
+		{Reason: "the suppression without patterns is ignored"},
+		{Code: "SWAGGER_NOT_TEST", File: "/(/", Reason: "invalid pattern"},
+		{Code: "SWAGGER_NOT_TEST", File: "*.json", Reason: "the swagger files are tested by other cases", Expires: "2999-12-31"},
+	})
+
+	testcases := []struct {
+		finding    report.Finding
+		suppressed bool
+	}{
+		{report.Finding{Rule: "ROUNDTRIP_INCONSISTENT_PROPERTY", ResourceType: "Microsoft.Automation/automationAccounts@2022-08-08", Property: "properties.sku.name"}, true},
+		{report.Finding{Rule: "ROUNDTRIP_INCONSISTENT_PROPERTY", ResourceType: "Microsoft.Automation/automationAccounts@2022-08-08", Property: "properties.encryption"}, false},
+		{report.Finding{Rule: "ROUNDTRIP_MISSING_PROPERTY", ResourceType: "Microsoft.Network/virtualNetworks@2023-04-01", Property: "properties.sku.name"}, false},
+		{report.Finding{Rule: "invalidparameter", Operation: "AutomationAccount_CreateOrUpdate"}, true},
+		{report.Finding{Rule: "InvalidParameter", Operation: "AutomationAccount_Delete"}, false},
+		{report.Finding{Rule: "InvalidParameter"}, false},
+		{report.Finding{Rule: "OPERATION_NOT_TEST", File: "/specification/automation/account.json", Operation: "AutomationAccount_Delete"}, false},
+		{report.Finding{Rule: "OPERATION_NOT_TEST", File: "/specification/automation/runbook.json", Operation: "Runbook_Delete"}, false},
+		{report.Finding{Rule: "SWAGGER_NOT_TEST", File: "/specification/automation/account.json"}, true},
+	}
+	for _, tc := range testcases {
+		if got := suppressions.Suppress(report.AccuracyReportName, tc.finding); got != tc.suppressed {
+			t.Errorf("expect %+v suppressed to be %v, but got %v", tc.finding, tc.suppressed, got)
+		}
+	}
+
+	findings := suppressions.Findings()
+	if len(findings) != 3 {
+		t.Fatalf("expect 3 suppressed findings, but got %+v", findings)
+	}
+	if findings[2].Reason != "the swagger files are tested by other cases" || findings[2].Expires != "2999-12-31" {
+		t.Errorf("expect the reason and expiry date of the matched suppression, but got %+v", findings[2])
+	}
+	content := report.SuppressedFindingsMarkdown(findings)
+	if !strings.Contains(content, "|accuracy|SWAGGER_NOT_TEST|/specification/automation/account.json||the swagger files are tested by other cases|2999-12-31|") {
+		t.Errorf("expect the suppressed finding in the appendix, but got %s", content)
+	}
+	if content := report.SuppressedFindingsMarkdown(nil); content != "No suppressed findings." {
+		t.Errorf("expect no suppressed findings, but got %s", content)
+	}
+}
+
+func Test_SuppressDiffs(t *testing.T) {
+	suppressions := report.NewSuppressions([]report.Suppression{
+		{Code: "ROUNDTRIP_MISSING_PROPERTY", Property: "properties.rules[].password", Reason: "the secret is not returned"},
+		{ResourceType: "Microsoft.Automation/automationAccounts", Property: "properties.sku.name", Reason: "the sku is normalized by the service"},
+	})
+	diffReport := types.DiffReport{
+		Diffs: []types.Diff{
+			{
+				Address: "azapi_resource.automationAccount",
+				Type:    "Microsoft.Automation/automationAccounts@2022-08-08",
+				Change: types.Change{
+					Before: `{"properties":{"sku":{"name":"BASIC"}}}`,
+					After:  `{"properties":{"sku":{"name":"Basic"}}}`,
+				},
+			},
+			{
+				Address: "azapi_resource.firewall",
+				Type:    "Microsoft.Network/firewallPolicies@2023-04-01",
+				Change: types.Change{
+					Before: `{"properties":{"rules":[{"name":"a"}],"sku":{"name":"Premium"}}}`,
+					After:  `{"properties":{"rules":[{"name":"a","password":"secret"}],"sku":{"name":"Standard"}}}`,
+				},
+			},
+		},
+	}
+	suppressions.SuppressDiffs(&diffReport)
+
+	if len(diffReport.Diffs) != 1 || diffReport.Diffs[0].Address != "azapi_resource.firewall" {
+		t.Fatalf("expect only the firewall diff is left, but got %+v", diffReport.Diffs)
+	}
+	if after := diffReport.Diffs[0].Change.After; after != `{"properties":{"rules":[{"name":"a"}],"sku":{"name":"Standard"}}}` {
+		t.Errorf("expect the suppressed property is removed from the configuration, but got %s", after)
+	}
+	findings := suppressions.Findings()
+	if len(findings) != 2 || findings[0].Rule != "ROUNDTRIP_INCONSISTENT_PROPERTY" || findings[1].Rule != "ROUNDTRIP_MISSING_PROPERTY" {
+		t.Errorf("expect 2 suppressed diff findings, but got %+v", findings)
+	}
+}
+
+func Test_SuppressErrors(t *testing.T) {
+	suppressions := report.NewSuppressions([]report.Suppression{
+		{Code: "MissingSubscriptionRegistration", Reason: "the namespace is registered in the pipeline"},
+	})
+	errorReport := types.ErrorReport{
+		Errors: []types.Error{
+			{Type: "Microsoft.Automation/automationAccounts@2022-08-08", Code: "MissingSubscriptionRegistration"},
+			{Type: "Microsoft.Automation/automationAccounts@2022-08-08", Code: "InvalidParameter"},
+		},
+	}
+	suppressions.SuppressErrors(&errorReport)
+	if len(errorReport.Errors) != 1 || errorReport.Errors[0].Code != "InvalidParameter" {
+		t.Errorf("expect only the InvalidParameter error is left, but got %+v", errorReport.Errors)
+	}
+}
+
+func Test_SuppressCoverage(t *testing.T) {
+	leaf := func(identifier string, covered bool) *coverage.Model {
+		return &coverage.Model{Identifier: identifier, IsAnyCovered: covered}
+	}
+	model := &coverage.Model{
+		Identifier:   "#",
+		IsRoot:       true,
+		IsAnyCovered: true,
+		Properties: &map[string]*coverage.Model{
+			"location": leaf("#.location", true),
+			"properties": {
+				Identifier:   "#.properties",
+				IsAnyCovered: true,
+				Properties: &map[string]*coverage.Model{
+					"publicNetworkAccess": leaf("#.properties.publicNetworkAccess", false),
+					"disableLocalAuth":    leaf("#.properties.disableLocalAuth", false),
+					"encryption":          leaf("#.properties.encryption", true),
+				},
+			},
+		},
+	}
+	model.CountCoverage()
+	coverageReport := coverage.CoverageReport{
+		Coverages: map[string]*coverage.CoverageItem{
+			"/accounts/{name}": {ApiPath: "/accounts/{name}", DisplayName: "Microsoft.Automation/automationAccounts@2022-08-08", Model: model},
+		},
+	}
+
+	suppressions := report.NewSuppressions([]report.Suppression{
+		{Code: report.PropertyNotCoveredRule, ResourceType: "Microsoft.Automation/automationAccounts", Property: "properties.*Access", Reason: "the property is tested manually"},
+		{Code: report.PropertyNotCoveredRule, Property: "properties.encryption", Reason: "the covered properties are not suppressed"},
+	})
+	suppressions.SuppressCoverage(&coverageReport, false)
+
+	if model.RootCoveredCount != 2 || model.RootTotalCount != 3 {
+		t.Errorf("expect 2/3 properties covered, but got %d/%d", model.RootCoveredCount, model.RootTotalCount)
+	}
+	findings := suppressions.Findings()
+	if len(findings) != 1 || findings[0].Target != "Microsoft.Automation/automationAccounts@2022-08-08 properties.publicNetworkAccess" {
+		t.Errorf("expect the publicNetworkAccess is suppressed, but got %+v", findings)
+	}
+}
+
+func Test_LoadSuppressions_ApiTestConfig(t *testing.T) {
+	wd := t.TempDir()
+	config := `{"suppressionList": [{"rule": "OPERATION_NOT_TEST", "file": "runbook.json"}, {"rule": "SWAGGER_NOT_TEST", "file": "account.json", "reason": "tested by other cases"}]}`
+	if err := os.WriteFile(filepath.Join(wd, report.ApiTestConfigFileName), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	suppressions := report.NewSuppressions(report.LoadSuppressions(wd, []report.Suppression{
+		{Code: "OPERATION_NOT_TEST", File: "account.json"},
+	}))
+	testcases := []struct {
+		Finding    report.Finding
+		Suppressed bool
+	}{
+		// the suppression in ApiTestConfig.json without reason takes effect
+		{report.Finding{Rule: "OPERATION_NOT_TEST", File: "/specification/automation/runbook.json"}, true},
+		{report.Finding{Rule: "SWAGGER_NOT_TEST", File: "/specification/automation/account.json"}, true},
+		// the given suppression without reason is ignored
+		{report.Finding{Rule: "OPERATION_NOT_TEST", File: "/specification/automation/account.json"}, false},
+	}
+	for _, tc := range testcases {
+		if got := suppressions.Suppress(report.AccuracyReportName, tc.Finding); got != tc.Suppressed {
+			t.Errorf("expect %+v suppressed to be %v, but got %v", tc.Finding, tc.Suppressed, got)
+		}
+	}
+	if findings := suppressions.Findings(); len(findings) != 2 || findings[0].Reason != "suppressed in ApiTestConfig.json" {
+		t.Errorf("expect the default reason of the suppression in ApiTestConfig.json, but got %+v", findings)
+	}
+}
@@ -0,0 +1,210 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/types"
+	paltypes "github.com/ms-henglu/pal/types"
+	"github.com/sirupsen/logrus"
+)
+
+const permalinkExample = "https://github.com/Azure/azure-rest-api-specs/blob/60723d13309c8f8060d020a7f3dd9d6e380f0bbd/specification/compute/resource-manager/Microsoft.Compute/stable/2020-06-01/compute.json#L9065-L9101"
+
+// matches the error code in the error message, e.g., "code": "InvalidParameter" and Code="InvalidParameter"
+var errorCodeRegex = regexp.MustCompile(`(?i)"?code"?\s*[:=]\s*"([^"]+)"`)
+
+// SwaggerResolver resolves the swagger operations of the requests in the reports, so the operation ids and the swagger permalinks are filled.
+// The local swagger files are used when they're specified, otherwise the online index is used and the permalinks point to the commit of the index.
+type SwaggerResolver struct {
+	SwaggerPath   string
+	IndexFilePath string
+}
+
+// ResolveErrors fills the operation ids, the swagger permalinks and the error codes of the errors, the failed requests are found in the logs,
+// the operation of the method on the resource is used if the failed request is not found, e.g., PUT for apply and DELETE for destroy.
+func (r SwaggerResolver) ResolveErrors(errorReport *types.ErrorReport, method string) {
+	for i, e := range errorReport.Errors {
+		method, requestUrl := method, ""
+		if traces := FailedRequests(e.Id, errorReport.Logs); len(traces) != 0 {
+			trace := traces[len(traces)-1]
+			method, requestUrl = trace.Method, trace.Url
+			errorReport.Errors[i].Code = responseErrorCode(trace.Response)
+		}
+		if errorReport.Errors[i].Code == "" {
+			if matches := errorCodeRegex.FindStringSubmatch(e.Message); matches != nil {
+				errorReport.Errors[i].Code = matches[1]
+			}
+		}
+		if e.Id == "" {
+			continue
+		}
+		if requestUrl == "" {
+			requestUrl = e.Id + "?api-version=" + apiVersionOf(e.Type)
+		}
+		operation := r.operation(method, requestUrl)
+		if operation == nil {
+			continue
+		}
+		errorReport.Errors[i].OperationId = operation.OperationID
+		permalink, err := coverage.SwaggerPermalink(operation.SwaggerPath, operation.Commit, operation.Pointer)
+		if err != nil {
+			logrus.Warnf("building the swagger permalink of %s: %+v", operation.OperationID, err)
+			continue
+		}
+		errorReport.Errors[i].Permalink = permalink
+	}
+}
+
+// ResolveDiffs fills the operation ids of the GET operations which return the differences, and the swagger permalinks of the first different properties,
+// the permalink points to the request body model of the PUT operation if the property is not found.
+func (r SwaggerResolver) ResolveDiffs(diffReport *types.DiffReport) {
+	for i, d := range diffReport.Diffs {
+		apiVersion := apiVersionOf(d.Type)
+		if getOperation := r.operation("GET", d.Id+"?api-version="+apiVersion); getOperation != nil {
+			diffReport.Diffs[i].OperationId = getOperation.OperationID
+		}
+
+		putOperation := r.operation("PUT", d.Id+"?api-version="+apiVersion)
+		if putOperation == nil {
+			continue
+		}
+		swaggerPath, commit, pointer := putOperation.SwaggerPath, putOperation.Commit, putOperation.Pointer
+		if putOperation.ModelName != "" {
+			swaggerPath, pointer = putOperation.ModelSwaggerPath, coverage.DefinitionPointer(putOperation.ModelName)
+			if model, err := expandModel(putOperation.ModelName, putOperation.ModelSwaggerPath); err == nil {
+				if propertySwaggerPath, propertyPointer := model.PropertyLocation(diffPropertyPath(d.Change)); propertyPointer != "" {
+					if permalink, err := coverage.SwaggerPermalink(propertySwaggerPath, commit, propertyPointer); err == nil {
+						diffReport.Diffs[i].Permalink = permalink
+						continue
+					}
+				}
+			} else {
+				logrus.Warnf("expanding model %s: %+v", putOperation.ModelName, err)
+			}
+		}
+		permalink, err := coverage.SwaggerPermalink(swaggerPath, commit, pointer)
+		if err != nil {
+			logrus.Warnf("building the swagger permalink of %s: %+v", putOperation.OperationID, err)
+			continue
+		}
+		diffReport.Diffs[i].Permalink = permalink
+	}
+}
+
+// operation returns the swagger operation which serves the request, it returns nil if not found
+func (r SwaggerResolver) operation(method, requestUrl string) *coverage.SwaggerOperation {
+	u, err := url.Parse(requestUrl)
+	if err != nil {
+		logrus.Warnf("parsing request url %s: %+v", requestUrl, err)
+		return nil
+	}
+	if r.SwaggerPath != "" {
+		operation, err := coverage.GetOperationFromLocalDir(u.Path, method, r.SwaggerPath)
+		if err != nil {
+			logrus.Warnf("finding the %s operation of %s in %s: %+v", method, u.Path, r.SwaggerPath, err)
+		}
+		if operation != nil {
+			return operation
+		}
+	}
+	operation, err := coverage.GetOperationFromIndex(u.Path, u.Query().Get("api-version"), method, r.IndexFilePath)
+	if err != nil {
+		logrus.Warnf("finding the %s operation of %s in the index: %+v", method, u.Path, err)
+		return nil
+	}
+	return operation
+}
+
+// responseErrorCode returns the error code in the ARM error response, e.g., {"error": {"code": "InvalidParameter"}}
+func responseErrorCode(response *paltypes.HttpResponse) string {
+	if response == nil {
+		return ""
+	}
+	var body struct {
+		Code  string `json:"code"`
+		Error *struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(response.Body), &body); err != nil {
+		return ""
+	}
+	if body.Error != nil && body.Error.Code != "" {
+		return body.Error.Code
+	}
+	return body.Code
+}
+
+// diffPropertyPath returns the path of the first property which differs, the properties are compared in alphabetical order
+func diffPropertyPath(change types.Change) []string {
+	var before, after interface{}
+	_ = json.Unmarshal([]byte(change.Before), &before)
+	_ = json.Unmarshal([]byte(change.After), &after)
+	return firstDiffPath(before, after)
+}
+
+// firstDiffPath returns nil if the expected properties are the same as the returned ones, the extra properties in the response are ignored
+func firstDiffPath(got interface{}, expect interface{}) []string {
+	expectMap, ok := expect.(map[string]interface{})
+	if !ok {
+		if jsonEqual(got, expect) {
+			return nil
+		}
+		return []string{}
+	}
+	gotMap, ok := got.(map[string]interface{})
+	if !ok {
+		return []string{}
+	}
+	keys := make([]string, 0)
+	for key := range expectMap {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if path := firstDiffPath(gotMap[key], expectMap[key]); path != nil {
+			return append([]string{key}, path...)
+		}
+	}
+	return nil
+}
+
+// expandModel expands the model, the panics when resolving the references are returned as errors
+func expandModel(modelName, swaggerPath string) (model *coverage.Model, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%+v", r)
+		}
+	}()
+	return coverage.Expand(modelName, swaggerPath)
+}
+
+// detailOrTodo returns the resolved detail, or asks the user to fill it if it's not resolved
+func detailOrTodo(value string, example string) string {
+	if value != "" {
+		return value
+	}
+	if example == "" {
+		return "TODO"
+	}
+	return "TODO\ne.g., " + example
+}
+
+func jsonEqual(a, b interface{}) bool {
+	x, _ := json.Marshal(a)
+	y, _ := json.Marshal(b)
+	return string(x) == string(y)
+}
+
+func apiVersionOf(azapiResourceType string) string {
+	if parts := strings.Split(azapiResourceType, "@"); len(parts) == 2 {
+		return parts[1]
+	}
+	return ""
+}
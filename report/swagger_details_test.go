@@ -0,0 +1,104 @@
+package report_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/types"
+	paltypes "github.com/ms-henglu/pal/types"
+)
+
+const (
+	testSwaggerPath = "../coverage/testdata/Microsoft.Automation/stable/2022-08-08"
+	testAccountId   = "/subscriptions/000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/test"
+)
+
+func Test_ResolveErrors(t *testing.T) {
+	errorReport := types.ErrorReport{
+		Errors: []types.Error{
+			{
+				Id:      testAccountId,
+				Type:    "Microsoft.Automation/automationAccounts@2022-08-08",
+				Label:   "automationAccount",
+				Message: "unexpected status 400",
+			},
+		},
+		Logs: []paltypes.RequestTrace{
+			{
+				Url:        testAccountId + "?api-version=2022-08-08",
+				Method:     "PUT",
+				StatusCode: 400,
+				Response: &paltypes.HttpResponse{
+					Body: `{"error":{"code":"InvalidParameter","message":"The sku is invalid."}}`,
+				},
+			},
+		},
+	}
+	report.SwaggerResolver{SwaggerPath: testSwaggerPath}.ResolveErrors(&errorReport, "PUT")
+
+	e := errorReport.Errors[0]
+	if e.Code != "InvalidParameter" {
+		t.Errorf("expect error code InvalidParameter, but got %s", e.Code)
+	}
+	if e.OperationId != "AutomationAccount_CreateOrUpdate" {
+		t.Errorf("expect operation id AutomationAccount_CreateOrUpdate, but got %s", e.OperationId)
+	}
+	if !strings.Contains(e.Permalink, "account.json#L") {
+		t.Errorf("expect the permalink to account.json, but got %s", e.Permalink)
+	}
+
+	content := report.ErrorMarkdownReport(e, errorReport.Logs, "terraform v1.9.5")
+	for _, expected := range []string{"AutomationAccount_CreateOrUpdate", e.Permalink, "InvalidParameter"} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expect %s in the report, but got:\n%s", expected, content)
+		}
+	}
+}
+
+func Test_ResolveDiffs(t *testing.T) {
+	diffReport := types.DiffReport{
+		Diffs: []types.Diff{
+			{
+				Id:      testAccountId,
+				Type:    "Microsoft.Automation/automationAccounts@2022-08-08",
+				Address: "azapi_resource.automationAccount",
+				Change: types.Change{
+					Before: `{"location":"westus","properties":{"sku":{"name":"Free"}}}`,
+					After:  `{"location":"westus","properties":{"sku":{"name":"Basic"}}}`,
+				},
+			},
+		},
+	}
+	report.SwaggerResolver{SwaggerPath: testSwaggerPath}.ResolveDiffs(&diffReport)
+
+	d := diffReport.Diffs[0]
+	if d.OperationId != "AutomationAccount_Get" {
+		t.Errorf("expect operation id AutomationAccount_Get, but got %s", d.OperationId)
+	}
+	// the permalink points to the sku name, which is the first different property
+	if !strings.HasSuffix(d.Permalink, "account.json#L565-L576") {
+		t.Errorf("expect the permalink to the sku name in account.json, but got %s", d.Permalink)
+	}
+
+	content := report.DiffMarkdownReport(d, nil)
+	if !strings.Contains(content, d.Permalink) || strings.Contains(content, "TODO\ne.g., VirtualMachines_Get") {
+		t.Errorf("expect the resolved details in the report, but got:\n%s", content)
+	}
+}
+
+func Test_FailedRequests(t *testing.T) {
+	logs := []paltypes.RequestTrace{
+		{Url: strings.ToUpper(testAccountId) + "?api-version=2022-08-08", StatusCode: 400},
+		{Url: testAccountId + "?api-version=2022-08-08", StatusCode: 200},
+		{Url: testAccountId + "/runbooks/test?api-version=2022-08-08", StatusCode: 409},
+		{Url: testAccountId + "2?api-version=2022-08-08", StatusCode: 400},
+	}
+	actual := report.FailedRequests(testAccountId, logs)
+	if len(actual) != 2 || actual[0].StatusCode != 400 || actual[1].StatusCode != 409 {
+		t.Errorf("expect the failed requests of the resource and its child resource, but got %+v", actual)
+	}
+	if actual := report.FailedRequests("", logs); len(actual) != 0 {
+		t.Errorf("expect no failed requests for the empty id, but got %+v", actual)
+	}
+}
@@ -0,0 +1,177 @@
+package resource
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// armNode is a node of the ARM template expression, e.g., `concat(parameters('name'), '-suffix')`
+type armNode struct {
+	Literal   *string
+	Number    string
+	Function  string
+	Args      []armNode
+	Accessors []armAccessor
+}
+
+// armAccessor is a property or index accessor, e.g., `.location` or `[0]`
+type armAccessor struct {
+	Property string
+	Index    *armNode
+}
+
+// isArmExpression returns true if the input is an ARM template expression, e.g., `[parameters('name')]`.
+// The string which starts with `[[` is an escaped literal.
+func isArmExpression(input string) bool {
+	return strings.HasPrefix(input, "[") && !strings.HasPrefix(input, "[[") && strings.HasSuffix(input, "]")
+}
+
+func parseArmExpression(input string) (*armNode, error) {
+	p := armExpressionParser{input: []rune(input)}
+	node, err := p.parseExpression()
+	if err != nil {
+		return nil, fmt.Errorf("parsing expression %q: %+v", input, err)
+	}
+	p.skipSpaces()
+	if p.pos != len(p.input) {
+		return nil, fmt.Errorf("parsing expression %q: unexpected character %q at %d", input, p.input[p.pos], p.pos)
+	}
+	return node, nil
+}
+
+type armExpressionParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *armExpressionParser) parseExpression() (*armNode, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	var node *armNode
+	switch c := p.input[p.pos]; {
+	case c == '\'':
+		literal, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		node = &armNode{Literal: &literal}
+	case c == '-' || unicode.IsDigit(c):
+		start := p.pos
+		p.pos++
+		for p.pos < len(p.input) && (unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '.') {
+			p.pos++
+		}
+		node = &armNode{Number: string(p.input[start:p.pos])}
+	case unicode.IsLetter(c):
+		name := p.parseIdentifier()
+		p.skipSpaces()
+		if p.pos >= len(p.input) || p.input[p.pos] != '(' {
+			return nil, fmt.Errorf("expect '(' after function %s", name)
+		}
+		p.pos++
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		node = &armNode{Function: name, Args: args}
+	default:
+		return nil, fmt.Errorf("unexpected character %q at %d", c, p.pos)
+	}
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return node, nil
+		}
+		switch p.input[p.pos] {
+		case '.':
+			p.pos++
+			property := p.parseIdentifier()
+			if property == "" {
+				return nil, fmt.Errorf("expect property name at %d", p.pos)
+			}
+			node.Accessors = append(node.Accessors, armAccessor{Property: property})
+		case '[':
+			p.pos++
+			index, err := p.parseExpression()
+			if err != nil {
+				return nil, err
+			}
+			p.skipSpaces()
+			if p.pos >= len(p.input) || p.input[p.pos] != ']' {
+				return nil, fmt.Errorf("expect ']' at %d", p.pos)
+			}
+			p.pos++
+			node.Accessors = append(node.Accessors, armAccessor{Index: index})
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *armExpressionParser) parseArguments() ([]armNode, error) {
+	args := make([]armNode, 0)
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == ')' {
+		p.pos++
+		return args, nil
+	}
+	for {
+		arg, err := p.parseExpression()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, *arg)
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return nil, fmt.Errorf("unexpected end of expression, expect ')'")
+		}
+		switch p.input[p.pos] {
+		case ',':
+			p.pos++
+		case ')':
+			p.pos++
+			return args, nil
+		default:
+			return nil, fmt.Errorf("unexpected character %q at %d", p.input[p.pos], p.pos)
+		}
+	}
+}
+
+// parseString parses the single-quoted string, the single quote is escaped by doubling it
+func (p *armExpressionParser) parseString() (string, error) {
+	p.pos++
+	out := make([]rune, 0)
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		if c != '\'' {
+			out = append(out, c)
+			continue
+		}
+		if p.pos < len(p.input) && p.input[p.pos] == '\'' {
+			out = append(out, c)
+			p.pos++
+			continue
+		}
+		return string(out), nil
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+func (p *armExpressionParser) parseIdentifier() string {
+	start := p.pos
+	for p.pos < len(p.input) && (unicode.IsLetter(p.input[p.pos]) || unicode.IsDigit(p.input[p.pos]) || p.input[p.pos] == '_') {
+		p.pos++
+	}
+	return string(p.input[start:p.pos])
+}
+
+func (p *armExpressionParser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
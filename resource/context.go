@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/azure/armstrong/cloud"
 	"github.com/azure/armstrong/dependency"
 	"github.com/azure/armstrong/resource/resolver"
 	"github.com/azure/armstrong/resource/types"
@@ -28,8 +29,44 @@ type Context struct {
 
 var DefaultProviderConfig string
 
+const defaultResourceNamePrefix = "acctest"
+
+// ResourceNamePrefix and DefaultLocation are the defaults of the resource_name and location variables in the generated configurations
+var (
+	ResourceNamePrefix = defaultResourceNamePrefix
+	DefaultLocation    = "westeurope"
+)
+
 func init() {
-	DefaultProviderConfig = fmt.Sprintf(`terraform {
+	DefaultProviderConfig = providerConfig()
+}
+
+// SetVariableDefaults changes the defaults of the resource_name and location variables, the empty values reset them to
+// the acctest prefix and the location of the selected cloud. The provider blocks are also updated to use the selected cloud.
+func SetVariableDefaults(namePrefix string, location string) {
+	if namePrefix == "" {
+		namePrefix = defaultResourceNamePrefix
+	}
+	if location == "" {
+		location = cloud.Current().Location
+	}
+	ResourceNamePrefix = namePrefix
+	DefaultLocation = location
+	DefaultProviderConfig = providerConfig()
+}
+
+func providerConfig() string {
+	env := cloud.Current()
+	azurermSettings, azapiSettings := "", ""
+	if env.Name != cloud.Public.Name || env.IsCustom() {
+		azurermSettings += fmt.Sprintf("  environment = %q\n", env.Name)
+		azapiSettings += fmt.Sprintf("  environment = %q\n", env.Name)
+	}
+	if env.IsCustom() {
+		azurermSettings += fmt.Sprintf("  metadata_host = %q\n", env.Host())
+		azapiSettings += fmt.Sprintf("  endpoint {\n    resource_manager_endpoint = %q\n  }\n", env.ResourceManagerEndpoint)
+	}
+	return fmt.Sprintf(`terraform {
   required_providers {
     azapi = {
       source = "Azure/azapi"
@@ -48,23 +85,23 @@ provider "azurerm" {
     }
   }
   skip_provider_registration = true
-}
+%s}
 
 provider "azapi" {
   skip_provider_registration = false
-}
+%s}
 
 variable "resource_name" {
   type    = string
-  default = "acctest%04d"
+  default = "%s%04d"
 }
 
 variable "location" {
   type    = string
-  default = "westeurope"
+  default = %q
 }
 
-`, rand.New(rand.NewSource(time.Now().UnixNano())).Intn(10000))
+`, azurermSettings, azapiSettings, ResourceNamePrefix, rand.New(rand.NewSource(time.Now().UnixNano())).Intn(10000), DefaultLocation)
 }
 
 func NewContext(referenceResolvers []resolver.ReferenceResolver) *Context {
@@ -98,7 +135,7 @@ func (c *Context) InitFile(content string) error {
 			case "location":
 				locationVarBlock = block
 			case "resource_name":
-				block.Body().SetAttributeValue("default", cty.StringVal(fmt.Sprintf("acctest%04d", R.Intn(10000))))
+				block.Body().SetAttributeValue("default", cty.StringVal(fmt.Sprintf("%s%04d", ResourceNamePrefix, R.Intn(10000))))
 			}
 		case "terraform":
 			terraformBlock = block
@@ -314,19 +351,9 @@ func (c *Context) AddHcl(input string, skipWhenDuplicate bool) (*types.Reference
 		}
 		logrus.Debugf("renaming labels: %v -> %v", labels[1], newLabel)
 		block.SetLabels([]string{labels[0], newLabel})
-		input = string(inputFile.BuildTokens(nil).Bytes())
-
-		// TODO: improve the following renaming labels logic
-		oldAddressPrefix := fmt.Sprintf("%s.", strings.Join(labels, "."))
-		if block.Type() == "data" {
-			oldAddressPrefix = "data." + oldAddressPrefix
-		}
-		newAddressPrefix := fmt.Sprintf("%s.", strings.Join(block.Labels(), "."))
-		if block.Type() == "data" {
-			newAddressPrefix = "data." + newAddressPrefix
-		}
-		input = strings.ReplaceAll(input, oldAddressPrefix, newAddressPrefix)
+		utils.RenameReferences(inputFile.Body(), utils.BlockAddress(block.Type(), labels), utils.BlockAddress(block.Type(), block.Labels()))
 	}
+	input = string(inputFile.BuildTokens(nil).Bytes())
 
 	inputFile, diags = hclwrite.ParseConfig([]byte(input), "", hcl.InitialPos)
 	if diags.HasErrors() {
@@ -343,7 +370,7 @@ func (c *Context) AddHcl(input string, skipWhenDuplicate bool) (*types.Reference
 		if locationAttr != nil {
 			defaultLocation := utils.AttributeValue(c.locationVarBlock.Body().GetAttribute("default"))
 			currentLocation := utils.AttributeValue(locationAttr)
-			if currentLocation != defaultLocation && !strings.Contains(currentLocation, "var.") {
+			if currentLocation != defaultLocation && !strings.Contains(currentLocation, "var.") && !strings.Contains(currentLocation, "local.") {
 				c.locationVarBlock.Body().SetAttributeValue("default", cty.StringVal(currentLocation))
 				block.Body().SetAttributeTraversal("location", hcl.Traversal{hcl.TraverseRoot{Name: "var"}, hcl.TraverseAttr{Name: "location"}})
 			}
@@ -488,7 +515,7 @@ func isRandomName(input string) bool {
 	if strings.Contains(input, "Microsoft.") {
 		return false
 	}
-	if strings.Contains(input, "var.") {
+	if strings.Contains(input, "var.") || strings.Contains(input, "local.") {
 		return false
 	}
 	return true
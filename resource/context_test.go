@@ -2,8 +2,10 @@ package resource_test
 
 import (
 	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/azure/armstrong/cloud"
 	"github.com/azure/armstrong/resource"
 )
 
@@ -50,3 +52,78 @@ variable "location" {
 		t.Fatalf("expected: %s, got: %s", expected, actual)
 	}
 }
+
+func Test_AddHclRenameConflictLabels(t *testing.T) {
+	context := resource.NewContext(nil)
+	_, err := context.AddHcl(`resource "azapi_resource" "test" {
+  type = "Microsoft.Resources/resourceGroups@2020-06-01"
+  name = "first"
+}
+`, false)
+	if err != nil {
+		t.Fatalf("failed to add hcl: %+v", err)
+	}
+
+	ref, err := context.AddHcl(`resource "azapi_resource" "test" {
+  type = "Microsoft.Network/virtualNetworks@2020-06-01"
+  name = "test"
+  body = {
+    description = "azapi_resource.test.name"
+  }
+}
+
+resource "azapi_resource" "subnet" {
+  type       = "Microsoft.Network/virtualNetworks/subnets@2020-06-01"
+  parent_id  = azapi_resource.test.id
+  name       = "subnet"
+  body = {
+    properties = {
+      prefix = "${azapi_resource.test.name}-subnet"
+    }
+  }
+  depends_on = [azapi_resource.test]
+}
+`, false)
+	if err != nil {
+		t.Fatalf("failed to add hcl: %+v", err)
+	}
+	if ref.String() != "azapi_resource.subnet.id" {
+		t.Fatalf("expected reference azapi_resource.subnet.id, got %s", ref.String())
+	}
+
+	actual := strings.Join(strings.Fields(context.String()), " ")
+	for _, expected := range []string{
+		`resource "azapi_resource" "test_1" {`,
+		`description = "azapi_resource.test.name"`,
+		`parent_id = azapi_resource.test_1.id`,
+		`prefix = "${azapi_resource.test_1.name}-subnet"`,
+		`depends_on = [azapi_resource.test_1]`,
+	} {
+		expected = strings.Join(strings.Fields(expected), " ")
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected %q in:\n%s", expected, actual)
+		}
+	}
+}
+
+func Test_NewContextInitSovereignCloud(t *testing.T) {
+	if err := cloud.Set(cloud.Environment{Name: "AzureChinaCloud", ResourceManagerEndpoint: "https://management.airgap.example/"}); err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	resource.SetVariableDefaults("", cloud.Current().Location)
+	defer func() {
+		_ = cloud.Set(cloud.Public)
+		resource.SetVariableDefaults("", cloud.Public.Location)
+	}()
+
+	actual := resource.NewContext(nil).String()
+	for _, expected := range []string{
+		`environment\s+= "china"\s+metadata_host\s+= "management.airgap.example"`,
+		`endpoint {\s+resource_manager_endpoint = "https://management.airgap.example"\s+}`,
+		`default = "chinanorth3"`,
+	} {
+		if !regexp.MustCompile(expected).MatchString(actual) {
+			t.Errorf("expect %s in the configuration, but got: %s", expected, actual)
+		}
+	}
+}
@@ -0,0 +1,855 @@
+package resource
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/azure/armstrong/hcl"
+	"github.com/azure/armstrong/resource/types"
+	"github.com/azure/armstrong/utils"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/sirupsen/logrus"
+)
+
+// ArmTemplate is the terraform configuration converted from an ARM template or a Bicep compiled JSON file.
+type ArmTemplate struct {
+	Definitions []types.AzapiDefinition
+	// Variables contains the variable, locals and data blocks converted from the template parameters and variables
+	Variables string
+}
+
+type armTemplateFile struct {
+	Schema     string                  `json:"$schema"`
+	Parameters map[string]armParameter `json:"parameters"`
+	Variables  map[string]interface{}  `json:"variables"`
+	Resources  json.RawMessage         `json:"resources"`
+}
+
+type armParameter struct {
+	Type         string      `json:"type"`
+	DefaultValue interface{} `json:"defaultValue"`
+	Metadata     struct {
+		Description string `json:"description"`
+	} `json:"metadata"`
+}
+
+type armResource struct {
+	SymbolicName string
+	RawName      string
+	Label        string
+	Type         string
+	ApiVersion   string
+	Names        []armString
+	Existing     bool
+	Parent       *armResource
+	Properties   map[string]interface{}
+}
+
+func (r armResource) address() string {
+	if r.Existing {
+		return fmt.Sprintf("data.azapi_resource.%s", r.Label)
+	}
+	return fmt.Sprintf("azapi_resource.%s", r.Label)
+}
+
+// armPart is a part of the string value, it's either a literal or a terraform expression
+type armPart struct {
+	Value        string
+	IsExpression bool
+}
+
+type armString []armPart
+
+func armLiteral(value string) armString {
+	return armString{{Value: value}}
+}
+
+func armExpression(value string) armString {
+	return armString{{Value: value, IsExpression: true}}
+}
+
+func (s armString) append(other armString) armString {
+	out := append(armString{}, s...)
+	for _, part := range other {
+		if len(out) != 0 && !part.IsExpression && !out[len(out)-1].IsExpression {
+			out[len(out)-1].Value += part.Value
+			continue
+		}
+		out = append(out, part)
+	}
+	return out
+}
+
+func (s armString) literal() (string, bool) {
+	switch {
+	case len(s) == 0:
+		return "", true
+	case len(s) == 1 && !s[0].IsExpression:
+		return s[0].Value, true
+	}
+	return "", false
+}
+
+// hcl returns the terraform expression, the expressions in the string are wrapped in a template, e.g., `"${var.prefix}-name"`
+func (s armString) hcl() string {
+	if len(s) == 1 && s[0].IsExpression {
+		return s[0].Value
+	}
+	out := ""
+	for _, part := range s {
+		if part.IsExpression {
+			out += fmt.Sprintf("${%s}", part.Value)
+			continue
+		}
+		value := strings.ReplaceAll(part.Value, "\\", "\\\\")
+		value = strings.ReplaceAll(value, "\"", "\\\"")
+		value = strings.ReplaceAll(value, "\n", "\\n")
+		value = strings.ReplaceAll(value, "${", "$${")
+		value = strings.ReplaceAll(value, "%{", "%%{")
+		out += value
+	}
+	return fmt.Sprintf(`"%s"`, out)
+}
+
+// value returns the literal string, or the terraform expression in the format of `${expression}` which is supported by the hcl marshaller
+func (s armString) value() interface{} {
+	if literal, ok := s.literal(); ok {
+		return literal
+	}
+	return fmt.Sprintf("${%s}", s.hcl())
+}
+
+// split splits the literal parts by the separator, the expression parts are kept as is
+func (s armString) split(sep string) []armString {
+	out := []armString{{}}
+	for _, part := range s {
+		if part.IsExpression {
+			out[len(out)-1] = out[len(out)-1].append(armString{part})
+			continue
+		}
+		for i, segment := range strings.Split(part.Value, sep) {
+			if i != 0 {
+				out = append(out, armString{})
+			}
+			if segment != "" {
+				out[len(out)-1] = out[len(out)-1].append(armLiteral(segment))
+			}
+		}
+	}
+	return out
+}
+
+type armTemplateConverter struct {
+	scope           string
+	parameters      map[string]armParameter
+	variables       map[string]interface{}
+	resources       []*armResource
+	labels          map[string]bool
+	useClientConfig bool
+}
+
+// NewAzapiDefinitionsFromArmTemplate converts the resources in the ARM template to azapi definitions.
+// The `parameters` and `variables` functions are converted to terraform variables or locals, the `resourceId` and `dependsOn` are converted to
+// references when the target resource is defined in the template, otherwise they're converted to resource ids which will be resolved by the context.
+func NewAzapiDefinitionsFromArmTemplate(templatePath string) (*ArmTemplate, error) {
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	var template armTemplateFile
+	if err = json.Unmarshal(data, &template); err != nil {
+		return nil, err
+	}
+
+	c := armTemplateConverter{
+		scope:      "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}",
+		parameters: template.Parameters,
+		variables:  template.Variables,
+		labels:     make(map[string]bool),
+	}
+	switch schema := strings.ToLower(template.Schema); {
+	case strings.Contains(schema, "subscriptiondeploymenttemplate"):
+		c.scope = "/subscriptions/{subscriptionId}"
+	case strings.Contains(schema, "tenantdeploymenttemplate"), strings.Contains(schema, "managementgroupdeploymenttemplate"):
+		c.scope = ""
+	}
+
+	if len(template.Resources) != 0 {
+		// resources are defined as an array, or a map whose keys are the symbolic names in the Bicep compiled JSON
+		var resourceList []map[string]interface{}
+		var resourceMap map[string]map[string]interface{}
+		switch {
+		case json.Unmarshal(template.Resources, &resourceList) == nil:
+			for _, raw := range resourceList {
+				c.loadResource("", raw, nil)
+			}
+		case json.Unmarshal(template.Resources, &resourceMap) == nil:
+			names := make([]string, 0)
+			for name := range resourceMap {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				c.loadResource(name, resourceMap[name], nil)
+			}
+		default:
+			return nil, fmt.Errorf("invalid resources in the template %s", templatePath)
+		}
+	}
+
+	out := ArmTemplate{
+		Definitions: make([]types.AzapiDefinition, 0),
+	}
+	for _, r := range c.resources {
+		out.Definitions = append(out.Definitions, c.azapiDefinition(*r))
+	}
+	out.Variables = c.variablesHcl()
+	return &out, nil
+}
+
+func (c *armTemplateConverter) loadResource(symbolicName string, raw map[string]interface{}, parent *armResource) {
+	r := armResource{
+		SymbolicName: symbolicName,
+		Parent:       parent,
+		Properties:   raw,
+	}
+	r.Type, _ = raw["type"].(string)
+	r.ApiVersion, _ = raw["apiVersion"].(string)
+	r.RawName, _ = raw["name"].(string)
+	r.Existing, _ = raw["existing"].(bool)
+	if parent != nil && !strings.Contains(strings.Split(r.Type, "/")[0], ".") {
+		r.Type = fmt.Sprintf("%s/%s", parent.Type, r.Type)
+	}
+	if r.Type == "" || r.ApiVersion == "" {
+		logrus.Warnf("skip the template resource %s %s, because its type or apiVersion is not specified", symbolicName, r.RawName)
+		return
+	}
+
+	name, err := c.translateString(r.RawName)
+	if err != nil {
+		logrus.Warnf("%+v, the name is kept as is", err)
+		name = armLiteral(r.RawName)
+	}
+	r.Names = name.split("/")
+	if depth := len(strings.Split(r.Type, "/")) - 1; parent != nil && len(r.Names) < depth {
+		r.Names = append(append([]armString{}, parent.Names...), r.Names...)
+	}
+
+	label := defaultLabel(r.Type)
+	if symbolicName != "" {
+		label = armIdentifier(symbolicName)
+	}
+	r.Label = label
+	for i := 1; c.labels[r.Label]; i++ {
+		r.Label = fmt.Sprintf("%s_%d", label, i)
+	}
+	c.labels[r.Label] = true
+	c.resources = append(c.resources, &r)
+
+	if children, ok := raw["resources"].([]interface{}); ok {
+		for _, child := range children {
+			if childMap, ok := child.(map[string]interface{}); ok {
+				c.loadResource("", childMap, &r)
+			}
+		}
+	}
+}
+
+func (c *armTemplateConverter) azapiDefinition(r armResource) types.AzapiDefinition {
+	for _, key := range []string{"condition", "copy"} {
+		if _, ok := r.Properties[key]; ok {
+			logrus.Warnf("`%s` of the template resource %s is not supported, it's ignored", key, r.Label)
+		}
+	}
+
+	def := types.AzapiDefinition{
+		Id:                c.resourceId(r),
+		Kind:              types.KindResource,
+		ResourceName:      "azapi_resource",
+		Label:             r.Label,
+		AzureResourceType: r.Type,
+		ApiVersion:        r.ApiVersion,
+		BodyFormat:        types.BodyFormatHcl,
+		AdditionalFields:  make(map[string]types.Value),
+		LeadingComments: []string{
+			fmt.Sprintf("ARM template resource: %s", r.RawName),
+		},
+	}
+	if r.Existing {
+		def.Kind = types.KindDataSource
+	}
+
+	// parent_id
+	switch {
+	case r.Parent != nil:
+		def.AdditionalFields["parent_id"] = types.NewReferenceValue(r.Parent.address() + ".id")
+	case r.Properties["scope"] != nil:
+		def.AdditionalFields["parent_id"] = c.fieldValue(r.Properties["scope"])
+	default:
+		def.AdditionalFields["parent_id"] = types.NewStringLiteralValue(utils.ParentIdOfResourceId(def.Id))
+		if len(r.Names) > 1 {
+			parentType := r.Type[:strings.LastIndex(r.Type, "/")]
+			if parent := c.findResource(parentType, r.Names[:len(r.Names)-1]); parent != nil {
+				def.AdditionalFields["parent_id"] = types.NewReferenceValue(parent.address() + ".id")
+			}
+		}
+	}
+
+	// name
+	if len(r.Names) != 0 {
+		name := r.Names[len(r.Names)-1]
+		if literal, ok := name.literal(); ok {
+			def.AdditionalFields["name"] = types.NewStringLiteralValue(literal)
+		} else {
+			def.AdditionalFields["name"] = types.NewRawValue(name.hcl())
+		}
+	}
+
+	if r.Existing {
+		return def
+	}
+
+	if location, ok := r.Properties["location"]; ok {
+		def.AdditionalFields["location"] = c.fieldValue(location)
+	}
+
+	body := make(map[string]interface{})
+	for key, value := range r.Properties {
+		switch key {
+		case "type", "apiVersion", "name", "location", "dependsOn", "resources", "condition", "copy", "comments", "scope", "metadata", "existing":
+			continue
+		}
+		body[key] = c.convertValue(value)
+	}
+	if len(body) != 0 {
+		def.Body = body
+	}
+
+	dependsOn := make([]string, 0)
+	dependsOnMap := make(map[string]bool)
+	if r.Parent != nil {
+		dependsOnMap[r.Parent.address()] = true
+	}
+	dependsOnList, _ := r.Properties["dependsOn"].([]interface{})
+	for _, item := range dependsOnList {
+		value, ok := item.(string)
+		if !ok {
+			continue
+		}
+		address := c.dependsOnAddress(value)
+		if address == "" {
+			logrus.Warnf("dependency %s of the template resource %s is not found in the template, it's ignored", value, r.Label)
+			continue
+		}
+		if dependsOnMap[address] {
+			continue
+		}
+		dependsOnMap[address] = true
+		dependsOn = append(dependsOn, address)
+	}
+
+	def.AdditionalFields["schema_validation_enabled"] = types.NewRawValue("false")
+	if len(dependsOn) != 0 {
+		def.AdditionalFields["depends_on"] = types.NewRawValue(fmt.Sprintf("[%s]", strings.Join(dependsOn, ", ")))
+	}
+	return def
+}
+
+// resourceId returns the resource id of the template resource, the name segments which are expressions are replaced with placeholders
+func (c *armTemplateConverter) resourceId(r armResource) string {
+	scope := c.scope
+	if value, ok := r.Properties["scope"].(string); ok {
+		if translated, err := c.translateString(value); err == nil {
+			if literal, ok := translated.literal(); ok {
+				scope = literal
+			}
+		}
+	}
+	return c.mockResourceId(scope, r.Type, r.Names)
+}
+
+func (c *armTemplateConverter) mockResourceId(scope string, resourceType string, names []armString) string {
+	typeSegments := strings.Split(resourceType, "/")
+	out := fmt.Sprintf("%s/providers/%s", strings.TrimSuffix(scope, "/"), typeSegments[0])
+	for i, segment := range typeSegments[1:] {
+		name := fmt.Sprintf("{%sName}", defaultLabel(segment))
+		if i < len(names) {
+			if literal, ok := names[i].literal(); ok && literal != "" {
+				name = literal
+			}
+		}
+		out += fmt.Sprintf("/%s/%s", segment, name)
+	}
+	return out
+}
+
+// findResource returns the template resource with the given type and names, if there's only one resource with the given type, it's returned
+func (c *armTemplateConverter) findResource(resourceType string, names []armString) *armResource {
+	candidates := make([]*armResource, 0)
+	for _, r := range c.resources {
+		if !strings.EqualFold(r.Type, resourceType) {
+			continue
+		}
+		candidates = append(candidates, r)
+		if len(r.Names) != len(names) {
+			continue
+		}
+		matched := true
+		for i := range names {
+			if !strings.EqualFold(r.Names[i].hcl(), names[i].hcl()) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return r
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0]
+	}
+	return nil
+}
+
+func (c *armTemplateConverter) dependsOnAddress(input string) string {
+	if isArmExpression(input) {
+		value, err := c.translateString(input)
+		if err != nil {
+			logrus.Warnf("%+v", err)
+			return ""
+		}
+		if len(value) == 1 && value[0].IsExpression && strings.HasSuffix(value[0].Value, ".id") {
+			return strings.TrimSuffix(value[0].Value, ".id")
+		}
+		return ""
+	}
+	for _, r := range c.resources {
+		if r.SymbolicName != "" && r.SymbolicName == input {
+			return r.address()
+		}
+	}
+	for _, r := range c.resources {
+		if strings.EqualFold(r.RawName, input) || strings.EqualFold(fmt.Sprintf("%s/%s", r.Type, r.RawName), input) {
+			return r.address()
+		}
+	}
+	return ""
+}
+
+// fieldValue converts the template value to the value of the azapi resource fields like `location`
+func (c *armTemplateConverter) fieldValue(input interface{}) types.Value {
+	value := c.convertValue(input)
+	if literal, ok := value.(string); ok {
+		if strings.HasPrefix(literal, "${") && strings.HasSuffix(literal, "}") {
+			return types.NewRawValue(literal[2 : len(literal)-1])
+		}
+		return types.NewStringLiteralValue(literal)
+	}
+	return types.NewRawValue(hcl.MarshalIndent(value, "  ", "  "))
+}
+
+// convertValue converts the expressions in the template value to terraform expressions
+func (c *armTemplateConverter) convertValue(input interface{}) interface{} {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for key, value := range v {
+			out[key] = c.convertValue(value)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0)
+		for _, value := range v {
+			out = append(out, c.convertValue(value))
+		}
+		return out
+	case string:
+		value, err := c.translateString(v)
+		if err != nil {
+			logrus.Warnf("%+v, the expression is kept as is", err)
+			return v
+		}
+		return value.value()
+	}
+	return input
+}
+
+func (c *armTemplateConverter) translateString(input string) (armString, error) {
+	switch {
+	case strings.HasPrefix(input, "[["):
+		return armLiteral(input[1:]), nil
+	case !isArmExpression(input):
+		return armLiteral(input), nil
+	}
+	node, err := parseArmExpression(input[1 : len(input)-1])
+	if err != nil {
+		return nil, err
+	}
+	return c.translate(*node)
+}
+
+func (c *armTemplateConverter) translate(node armNode) (armString, error) {
+	var out armString
+	accessors := node.Accessors
+	switch {
+	case node.Literal != nil:
+		out = armLiteral(*node.Literal)
+	case node.Number != "":
+		out = armExpression(node.Number)
+	default:
+		value, consumed, err := c.translateFunction(node)
+		if err != nil {
+			return nil, err
+		}
+		out = value
+		accessors = accessors[consumed:]
+	}
+	if len(accessors) == 0 {
+		return out, nil
+	}
+
+	if len(out) != 1 || !out[0].IsExpression {
+		return nil, fmt.Errorf("property accessors of function %s are not supported", node.Function)
+	}
+	expression := out[0].Value
+	for _, accessor := range accessors {
+		if accessor.Index == nil {
+			expression += "." + accessor.Property
+			continue
+		}
+		index, err := c.translate(*accessor.Index)
+		if err != nil {
+			return nil, err
+		}
+		expression += fmt.Sprintf("[%s]", index.hcl())
+	}
+	return armExpression(expression), nil
+}
+
+// translateFunction translates the function call, it returns the number of accessors which are consumed by the function
+func (c *armTemplateConverter) translateFunction(node armNode) (armString, int, error) {
+	args := make([]armString, 0)
+	for _, arg := range node.Args {
+		value, err := c.translate(arg)
+		if err != nil {
+			return nil, 0, err
+		}
+		args = append(args, value)
+	}
+	property := ""
+	if len(node.Accessors) != 0 {
+		property = strings.ToLower(node.Accessors[0].Property)
+	}
+
+	switch function := strings.ToLower(node.Function); function {
+	case "parameters", "variables":
+		if len(args) != 1 {
+			return nil, 0, fmt.Errorf("function %s expects 1 argument", node.Function)
+		}
+		name, ok := args[0].literal()
+		if !ok {
+			return nil, 0, fmt.Errorf("function %s expects a string literal", node.Function)
+		}
+		if function == "parameters" {
+			return armExpression(c.parameterReference(name)), 0, nil
+		}
+		return armExpression(c.variableReference(name)), 0, nil
+	case "concat":
+		out := armString{}
+		for _, arg := range args {
+			out = out.append(arg)
+		}
+		return out, 0, nil
+	case "format":
+		if len(args) == 0 {
+			return nil, 0, fmt.Errorf("function format expects at least 1 argument")
+		}
+		format, ok := args[0].literal()
+		if !ok {
+			return nil, 0, fmt.Errorf("function format expects a string literal as the format")
+		}
+		out, err := armFormat(format, args[1:])
+		return out, 0, err
+	case "resourceid", "subscriptionresourceid", "tenantresourceid", "extensionresourceid":
+		out, err := c.translateResourceId(function, args)
+		return out, 0, err
+	case "resourcegroup":
+		switch property {
+		case "location":
+			return armExpression("var.location"), 1, nil
+		case "id":
+			return armLiteral("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}"), 1, nil
+		}
+	case "subscription":
+		c.useClientConfig = true
+		switch property {
+		case "subscriptionid":
+			return armExpression("data.azapi_client_config.current.subscription_id"), 1, nil
+		case "tenantid":
+			return armExpression("data.azapi_client_config.current.tenant_id"), 1, nil
+		case "id":
+			return armLiteral("/subscriptions/").append(armExpression("data.azapi_client_config.current.subscription_id")), 1, nil
+		}
+	case "tenant":
+		if property == "tenantid" {
+			c.useClientConfig = true
+			return armExpression("data.azapi_client_config.current.tenant_id"), 1, nil
+		}
+	case "uniquestring":
+		return armExpression("var.resource_name"), 0, nil
+	case "guid":
+		// the guid is generated from the arguments, so that it's stable across the generations
+		seed := make([]string, 0)
+		for _, arg := range args {
+			seed = append(seed, arg.hcl())
+		}
+		h := md5.Sum([]byte(strings.Join(seed, ",")))
+		return armLiteral(fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])), 0, nil
+	case "tolower", "toupper":
+		if len(args) != 1 {
+			return nil, 0, fmt.Errorf("function %s expects 1 argument", node.Function)
+		}
+		if literal, ok := args[0].literal(); ok {
+			if function == "tolower" {
+				return armLiteral(strings.ToLower(literal)), 0, nil
+			}
+			return armLiteral(strings.ToUpper(literal)), 0, nil
+		}
+		return armExpression(fmt.Sprintf("%s(%s)", strings.TrimPrefix(function, "to"), args[0].hcl())), 0, nil
+	case "string":
+		if len(args) == 1 {
+			return args[0], 0, nil
+		}
+	case "true", "false":
+		return armExpression(function), 0, nil
+	}
+	return nil, 0, fmt.Errorf("function %s is not supported", node.Function)
+}
+
+func (c *armTemplateConverter) translateResourceId(function string, args []armString) (armString, error) {
+	scope := armLiteral(c.scope)
+	switch function {
+	case "subscriptionresourceid":
+		scope = armLiteral("/subscriptions/{subscriptionId}")
+	case "tenantresourceid":
+		scope = armLiteral("")
+	case "extensionresourceid":
+		if len(args) < 1 {
+			return nil, fmt.Errorf("function extensionResourceId expects at least 2 arguments")
+		}
+		scope = args[0]
+		args = args[1:]
+	}
+
+	// the optional subscription id and resource group name are before the resource type
+	typeIndex := -1
+	for i, arg := range args {
+		if literal, ok := arg.literal(); ok && strings.Contains(literal, "/") {
+			typeIndex = i
+			break
+		}
+	}
+	if typeIndex == -1 {
+		return nil, fmt.Errorf("function %s expects a resource type", function)
+	}
+	resourceType, _ := args[typeIndex].literal()
+	names := make([]armString, 0)
+	for _, arg := range args[typeIndex+1:] {
+		names = append(names, arg.split("/")...)
+	}
+
+	if r := c.findResource(resourceType, names); r != nil {
+		return armExpression(r.address() + ".id"), nil
+	}
+	if literal, ok := scope.literal(); ok {
+		return armLiteral(c.mockResourceId(literal, resourceType, names)), nil
+	}
+	return scope.append(armLiteral(c.mockResourceId("", resourceType, names))), nil
+}
+
+func (c *armTemplateConverter) parameterReference(name string) string {
+	identifier := armIdentifier(name)
+	if isReservedVariable(identifier) {
+		return fmt.Sprintf("var.%s", identifier)
+	}
+	if parameter, ok := c.parameters[name]; ok && armContainsExpression(parameter.DefaultValue) {
+		return fmt.Sprintf("local.%s", identifier)
+	}
+	return fmt.Sprintf("var.%s", identifier)
+}
+
+func (c *armTemplateConverter) variableReference(name string) string {
+	identifier := c.variableIdentifier(name)
+	if value, ok := c.variables[name]; ok && armContainsExpression(value) {
+		return fmt.Sprintf("local.%s", identifier)
+	}
+	return fmt.Sprintf("var.%s", identifier)
+}
+
+// variableIdentifier returns the identifier of the template variable, the parameters and the variables are in different namespaces in the template,
+// but they're both converted to the terraform variables and locals, so the variable is prefixed with "variable_" if its identifier is used by a parameter
+func (c *armTemplateConverter) variableIdentifier(name string) string {
+	identifier := armIdentifier(name)
+	used := func(identifier string) bool {
+		if isReservedVariable(identifier) {
+			return true
+		}
+		for parameterName := range c.parameters {
+			if armIdentifier(parameterName) == identifier {
+				return true
+			}
+		}
+		return false
+	}
+	for used(identifier) {
+		identifier = "variable_" + identifier
+	}
+	return identifier
+}
+
+// variablesHcl returns the variable blocks for the parameters and variables which have literal values,
+// and the locals block for the ones whose values contain expressions
+func (c *armTemplateConverter) variablesHcl() string {
+	blocks := make([]string, 0)
+	locals := make([]string, 0)
+
+	names := make([]string, 0)
+	for name := range c.parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		parameter := c.parameters[name]
+		identifier := armIdentifier(name)
+		switch {
+		case isReservedVariable(identifier):
+			continue
+		case armContainsExpression(parameter.DefaultValue):
+			locals = append(locals, fmt.Sprintf("  %s = %s", identifier, hcl.MarshalIndent(c.convertValue(parameter.DefaultValue), "  ", "  ")))
+			continue
+		}
+		attributes := []string{fmt.Sprintf("  type = %s", armParameterType(parameter.Type))}
+		if parameter.DefaultValue != nil {
+			attributes = append(attributes, fmt.Sprintf("  default = %s", hcl.MarshalIndent(parameter.DefaultValue, "  ", "  ")))
+		}
+		if parameter.Metadata.Description != "" {
+			attributes = append(attributes, fmt.Sprintf("  description = %s", hcl.MarshalIndent(parameter.Metadata.Description, "  ", "  ")))
+		}
+		if strings.HasPrefix(strings.ToLower(parameter.Type), "secure") {
+			attributes = append(attributes, "  sensitive = true")
+		}
+		blocks = append(blocks, fmt.Sprintf("variable %q {\n%s\n}\n", identifier, strings.Join(attributes, "\n")))
+	}
+
+	names = make([]string, 0)
+	for name := range c.variables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := c.variables[name]
+		identifier := c.variableIdentifier(name)
+		if armContainsExpression(value) {
+			locals = append(locals, fmt.Sprintf("  %s = %s", identifier, hcl.MarshalIndent(c.convertValue(value), "  ", "  ")))
+			continue
+		}
+		blocks = append(blocks, fmt.Sprintf("variable %q {\n  default = %s\n}\n", identifier, hcl.MarshalIndent(value, "  ", "  ")))
+	}
+
+	if len(locals) != 0 {
+		blocks = append(blocks, fmt.Sprintf("locals {\n%s\n}\n", strings.Join(locals, "\n")))
+	}
+	if c.useClientConfig {
+		blocks = append(blocks, "data \"azapi_client_config\" \"current\" {}\n")
+	}
+	return string(hclwrite.Format([]byte(strings.Join(blocks, "\n"))))
+}
+
+// armFormat converts the format function, e.g., `format('{0}-{1}', 'a', parameters('b'))`
+func armFormat(format string, args []armString) (armString, error) {
+	out := armString{}
+	literal := ""
+	for i := 0; i < len(format); i++ {
+		switch {
+		case strings.HasPrefix(format[i:], "{{"), strings.HasPrefix(format[i:], "}}"):
+			literal += format[i : i+1]
+			i++
+		case format[i] == '{':
+			end := strings.Index(format[i:], "}")
+			if end == -1 {
+				return nil, fmt.Errorf("invalid format %q", format)
+			}
+			index, err := strconv.Atoi(strings.Split(format[i+1:i+end], ":")[0])
+			if err != nil || index < 0 || index >= len(args) {
+				return nil, fmt.Errorf("invalid format %q", format)
+			}
+			out = out.append(armLiteral(literal)).append(args[index])
+			literal = ""
+			i += end
+		default:
+			literal += format[i : i+1]
+		}
+	}
+	return out.append(armLiteral(literal)), nil
+}
+
+func armContainsExpression(input interface{}) bool {
+	switch v := input.(type) {
+	case map[string]interface{}:
+		for _, value := range v {
+			if armContainsExpression(value) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, value := range v {
+			if armContainsExpression(value) {
+				return true
+			}
+		}
+	case string:
+		return isArmExpression(v)
+	}
+	return false
+}
+
+func armParameterType(input string) string {
+	switch strings.ToLower(input) {
+	case "string", "securestring":
+		return "string"
+	case "int":
+		return "number"
+	case "bool":
+		return "bool"
+	case "array":
+		return "list(any)"
+	}
+	return "any"
+}
+
+// armIdentifier converts the name to a valid terraform identifier
+func armIdentifier(input string) string {
+	out := ""
+	for _, c := range input {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '-':
+			out += string(c)
+		default:
+			out += "_"
+		}
+	}
+	if out == "" || (out[0] >= '0' && out[0] <= '9') || out[0] == '-' {
+		out = "_" + out
+	}
+	return out
+}
+
+// isReservedVariable returns true if the variable is defined in the default provider configuration
+func isReservedVariable(input string) bool {
+	return input == "location" || input == "resource_name"
+}
@@ -0,0 +1,226 @@
+package resource_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/resource"
+	"github.com/azure/armstrong/resource/types"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+)
+
+func Test_NewAzapiDefinitionsFromArmTemplate(t *testing.T) {
+	testcases := []struct {
+		Input         string
+		Want          []types.AzapiDefinition
+		WantVariables []string
+	}{
+		{
+			Input: "testdata/arm_templates/network.json",
+			Want: []types.AzapiDefinition{
+				{
+					Id:                "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{virtualNetworkName}",
+					Kind:              types.KindResource,
+					ResourceName:      "azapi_resource",
+					Label:             "virtualNetwork",
+					AzureResourceType: "Microsoft.Network/virtualNetworks",
+					ApiVersion:        "2022-07-01",
+					BodyFormat:        types.BodyFormatHcl,
+					Body: map[string]interface{}{
+						"properties": map[string]interface{}{
+							"addressSpace": map[string]interface{}{
+								"addressPrefixes": []interface{}{"${var.addressPrefix}"},
+							},
+						},
+					},
+					AdditionalFields: map[string]types.Value{
+						"parent_id":                 types.NewStringLiteralValue("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}"),
+						"name":                      types.NewRawValue("local.vnetName"),
+						"location":                  types.NewRawValue("var.location"),
+						"schema_validation_enabled": types.NewRawValue("false"),
+					},
+					LeadingComments: []string{"ARM template resource: [variables('vnetName')]"},
+				},
+				{
+					Id:                "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/virtualNetworks/{virtualNetworkName}/subnets/default",
+					Kind:              types.KindResource,
+					ResourceName:      "azapi_resource",
+					Label:             "subnet",
+					AzureResourceType: "Microsoft.Network/virtualNetworks/subnets",
+					ApiVersion:        "2022-07-01",
+					BodyFormat:        types.BodyFormatHcl,
+					Body: map[string]interface{}{
+						"properties": map[string]interface{}{
+							"addressPrefix": "10.0.0.0/24",
+							"networkSecurityGroup": map[string]interface{}{
+								"id": "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/networkSecurityGroups/nsg1",
+							},
+						},
+					},
+					AdditionalFields: map[string]types.Value{
+						"parent_id":                 types.NewReferenceValue("azapi_resource.virtualNetwork.id"),
+						"name":                      types.NewStringLiteralValue("default"),
+						"schema_validation_enabled": types.NewRawValue("false"),
+					},
+					LeadingComments: []string{"ARM template resource: default"},
+				},
+				{
+					Id:                "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Network/networkInterfaces/{networkInterfaceName}",
+					Kind:              types.KindResource,
+					ResourceName:      "azapi_resource",
+					Label:             "networkInterface",
+					AzureResourceType: "Microsoft.Network/networkInterfaces",
+					ApiVersion:        "2022-07-01",
+					BodyFormat:        types.BodyFormatHcl,
+					Body: map[string]interface{}{
+						"properties": map[string]interface{}{
+							"ipConfigurations": []interface{}{
+								map[string]interface{}{
+									"name": "ipconfig1",
+									"properties": map[string]interface{}{
+										"privateIPAllocationMethod": "Dynamic",
+										"subnet": map[string]interface{}{
+											"id": "${azapi_resource.subnet.id}",
+										},
+									},
+								},
+							},
+						},
+					},
+					AdditionalFields: map[string]types.Value{
+						"parent_id":                 types.NewStringLiteralValue("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}"),
+						"name":                      types.NewRawValue(`"${var.prefix}-nic"`),
+						"location":                  types.NewRawValue("var.location"),
+						"schema_validation_enabled": types.NewRawValue("false"),
+						"depends_on":                types.NewRawValue("[azapi_resource.subnet]"),
+					},
+					LeadingComments: []string{"ARM template resource: [concat(parameters('prefix'), '-nic')]"},
+				},
+			},
+			WantVariables: []string{
+				`variable "adminPassword" { type = string sensitive = true }`,
+				`variable "prefix" { type = string default = "acctest" description = "The prefix of the resource names." }`,
+				`variable "addressPrefix" { default = "10.0.0.0/16" }`,
+				`locals { vnetName = "${var.prefix}-vnet" }`,
+			},
+		},
+		{
+			Input: "testdata/arm_templates/symbolic.json",
+			Want: []types.AzapiDefinition{
+				{
+					Id:                "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Storage/storageAccounts/{storageAccountName}",
+					Kind:              types.KindResource,
+					ResourceName:      "azapi_resource",
+					Label:             "account",
+					AzureResourceType: "Microsoft.Storage/storageAccounts",
+					ApiVersion:        "2023-01-01",
+					BodyFormat:        types.BodyFormatHcl,
+					Body: map[string]interface{}{
+						"kind": "StorageV2",
+						"sku": map[string]interface{}{
+							"name": "Standard_LRS",
+						},
+					},
+					AdditionalFields: map[string]types.Value{
+						"parent_id":                 types.NewStringLiteralValue("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}"),
+						"name":                      types.NewRawValue("lower(var.name)"),
+						"location":                  types.NewStringLiteralValue("westus"),
+						"schema_validation_enabled": types.NewRawValue("false"),
+					},
+					LeadingComments: []string{"ARM template resource: [toLower(parameters('name'))]"},
+				},
+				{
+					Id:                "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Storage/storageAccounts/{storageAccountName}/blobServices/default/containers/data",
+					Kind:              types.KindResource,
+					ResourceName:      "azapi_resource",
+					Label:             "container",
+					AzureResourceType: "Microsoft.Storage/storageAccounts/blobServices/containers",
+					ApiVersion:        "2023-01-01",
+					BodyFormat:        types.BodyFormatHcl,
+					Body: map[string]interface{}{
+						"properties": map[string]interface{}{
+							"metadata": map[string]interface{}{
+								"tenant": "${data.azapi_client_config.current.tenant_id}",
+							},
+						},
+					},
+					AdditionalFields: map[string]types.Value{
+						"parent_id":                 types.NewStringLiteralValue("/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Storage/storageAccounts/{storageAccountName}/blobServices/default"),
+						"name":                      types.NewStringLiteralValue("data"),
+						"schema_validation_enabled": types.NewRawValue("false"),
+						"depends_on":                types.NewRawValue("[azapi_resource.account]"),
+					},
+					LeadingComments: []string{"ARM template resource: [format('{0}/default/{1}', toLower(parameters('name')), 'data')]"},
+				},
+			},
+			WantVariables: []string{
+				`variable "name" { type = string }`,
+				`data "azapi_client_config" "current" {}`,
+			},
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("[DEBUG] testcase: %+v", testcase.Input)
+		got, err := resource.NewAzapiDefinitionsFromArmTemplate(testcase.Input)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if !reflect.DeepEqual(got.Definitions, testcase.Want) {
+			t.Fatalf("expected %+v, got %+v", testcase.Want, got.Definitions)
+		}
+		variables := strings.Join(strings.Fields(got.Variables), " ")
+		for _, expected := range testcase.WantVariables {
+			if !strings.Contains(variables, expected) {
+				t.Errorf("expected %q in variables:\n%s", expected, got.Variables)
+			}
+		}
+	}
+}
+
+func Test_NewAzapiDefinitionsFromArmTemplate_DuplicateNames(t *testing.T) {
+	got, err := resource.NewAzapiDefinitionsFromArmTemplate("testdata/arm_templates/duplicate_names.json")
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(got.Definitions) != 1 {
+		t.Fatalf("expected 1 definition, got %+v", got.Definitions)
+	}
+
+	// the parameters and variables of the same names are converted to different variables and locals
+	file, diags := hclsyntax.ParseConfig([]byte(got.Variables), "variables.tf", hcl.InitialPos)
+	if diags.HasErrors() {
+		t.Fatalf("parsing variables: %+v", diags)
+	}
+	names := make(map[string]bool)
+	for _, block := range file.Body.(*hclsyntax.Body).Blocks {
+		if block.Type == "variable" {
+			if names[block.Labels[0]] {
+				t.Errorf("duplicate variable %s in variables:\n%s", block.Labels[0], got.Variables)
+			}
+			names[block.Labels[0]] = true
+		}
+	}
+	variables := strings.Join(strings.Fields(got.Variables), " ")
+	for _, expected := range []string{
+		`variable "sku" { type = string default = "Standard_LRS" }`,
+		`variable "variable_sku" { default = "Premium_LRS" }`,
+		`variable "variable_location" { default = "westus" }`,
+		`variable_storageName = "${var.storageName}sa"`,
+	} {
+		if !strings.Contains(variables, expected) {
+			t.Errorf("expected %q in variables:\n%s", expected, got.Variables)
+		}
+	}
+
+	fields := got.Definitions[0].AdditionalFields
+	if fields["name"] != types.NewRawValue("local.variable_storageName") || fields["location"] != types.NewRawValue("var.variable_location") {
+		t.Errorf("expected the references to the prefixed variables, got %+v", fields)
+	}
+	sku := got.Definitions[0].Body.(map[string]interface{})["sku"].(map[string]interface{})
+	if sku["name"] != "${var.variable_sku}" {
+		t.Errorf("expected the reference to the prefixed variable, got %+v", sku)
+	}
+}
@@ -0,0 +1,368 @@
+package resource
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+)
+
+// ProvenancePrefix is the prefix of the comment which marks a block as generated by armstrong.
+// The comment records the checksum of each generated attribute, e.g., `// armstrong:generated body=1a2b3c4d name=5e6f7a8b`,
+// so that the user changes can be detected when the configuration is regenerated.
+const ProvenancePrefix = "armstrong:generated"
+
+type MergeConflict struct {
+	Address   string
+	Attribute string
+	Message   string
+}
+
+func (c MergeConflict) String() string {
+	if c.Attribute == "" {
+		return fmt.Sprintf("%s: %s", c.Address, c.Message)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.Address, c.Attribute, c.Message)
+}
+
+// AddProvenance adds the provenance comment to all the resource and data blocks in the input configuration.
+func AddProvenance(input string) (string, error) {
+	file, diags := hclwrite.ParseConfig([]byte(input), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", diags
+	}
+	blocks := make([]string, 0)
+	for _, block := range file.Body().Blocks() {
+		provenance := ""
+		if isProvenanceBlock(block) {
+			provenance = newProvenance(block.Body())
+		}
+		blocks = append(blocks, blockString(block, provenance))
+	}
+	return string(hclwrite.Format([]byte(strings.Join(blocks, "\n")))), nil
+}
+
+// MergeConfig merges the newly generated configuration into the existing configuration.
+// The generated blocks and attributes which are not modified by the user are updated in place,
+// the blocks and attributes added by the user are kept. When the user modified a generated attribute
+// and the newly generated value is different, the user's value is kept and a conflict is returned.
+// If the existing configuration has no provenance comments, its blocks of the generated addresses are merged as generated blocks.
+func MergeConfig(existing string, generated string) (string, []MergeConflict, error) {
+	existingFile, diags := hclwrite.ParseConfig([]byte(existing), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", nil, fmt.Errorf("parsing existing configuration: %+v", diags)
+	}
+	generatedFile, diags := hclwrite.ParseConfig([]byte(generated), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", nil, fmt.Errorf("parsing generated configuration: %+v", diags)
+	}
+
+	generatedBlockMap := make(map[string]*hclwrite.Block)
+	for _, block := range generatedFile.Body().Blocks() {
+		generatedBlockMap[blockKey(block)] = block
+	}
+
+	// the configuration without any provenance comment is generated before the provenance is recorded, it's the first merge,
+	// so the blocks which have the same addresses as the generated blocks are treated as generated, and all their attributes as modified
+	isFirstMerge := true
+	for _, block := range existingFile.Body().Blocks() {
+		if _, ok := parseProvenance(block); ok {
+			isFirstMerge = false
+			break
+		}
+	}
+
+	conflicts := make([]MergeConflict, 0)
+	existingBlockMap := make(map[string]bool)
+	blocks := make([]string, 0)
+	for _, block := range existingFile.Body().Blocks() {
+		key := blockKey(block)
+		existingBlockMap[key] = true
+		provenance, isGenerated := parseProvenance(block)
+		generatedBlock := generatedBlockMap[key]
+		if isFirstMerge && generatedBlock != nil {
+			provenance, isGenerated = map[string]string{}, true
+		}
+
+		switch {
+		case !isProvenanceBlock(block):
+			// terraform, provider, variable and other blocks are kept as is
+			blocks = append(blocks, blockString(block, ""))
+		case generatedBlock == nil:
+			if isGenerated {
+				conflicts = append(conflicts, MergeConflict{
+					Address: key,
+					Message: "the block is no longer generated, it's kept as is",
+				})
+			}
+			blocks = append(blocks, blockString(block, ""))
+		case !isGenerated:
+			conflicts = append(conflicts, MergeConflict{
+				Address: key,
+				Message: "the block is not generated by armstrong but has the same address as a generated block, it's kept as is",
+			})
+			blocks = append(blocks, blockString(block, ""))
+		default:
+			conflicts = append(conflicts, mergeBody(key, block.Body(), generatedBlock.Body(), provenance, "")...)
+			blocks = append(blocks, blockString(block, newProvenance(generatedBlock.Body())))
+		}
+	}
+
+	for _, block := range generatedFile.Body().Blocks() {
+		if existingBlockMap[blockKey(block)] {
+			continue
+		}
+		blocks = append(blocks, blockString(block, ""))
+	}
+
+	return string(hclwrite.Format([]byte(strings.Join(blocks, "\n")))), conflicts, nil
+}
+
+// mergeBody merges the generated attributes and nested blocks into the existing body, the nested blocks are merged recursively.
+// The provenance keys of the attributes in the nested blocks are prefixed with the nested block keys, e.g., identity.type,
+// and the nested blocks themselves are recorded with the "{}" suffix, e.g., identity{}.
+func mergeBody(address string, existing *hclwrite.Body, generated *hclwrite.Body, provenance map[string]string, prefix string) []MergeConflict {
+	conflicts := make([]MergeConflict, 0)
+	generatedAttrs := generated.Attributes()
+	existingAttrs := existing.Attributes()
+
+	names := make([]string, 0)
+	for name := range generatedAttrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		generatedAttr := generatedAttrs[name]
+		existingAttr, ok := existingAttrs[name]
+		if !ok {
+			// the attribute is removed by the user, keep it removed
+			if _, isGenerated := provenance[prefix+name]; isGenerated {
+				continue
+			}
+			existing.SetAttributeRaw(name, generatedAttr.Expr().BuildTokens(nil))
+			continue
+		}
+
+		existingValue := expressionString(existingAttr.Expr())
+		generatedValue := expressionString(generatedAttr.Expr())
+		if existingValue == generatedValue {
+			continue
+		}
+		if checksum, isGenerated := provenance[prefix+name]; isGenerated && checksum == expressionChecksum(existingAttr.Expr()) {
+			existing.SetAttributeRaw(name, generatedAttr.Expr().BuildTokens(nil))
+			continue
+		}
+		conflicts = append(conflicts, MergeConflict{
+			Address:   address,
+			Attribute: prefix + name,
+			Message:   fmt.Sprintf("the attribute is modified, the user's value is kept, the generated value is: %s", generatedValue),
+		})
+	}
+
+	// remove the attributes which are no longer generated and not modified by the user
+	for key, checksum := range provenance {
+		name := strings.TrimPrefix(key, prefix)
+		if !strings.HasPrefix(key, prefix) || strings.ContainsAny(name, ".{") {
+			continue
+		}
+		if _, ok := generatedAttrs[name]; ok {
+			continue
+		}
+		if existingAttr, ok := existingAttrs[name]; ok && checksum == expressionChecksum(existingAttr.Expr()) {
+			existing.RemoveAttribute(name)
+		}
+	}
+
+	existingBlocks, existingDuplicates := nestedBlocks(existing)
+	generatedBlocks, generatedDuplicates := nestedBlocks(generated)
+	keys := make([]string, 0)
+	for key := range generatedBlocks {
+		keys = append(keys, key)
+	}
+	for key := range existingDuplicates {
+		if _, ok := generatedBlocks[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for key := range generatedDuplicates {
+		if _, ok := generatedBlocks[key]; !ok && !existingDuplicates[key] {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if _, ok := existingBlocks[key]; !ok && !existingDuplicates[key] && generatedDuplicates[key] {
+			if _, isGenerated := provenance[prefix+key+"{}"]; !isGenerated {
+				for _, block := range generated.Blocks() {
+					if blockKey(block) == key {
+						existing.AppendBlock(block)
+					}
+				}
+			}
+			continue
+		}
+		if existingDuplicates[key] || generatedDuplicates[key] {
+			// the repeated nested blocks can't be matched, they're kept as is
+			conflicts = append(conflicts, MergeConflict{
+				Address:   address,
+				Attribute: prefix + key,
+				Message:   "the nested blocks are repeated and can't be merged, the user's blocks are kept",
+			})
+			continue
+		}
+		generatedBlock := generatedBlocks[key]
+		existingBlock, ok := existingBlocks[key]
+		if !ok {
+			// the nested block is removed by the user, keep it removed
+			if _, isGenerated := provenance[prefix+key+"{}"]; isGenerated {
+				continue
+			}
+			existing.AppendBlock(generatedBlock)
+			continue
+		}
+		conflicts = append(conflicts, mergeBody(address, existingBlock.Body(), generatedBlock.Body(), provenance, prefix+key+".")...)
+	}
+
+	// remove the nested blocks which are no longer generated and not modified by the user
+	for key, existingBlock := range existingBlocks {
+		if _, ok := generatedBlocks[key]; ok || generatedDuplicates[key] {
+			continue
+		}
+		if checksum, isGenerated := provenance[prefix+key+"{}"]; isGenerated && checksum == blockChecksum(existingBlock) {
+			existing.RemoveBlock(existingBlock)
+		}
+	}
+
+	return conflicts
+}
+
+// nestedBlocks returns the nested blocks keyed by the block types and labels, and the keys of the repeated nested blocks
+func nestedBlocks(body *hclwrite.Body) (map[string]*hclwrite.Block, map[string]bool) {
+	blocks := make(map[string]*hclwrite.Block)
+	duplicates := make(map[string]bool)
+	for _, block := range body.Blocks() {
+		key := blockKey(block)
+		if _, ok := blocks[key]; ok || duplicates[key] {
+			delete(blocks, key)
+			duplicates[key] = true
+			continue
+		}
+		blocks[key] = block
+	}
+	return blocks, duplicates
+}
+
+func isProvenanceBlock(block *hclwrite.Block) bool {
+	return (block.Type() == "resource" || block.Type() == "data") && len(block.Labels()) == 2
+}
+
+func blockKey(block *hclwrite.Block) string {
+	return strings.Join(append([]string{block.Type()}, block.Labels()...), ".")
+}
+
+func newProvenance(body *hclwrite.Body) string {
+	checksums := make(map[string]string)
+	addChecksums(checksums, body, "")
+	names := make([]string, 0)
+	for name := range checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fields := []string{ProvenancePrefix}
+	for _, name := range names {
+		fields = append(fields, fmt.Sprintf("%s=%s", name, checksums[name]))
+	}
+	return strings.Join(fields, " ")
+}
+
+// addChecksums adds the checksums of the attributes and the nested blocks in the body, the repeated nested blocks are skipped because they can't be merged
+func addChecksums(checksums map[string]string, body *hclwrite.Body, prefix string) {
+	for name, attr := range body.Attributes() {
+		checksums[prefix+name] = expressionChecksum(attr.Expr())
+	}
+	blocks, _ := nestedBlocks(body)
+	for key, block := range blocks {
+		checksums[prefix+key+"{}"] = blockChecksum(block)
+		addChecksums(checksums, block.Body(), prefix+key+".")
+	}
+}
+
+// parseProvenance returns the attribute checksums recorded in the provenance comment and whether the comment is found
+func parseProvenance(block *hclwrite.Block) (map[string]string, bool) {
+	comments, _ := splitLeadComments(block)
+	for _, comment := range comments {
+		fields := strings.Fields(commentText(comment))
+		if len(fields) == 0 || fields[0] != ProvenancePrefix {
+			continue
+		}
+		out := make(map[string]string)
+		for _, field := range fields[1:] {
+			if name, checksum, ok := strings.Cut(field, "="); ok {
+				out[name] = checksum
+			}
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// blockString returns the block content, the existing provenance comment will be replaced if the provenance is not empty
+func blockString(block *hclwrite.Block, provenance string) string {
+	comments, rest := splitLeadComments(block)
+	out := ""
+	for _, comment := range comments {
+		if provenance != "" && strings.HasPrefix(commentText(comment), ProvenancePrefix) {
+			continue
+		}
+		out += strings.TrimRight(comment, "\n") + "\n"
+	}
+	if provenance != "" {
+		out += fmt.Sprintf("// %s\n", provenance)
+	}
+	return out + rest
+}
+
+func splitLeadComments(block *hclwrite.Block) ([]string, string) {
+	tokens := block.BuildTokens(nil)
+	comments := make([]string, 0)
+	i := 0
+	for ; i < len(tokens) && tokens[i].Type == hclsyntax.TokenComment; i++ {
+		comments = append(comments, string(tokens[i].Bytes))
+	}
+	return comments, string(tokens[i:].Bytes())
+}
+
+func commentText(comment string) string {
+	comment = strings.TrimSpace(comment)
+	switch {
+	case strings.HasPrefix(comment, "//"):
+		comment = strings.TrimPrefix(comment, "//")
+	case strings.HasPrefix(comment, "#"):
+		comment = strings.TrimPrefix(comment, "#")
+	}
+	return strings.TrimSpace(comment)
+}
+
+func expressionString(expr *hclwrite.Expression) string {
+	return strings.Join(strings.Fields(string(expr.BuildTokens(nil).Bytes())), " ")
+}
+
+func expressionChecksum(expr *hclwrite.Expression) string {
+	return checksum(expressionString(expr))
+}
+
+func blockChecksum(block *hclwrite.Block) string {
+	return checksum(strings.Join(strings.Fields(string(block.Body().BuildTokens(nil).Bytes())), " "))
+}
+
+func checksum(input string) string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(input))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
@@ -0,0 +1,261 @@
+package resource_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/resource"
+)
+
+func Test_MergeConfig(t *testing.T) {
+	oldGenerated, err := resource.AddProvenance(`variable "location" {
+  type    = string
+  default = "westeurope"
+}
+
+// OperationId: Accounts_CreateOrUpdate
+resource "azapi_resource" "account" {
+  type      = "Microsoft.Purview/accounts@2021-07-01"
+  parent_id = azapi_resource.resourceGroup.id
+  name      = var.resource_name
+  location  = var.location
+  body = {
+    properties = {
+      publicNetworkAccess = "Enabled"
+    }
+  }
+  schema_validation_enabled = false
+}
+
+resource "azapi_resource" "removed" {
+  type = "Microsoft.Purview/removed@2021-07-01"
+  name = var.resource_name
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+	if !strings.Contains(oldGenerated, "// OperationId: Accounts_CreateOrUpdate\n// armstrong:generated body=") {
+		t.Fatalf("expected provenance comment after the leading comments, got:\n%s", oldGenerated)
+	}
+
+	// the user modified the location, removed the schema_validation_enabled, added a new attribute and a new resource
+	existing := strings.ReplaceAll(oldGenerated, "location  = var.location", `location  = "eastus"`)
+	existing = strings.ReplaceAll(existing, "schema_validation_enabled = false", "ignore_missing_property = true")
+	existing = strings.ReplaceAll(existing, "default = \"westeurope\"", "default = \"eastus\"")
+	existing += `
+resource "azapi_resource" "roleAssignment" {
+  type = "Microsoft.Authorization/roleAssignments@2022-04-01"
+  name = "00000000-0000-0000-0000-000000000000"
+}
+`
+
+	newGenerated, err := resource.AddProvenance(`variable "location" {
+  type    = string
+  default = "westeurope"
+}
+
+// OperationId: Accounts_CreateOrUpdate
+resource "azapi_resource" "account" {
+  type      = "Microsoft.Purview/accounts@2021-12-01"
+  parent_id = azapi_resource.resourceGroup.id
+  name      = var.resource_name
+  location  = var.location
+  body = {
+    properties = {
+      publicNetworkAccess = "Disabled"
+    }
+  }
+  schema_validation_enabled = false
+}
+
+resource "azapi_resource" "kafkaConfiguration" {
+  type      = "Microsoft.Purview/accounts/kafkaConfigurations@2021-12-01"
+  parent_id = azapi_resource.account.id
+  name      = var.resource_name
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+
+	merged, conflicts, err := resource.MergeConfig(existing, newGenerated)
+	if err != nil {
+		t.Fatalf("failed to merge config: %+v", err)
+	}
+
+	actual := strings.Join(strings.Fields(merged), " ")
+	for _, expected := range []string{
+		`default = "eastus"`,
+		`type = "Microsoft.Purview/accounts@2021-12-01"`,
+		`publicNetworkAccess = "Disabled"`,
+		`location = "eastus"`,
+		`ignore_missing_property = true`,
+		`resource "azapi_resource" "roleAssignment"`,
+		`resource "azapi_resource" "kafkaConfiguration"`,
+		`resource "azapi_resource" "removed"`,
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected %q in merged config:\n%s", expected, merged)
+		}
+	}
+	if strings.Contains(actual, "schema_validation_enabled =") {
+		t.Errorf("expected the removed attribute is kept removed:\n%s", merged)
+	}
+	if strings.Count(merged, "armstrong:generated") != 3 {
+		t.Errorf("expected 3 provenance comments in merged config:\n%s", merged)
+	}
+
+	conflictMap := make(map[string]bool)
+	for _, conflict := range conflicts {
+		conflictMap[conflict.Address+"."+conflict.Attribute] = true
+	}
+	for _, expected := range []string{"resource.azapi_resource.account.location", "resource.azapi_resource.removed."} {
+		if !conflictMap[expected] {
+			t.Errorf("expected conflict %s, got %v", expected, conflicts)
+		}
+	}
+	if len(conflicts) != 2 {
+		t.Errorf("expected 2 conflicts, got %v", conflicts)
+	}
+
+	// merge again, the updated attributes should not be reported
+	_, conflicts, err = resource.MergeConfig(merged, newGenerated)
+	if err != nil {
+		t.Fatalf("failed to merge config: %+v", err)
+	}
+	if len(conflicts) != 2 {
+		t.Errorf("expected 2 conflicts, got %v", conflicts)
+	}
+}
+
+func Test_MergeConfigNestedBlocks(t *testing.T) {
+	oldGenerated, err := resource.AddProvenance(`resource "azapi_resource" "account" {
+  type = "Microsoft.Purview/accounts@2021-07-01"
+  name = var.resource_name
+  identity {
+    type         = "SystemAssigned"
+    identity_ids = []
+  }
+  timeouts {
+    create = "30m"
+  }
+  lifecycle {
+    ignore_changes = [tags]
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+
+	// the user modified the identity type and removed the lifecycle block
+	existing := strings.ReplaceAll(oldGenerated, `"SystemAssigned"`, `"UserAssigned"`)
+	existing = strings.ReplaceAll(existing, "lifecycle {\n    ignore_changes = [tags]\n  }", "")
+
+	newGenerated, err := resource.AddProvenance(`resource "azapi_resource" "account" {
+  type = "Microsoft.Purview/accounts@2021-12-01"
+  name = var.resource_name
+  identity {
+    type         = "SystemAssigned, UserAssigned"
+    identity_ids = [azapi_resource.userAssignedIdentity.id]
+  }
+  timeouts {
+    create = "60m"
+  }
+  lifecycle {
+    ignore_changes = [tags]
+  }
+  retry {
+    error_message_regex = ["Conflict"]
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+
+	merged, conflicts, err := resource.MergeConfig(existing, newGenerated)
+	if err != nil {
+		t.Fatalf("failed to merge config: %+v", err)
+	}
+
+	actual := strings.Join(strings.Fields(merged), " ")
+	for _, expected := range []string{
+		`type = "Microsoft.Purview/accounts@2021-12-01"`,
+		`type = "UserAssigned"`,
+		`identity_ids = [azapi_resource.userAssignedIdentity.id]`,
+		`create = "60m"`,
+		`retry { error_message_regex = ["Conflict"] }`,
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected %q in merged config:\n%s", expected, merged)
+		}
+	}
+	if strings.Contains(actual, "lifecycle {") {
+		t.Errorf("expected the removed nested block is kept removed:\n%s", merged)
+	}
+	if len(conflicts) != 1 || conflicts[0].Attribute != "identity.type" {
+		t.Errorf("expected the conflict of identity.type, got %v", conflicts)
+	}
+}
+
+func Test_MergeConfigWithoutProvenance(t *testing.T) {
+	// the configuration is generated without the provenance comments, and the user modified the location
+	existing := `// OperationId: Accounts_CreateOrUpdate
+resource "azapi_resource" "account" {
+  type      = "Microsoft.Purview/accounts@2021-07-01"
+  parent_id = azapi_resource.resourceGroup.id
+  name      = var.resource_name
+  location  = "eastus"
+}
+`
+	newGenerated, err := resource.AddProvenance(`// OperationId: Accounts_CreateOrUpdate
+resource "azapi_resource" "account" {
+  type      = "Microsoft.Purview/accounts@2021-07-01"
+  parent_id = azapi_resource.resourceGroup.id
+  name      = var.resource_name
+  location  = var.location
+  body = {
+    properties = {
+      publicNetworkAccess = "Disabled"
+    }
+  }
+}
+`)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+
+	merged, conflicts, err := resource.MergeConfig(existing, newGenerated)
+	if err != nil {
+		t.Fatalf("failed to merge config: %+v", err)
+	}
+	actual := strings.Join(strings.Fields(merged), " ")
+	for _, expected := range []string{
+		`location = "eastus"`,
+		`publicNetworkAccess = "Disabled"`,
+		"armstrong:generated",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected %q in merged config:\n%s", expected, merged)
+		}
+	}
+	if len(conflicts) != 1 || conflicts[0].Attribute != "location" {
+		t.Errorf("expected the conflict of location, got %v", conflicts)
+	}
+
+	// the block is updated in place when it's generated again
+	newGenerated = strings.ReplaceAll(newGenerated, "@2021-07-01", "@2021-12-01")
+	newGenerated, err = resource.AddProvenance(newGenerated)
+	if err != nil {
+		t.Fatalf("failed to add provenance: %+v", err)
+	}
+	merged, _, err = resource.MergeConfig(merged, newGenerated)
+	if err != nil {
+		t.Fatalf("failed to merge config: %+v", err)
+	}
+	if !strings.Contains(merged, "Microsoft.Purview/accounts@2021-12-01") {
+		t.Errorf("expected the type is updated in place:\n%s", merged)
+	}
+}
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/azure/armstrong/dependency"
@@ -24,14 +25,13 @@ func (r AzapiDependencyResolver) Resolve(pattern dependency.Pattern) (*ResolvedR
 	return nil, nil
 }
 
-func NewAzapiDependencyResolver() AzapiDependencyResolver {
+func NewAzapiDependencyResolver() (AzapiDependencyResolver, error) {
 	azapiDeps, err := dependency.LoadAzapiDependencies()
 	if err != nil {
-		logrus.Fatalf("loading azapi dependencies: %+v", err)
-		return AzapiDependencyResolver{}
+		return AzapiDependencyResolver{}, fmt.Errorf("loading azapi dependencies: %+v", err)
 	}
 	logrus.Debugf("loaded %d azapi dependencies", len(azapiDeps))
 	return AzapiDependencyResolver{
 		dependencies: azapiDeps,
-	}
+	}, nil
 }
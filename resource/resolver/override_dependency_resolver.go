@@ -0,0 +1,32 @@
+package resolver
+
+import (
+	"strings"
+
+	"github.com/azure/armstrong/dependency"
+)
+
+var _ ReferenceResolver = &OverrideDependencyResolver{}
+
+// OverrideDependencyResolver resolves the dependencies with the user provided configurations, they take precedence over the built-in dependencies
+type OverrideDependencyResolver struct {
+	// configs are the terraform configurations keyed by the ARM resource types, the last block is the referenced dependency
+	configs map[string]string
+}
+
+func (r OverrideDependencyResolver) Resolve(pattern dependency.Pattern) (*ResolvedResult, error) {
+	for resourceType, config := range r.configs {
+		if strings.EqualFold(resourceType, pattern.AzureResourceType) {
+			return &ResolvedResult{
+				HclToAdd: config,
+			}, nil
+		}
+	}
+	return nil, nil
+}
+
+func NewOverrideDependencyResolver(configs map[string]string) OverrideDependencyResolver {
+	return OverrideDependencyResolver{
+		configs: configs,
+	}
+}
@@ -0,0 +1,601 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/azure/armstrong/autorest"
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/resource"
+	"github.com/azure/armstrong/resource/resolver"
+	"github.com/azure/armstrong/resource/types"
+	"github.com/azure/armstrong/swagger"
+	"github.com/azure/armstrong/tf"
+	"github.com/azure/armstrong/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"golang.org/x/exp/slices"
+)
+
+// GenerateOptions are the options of the generate workflow, exactly one of ExamplePath, SwaggerPath, ReadmePath and ArmTemplatePath must be specified
+type GenerateOptions struct {
+	// WorkingDir is the output directory of the terraform configurations, default is the current directory
+	WorkingDir string
+	// RawJsonPayload uses the raw json payload in 'body'
+	RawJsonPayload bool
+	// ProviderVersions are pinned in the .terraform.lock.hcl in the format of <provider>=<version>, e.g., azapi=1.12.1
+	ProviderVersions []string
+	// PluginMirror is the filesystem mirror directory, the latest provider versions and the hashes in it are pinned in the .terraform.lock.hcl
+	PluginMirror string
+	// Defaults are the defaults of the resource_name and location variables, the location of the cloud is used if the location is not set
+	Defaults config.Defaults
+	// Cloud selects the environment of the provider blocks and the default location, the ARM_ENVIRONMENT environment variable is used if it's not set
+	Cloud cloud.Environment
+	// DependencyConfigs are the configurations which override the built-in dependencies, keyed by the ARM resource types
+	DependencyConfigs map[string]string
+
+	// ExamplePath is the path to a swagger 'Create' example
+	ExamplePath string
+	// ResourceType is the type of the resource generated from the example, allowed values: 'resource'(supports CRUD) and 'data'(read-only), default is 'resource'
+	ResourceType string
+	// Overwrite removes the existing terraform configurations
+	Overwrite bool
+
+	// SwaggerPath is the path or directory to the swagger files
+	SwaggerPath string
+	// Merge merges the generated terraform configurations into the existing ones, the user's changes are kept
+	Merge bool
+
+	// ArmTemplatePath is the path to an ARM template or a Bicep compiled JSON file
+	ArmTemplatePath string
+
+	// ReadmePath and Tag are the autorest config file(readme.md) and the tag in it
+	ReadmePath string
+	Tag        string
+}
+
+// GenerateResult is the result of the generate workflow
+type GenerateResult struct {
+	// WorkingDir is the absolute path of the output directory
+	WorkingDir string
+	// Files are the written terraform configuration and lock files
+	Files []string
+}
+
+// Validate returns an error if the inputs are not specified correctly
+func (o GenerateOptions) Validate() error {
+	specified := 0
+	for _, input := range []string{o.SwaggerPath, o.ExamplePath, o.ReadmePath, o.ArmTemplatePath} {
+		if input != "" {
+			specified++
+		}
+	}
+	if specified > 1 {
+		return fmt.Errorf("only one of 'swagger', 'path', 'readme' and 'arm-template' can be specified")
+	}
+	if specified == 0 {
+		return fmt.Errorf("one of 'swagger', 'path', 'readme' and 'arm-template' must be specified")
+	}
+	if o.ReadmePath != "" && o.Tag == "" {
+		return fmt.Errorf("tag must be specified when 'readme' is specified")
+	}
+	if o.ReadmePath == "" && o.Tag != "" {
+		return fmt.Errorf("tag can only be specified when 'readme' is specified")
+	}
+	return nil
+}
+
+type generator struct {
+	*Runner
+	GenerateOptions
+	files []string
+}
+
+// Generate generates the terraform configurations for the testing resources and their dependencies
+func (r *Runner) Generate(opts GenerateOptions) (*GenerateResult, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("getting working directory: %+v", err)
+	}
+	if opts.WorkingDir != "" {
+		wd, err = filepath.Abs(opts.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("getting absolute path of working directory: %+v", err)
+		}
+	}
+	if opts.ResourceType == "" {
+		opts.ResourceType = "resource"
+	}
+	opts.WorkingDir = wd
+	r.logger.Infof("working directory: %s", wd)
+	workflowMutex.Lock()
+	defer workflowMutex.Unlock()
+	if err := setGlobals(opts.Cloud, "", opts.Defaults); err != nil {
+		return nil, err
+	}
+
+	g := &generator{Runner: r, GenerateOptions: opts}
+	switch {
+	case opts.SwaggerPath != "":
+		err = g.fromSwaggerPath()
+	case opts.ExamplePath != "":
+		err = g.fromExamplePath()
+	case opts.ReadmePath != "":
+		err = g.fromAutorestConfig()
+	case opts.ArmTemplatePath != "":
+		err = g.fromArmTemplate()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &GenerateResult{
+		WorkingDir: wd,
+		Files:      g.files,
+	}, nil
+}
+
+func (g *generator) fromExamplePath() error {
+	wd := g.WorkingDir
+	if g.Overwrite {
+		g.logger.Infof("overwriting existing terraform configurations...")
+		_ = os.RemoveAll(path.Join(wd, "testing.tf"))
+		_ = os.RemoveAll(path.Join(wd, "dependency.tf"))
+	}
+	err := os.WriteFile(path.Join(wd, "provider.tf"), hclwrite.Format([]byte(resource.DefaultProviderConfig)), 0644)
+	if err != nil {
+		g.logger.Errorf("writing provider.tf: %+v", err)
+	} else {
+		g.files = append(g.files, path.Join(wd, "provider.tf"))
+	}
+	g.logger.Infof("provider configuration is written to %s", path.Join(wd, "provider.tf"))
+
+	// load example
+	g.logger.Infof("loading example: %s", g.ExamplePath)
+	example, err := resource.NewAzapiDefinitionFromExample(g.ExamplePath, g.ResourceType)
+	if err != nil {
+		return fmt.Errorf("loading example: %+v", err)
+	}
+	if g.RawJsonPayload {
+		g.logger.Infof("using raw json payload in 'body'...")
+		example.BodyFormat = types.BodyFormatJson
+	}
+
+	// load dependencies
+	g.logger.Infof("loading dependencies...")
+	azapiDependencyResolver, err := resolver.NewAzapiDependencyResolver()
+	if err != nil {
+		return err
+	}
+	referenceResolvers := []resolver.ReferenceResolver{
+		resolver.NewExistingDependencyResolver(wd),
+		resolver.NewOverrideDependencyResolver(g.DependencyConfigs),
+		azapiDependencyResolver,
+		resolver.NewAzurermDependencyResolver(),
+		resolver.NewProviderIDResolver(),
+		resolver.NewLocationIDResolver(),
+		resolver.NewAzapiResourcePlaceholderResolver(),
+	}
+	context := resource.NewContext(referenceResolvers)
+	err = context.InitFile(g.allTerraformConfig(wd))
+	if err != nil {
+		return fmt.Errorf("initializing terraform configurations: %+v", err)
+	}
+
+	g.logger.Infof("generating terraform configurations...")
+	err = context.AddAzapiDefinition(example)
+	if err != nil {
+		g.logger.Warnf("adding azapi definition for %s: %+v", example.Id, err)
+		return nil
+	}
+
+	g.logger.Infof("writing terraform configurations...")
+	blockMap := g.blockFileMap(wd)
+	contentToAppend := make(map[string]string)
+	len := len(context.File.Body().Blocks())
+	for i, block := range context.File.Body().Blocks() {
+		switch block.Type() {
+		case "terraform", "provider", "variable":
+			continue
+		default:
+			key := fmt.Sprintf("%s.%s", block.Type(), strings.Join(block.Labels(), "."))
+			if _, ok := blockMap[key]; ok {
+				continue
+			}
+			outputFilename := "dependency.tf"
+			if i == len-1 {
+				outputFilename = "testing.tf"
+			}
+			contentToAppend[outputFilename] = contentToAppend[outputFilename] + "\n" + string(block.BuildTokens(nil).Bytes())
+		}
+	}
+
+	for filename, content := range contentToAppend {
+		err := g.appendContent(path.Join(wd, filename), content)
+		if err != nil {
+			g.logger.Errorf("writing %s: %+v", filename, err)
+			continue
+		}
+		g.files = append(g.files, path.Join(wd, filename))
+		g.logger.Infof("configuration is written to %s", path.Join(wd, filename))
+	}
+	return g.writeLockFile(wd)
+}
+
+func (g *generator) fromSwaggerPath() error {
+	swaggerPath, err := filepath.Abs(g.SwaggerPath)
+	if err == nil {
+		g.SwaggerPath = swaggerPath
+	}
+	g.logger.Infof("loading swagger spec: %s...", g.SwaggerPath)
+	file, err := os.Stat(g.SwaggerPath)
+	if err != nil {
+		return fmt.Errorf("loading swagger spec: %+v", err)
+	}
+	apiPathsAll := make([]swagger.ApiPath, 0)
+	if file.IsDir() {
+		g.logger.Infof("swagger spec is a directory")
+		g.logger.Infof("loading swagger spec directory: %s...", g.SwaggerPath)
+		filenames, err := utils.ListFiles(g.SwaggerPath, ".json", 1)
+		if err != nil {
+			return fmt.Errorf("reading swagger spec directory: %+v", err)
+		}
+		for _, filename := range filenames {
+			g.logger.Infof("parsing swagger spec: %s...", filename)
+			apiPaths, err := swagger.Load(filename)
+			if err != nil {
+				return fmt.Errorf("parsing swagger spec: %+v", err)
+			}
+			apiPathsAll = append(apiPathsAll, apiPaths...)
+		}
+	} else {
+		g.logger.Infof("parsing swagger spec: %s...", g.SwaggerPath)
+		apiPaths, err := swagger.Load(g.SwaggerPath)
+		if err != nil {
+			return fmt.Errorf("parsing swagger spec: %+v", err)
+		}
+		apiPathsAll = append(apiPathsAll, apiPaths...)
+	}
+
+	g.logger.Infof("found %d api paths", len(apiPathsAll))
+	return g.generate(apiPathsAll)
+}
+
+func (g *generator) fromAutorestConfig() error {
+	g.logger.Infof("parsing autorest config: %s...", g.ReadmePath)
+	packages := autorest.ParseAutoRestConfig(g.ReadmePath)
+	g.logger.Debugf("found %d packages", len(packages))
+	var targetPackage *autorest.Package
+	for _, pkg := range packages {
+		if pkg.Tag == g.Tag {
+			targetPackage = &pkg
+			break
+		}
+	}
+	if targetPackage == nil {
+		return fmt.Errorf("package with tag %s not found in %s", g.Tag, g.ReadmePath)
+	}
+
+	apiPathsAll := make([]swagger.ApiPath, 0)
+	for _, swaggerPath := range targetPackage.InputFiles {
+		g.logger.Infof("parsing swagger spec: %s...", swaggerPath)
+		azapiPaths, err := swagger.Load(swaggerPath)
+		if err != nil {
+			return fmt.Errorf("parsing swagger spec: %+v", err)
+		}
+		apiPathsAll = append(apiPathsAll, azapiPaths...)
+	}
+
+	return g.generate(apiPathsAll)
+}
+
+func (g *generator) generate(apiPaths []swagger.ApiPath) error {
+	wd := g.WorkingDir
+	azapiDefinitionsAll := make([]types.AzapiDefinition, 0)
+	for _, apiPath := range apiPaths {
+		azapiDefinitionsAll = append(azapiDefinitionsAll, resource.NewAzapiDefinitionsFromSwagger(apiPath)...)
+	}
+
+	if g.RawJsonPayload {
+		g.logger.Infof("using raw json payload in 'body'...")
+		for i := range azapiDefinitionsAll {
+			azapiDefinitionsAll[i].BodyFormat = types.BodyFormatJson
+		}
+	}
+
+	azapiDefinitionByResourceType := make(map[string][]types.AzapiDefinition)
+	for _, azapiDefinition := range azapiDefinitionsAll {
+		azureResourceType := azapiDefinition.AzureResourceType
+		// To avoid the case that there are multiple resource types with the same name but different casing
+		for resourceType := range azapiDefinitionByResourceType {
+			if strings.EqualFold(resourceType, azureResourceType) {
+				azureResourceType = resourceType
+			}
+		}
+		azapiDefinitionByResourceType[azureResourceType] = append(azapiDefinitionByResourceType[azureResourceType], azapiDefinition)
+	}
+
+	resourceTypes := make([]string, 0)
+	for resourceType := range azapiDefinitionByResourceType {
+		slices.SortFunc(azapiDefinitionByResourceType[resourceType], func(i, j types.AzapiDefinition) int {
+			return azapiDefinitionOrder(i) - azapiDefinitionOrder(j)
+		})
+		resourceTypes = append(resourceTypes, resourceType)
+	}
+
+	sort.Strings(resourceTypes)
+
+	azapiDependencyResolver, err := resolver.NewAzapiDependencyResolver()
+	if err != nil {
+		return err
+	}
+	referenceResolvers := []resolver.ReferenceResolver{
+		resolver.NewOverrideDependencyResolver(g.DependencyConfigs),
+		azapiDependencyResolver,
+		resolver.NewAzapiDefinitionResolver(azapiDefinitionsAll),
+		resolver.NewProviderIDResolver(),
+		resolver.NewLocationIDResolver(),
+		resolver.NewAzapiResourceIdResolver(),
+	}
+
+	for _, resourceType := range resourceTypes {
+		g.logger.Infof("generating terraform configurations for %s...", resourceType)
+		azapiDefinitions := azapiDefinitionByResourceType[resourceType]
+		context := resource.NewContext(referenceResolvers)
+
+		for _, azapiDefinition := range azapiDefinitions {
+			g.logger.Debugf("generating terraform configurations for %s...", azapiDefinition.Id)
+			err := context.AddAzapiDefinition(azapiDefinition)
+			if err != nil {
+				g.logger.Warnf("adding azapi definition for %s: %+v", azapiDefinition.Id, err)
+			}
+		}
+
+		content := g.addProvenance(context.String())
+
+		folderName := strings.ReplaceAll(resourceType, "/", "_")
+		filename := path.Join(wd, folderName, "main.tf")
+		if !g.Merge || !utils.Exists(filename) {
+			// remove existing folders by default
+			err = os.RemoveAll(path.Join(wd, folderName))
+			if err != nil {
+				g.logger.Errorf("removing existing folder: %+v", err)
+			}
+			err = os.MkdirAll(path.Join(wd, folderName), 0755)
+			if err != nil {
+				return fmt.Errorf("creating folder: %+v", err)
+			}
+		}
+
+		if err = g.writeConfig(filename, content); err != nil {
+			g.logger.Errorf("writing %s: %+v", filename, err)
+		} else {
+			g.files = append(g.files, filename)
+		}
+		if err := g.writeLockFile(path.Join(wd, folderName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (g *generator) fromArmTemplate() error {
+	g.logger.Infof("loading ARM template: %s...", g.ArmTemplatePath)
+	template, err := resource.NewAzapiDefinitionsFromArmTemplate(g.ArmTemplatePath)
+	if err != nil {
+		return fmt.Errorf("loading ARM template: %+v", err)
+	}
+	g.logger.Infof("found %d resources in the ARM template", len(template.Definitions))
+	if g.RawJsonPayload {
+		g.logger.Warnf("'raw' is not supported when generating from ARM templates, it's ignored")
+	}
+
+	filename := path.Join(g.WorkingDir, "main.tf")
+	if utils.Exists(filename) && !g.Merge && !g.Overwrite {
+		return fmt.Errorf("%s already exists, please specify 'overwrite' or 'merge' option", filename)
+	}
+
+	// the resources in the template reference each other directly, other dependencies are resolved by the resolvers
+	azapiDependencyResolver, err := resolver.NewAzapiDependencyResolver()
+	if err != nil {
+		return err
+	}
+	referenceResolvers := []resolver.ReferenceResolver{
+		resolver.NewOverrideDependencyResolver(g.DependencyConfigs),
+		azapiDependencyResolver,
+		resolver.NewProviderIDResolver(),
+		resolver.NewLocationIDResolver(),
+		resolver.NewAzapiResourceIdResolver(),
+	}
+	context := resource.NewContext(referenceResolvers)
+	for _, azapiDefinition := range template.Definitions {
+		g.logger.Debugf("generating terraform configurations for %s...", azapiDefinition.Id)
+		err := context.AddAzapiDefinition(azapiDefinition)
+		if err != nil {
+			g.logger.Warnf("adding azapi definition for %s: %+v", azapiDefinition.Id, err)
+		}
+	}
+
+	content := g.addProvenance(context.String() + "\n" + template.Variables)
+	if err = g.writeConfig(filename, content); err != nil {
+		return fmt.Errorf("writing %s: %+v", filename, err)
+	}
+	g.files = append(g.files, filename)
+	g.logger.Infof("configuration is written to %s", filename)
+	return g.writeLockFile(g.WorkingDir)
+}
+
+// addProvenance adds the provenance comments to the generated blocks, so that the user's changes can be detected
+// when the configuration is regenerated with 'merge'.
+func (g *generator) addProvenance(content string) string {
+	out, err := resource.AddProvenance(content)
+	if err != nil {
+		g.logger.Errorf("adding provenance comments: %+v", err)
+		return content
+	}
+	return out
+}
+
+// writeConfig writes the generated configuration to the file, when 'merge' is specified and the file exists,
+// the generated configuration is merged into the existing one and the conflicts are logged.
+func (g *generator) writeConfig(filename string, content string) error {
+	if g.Merge && utils.Exists(filename) {
+		g.logger.Infof("merging terraform configurations into %s...", filename)
+		existing, err := os.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+		merged, conflicts, err := resource.MergeConfig(string(existing), content)
+		if err != nil {
+			return fmt.Errorf("merging configurations: %+v", err)
+		}
+		if len(conflicts) != 0 {
+			for _, conflict := range conflicts {
+				g.logger.Warnf("conflict in %s: %s", filename, conflict)
+			}
+			if err := os.WriteFile(filename+".orig", existing, 0644); err != nil {
+				g.logger.Errorf("writing %s.orig: %+v", filename, err)
+			} else {
+				g.logger.Infof("found %d conflicts, the original configuration is saved to %s.orig", len(conflicts), filename)
+			}
+		}
+		content = merged
+	}
+	return os.WriteFile(filename, hclwrite.Format([]byte(content)), 0644)
+}
+
+// writeLockFile writes the .terraform.lock.hcl which pins the provider versions, when 'provider-version' or 'plugin-mirror' is specified.
+// The providers used by the generated configurations are pinned to the latest versions in the plugin mirror unless their versions are specified.
+func (g *generator) writeLockFile(dir string) error {
+	if len(g.ProviderVersions) == 0 && g.PluginMirror == "" {
+		return nil
+	}
+	flavor := tf.OptionsFromEnv().Flavor
+	versions, err := tf.ParseProviderPairs(flavor, g.ProviderVersions)
+	if err != nil {
+		return fmt.Errorf("parsing provider versions: %+v", err)
+	}
+	if g.PluginMirror != "" {
+		for _, source := range tf.DefaultProviders {
+			address, err := tf.ProviderAddress(flavor, source)
+			if err != nil {
+				return fmt.Errorf("parsing provider source %s: %+v", source, err)
+			}
+			if _, ok := versions[address]; !ok {
+				versions[address] = ""
+			}
+		}
+	}
+	locks, err := tf.ProviderLocks(g.PluginMirror, versions)
+	if err != nil {
+		return fmt.Errorf("resolving provider versions: %+v", err)
+	}
+	filename := path.Join(dir, tf.LockFileName)
+	if err := os.WriteFile(filename, tf.LockFile(locks), 0644); err != nil {
+		return fmt.Errorf("writing %s: %+v", filename, err)
+	}
+	g.files = append(g.files, filename)
+	g.logger.Infof("provider versions are pinned in %s", filename)
+	return nil
+}
+
+func azapiDefinitionOrder(azapiDefinition types.AzapiDefinition) int {
+	// 0. resource.azapi_resource
+	// 1. resource.azapi_update_resource Note: Now it will not be generated
+	// 2. azapi_resource_action with empty action
+	// 3. azapi_resource_action with action
+	// 4. data.azapi_resource
+	// 5. azapi_resource_list
+
+	switch azapiDefinition.ResourceName {
+	case "azapi_resource":
+		if azapiDefinition.Kind == types.KindResource {
+			return 0
+		}
+		return 4
+	case "azapi_update_resource":
+		return 1
+	case "azapi_resource_action":
+		if actionField := azapiDefinition.AdditionalFields["action"]; actionField == nil || actionField.String() == `""` {
+			return 2
+		}
+		return 3
+	case "azapi_resource_list":
+		return 5
+	}
+	return 6
+}
+
+func (g *generator) appendContent(filename string, hclContent string) error {
+	content := hclContent
+	if _, err := os.Stat(filename); err == nil {
+		existingHcl, err := os.ReadFile(filename)
+		if err != nil {
+			g.logger.Warnf("reading existing file: %+v", err)
+		}
+		content = string(existingHcl) + "\n" + content
+	}
+	return os.WriteFile(filename, hclwrite.Format([]byte(content)), 0644)
+}
+
+func (g *generator) blockFileMap(workingDirectory string) map[string]string {
+	files, err := os.ReadDir(workingDirectory)
+	if err != nil {
+		g.logger.Warnf("reading dir %s: %+v", workingDirectory, err)
+		return nil
+	}
+	out := make(map[string]string)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".tf") {
+			continue
+		}
+		src, err := os.ReadFile(path.Join(workingDirectory, file.Name()))
+		if err != nil {
+			g.logger.Warnf("reading file %s: %+v", file.Name(), err)
+			continue
+		}
+		f, diag := hclwrite.ParseConfig(src, file.Name(), hcl.InitialPos)
+		if diag.HasErrors() {
+			g.logger.Warnf("parsing file %s: %+v", file.Name(), diag.Error())
+			continue
+		}
+		if f == nil || f.Body() == nil {
+			continue
+		}
+		for _, block := range f.Body().Blocks() {
+			key := fmt.Sprintf("%s.%s", block.Type(), strings.Join(block.Labels(), "."))
+			out[key] = file.Name()
+		}
+	}
+
+	return out
+}
+
+func (g *generator) allTerraformConfig(workingDirectory string) string {
+	out := ""
+	files, err := os.ReadDir(workingDirectory)
+	if err != nil {
+		g.logger.Warnf("reading dir %s: %+v", workingDirectory, err)
+		return out
+	}
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".tf") {
+			continue
+		}
+		src, err := os.ReadFile(path.Join(workingDirectory, file.Name()))
+		if err != nil {
+			g.logger.Warnf("reading file %s: %+v", file.Name(), err)
+			continue
+		}
+		out += string(src)
+	}
+
+	return out
+}
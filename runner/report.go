@@ -0,0 +1,52 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/report"
+)
+
+// ReportOptions are the options of the report workflow
+type ReportOptions struct {
+	// WorkingDir is the directory which contains all the test cases, default is the current directory
+	WorkingDir string
+	// SwaggerPath is the path to the swagger which is being test, it's required
+	SwaggerPath string
+	// Cloud selects the ARM endpoint which replaces the sanitized hosts in the recordings, the ARM_ENVIRONMENT environment variable is used if it's not set
+	Cloud cloud.Environment
+	// Suppressions are merged with the ones in ApiTestConfig.json of the working directory
+	Suppressions []report.Suppression
+}
+
+// Report generates the swagger accuracy reports and the operation properties coverage reports from the traces of the test cases
+func (r *Runner) Report(opts ReportOptions) (*report.ApiTestReports, error) {
+	if opts.SwaggerPath == "" {
+		return nil, fmt.Errorf("swagger path is required")
+	}
+	workflowMutex.Lock()
+	defer workflowMutex.Unlock()
+	if err := setGlobals(opts.Cloud, "", config.Defaults{}); err != nil {
+		return nil, err
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %+v", err)
+	}
+	if opts.WorkingDir != "" {
+		wd, err = filepath.Abs(opts.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("working directory is invalid: %+v", err)
+		}
+	}
+
+	r.logger.Infof("generating swagger accuracy reports for the test cases in %s...", wd)
+	reports, err := report.GenerateApiTestReports(wd, opts.SwaggerPath, opts.Suppressions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate swagger accuracy report: %+v", err)
+	}
+	return reports, nil
+}
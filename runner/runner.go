@@ -0,0 +1,52 @@
+// Package runner runs the armstrong workflows programmatically, the CLI commands are thin wrappers over it.
+// The methods return the typed results and errors instead of exit codes, and never exit the process.
+package runner
+
+import (
+	"sync"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/resource"
+	"github.com/sirupsen/logrus"
+)
+
+// workflowMutex serializes the workflows of all the runners, because the selected cloud, the pinned index commit and the variable defaults
+// are package-level state of the cloud, coverage and resource packages.
+var workflowMutex sync.Mutex
+
+// Logger receives the progress messages of the workflows, both *logrus.Logger and *logrus.Entry implement it
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Runner runs the generate, test and report workflows. The workflows can be called concurrently, but they're run one at a time,
+// and each of them resets the package-level state which it uses, so the options of the previous workflows don't leak into it.
+type Runner struct {
+	logger Logger
+}
+
+// New returns a runner which writes the progress messages to the logger, the standard logrus logger is used if it's nil.
+// The packages used by the workflows, e.g., terraform and coverage, still log to the standard logrus logger.
+func New(logger Logger) *Runner {
+	if logger == nil {
+		logger = logrus.StandardLogger()
+	}
+	return &Runner{
+		logger: logger,
+	}
+}
+
+// setGlobals sets all the package-level state used by the workflows, the caller must hold the workflowMutex
+func setGlobals(env cloud.Environment, indexCommit string, defaults config.Defaults) error {
+	if err := cloud.Set(env); err != nil {
+		return err
+	}
+	coverage.SetIndexCommit(indexCommit)
+	resource.SetVariableDefaults(defaults.NamePrefix, defaults.Location)
+	return nil
+}
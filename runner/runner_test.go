@@ -0,0 +1,95 @@
+package runner_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/runner"
+	"github.com/sirupsen/logrus"
+)
+
+func newRunner() *runner.Runner {
+	logger := logrus.New()
+	logger.SetLevel(logrus.WarnLevel)
+	return runner.New(logger)
+}
+
+func TestRunner_Generate(t *testing.T) {
+	wd := t.TempDir()
+	result, err := newRunner().Generate(runner.GenerateOptions{
+		WorkingDir:  wd,
+		SwaggerPath: filepath.Join("..", "commands", "testdata", "TestGenerateCommand_fromSwagger", "purview.json"),
+	})
+	if err != nil {
+		t.Fatalf("expect no error, but got %+v", err)
+	}
+	if result.WorkingDir != wd {
+		t.Errorf("expect working directory %s, but got %s", wd, result.WorkingDir)
+	}
+	if len(result.Files) == 0 {
+		t.Fatalf("expect the written files in the result")
+	}
+	for _, file := range result.Files {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("expect %s is written, but got %+v", file, err)
+		}
+	}
+}
+
+func TestRunner_GenerateErrors(t *testing.T) {
+	testcases := []struct {
+		name string
+		opts runner.GenerateOptions
+	}{
+		{name: "no input", opts: runner.GenerateOptions{}},
+		{name: "multiple inputs", opts: runner.GenerateOptions{SwaggerPath: "swagger.json", ExamplePath: "example.json"}},
+		{name: "readme without tag", opts: runner.GenerateOptions{ReadmePath: "readme.md"}},
+		{name: "swagger not found", opts: runner.GenerateOptions{SwaggerPath: filepath.Join(t.TempDir(), "not_found.json")}},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.WorkingDir = t.TempDir()
+			if _, err := newRunner().Generate(tc.opts); err == nil {
+				t.Errorf("expect an error, but got nil")
+			}
+		})
+	}
+}
+
+func TestRunner_ReportWithoutSwagger(t *testing.T) {
+	if _, err := newRunner().Report(runner.ReportOptions{WorkingDir: t.TempDir()}); err == nil {
+		t.Errorf("expect an error when the swagger path is not specified")
+	}
+}
+
+func TestRunner_GenerateResetsDefaults(t *testing.T) {
+	generate := func(defaults config.Defaults) string {
+		result, err := newRunner().Generate(runner.GenerateOptions{
+			WorkingDir:  t.TempDir(),
+			SwaggerPath: filepath.Join("..", "commands", "testdata", "TestGenerateCommand_fromSwagger", "purview.json"),
+			Defaults:    defaults,
+		})
+		if err != nil {
+			t.Fatalf("expect no error, but got %+v", err)
+		}
+		content := ""
+		for _, file := range result.Files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			content += string(data)
+		}
+		return content
+	}
+
+	if content := generate(config.Defaults{NamePrefix: "armtest", Location: "eastus"}); !strings.Contains(content, `"armtest`) || !strings.Contains(content, `"eastus"`) {
+		t.Fatalf("expect the specified defaults in the configurations, but got:\n%s", content)
+	}
+	if content := generate(config.Defaults{}); strings.Contains(content, `"armtest`) || strings.Contains(content, `"eastus"`) {
+		t.Errorf("expect the defaults of the previous workflow to be reset, but got:\n%s", content)
+	}
+}
@@ -0,0 +1,403 @@
+package runner
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/azure/armstrong/cloud"
+	"github.com/azure/armstrong/config"
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/knownerror"
+	"github.com/azure/armstrong/redact"
+	"github.com/azure/armstrong/report"
+	"github.com/azure/armstrong/tf"
+	"github.com/azure/armstrong/types"
+	"github.com/azure/armstrong/utils"
+	"github.com/ms-henglu/pal/trace"
+)
+
+const (
+	allPassedReportFileName     = "Onboard Terraform - all_passed_report.md"
+	partialPassedReportFileName = "Onboard Terraform - partial_passed_report.md"
+)
+
+// TestOptions are the options of the test workflow
+type TestOptions struct {
+	// WorkingDir is the directory of the terraform configurations, default is the current directory
+	WorkingDir string
+	// Verbose shows the terraform logs
+	Verbose bool
+	// DestroyAfterTest destroys the created resources after the test
+	DestroyAfterTest bool
+	// SwaggerPath is the path to the swagger which is being test, the swagger accuracy report is generated if it's specified
+	SwaggerPath string
+	// RedactPatterns are the regular expressions of the values which are redacted in the traces and reports
+	RedactPatterns []string
+	// KnownErrors are the files or directories of the known error rules, which are matched besides the built-in rules
+	KnownErrors []string
+	// Cloud selects the Azure cloud of the testing resources, the ARM_ENVIRONMENT environment variable is used if it's not set
+	Cloud cloud.Environment
+	// IndexCommit pins the commit of the cached swagger index, which is used by the coverage report when the swagger path is not specified
+	IndexCommit string
+	// Terraform configures the terraform executable and the provider installation, the environment variables are used for the unset fields
+	Terraform tf.Options
+	// Thresholds are checked after the test, the violations are returned in the result
+	Thresholds config.Thresholds
+	// Suppressions are merged with the ones in ApiTestConfig.json of the working directory
+	Suppressions []report.Suppression
+	// CommandLine and ToolVersion are shown in the run summary
+	CommandLine string
+	ToolVersion string
+}
+
+// TestResult is the result of the test workflow
+type TestResult struct {
+	// ReportDir is the directory of the reports
+	ReportDir      string
+	PassReport     types.PassReport
+	ErrorReport    types.ErrorReport
+	DiffReport     types.DiffReport
+	CoverageReport coverage.CoverageReport
+	Summary        types.RunSummary
+	// Violations are the thresholds which are not met
+	Violations []string
+}
+
+// Test provisions the testing resources, verifies them by the plan and writes the reports in a new report directory of the working directory.
+// The errors when creating the resources and the differences are returned in the result, the error is returned only if the test can't be run.
+func (r *Runner) Test(opts TestOptions) (*TestResult, error) {
+	startTime := time.Now()
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get working directory: %+v", err)
+	}
+	if opts.WorkingDir != "" {
+		wd, err = filepath.Abs(opts.WorkingDir)
+		if err != nil {
+			return nil, fmt.Errorf("working directory is invalid: %+v", err)
+		}
+	}
+	if opts.SwaggerPath != "" {
+		opts.SwaggerPath, err = filepath.Abs(opts.SwaggerPath)
+		if err != nil {
+			return nil, fmt.Errorf("swagger file path is invalid: %+v", err)
+		}
+	}
+	workflowMutex.Lock()
+	defer workflowMutex.Unlock()
+	if err := setGlobals(opts.Cloud, opts.IndexCommit, config.Defaults{}); err != nil {
+		return nil, err
+	}
+	redactor, err := redact.NewRedactor(opts.SwaggerPath, opts.RedactPatterns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create redactor: %+v", err)
+	}
+	knowledgeBase, err := knownerror.NewKnowledgeBase(opts.KnownErrors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the known error rules: %+v", err)
+	}
+	suppressions := report.NewSuppressions(report.LoadSuppressions(wd, opts.Suppressions))
+	terraform, err := tf.NewTerraform(wd, opts.Verbose, opts.Terraform)
+	if err != nil {
+		return nil, fmt.Errorf("error creating terraform executable: %+v", err)
+	}
+
+	r.logger.Infof("prepare working directory\n")
+	_ = terraform.Init()
+
+	r.logger.Infof("running plan command to check changes...")
+	plan, err := terraform.Plan()
+	if err != nil {
+		return nil, fmt.Errorf("error running terraform plan: %+v", err)
+	}
+
+	actions := tf.GetChanges(plan)
+	create, replace, update, delete := 0, 0, 0, 0
+	for _, action := range actions {
+		switch action {
+		case tf.ActionCreate:
+			create++
+		case tf.ActionReplace:
+			replace++
+		case tf.ActionUpdate:
+			update++
+		case tf.ActionDelete:
+			delete++
+		}
+	}
+	r.logger.Infof("found %d changes in total, create: %d, replace: %d, update: %d, delete: %d\n", create+replace+update+delete, create, replace, update, delete)
+	phases := []types.PhaseSummary{
+		{
+			Name:   "plan",
+			Status: types.StatusPassed,
+			Detail: fmt.Sprintf("%d changes, create: %d, replace: %d, update: %d, delete: %d", create+replace+update+delete, create, replace, update, delete),
+		},
+	}
+	r.logger.Infof("running apply command to provision test resource...")
+	applyErr := terraform.Apply()
+	applyPhase := len(phases)
+	if applyErr != nil {
+		r.logger.Errorf("error running terraform apply: %+v\n", applyErr)
+		phases = append(phases, types.PhaseSummary{Name: "apply", Status: types.StatusFailed})
+	} else {
+		r.logger.Infof("test resource has been provisioned")
+		phases = append(phases, types.PhaseSummary{Name: "apply", Status: types.StatusPassed})
+	}
+
+	r.logger.Infof("running plan command to verify test resource...")
+	plan, planErr := terraform.Plan()
+	if planErr != nil {
+		r.logger.Errorf("error running terraform plan: %+v\n", planErr)
+		phases = append(phases, types.PhaseSummary{Name: "verify", Status: types.StatusFailed, Detail: fmt.Sprintf("%+v", planErr)})
+	}
+
+	reportDir := fmt.Sprintf("armstrong_reports_%s", time.Now().Format(time.DateTime))
+	reportDir = strings.ReplaceAll(reportDir, ":", "-")
+	reportDir = strings.ReplaceAll(reportDir, " ", "_")
+	reportDir = path.Join(wd, reportDir)
+	r.logger.Infof("creating report directory %s\n", reportDir)
+	err = os.Mkdir(reportDir, 0755)
+	if err != nil {
+		return nil, fmt.Errorf("error creating report dir %s: %+v", reportDir, err)
+	}
+
+	r.logger.Infof("parsing log.txt...")
+	logs, err := trace.NewRequestTraceParser(trace.TextParser).ParseFromFile(path.Join(wd, "log.txt"))
+	if err != nil {
+		r.logger.Errorf("parsing log.txt: %+v", err)
+	}
+	// collect the secrets before writing the reports, so they're redacted in the reports
+	redactor.Collect(logs)
+
+	r.logger.Infof("generating reports...")
+	result := &TestResult{
+		ReportDir: reportDir,
+	}
+	if planErr == nil {
+		if applyErr == nil && len(tf.GetChanges(plan)) == 0 {
+			state, err := terraform.Show()
+			if err != nil {
+				return nil, fmt.Errorf("error showing terraform state: %+v", err)
+			}
+			result.PassReport = tf.NewPassReportFromState(state)
+			result.PassReport.TerraformVersion = terraform.Executable.String()
+			result.CoverageReport, err = tf.NewCoverageReportFromState(state, opts.SwaggerPath)
+			if err != nil {
+				r.logger.Errorf("error producing coverage report: %+v", err)
+			}
+			suppressions.SuppressCoverage(&result.CoverageReport, false)
+			r.storePassReport(result.PassReport, result.CoverageReport, reportDir, allPassedReportFileName, redactor)
+		} else {
+			result.PassReport = tf.NewPassReport(plan)
+			result.PassReport.TerraformVersion = terraform.Executable.String()
+			result.CoverageReport, err = tf.NewCoverageReport(plan, opts.SwaggerPath)
+			if err != nil {
+				r.logger.Errorf("error producing coverage report: %+v", err)
+			}
+			suppressions.SuppressCoverage(&result.CoverageReport, false)
+			r.storePassReport(result.PassReport, result.CoverageReport, reportDir, partialPassedReportFileName, redactor)
+		}
+	}
+
+	errorReport := tf.NewErrorReport(applyErr, logs)
+	errorReport.TerraformVersion = terraform.Executable.String()
+	swaggerResolver := report.SwaggerResolver{SwaggerPath: opts.SwaggerPath}
+	swaggerResolver.ResolveErrors(&errorReport, "PUT")
+	knowledgeBase.ResolveErrors(&errorReport)
+	suppressions.SuppressErrors(&errorReport)
+	errorReportFiles := r.storeErrorReport(errorReport, reportDir, redactor)
+	if applyErr != nil {
+		phases[applyPhase].Detail = fmt.Sprintf("%d errors", len(errorReport.Errors))
+	}
+	result.ErrorReport = errorReport
+
+	diffReport := tf.NewDiffReport(plan, logs)
+	swaggerResolver.ResolveDiffs(&diffReport)
+	suppressions.SuppressDiffs(&diffReport)
+	diffReportFiles := r.storeDiffReport(diffReport, reportDir, redactor)
+	if planErr == nil {
+		verifyPhase := types.PhaseSummary{Name: "verify", Status: types.StatusPassed, Detail: fmt.Sprintf("%d resources with API issues", len(diffReport.Diffs))}
+		if len(diffReport.Diffs) != 0 {
+			verifyPhase.Status = types.StatusFailed
+		}
+		phases = append(phases, verifyPhase)
+	}
+	result.DiffReport = diffReport
+
+	if applyErr == nil && planErr == nil && opts.DestroyAfterTest {
+		r.logger.Infof("running destroy command to delete resources...")
+		destroyErr := terraform.Destroy()
+		if destroyErr != nil {
+			r.logger.Errorf("error running terraform destroy: %+v\n", destroyErr)
+			phases = append(phases, types.PhaseSummary{Name: "destroy", Status: types.StatusFailed, Detail: fmt.Sprintf("%+v", destroyErr)})
+		} else {
+			r.logger.Infof("test resource has been deleted")
+			phases = append(phases, types.PhaseSummary{Name: "destroy", Status: types.StatusPassed})
+		}
+		r.logger.Infof("parsing log.txt...")
+		newLogs, err := trace.NewRequestTraceParser(trace.TextParser).ParseFromFile(path.Join(wd, "log.txt"))
+		if err != nil {
+			r.logger.Errorf("parsing log.txt: %+v", err)
+		}
+		logs = newLogs
+	} else {
+		r.logger.Warnf("the created resources will not be destroyed because either there is an error or destroy-after-test flag is not set")
+		phases = append(phases, types.PhaseSummary{Name: "destroy", Status: types.StatusSkipped})
+	}
+
+	r.logger.Infof("generating traces...")
+	traceDir := path.Join(wd, "traces")
+	if !utils.Exists(traceDir) {
+		err = os.Mkdir(traceDir, 0755)
+		if err != nil {
+			r.logger.Errorf("error creating trace dir %s: %+v", traceDir, err)
+		}
+	}
+
+	traceFiles := report.StoreOavTraffic(redactor.Traces(logs), traceDir)
+	r.logger.Infof("copying traces to report directory...")
+	if err := utils.Copy(traceDir, path.Join(reportDir, "traces")); err != nil {
+		r.logger.Errorf("error copying traces: %+v", err)
+	}
+
+	if opts.SwaggerPath != "" {
+		accuracyPhase := types.PhaseSummary{Name: "swagger accuracy", Status: types.StatusPassed}
+		accuracy, err := report.StoreSwaggerReports(traceDir, opts.SwaggerPath, reportDir, suppressions, r.logger)
+		switch {
+		case err != nil:
+			accuracyPhase.Status = types.StatusFailed
+			accuracyPhase.Detail = fmt.Sprintf("%+v", err)
+		case len(accuracy.Errors) != 0:
+			accuracyPhase.Status = types.StatusFailed
+			accuracyPhase.Detail = fmt.Sprintf("%d swagger accuracy errors", len(accuracy.Errors))
+		}
+		phases = append(phases, accuracyPhase)
+	} else {
+		r.logger.Warnf("no swagger file provided, swagger accuracy report will not be generated")
+		phases = append(phases, types.PhaseSummary{Name: "swagger accuracy", Status: types.StatusSkipped, Detail: "no swagger file provided"})
+	}
+
+	reportFiles := make(map[string][]string)
+	for address, files := range errorReportFiles {
+		reportFiles[address] = append(reportFiles[address], files...)
+	}
+	for address, files := range diffReportFiles {
+		reportFiles[address] = append(reportFiles[address], files...)
+	}
+	resources := report.NewResourceSummaries(result.PassReport, errorReport, diffReport, reportFiles)
+	traceUrls := make([]string, 0)
+	for _, l := range logs {
+		traceUrls = append(traceUrls, l.Url)
+	}
+	for i := range traceFiles {
+		if traceFiles[i] != "" {
+			traceFiles[i] = path.Join("traces", traceFiles[i])
+		}
+	}
+	report.LinkTraces(resources, traceFiles, traceUrls)
+	coverages := report.NewCoverageSummaries(result.CoverageReport)
+	result.Violations = opts.Thresholds.Check(coverages, len(errorReport.Errors), len(diffReport.Diffs))
+	if opts.Thresholds.IsSet() {
+		thresholdsPhase := types.PhaseSummary{Name: "thresholds", Status: types.StatusPassed}
+		if len(result.Violations) != 0 {
+			thresholdsPhase.Status = types.StatusFailed
+			thresholdsPhase.Detail = strings.Join(result.Violations, "\n")
+		}
+		phases = append(phases, thresholdsPhase)
+	}
+	result.Summary = r.storeRunSummary(types.RunSummary{
+		CommandLine:        opts.CommandLine,
+		ToolVersion:        opts.ToolVersion,
+		TerraformVersion:   terraform.Executable.String(),
+		StartTime:          startTime,
+		EndTime:            time.Now(),
+		Phases:             phases,
+		Resources:          resources,
+		Coverages:          coverages,
+		SwaggerIndexCommit: result.CoverageReport.IndexCommit,
+		Suppressed:         suppressions.Findings(),
+	}, reportDir, redactor)
+
+	r.logger.Infof("---------------- Summary ----------------")
+	r.logger.Infof("%d resources passed the tests.", len(result.PassReport.Resources))
+	if len(errorReport.Errors) != 0 {
+		r.logger.Infof("%d errors when creating the testing resources.", len(errorReport.Errors))
+	}
+	if len(diffReport.Diffs) != 0 {
+		r.logger.Infof("%d API issues.", len(diffReport.Diffs))
+	}
+	r.logger.Infof("all reports have been saved in the report directory: %s, please check.", reportDir)
+	return result, nil
+}
+
+func (r *Runner) storePassReport(passReport types.PassReport, coverageReport coverage.CoverageReport, reportDir string, reportName string, redactor *redact.Redactor) {
+	if len(passReport.Resources) != 0 {
+		err := os.WriteFile(path.Join(reportDir, reportName), []byte(redactor.String(report.PassedMarkdownReport(passReport, coverageReport))), 0644)
+		if err != nil {
+			r.logger.Warnf("failed to save passed markdown report to %s: %+v", reportName, err)
+		} else {
+			r.logger.Infof("markdown report saved to %s", reportName)
+		}
+	}
+}
+
+// storeErrorReport writes the error reports, it returns the written file names keyed by the resource addresses
+func (r *Runner) storeErrorReport(errorReport types.ErrorReport, reportDir string, redactor *redact.Redactor) map[string][]string {
+	out := make(map[string][]string)
+	for _, e := range errorReport.Errors {
+		address := report.ErrorAddress(e)
+		r.logger.Warnf("found an error when creating %s, address: %s\n", e.Type, address)
+		markdownFilename := fmt.Sprintf("Error - %s_%s.md", strings.ReplaceAll(e.Type, "/", "_"), e.Label)
+		err := os.WriteFile(path.Join(reportDir, markdownFilename), []byte(redactor.String(report.ErrorMarkdownReport(e, errorReport.Logs, errorReport.TerraformVersion))), 0644)
+		if err != nil {
+			r.logger.Warnf("failed to save markdown report to %s: %+v", markdownFilename, err)
+		} else {
+			r.logger.Infof("markdown report saved to %s", markdownFilename)
+			out[address] = append(out[address], markdownFilename)
+		}
+	}
+	return out
+}
+
+// storeDiffReport writes the diff reports, it returns the written file names keyed by the resource addresses
+func (r *Runner) storeDiffReport(diffReport types.DiffReport, reportDir string, redactor *redact.Redactor) map[string][]string {
+	out := make(map[string][]string)
+	for _, d := range diffReport.Diffs {
+		r.logger.Warnf("found differences between response and configuration:\n\naddress: %s\n\n%s\n", d.Address, report.DiffMessageTerraform(d.Change))
+		r.logger.Infof("report:\n\naddresss: %s\t%s\n", d.Address, report.DiffMessageReadable(d.Change))
+		markdownFilename := fmt.Sprintf("Error - %s_%s.md", strings.ReplaceAll(d.Type, "/", "_"), strings.TrimPrefix(d.Address, "azapi_resource."))
+		err := os.WriteFile(path.Join(reportDir, markdownFilename), []byte(redactor.String(report.DiffMarkdownReport(d, diffReport.Logs))), 0644)
+		if err != nil {
+			r.logger.Warnf("failed to save markdown report to %s: %+v", markdownFilename, err)
+		} else {
+			r.logger.Infof("markdown report saved to %s", markdownFilename)
+			out[d.Address] = append(out[d.Address], markdownFilename)
+		}
+	}
+	return out
+}
+
+// storeRunSummary writes the run summary as the index of the report directory, in both markdown and html formats, it returns the summary with the artifacts
+func (r *Runner) storeRunSummary(summary types.RunSummary, reportDir string, redactor *redact.Redactor) types.RunSummary {
+	artifacts, err := report.ListArtifacts(reportDir)
+	if err != nil {
+		r.logger.Warnf("failed to list the files in the report directory: %+v", err)
+	}
+	summary.Artifacts = artifacts
+	content := redactor.String(report.RunSummaryMarkdownReport(summary))
+	if err := os.WriteFile(path.Join(reportDir, report.RunSummaryMarkdownFileName), []byte(content), 0644); err != nil {
+		r.logger.Warnf("failed to save run summary to %s: %+v", report.RunSummaryMarkdownFileName, err)
+		return summary
+	}
+	if err := os.WriteFile(path.Join(reportDir, report.RunSummaryHtmlFileName), []byte(report.RunSummaryHtmlReport(content)), 0644); err != nil {
+		r.logger.Warnf("failed to save run summary to %s: %+v", report.RunSummaryHtmlFileName, err)
+		return summary
+	}
+	r.logger.Infof("run summary saved to %s", report.RunSummaryMarkdownFileName)
+	return summary
+}
@@ -0,0 +1,149 @@
+package sarif
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+
+	ToolName           = "armstrong"
+	ToolInformationUri = "https://github.com/Azure/armstrong"
+
+	LevelError   = "error"
+	LevelWarning = "warning"
+	LevelNote    = "note"
+)
+
+// Log is the root object of a SARIF 2.1.0 file, only the properties used by armstrong are defined,
+// the specification is https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type Log struct {
+	Version string `json:"version"`
+	Schema  string `json:"$schema"`
+	Runs    []Run  `json:"runs"`
+}
+
+type Run struct {
+	Tool    Tool     `json:"tool"`
+	Results []Result `json:"results"`
+	// VersionControlProvenance are the commits of the repos which the results are based on, e.g., the swagger index commit
+	VersionControlProvenance []VersionControlDetails `json:"versionControlProvenance,omitempty"`
+}
+
+type VersionControlDetails struct {
+	RepositoryUri string `json:"repositoryUri"`
+	RevisionId    string `json:"revisionId,omitempty"`
+}
+
+type Tool struct {
+	Driver Driver `json:"driver"`
+}
+
+type Driver struct {
+	Name           string `json:"name"`
+	InformationUri string `json:"informationUri"`
+	Rules          []Rule `json:"rules"`
+}
+
+type Rule struct {
+	Id               string   `json:"id"`
+	ShortDescription *Message `json:"shortDescription,omitempty"`
+	HelpUri          string   `json:"helpUri,omitempty"`
+}
+
+type Result struct {
+	RuleId    string     `json:"ruleId"`
+	Level     string     `json:"level"`
+	Message   Message    `json:"message"`
+	Locations []Location `json:"locations,omitempty"`
+}
+
+type Message struct {
+	Text string `json:"text"`
+}
+
+type Location struct {
+	PhysicalLocation PhysicalLocation `json:"physicalLocation"`
+}
+
+type PhysicalLocation struct {
+	ArtifactLocation ArtifactLocation `json:"artifactLocation"`
+	Region           *Region          `json:"region,omitempty"`
+}
+
+type ArtifactLocation struct {
+	Uri string `json:"uri"`
+}
+
+type Region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+	EndLine     int `json:"endLine,omitempty"`
+}
+
+// NewLog returns a SARIF log with a single run of armstrong, the rules are sorted by id
+func NewLog(rules []Rule, results []Result) Log {
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Id < rules[j].Id
+	})
+	if results == nil {
+		results = make([]Result, 0)
+	}
+	return Log{
+		Version: Version,
+		Schema:  Schema,
+		Runs: []Run{
+			{
+				Tool: Tool{
+					Driver: Driver{
+						Name:           ToolName,
+						InformationUri: ToolInformationUri,
+						Rules:          rules,
+					},
+				},
+				Results: results,
+			},
+		},
+	}
+}
+
+// NewLocation returns the location of the file, the line is omitted if it's not positive
+func NewLocation(path string, line int, column int) Location {
+	out := Location{
+		PhysicalLocation: PhysicalLocation{
+			ArtifactLocation: ArtifactLocation{
+				Uri: ArtifactUri(path),
+			},
+		},
+	}
+	if line > 0 {
+		out.PhysicalLocation.Region = &Region{
+			StartLine:   line,
+			StartColumn: column,
+		}
+	}
+	return out
+}
+
+// ArtifactUri returns the uri of the file, the files under the current directory are relative to it,
+// so the code scanning tools can map them to the files in the repository
+func ArtifactUri(path string) string {
+	if !filepath.IsAbs(path) {
+		return filepath.ToSlash(path)
+	}
+	if wd, err := os.Getwd(); err == nil {
+		if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+			return filepath.ToSlash(rel)
+		}
+	}
+	return "file://" + filepath.ToSlash(path)
+}
+
+func (l Log) MarshalIndent() ([]byte, error) {
+	return json.MarshalIndent(l, "", "  ")
+}
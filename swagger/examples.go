@@ -0,0 +1,350 @@
+package swagger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/azure/armstrong/coverage"
+	"github.com/azure/armstrong/redact"
+	"github.com/azure/armstrong/utils"
+	"github.com/go-openapi/loads"
+	"github.com/go-openapi/spec"
+	"github.com/ms-henglu/pal/formatter"
+	"github.com/sirupsen/logrus"
+)
+
+// the response headers which are kept in the examples, they're used by the long-running operations
+var exampleResponseHeaders = []string{"Location", "Azure-AsyncOperation", "Retry-After"}
+
+// Example is an x-ms-examples file generated from the test traffic
+type Example struct {
+	Title       string
+	OperationId string
+	Method      string
+	ApiPath     string
+	// SwaggerPath is the swagger file which defines the operation
+	SwaggerPath string
+	Parameters  map[string]interface{}
+	// Responses is the responses by status code, e.g., {"200": {"body": {...}}}
+	Responses map[string]interface{}
+}
+
+// FileName returns the file name of the example, e.g., AutomationAccount_CreateOrUpdate.json
+func (e Example) FileName() string {
+	return e.Title + ".json"
+}
+
+// MarshalIndent returns the content of the example file in the x-ms-examples format
+func (e Example) MarshalIndent() (string, error) {
+	out, err := marshalIndent(map[string]interface{}{
+		"parameters": e.Parameters,
+		"responses":  e.Responses,
+	})
+	if err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// NewExamplesFromTraces generates the examples from the successful requests in the trace directory,
+// each request is mapped to its operation in the swagger, the IDs and secrets in the requests are redacted.
+func NewExamplesFromTraces(traceDir string, swaggerPath string) ([]Example, error) {
+	files, err := os.ReadDir(traceDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return traceIndex(files[i].Name()) < traceIndex(files[j].Name())
+	})
+	redactor, err := redact.NewRedactor("", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	exampleMap := make(map[string]*Example)
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+
+		data, err := os.ReadFile(path.Join(traceDir, file.Name()))
+		if err != nil {
+			logrus.Warnf("failed to read file %s: %+v", file.Name(), err)
+			continue
+		}
+
+		var trace formatter.OavTraffic
+		if err := json.Unmarshal(data, &trace); err != nil {
+			logrus.Warnf("failed to unmarshal file %s: %+v", file.Name(), err)
+			continue
+		}
+
+		statusCode, _ := strconv.Atoi(trace.LiveResponse.StatusCode)
+		if statusCode < 200 || statusCode >= 300 {
+			continue
+		}
+
+		requestUrl, err := url.Parse(trace.LiveRequest.Url)
+		if err != nil {
+			logrus.Warnf("failed to parse url %s: %+v", trace.LiveRequest.Url, err)
+			continue
+		}
+		method := strings.ToUpper(trace.LiveRequest.Method)
+		swaggerModel, err := coverage.GetModelInfoFromLocalDir(requestUrl.Path, swaggerPath, method)
+		if err != nil {
+			logrus.Warnf("failed to get model info from local dir: %+v", err)
+			continue
+		}
+		if swaggerModel == nil {
+			// the API is not in the swagger file, usually it's an API that out of the testing scope
+			continue
+		}
+
+		key := fmt.Sprintf("%s-%s", method, swaggerModel.ApiPath)
+		example, ok := exampleMap[key]
+		if !ok {
+			swaggerFile, operation, err := findOperation(swaggerPath, swaggerModel.ApiPath, method)
+			if err != nil {
+				logrus.Warnf("failed to find operation %s %s: %+v", method, swaggerModel.ApiPath, err)
+				continue
+			}
+			example = &Example{
+				Title:       swaggerModel.OperationID,
+				OperationId: swaggerModel.OperationID,
+				Method:      method,
+				ApiPath:     swaggerModel.ApiPath,
+				SwaggerPath: swaggerFile,
+				Parameters:  exampleParameters(swaggerFile, swaggerModel.ApiPath, operation, requestUrl, trace.LiveRequest, redactor),
+				Responses:   make(map[string]interface{}),
+			}
+			exampleMap[key] = example
+		}
+
+		if _, ok := example.Responses[trace.LiveResponse.StatusCode]; ok {
+			continue
+		}
+		response := make(map[string]interface{})
+		if trace.LiveResponse.Body != nil {
+			response["body"] = redactor.Value("", trace.LiveResponse.Body)
+		}
+		headers := make(map[string]interface{})
+		for _, name := range exampleResponseHeaders {
+			if value := headerValue(trace.LiveResponse.Headers, name); value != "" {
+				headers[name] = redactor.Value(name, value)
+			}
+		}
+		if len(headers) != 0 {
+			response["headers"] = headers
+		}
+		example.Responses[trace.LiveResponse.StatusCode] = response
+	}
+
+	out := make([]Example, 0)
+	for _, example := range exampleMap {
+		out = append(out, *example)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].ApiPath != out[j].ApiPath {
+			return out[i].ApiPath < out[j].ApiPath
+		}
+		return out[i].Method < out[j].Method
+	})
+	return out, nil
+}
+
+// AddExampleRef adds the reference to the example file in the x-ms-examples of the operation, only the x-ms-examples is changed, the other content in the swagger file is kept as is
+func AddExampleRef(example Example, exampleFile string) error {
+	ref, err := filepath.Rel(filepath.Dir(example.SwaggerPath), exampleFile)
+	if err != nil {
+		return err
+	}
+	ref = filepath.ToSlash(ref)
+	if !strings.HasPrefix(ref, ".") {
+		ref = "./" + ref
+	}
+
+	data, err := os.ReadFile(example.SwaggerPath)
+	if err != nil {
+		return err
+	}
+	operation, ok, err := objectPath(data, "paths", example.ApiPath, strings.ToLower(example.Method))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %+v", example.SwaggerPath, err)
+	}
+	if !ok {
+		return fmt.Errorf("operation %s %s is not found in %s", example.Method, example.ApiPath, example.SwaggerPath)
+	}
+	exampleRef := map[string]interface{}{"$ref": ref}
+
+	examples, ok, err := objectMember(data, operation, "x-ms-examples")
+	if err != nil {
+		return fmt.Errorf("parsing %s: %+v", example.SwaggerPath, err)
+	}
+	if !ok {
+		data, err = insertMember(data, operation, "x-ms-examples", map[string]interface{}{example.Title: exampleRef})
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(example.SwaggerPath, data, 0644)
+	}
+
+	existing, ok, err := objectMember(data, examples, example.Title)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %+v", example.SwaggerPath, err)
+	}
+	if !ok {
+		data, err = insertMember(data, examples, example.Title, exampleRef)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(example.SwaggerPath, data, 0644)
+	}
+
+	var existingRef map[string]interface{}
+	if err := json.Unmarshal(data[existing.start:existing.end], &existingRef); err == nil && existingRef["$ref"] == ref {
+		return nil
+	}
+	// the existing reference to another file is replaced
+	out := append([]byte{}, data[:existing.start]...)
+	refJSON, err := json.Marshal(ref)
+	if err != nil {
+		return err
+	}
+	out = append(out, `{"$ref": `+string(refJSON)+`}`...)
+	out = append(out, data[existing.end:]...)
+	return os.WriteFile(example.SwaggerPath, out, 0644)
+}
+
+// findOperation returns the swagger file which defines the operation and the operation itself
+func findOperation(swaggerPath string, apiPath string, method string) (string, *spec.Operation, error) {
+	swaggerPath, err := filepath.Abs(swaggerPath)
+	if err != nil {
+		return "", nil, err
+	}
+	file, err := os.Stat(swaggerPath)
+	if err != nil {
+		return "", nil, err
+	}
+	files := []string{swaggerPath}
+	if file.IsDir() {
+		files, err = utils.ListFiles(swaggerPath, ".json", 1)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+	for _, filename := range files {
+		doc, err := loads.JSONSpec(filename)
+		if err != nil || doc.Spec().Paths == nil {
+			continue
+		}
+		pathItem, ok := doc.Spec().Paths.Paths[apiPath]
+		if !ok {
+			continue
+		}
+		var operation *spec.Operation
+		switch method {
+		case http.MethodGet:
+			operation = pathItem.Get
+		case http.MethodPut:
+			operation = pathItem.Put
+		case http.MethodPost:
+			operation = pathItem.Post
+		case http.MethodDelete:
+			operation = pathItem.Delete
+		case http.MethodPatch:
+			operation = pathItem.Patch
+		case http.MethodHead:
+			operation = pathItem.Head
+		}
+		if operation != nil {
+			return filename, operation, nil
+		}
+	}
+	return "", nil, fmt.Errorf("no swagger file defines the operation")
+}
+
+// exampleParameters returns the parameters of the example, the path parameters are parsed from the request url by the api path,
+// the body parameter uses the request body, the query and header parameters use the values in the request.
+func exampleParameters(swaggerFile string, apiPath string, operation *spec.Operation, requestUrl *url.URL, request formatter.LiveRequest, redactor *redact.Redactor) map[string]interface{} {
+	out := make(map[string]interface{})
+	for name, value := range pathParameters(apiPath, requestUrl.Path) {
+		out[name] = redactor.Value(name, value)
+	}
+	for name, values := range requestUrl.Query() {
+		if len(values) != 0 {
+			out[name] = redactor.Value(name, values[0])
+		}
+	}
+
+	for _, param := range operation.Parameters {
+		if param.Ref.String() != "" {
+			resolved, err := spec.ResolveParameterWithBase(nil, param.Ref, &spec.ExpandOptions{RelativeBase: swaggerFile})
+			if err != nil {
+				logrus.Debugf("failed to resolve parameter %s: %+v", param.Ref.String(), err)
+				continue
+			}
+			param = *resolved
+		}
+		switch param.In {
+		case "body":
+			if request.Body != nil {
+				out[param.Name] = redactor.Value("", request.Body)
+			}
+		case "header":
+			if value := headerValue(request.Headers, param.Name); value != "" {
+				out[param.Name] = redactor.Value(param.Name, value)
+			}
+		}
+	}
+	return out
+}
+
+// pathParameters matches the request path with the api path from the end, the values of the placeholders are returned,
+// the first placeholder like {scope} and {resourceId} could match multiple segments.
+func pathParameters(apiPath string, requestPath string) map[string]string {
+	out := make(map[string]string)
+	pathParts := strings.Split(strings.Trim(apiPath, "/"), "/")
+	requestParts := strings.Split(strings.Trim(requestPath, "/"), "/")
+	i := len(pathParts) - 1
+	j := len(requestParts) - 1
+	for i >= 0 && j >= 0 {
+		part := pathParts[i]
+		if strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") {
+			name := strings.TrimSuffix(strings.TrimPrefix(part, "{"), "}")
+			if i == 0 {
+				out[name] = strings.Join(requestParts[:j+1], "/")
+				break
+			}
+			out[name] = requestParts[j]
+		}
+		i--
+		j--
+	}
+	return out
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
+
+// traceIndex returns the index of the trace file, e.g., 10 for trace-10.json, so the traces are processed in the order they're recorded
+func traceIndex(filename string) int {
+	index, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(filename, "trace-"), ".json"))
+	if err != nil {
+		return 0
+	}
+	return index
+}
@@ -0,0 +1,152 @@
+package swagger_test
+
+import (
+	"encoding/json"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/redact"
+	"github.com/azure/armstrong/swagger"
+)
+
+const (
+	exampleApiPath  = "/subscriptions/{subscriptionId}/resourceGroups/{resourceGroupName}/providers/Microsoft.Storage/storageAccounts/{accountName}"
+	exampleTraceDir = "testdata/examples/traces"
+	exampleSwagger  = "testdata/examples/storage.json"
+)
+
+func Test_NewExamplesFromTraces(t *testing.T) {
+	examples, err := swagger.NewExamplesFromTraces(exampleTraceDir, exampleSwagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(examples) != 2 {
+		t.Fatalf("expected 2 examples, got %d", len(examples))
+	}
+
+	get, put := examples[0], examples[1]
+	if get.OperationId != "StorageAccounts_Get" || put.OperationId != "StorageAccounts_Create" {
+		t.Fatalf("unexpected operations: %s, %s", get.OperationId, put.OperationId)
+	}
+	if get.ApiPath != exampleApiPath || !strings.HasSuffix(get.SwaggerPath, exampleSwagger) {
+		t.Errorf("unexpected api path %s or swagger path %s", get.ApiPath, get.SwaggerPath)
+	}
+
+	expectedParameters := map[string]interface{}{
+		"subscriptionId":    "00000000-0000-0000-0000-000000000001",
+		"resourceGroupName": "acctest1234",
+		"accountName":       "acctest1234",
+		"api-version":       "2023-01-01",
+		"parameters": map[string]interface{}{
+			"location": "westeurope",
+			"properties": map[string]interface{}{
+				"adminPassword": redact.RedactedValue,
+			},
+		},
+	}
+	if !reflect.DeepEqual(put.Parameters, expectedParameters) {
+		t.Errorf("expected parameters %v, got %v", expectedParameters, put.Parameters)
+	}
+	expectedResponses := map[string]interface{}{
+		"202": map[string]interface{}{
+			"headers": map[string]interface{}{
+				"Location":    "https://management.azure.com/subscriptions/00000000-0000-0000-0000-000000000001/providers/Microsoft.Storage/locations/westeurope/asyncoperations/00000000-0000-0000-0000-000000000002?api-version=2023-01-01",
+				"Retry-After": "10",
+			},
+		},
+	}
+	if !reflect.DeepEqual(put.Responses, expectedResponses) {
+		t.Errorf("expected responses %v, got %v", expectedResponses, put.Responses)
+	}
+
+	// the failed request is skipped
+	if len(get.Responses) != 1 || get.Responses["200"] == nil {
+		t.Errorf("expected only the 200 response, got %v", get.Responses)
+	}
+
+	content, err := get.MarshalIndent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var example map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &example); err != nil {
+		t.Fatal(err)
+	}
+	if example["parameters"] == nil || example["responses"] == nil {
+		t.Errorf("expected parameters and responses in the example, got %s", content)
+	}
+}
+
+func Test_AddExampleRef(t *testing.T) {
+	dir := t.TempDir()
+	data, err := os.ReadFile(exampleSwagger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swaggerFile := path.Join(dir, "storage.json")
+	if err := os.WriteFile(swaggerFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	examples, err := swagger.NewExamplesFromTraces(exampleTraceDir, swaggerFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, example := range examples {
+		if err := swagger.AddExampleRef(example, path.Join(dir, "examples", example.FileName())); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	updated, err := os.ReadFile(swaggerFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := string(updated)
+	for _, expected := range []string{
+		`"$ref": "./examples/StorageAccounts_Create.json"`,
+		`"$ref": "./examples/StorageAccounts_Get.json"`,
+		`"$ref": "./examples/getStorageAccount.json"`,
+		`the specified parameters & returns`,
+	} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %s in the updated swagger", expected)
+		}
+	}
+	if !strings.HasPrefix(content, "{\n  \"swagger\": \"2.0\",\n  \"info\"") {
+		t.Errorf("expected the order of the fields to be kept, got %s", content[:50])
+	}
+	if strings.Index(content, `"x-ms-long-running-operation"`) > strings.Index(content, "StorageAccounts_Create.json") {
+		t.Errorf("expected the x-ms-examples to be appended to the operation")
+	}
+
+	if !strings.Contains(content, `"Get storage account": {`) {
+		t.Errorf("expected the existing examples to be kept")
+	}
+
+	// the other content is kept as is, the original lines are kept in order, only the commas are appended before the added members
+	updatedLines := strings.Split(content, "\n")
+	i := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		for i < len(updatedLines) && line != updatedLines[i] && line+"," != updatedLines[i] {
+			i++
+		}
+		if i == len(updatedLines) {
+			t.Fatalf("expected the line %q to be kept in the updated swagger:\n%s", line, content)
+		}
+		i++
+	}
+
+	// adding the same references again doesn't change the swagger
+	for _, example := range examples {
+		if err := swagger.AddExampleRef(example, path.Join(dir, "examples", example.FileName())); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if again, _ := os.ReadFile(swaggerFile); string(again) != content {
+		t.Errorf("expected the swagger to be unchanged when adding the same references")
+	}
+}
@@ -0,0 +1,160 @@
+package swagger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// marshalIndent returns the indented JSON with a trailing newline, the HTML characters are not escaped
+// and the keys of the maps are sorted.
+func marshalIndent(input interface{}) (string, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(input); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// jsonSpan is the byte range of a JSON value in the document
+type jsonSpan struct {
+	start int
+	end   int
+}
+
+// objectMember returns the span of the member value in the JSON object, the object is the span of the object in the document,
+// it returns false if the object doesn't have the member.
+func objectMember(data []byte, object jsonSpan, key string) (jsonSpan, bool, error) {
+	members, err := objectMembers(data, object)
+	if err != nil {
+		return jsonSpan{}, false, err
+	}
+	for _, member := range members {
+		if member.key == key {
+			return member.value, true, nil
+		}
+	}
+	return jsonSpan{}, false, nil
+}
+
+// objectPath returns the span of the value in the JSON document by the keys of the nested objects, it returns false if any key is not found
+func objectPath(data []byte, keys ...string) (jsonSpan, bool, error) {
+	current := jsonSpan{start: skipSpaces(data, 0), end: len(bytes.TrimRight(data, " \t\r\n"))}
+	for _, key := range keys {
+		value, ok, err := objectMember(data, current, key)
+		if err != nil || !ok {
+			return jsonSpan{}, ok, err
+		}
+		current = value
+	}
+	return current, true, nil
+}
+
+type jsonMember struct {
+	key string
+	// keyStart is the offset of the quote which starts the key
+	keyStart int
+	value    jsonSpan
+}
+
+// objectMembers returns the members of the JSON object in the order they're defined
+func objectMembers(data []byte, object jsonSpan) ([]jsonMember, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data[object.start:object.end]))
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("expect a JSON object at offset %d", object.start)
+	}
+	out := make([]jsonMember, 0)
+	for decoder.More() {
+		keyStart := skipSpaces(data, object.start+int(decoder.InputOffset()))
+		if data[keyStart] == ',' {
+			keyStart = skipSpaces(data, keyStart+1)
+		}
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return nil, fmt.Errorf("expect object key at offset %d, got %v", keyStart, token)
+		}
+		valueStart := skipSpaces(data, object.start+int(decoder.InputOffset()))
+		if data[valueStart] == ':' {
+			valueStart = skipSpaces(data, valueStart+1)
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		out = append(out, jsonMember{
+			key:      key,
+			keyStart: keyStart,
+			value:    jsonSpan{start: valueStart, end: object.start + int(decoder.InputOffset())},
+		})
+	}
+	return out, nil
+}
+
+// insertMember returns the document with the member appended to the end of the JSON object, the other content is kept as is.
+// The member is indented the same as the other members in the object.
+func insertMember(data []byte, object jsonSpan, key string, value interface{}) ([]byte, error) {
+	members, err := objectMembers(data, object)
+	if err != nil {
+		return nil, err
+	}
+	closeIndent := lineIndent(data, object.end-1)
+	unit := "  "
+	if len(members) != 0 {
+		if indent := lineIndent(data, members[0].keyStart); len(indent) > len(closeIndent) {
+			unit = indent[len(closeIndent):]
+		}
+	}
+	memberIndent := closeIndent + unit
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(closeIndent, unit)
+	if err := encoder.Encode(map[string]interface{}{key: value}); err != nil {
+		return nil, err
+	}
+	// the encoded object is {\n<member>\n}\n, only the member is kept
+	member := strings.TrimSpace(buf.String())
+	member = strings.TrimSpace(member[1 : len(member)-1])
+
+	out := make([]byte, 0, len(data)+len(member)+len(memberIndent)+2)
+	if len(members) == 0 {
+		out = append(out, data[:object.start]...)
+		out = append(out, "{\n"+memberIndent+member+"\n"+closeIndent+"}"...)
+		return append(out, data[object.end:]...), nil
+	}
+	last := members[len(members)-1].value.end
+	out = append(out, data[:last]...)
+	out = append(out, ",\n"+memberIndent+member...)
+	return append(out, data[last:]...), nil
+}
+
+// skipSpaces returns the offset of the first non-whitespace character from the offset
+func skipSpaces(data []byte, offset int) int {
+	for offset < len(data) && strings.ContainsRune(" \t\r\n", rune(data[offset])) {
+		offset++
+	}
+	return offset
+}
+
+// lineIndent returns the leading whitespaces of the line which contains the offset
+func lineIndent(data []byte, offset int) string {
+	start := bytes.LastIndexByte(data[:offset], '\n') + 1
+	end := start
+	for end < len(data) && (data[end] == ' ' || data[end] == '\t') {
+		end++
+	}
+	return string(data[start:end])
+}
@@ -0,0 +1,154 @@
+package tf
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// the message types in the terraform machine-readable UI output, https://developer.hashicorp.com/terraform/internals/machine-readable-ui
+const (
+	uiMessageTypeDiagnostic = "diagnostic"
+
+	DiagnosticSeverityError = "error"
+)
+
+// matches the block header in the diagnostic snippet, e.g., resource "azapi_resource" "test"
+var snippetContextRegex = regexp.MustCompile(`^(resource|data) "([^"]+)" "([^"]+)"`)
+
+// Diagnostic is the diagnostic reported by terraform in the JSON UI output
+type Diagnostic struct {
+	Severity string `json:"severity"`
+	Summary  string `json:"summary"`
+	Detail   string `json:"detail"`
+	// Address is the address of the resource which causes the diagnostic, it's empty if the diagnostic is not related to a resource
+	Address string             `json:"address,omitempty"`
+	Range   *DiagnosticRange   `json:"range,omitempty"`
+	Snippet *DiagnosticSnippet `json:"snippet,omitempty"`
+}
+
+type DiagnosticRange struct {
+	Filename string         `json:"filename"`
+	Start    DiagnosticPos  `json:"start"`
+	End      *DiagnosticPos `json:"end,omitempty"`
+}
+
+type DiagnosticPos struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type DiagnosticSnippet struct {
+	Context *string `json:"context"`
+	Code    string  `json:"code"`
+}
+
+func (d Diagnostic) String() string {
+	severity := "Error"
+	if d.Severity != DiagnosticSeverityError {
+		severity = "Warning"
+	}
+	out := fmt.Sprintf("%s: %s", severity, d.Summary)
+	if d.Address != "" || d.Range != nil {
+		out += "\n"
+		if d.Address != "" {
+			out += fmt.Sprintf("\n  with %s,", d.Address)
+		}
+		if d.Range != nil {
+			out += fmt.Sprintf("\n  on %s line %d", d.Range.Filename, d.Range.Start.Line)
+		}
+	}
+	if d.Detail != "" {
+		out += "\n\n" + d.Detail
+	}
+	return out
+}
+
+// DiagnosticsError is returned when terraform apply or destroy fails and the errors are reported in the JSON UI output
+type DiagnosticsError struct {
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+func (e *DiagnosticsError) Error() string {
+	messages := make([]string, 0)
+	for _, d := range e.Diagnostics {
+		if d.Severity == DiagnosticSeverityError {
+			messages = append(messages, d.String())
+		}
+	}
+	return fmt.Sprintf("%+v\n\n%s", e.Err, strings.Join(messages, "\n\n"))
+}
+
+func (e *DiagnosticsError) Unwrap() error {
+	return e.Err
+}
+
+type uiMessage struct {
+	Level      string      `json:"@level"`
+	Message    string      `json:"@message"`
+	Type       string      `json:"type"`
+	Diagnostic *Diagnostic `json:"diagnostic,omitempty"`
+}
+
+// ParseJSONUI returns the diagnostics in the terraform JSON UI output, the lines which are not JSON messages are ignored.
+// The address of the diagnostic is resolved from the snippet if terraform doesn't report it.
+func ParseJSONUI(r io.Reader) []Diagnostic {
+	out := make([]Diagnostic, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var message uiMessage
+		if err := json.Unmarshal(scanner.Bytes(), &message); err != nil {
+			continue
+		}
+		if message.Type != uiMessageTypeDiagnostic || message.Diagnostic == nil {
+			continue
+		}
+		d := *message.Diagnostic
+		if d.Address == "" && d.Snippet != nil && d.Snippet.Context != nil {
+			if matches := snippetContextRegex.FindStringSubmatch(*d.Snippet.Context); matches != nil {
+				d.Address = fmt.Sprintf("%s.%s", matches[2], matches[3])
+				if matches[1] == "data" {
+					d.Address = "data." + d.Address
+				}
+			}
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// uiWriter writes the human-readable messages of the terraform JSON UI output to the underlying writer
+type uiWriter struct {
+	w      io.Writer
+	buffer bytes.Buffer
+}
+
+func (u *uiWriter) Write(p []byte) (int, error) {
+	u.buffer.Write(p)
+	for {
+		line, err := u.buffer.ReadBytes('\n')
+		if err != nil {
+			// keep the incomplete line for the next write
+			u.buffer.Reset()
+			u.buffer.Write(line)
+			break
+		}
+		var message uiMessage
+		if err := json.Unmarshal(line, &message); err != nil {
+			_, _ = u.w.Write(line)
+			continue
+		}
+		text := message.Message
+		if message.Diagnostic != nil {
+			text = message.Diagnostic.String()
+		}
+		_, _ = fmt.Fprintln(u.w, text)
+	}
+	return len(p), nil
+}
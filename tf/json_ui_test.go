@@ -0,0 +1,112 @@
+package tf_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/azure/armstrong/tf"
+	"github.com/azure/armstrong/types"
+)
+
+func Test_ParseJSONUI(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "apply_errored.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	diagnostics := tf.ParseJSONUI(f)
+	expected := []struct {
+		Severity string
+		Address  string
+	}{
+		{Severity: "warning", Address: "azurerm_resource_group.test"},
+		{Severity: "error", Address: "azapi_resource.dataCollectionRule"},
+		{Severity: "error", Address: "azurerm_resource_group.test"},
+		{Severity: "error", Address: ""},
+	}
+	if len(diagnostics) != len(expected) {
+		t.Fatalf("expect %d diagnostics, but got %d", len(expected), len(diagnostics))
+	}
+	for i, d := range diagnostics {
+		if d.Severity != expected[i].Severity || d.Address != expected[i].Address {
+			t.Errorf("expect diagnostic %s %s, but got %s %s", expected[i].Severity, expected[i].Address, d.Severity, d.Address)
+		}
+	}
+}
+
+func Test_NewErrorReportFromDiagnostics(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "apply_errored.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	applyErr := fmt.Errorf("error running terraform apply: %w", &tf.DiagnosticsError{
+		Diagnostics: tf.ParseJSONUI(f),
+		Err:         fmt.Errorf("exit status 1"),
+	})
+
+	expected := []types.Error{
+		{
+			Id:      "/subscriptions/******/resourceGroups/acctest0001/providers/Microsoft.Insights/dataCollectionRules/acctest0001",
+			Type:    "Microsoft.Insights/dataCollectionRules@2022-06-01",
+			Label:   "dataCollectionRule",
+			Address: "azapi_resource.dataCollectionRule",
+		},
+		{
+			Type:    "azurerm_resource_group",
+			Label:   "test",
+			Address: "azurerm_resource_group.test",
+		},
+	}
+
+	actual := tf.NewErrorReport(applyErr, nil)
+	if len(actual.Errors) != len(expected) {
+		t.Fatalf("expect %d errors, but got %d", len(expected), len(actual.Errors))
+	}
+	for i, e := range actual.Errors {
+		if e.Id != expected[i].Id || e.Type != expected[i].Type || e.Label != expected[i].Label || e.Address != expected[i].Address {
+			t.Errorf("expect error %+v, but got %+v", expected[i], e)
+		}
+		if e.Message == "" {
+			t.Errorf("expect error message for %s", e.Address)
+		}
+	}
+
+	cleanup := tf.NewCleanupErrorReport(applyErr, nil)
+	for i, e := range cleanup.Errors {
+		if e.Label != expected[i].Address {
+			t.Errorf("expect cleanup error label %s, but got %s", expected[i].Address, e.Label)
+		}
+	}
+}
+
+func Test_NewErrorReportFromDiagnostics_Addresses(t *testing.T) {
+	testcases := []struct {
+		Address string
+		Type    string
+		Label   string
+	}{
+		{Address: "azurerm_resource_group.test", Type: "azurerm_resource_group", Label: "test"},
+		{Address: "data.azapi_resource.x", Type: "azapi_resource", Label: "x"},
+		{Address: "azapi_resource.x[0]", Type: "azapi_resource", Label: "x"},
+		{Address: `module.m.azapi_resource.x["a.b"]`, Type: "azapi_resource", Label: "x"},
+		{Address: `module.m["a.b"].module.n[1].data.azapi_resource.x`, Type: "azapi_resource", Label: "x"},
+		{Address: "invalid", Type: "", Label: ""},
+	}
+	for _, tc := range testcases {
+		applyErr := &tf.DiagnosticsError{
+			Diagnostics: []tf.Diagnostic{{Severity: tf.DiagnosticSeverityError, Summary: "failed", Address: tc.Address}},
+			Err:         fmt.Errorf("exit status 1"),
+		}
+		actual := tf.NewErrorReport(applyErr, nil)
+		if len(actual.Errors) != 1 {
+			t.Fatalf("expect 1 error of %s, but got %d", tc.Address, len(actual.Errors))
+		}
+		if e := actual.Errors[0]; e.Type != tc.Type || e.Label != tc.Label || e.Address != tc.Address {
+			t.Errorf("expect the type %q and the label %q of %s, but got %+v", tc.Type, tc.Label, tc.Address, e)
+		}
+	}
+}
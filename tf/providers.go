@@ -0,0 +1,391 @@
+package tf
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclwrite"
+	"github.com/hashicorp/terraform-exec/tfexec"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	registryTerraform = "registry.terraform.io"
+	registryOpenTofu  = "registry.opentofu.org"
+
+	cliConfigEnvVar = "TF_CLI_CONFIG_FILE"
+	cliConfigFile   = "armstrong.tfrc"
+	LockFileName    = ".terraform.lock.hcl"
+)
+
+// DefaultProviders are the providers used by the generated configurations
+var DefaultProviders = []string{"azure/azapi", "hashicorp/azurerm"}
+
+// matches the packed provider package in the filesystem mirror, e.g., terraform-provider-azapi_1.12.1_linux_amd64.zip
+var packedProviderRegex = regexp.MustCompile(`^terraform-provider-([^_]+)_([^_]+)_([^_]+_[^_]+)\.zip$`)
+
+// ProviderAddress returns the fully qualified address of the provider, the registry host depends on the flavor.
+// The source can be the type, e.g., azapi, the source in the required_providers block, e.g., Azure/azapi, or the fully qualified address.
+func ProviderAddress(flavor string, source string) (string, error) {
+	host := registryTerraform
+	if flavor == FlavorOpenTofu {
+		host = registryOpenTofu
+	}
+	source = strings.ToLower(strings.TrimSpace(source))
+	parts := strings.Split(source, "/")
+	for _, part := range parts {
+		if part == "" {
+			return "", fmt.Errorf("provider source %q is invalid", source)
+		}
+	}
+	switch len(parts) {
+	case 1:
+		for _, p := range DefaultProviders {
+			if strings.HasSuffix(p, "/"+source) {
+				return host + "/" + p, nil
+			}
+		}
+		// the same as terraform, the providers without namespace are in the hashicorp namespace
+		return host + "/hashicorp/" + source, nil
+	case 2:
+		return host + "/" + source, nil
+	case 3:
+		return source, nil
+	}
+	return "", fmt.Errorf("provider source %q is invalid", source)
+}
+
+// ParseProviderPairs parses the values in the format of <provider source>=<value>, e.g., azapi=/path/to/azapi, the keys are the fully qualified addresses
+func ParseProviderPairs(flavor string, values []string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, value := range values {
+		source, v, ok := strings.Cut(value, "=")
+		if !ok || strings.TrimSpace(v) == "" {
+			return nil, fmt.Errorf("%q is invalid, it should be in the format of <provider>=<value>", value)
+		}
+		address, err := ProviderAddress(flavor, source)
+		if err != nil {
+			return nil, err
+		}
+		out[address] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
+
+// CLIConfig returns the CLI configuration which installs the providers from the plugin mirror and the dev overrides,
+// the providers are not downloaded from the registry when the plugin mirror is specified.
+func CLIConfig(pluginMirror string, devOverrides map[string]string) (string, error) {
+	buf := &strings.Builder{}
+	buf.WriteString("provider_installation {\n")
+	if len(devOverrides) != 0 {
+		buf.WriteString("dev_overrides {\n")
+		addresses := make([]string, 0)
+		for address := range devOverrides {
+			addresses = append(addresses, address)
+		}
+		sort.Strings(addresses)
+		for _, address := range addresses {
+			dir, err := filepath.Abs(devOverrides[address])
+			if err != nil {
+				return "", fmt.Errorf("dev override path %q is invalid: %+v", devOverrides[address], err)
+			}
+			buf.WriteString(fmt.Sprintf("%q = %q\n", address, filepath.ToSlash(dir)))
+		}
+		buf.WriteString("}\n")
+	}
+	if pluginMirror != "" {
+		dir, err := filepath.Abs(pluginMirror)
+		if err != nil {
+			return "", fmt.Errorf("plugin mirror path %q is invalid: %+v", pluginMirror, err)
+		}
+		buf.WriteString(fmt.Sprintf("filesystem_mirror {\npath = %q\n}\n", filepath.ToSlash(dir)))
+	} else {
+		buf.WriteString("direct {}\n")
+	}
+	buf.WriteString("}\n")
+	return string(hclwrite.Format([]byte(buf.String()))), nil
+}
+
+// MergeCLIConfig returns the CLI configuration which contains the settings of the existing configuration, e.g., the credentials and the plugin cache,
+// and the generated provider installation, the provider_installation blocks in the existing configuration are replaced.
+func MergeCLIConfig(existing []byte, filename string, content string) (string, error) {
+	file, diags := hclwrite.ParseConfig(existing, filename, hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", fmt.Errorf("parsing %s: %+v", filename, diags)
+	}
+	body := file.Body()
+	for _, block := range body.Blocks() {
+		if block.Type() == "provider_installation" {
+			logrus.Warnf("the provider_installation block in the CLI configuration %s is replaced", filename)
+			body.RemoveBlock(block)
+		}
+	}
+	out := strings.TrimRight(string(file.Bytes()), "\n")
+	if out == "" {
+		return content, nil
+	}
+	return out + "\n\n" + content, nil
+}
+
+// writeCLIConfig writes the CLI configuration in the .terraform folder of the working directory and returns its path.
+// The existing CLI configuration specified by the TF_CLI_CONFIG_FILE environment variable is merged into it.
+func writeCLIConfig(workingDirectory string, flavor string, options Options) (string, error) {
+	devOverrides, err := ParseProviderPairs(flavor, options.DevOverrides)
+	if err != nil {
+		return "", fmt.Errorf("parsing dev overrides: %+v", err)
+	}
+	content, err := CLIConfig(options.PluginMirror, devOverrides)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(workingDirectory, ".terraform")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("creating %s: %+v", dir, err)
+	}
+	filename := filepath.Join(dir, cliConfigFile)
+	if existing := os.Getenv(cliConfigEnvVar); existing != "" && existing != filename {
+		switch data, err := os.ReadFile(existing); {
+		case err != nil:
+			logrus.Warnf("failed to read the CLI configuration %s, it's not merged: %+v", existing, err)
+		case strings.HasSuffix(existing, ".json"):
+			logrus.Warnf("the CLI configuration %s in JSON syntax is not merged", existing)
+		default:
+			if content, err = MergeCLIConfig(data, existing, content); err != nil {
+				return "", err
+			}
+		}
+	}
+	if err := os.WriteFile(filename, []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("writing %s: %+v", filename, err)
+	}
+	logrus.Infof("using CLI configuration: %s", filename)
+	return filename, nil
+}
+
+// cliConfigEnv returns the environment of the terraform commands which use the CLI configuration, it's the current environment with the TF_CLI_CONFIG_FILE.
+// The terraform-exec doesn't allow the TF_VAR_* and TF_CLI_ARGS* variables in the customized environment, so they're dropped.
+func cliConfigEnv(filename string) map[string]string {
+	env := make(map[string]string)
+	for _, pair := range os.Environ() {
+		if key, value, ok := strings.Cut(pair, "="); ok {
+			env[key] = value
+		}
+	}
+	env[cliConfigEnvVar] = filename
+	dropped := make([]string, 0)
+	for _, key := range tfexec.ProhibitedEnv(env) {
+		if strings.HasPrefix(key, "TF_VAR_") || strings.HasPrefix(key, "TF_CLI_ARGS") {
+			dropped = append(dropped, key)
+		}
+	}
+	if len(dropped) != 0 {
+		sort.Strings(dropped)
+		logrus.Warnf("the environment variables %s are ignored when the CLI configuration is used", strings.Join(dropped, ", "))
+	}
+	return tfexec.CleanEnv(env)
+}
+
+// ProviderLock is the provider version pinned in the dependency lock file
+type ProviderLock struct {
+	// Address is the fully qualified address, e.g., registry.terraform.io/azure/azapi
+	Address string
+	Version string
+	// Hashes are the checksums of the provider packages, e.g., zh:<sha256 of the zip> and h1:<hash of the unpacked files>
+	Hashes []string
+}
+
+// ProviderLocks returns the provider versions which are pinned in the lock file, the versions are keyed by the fully qualified addresses.
+// When the plugin mirror is specified, the empty versions are resolved to the latest versions in the mirror, and the hashes are computed from the packages in the mirror.
+// Both the packed layout, HOSTNAME/NAMESPACE/TYPE/terraform-provider-TYPE_VERSION_TARGET.zip,
+// and the unpacked layout, HOSTNAME/NAMESPACE/TYPE/VERSION/TARGET, are supported.
+func ProviderLocks(pluginMirror string, versions map[string]string) ([]ProviderLock, error) {
+	addresses := make([]string, 0)
+	for address, v := range versions {
+		if v != "" {
+			if _, err := version.NewVersion(v); err != nil {
+				return nil, fmt.Errorf("version %q of %s is invalid: %+v", v, address, err)
+			}
+		}
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	out := make([]ProviderLock, 0)
+	for _, address := range addresses {
+		pinned := versions[address]
+		hashes := make(map[string][]string)
+		if pluginMirror != "" {
+			var err error
+			hashes, err = mirrorProviderHashes(pluginMirror, address)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var selected *version.Version
+		selectedVersion := ""
+		for v := range hashes {
+			current := version.Must(version.NewVersion(v))
+			if pinned != "" {
+				if current.Equal(version.Must(version.NewVersion(pinned))) {
+					selectedVersion = v
+					break
+				}
+				continue
+			}
+			if selected == nil || current.GreaterThan(selected) {
+				selected = current
+				selectedVersion = v
+			}
+		}
+
+		switch {
+		case selectedVersion != "":
+			sort.Strings(hashes[selectedVersion])
+			out = append(out, ProviderLock{
+				Address: address,
+				Version: selectedVersion,
+				Hashes:  hashes[selectedVersion],
+			})
+		case pinned != "":
+			if pluginMirror != "" {
+				logrus.Warnf("%s v%s is not found in the plugin mirror %s", address, pinned, pluginMirror)
+			}
+			// terraform records the hashes when the provider is installed
+			out = append(out, ProviderLock{
+				Address: address,
+				Version: pinned,
+			})
+		default:
+			logrus.Warnf("no version of %s is found in the plugin mirror %s", address, pluginMirror)
+		}
+	}
+	return out, nil
+}
+
+// mirrorProviderHashes returns the hashes of the provider packages in the plugin mirror, they're keyed by the versions
+func mirrorProviderHashes(pluginMirror string, address string) (map[string][]string, error) {
+	out := make(map[string][]string)
+	providerDir := filepath.Join(pluginMirror, filepath.FromSlash(address))
+	entries, err := os.ReadDir(providerDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return out, nil
+		}
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			if _, err := version.NewVersion(entry.Name()); err != nil {
+				continue
+			}
+			targets, err := os.ReadDir(filepath.Join(providerDir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			for _, target := range targets {
+				if !target.IsDir() {
+					continue
+				}
+				hash, err := PackageHashV1(filepath.Join(providerDir, entry.Name(), target.Name()))
+				if err != nil {
+					return nil, err
+				}
+				out[entry.Name()] = append(out[entry.Name()], hash)
+			}
+			continue
+		}
+		matches := packedProviderRegex.FindStringSubmatch(entry.Name())
+		if matches == nil || !strings.HasSuffix(address, "/"+matches[1]) {
+			continue
+		}
+		if _, err := version.NewVersion(matches[2]); err != nil {
+			continue
+		}
+		hash, err := fileSha256(filepath.Join(providerDir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		out[matches[2]] = append(out[matches[2]], "zh:"+hash)
+	}
+	return out, nil
+}
+
+// LockFile returns the content of the dependency lock file which pins the provider versions
+func LockFile(locks []ProviderLock) []byte {
+	sort.Slice(locks, func(i, j int) bool {
+		return locks[i].Address < locks[j].Address
+	})
+	buf := &strings.Builder{}
+	buf.WriteString("# This file is maintained automatically by \"terraform init\".\n# Manual edits may be lost in future updates.\n")
+	for _, lock := range locks {
+		buf.WriteString(fmt.Sprintf("\nprovider %q {\nversion = %q\n", lock.Address, lock.Version))
+		if len(lock.Hashes) != 0 {
+			buf.WriteString("hashes = [\n")
+			for _, hash := range lock.Hashes {
+				buf.WriteString(fmt.Sprintf("%q,\n", hash))
+			}
+			buf.WriteString("]\n")
+		}
+		buf.WriteString("}\n")
+	}
+	return hclwrite.Format([]byte(buf.String()))
+}
+
+// PackageHashV1 returns the h1: hash of the unpacked provider package, it's the same as the dirhash used by terraform
+func PackageHashV1(dir string) (string, error) {
+	files := make([]string, 0)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(files)
+
+	summary := sha256.New()
+	for _, file := range files {
+		if strings.Contains(file, "\n") {
+			return "", fmt.Errorf("file name %q contains newline", file)
+		}
+		hash, err := fileSha256(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return "", err
+		}
+		_, _ = fmt.Fprintf(summary, "%s  %s\n", hash, file)
+	}
+	return "h1:" + base64.StdEncoding.EncodeToString(summary.Sum(nil)), nil
+}
+
+func fileSha256(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
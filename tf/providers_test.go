@@ -0,0 +1,188 @@
+package tf_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/tf"
+)
+
+func Test_ProviderAddress(t *testing.T) {
+	testcases := []struct {
+		Flavor   string
+		Source   string
+		Expected string
+		Error    bool
+	}{
+		{
+			Flavor:   tf.FlavorTerraform,
+			Source:   "azapi",
+			Expected: "registry.terraform.io/azure/azapi",
+		},
+		{
+			Flavor:   tf.FlavorTerraform,
+			Source:   "Azure/azapi",
+			Expected: "registry.terraform.io/azure/azapi",
+		},
+		{
+			Flavor:   tf.FlavorOpenTofu,
+			Source:   "azurerm",
+			Expected: "registry.opentofu.org/hashicorp/azurerm",
+		},
+		{
+			Flavor:   tf.FlavorTerraform,
+			Source:   "random",
+			Expected: "registry.terraform.io/hashicorp/random",
+		},
+		{
+			Flavor:   tf.FlavorOpenTofu,
+			Source:   "registry.terraform.io/azure/azapi",
+			Expected: "registry.terraform.io/azure/azapi",
+		},
+		{
+			Flavor: tf.FlavorTerraform,
+			Source: "azure//azapi",
+			Error:  true,
+		},
+	}
+	for _, testcase := range testcases {
+		actual, err := tf.ProviderAddress(testcase.Flavor, testcase.Source)
+		if testcase.Error {
+			if err == nil {
+				t.Errorf("expect error for %s, but got %s", testcase.Source, actual)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if actual != testcase.Expected {
+			t.Errorf("expect %s for %s, but got %s", testcase.Expected, testcase.Source, actual)
+		}
+	}
+}
+
+func Test_CLIConfig(t *testing.T) {
+	actual, err := tf.CLIConfig("/mirror", map[string]string{
+		"registry.terraform.io/azure/azapi": "/azapi",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	for _, expected := range []string{`"registry.terraform.io/azure/azapi" = "/azapi"`, `path = "/mirror"`} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expect %s in the CLI configuration, but got:\n%s", expected, actual)
+		}
+	}
+	if strings.Contains(actual, "direct") {
+		t.Errorf("expect no direct installation when the plugin mirror is specified, but got:\n%s", actual)
+	}
+
+	actual, err = tf.CLIConfig("", map[string]string{
+		"registry.terraform.io/azure/azapi": "/azapi",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if !strings.Contains(actual, "direct {}") {
+		t.Errorf("expect the direct installation for the other providers, but got:\n%s", actual)
+	}
+}
+
+func Test_MergeCLIConfig(t *testing.T) {
+	existing := `plugin_cache_dir = "/cache"
+
+credentials "app.terraform.io" {
+  token = "token"
+}
+
+provider_installation {
+  network_mirror {
+    url = "https://mirror.example.com/"
+  }
+}
+`
+	content, err := tf.CLIConfig("/mirror", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	actual, err := tf.MergeCLIConfig([]byte(existing), "user.tfrc", content)
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	for _, expected := range []string{`plugin_cache_dir = "/cache"`, `credentials "app.terraform.io"`, `path = "/mirror"`} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expect %s in the CLI configuration, but got:\n%s", expected, actual)
+		}
+	}
+	if strings.Contains(actual, "network_mirror") || strings.Count(actual, "provider_installation") != 1 {
+		t.Errorf("expect the existing provider installation is replaced, but got:\n%s", actual)
+	}
+
+	if _, err := tf.MergeCLIConfig([]byte("credentials {"), "user.tfrc", content); err == nil {
+		t.Errorf("expect an error for the invalid CLI configuration")
+	}
+}
+
+func Test_ProviderLocks(t *testing.T) {
+	mirror := t.TempDir()
+	writeFile := func(name string, content string) {
+		p := filepath.Join(mirror, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// packed layout
+	writeFile("registry.terraform.io/azure/azapi/terraform-provider-azapi_1.10.0_linux_amd64.zip", "1.10.0")
+	writeFile("registry.terraform.io/azure/azapi/terraform-provider-azapi_1.12.1_linux_amd64.zip", "1.12.1")
+	writeFile("registry.terraform.io/azure/azapi/terraform-provider-azapi_1.12.1_windows_amd64.zip", "1.12.1")
+	// unpacked layout
+	writeFile("registry.terraform.io/hashicorp/azurerm/3.90.0/linux_amd64/terraform-provider-azurerm_v3.90.0_x5", "3.90.0")
+
+	locks, err := tf.ProviderLocks(mirror, map[string]string{
+		"registry.terraform.io/azure/azapi":       "",
+		"registry.terraform.io/hashicorp/azurerm": "",
+		"registry.terraform.io/hashicorp/random":  "3.6.0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(locks) != 3 {
+		t.Fatalf("expect 3 locks, but got %v", locks)
+	}
+	if locks[0].Version != "1.12.1" || len(locks[0].Hashes) != 2 || !strings.HasPrefix(locks[0].Hashes[0], "zh:") {
+		t.Errorf("expect the latest azapi with 2 zh: hashes, but got %v", locks[0])
+	}
+	if locks[1].Version != "3.90.0" || len(locks[1].Hashes) != 1 || !strings.HasPrefix(locks[1].Hashes[0], "h1:") {
+		t.Errorf("expect azurerm with 1 h1: hash, but got %v", locks[1])
+	}
+	if locks[2].Version != "3.6.0" || len(locks[2].Hashes) != 0 {
+		t.Errorf("expect the pinned random without hashes, but got %v", locks[2])
+	}
+
+	locks, err = tf.ProviderLocks(mirror, map[string]string{
+		"registry.terraform.io/azure/azapi": "1.10.0",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %+v", err)
+	}
+	if len(locks) != 1 || locks[0].Version != "1.10.0" || len(locks[0].Hashes) != 1 {
+		t.Errorf("expect the pinned azapi in the mirror, but got %v", locks)
+	}
+
+	content := string(tf.LockFile(locks))
+	for _, expected := range []string{`provider "registry.terraform.io/azure/azapi" {`, `version = "1.10.0"`, locks[0].Hashes[0]} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expect %s in the lock file, but got:\n%s", expected, content)
+		}
+	}
+
+	if _, err := tf.ProviderLocks("", map[string]string{"registry.terraform.io/azure/azapi": "latest"}); err == nil {
+		t.Errorf("expect error for the invalid version")
+	}
+}
@@ -1,11 +1,15 @@
 package tf
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"os/exec"
 	"path"
+	"strings"
 
 	"github.com/hashicorp/terraform-exec/tfexec"
 	tfjson "github.com/hashicorp/terraform-json"
@@ -15,30 +19,56 @@ import (
 type Terraform struct {
 	exec       *tfexec.Terraform
 	LogEnabled bool
+	// Executable is the terraform or tofu executable which runs the commands
+	Executable *Executable
+
+	workingDirectory string
+	// env is the environment of the terraform commands, the current environment is used if it's nil
+	env     map[string]string
+	logPath string
 }
 
 const planfile = "tfplan"
 
-func NewTerraform(workingDirectory string, logEnabled bool) (*Terraform, error) {
+// NewTerraform returns a terraform runner in the working directory, the executable is found by the options,
+// the options which are not specified are read from the environment variables.
+// When the plugin mirror or the dev overrides are specified, the CLI configuration which installs the providers from them is used.
+func NewTerraform(workingDirectory string, logEnabled bool, options Options) (*Terraform, error) {
 	os.Setenv("ARM_PROVIDER_ENHANCED_VALIDATION", "false")
 	os.Setenv("ARM_SKIP_PROVIDER_REGISTRATION", "true")
-	execPath, err := FindTerraform(context.TODO())
+	options = options.Merge(OptionsFromEnv())
+	executable, err := FindTerraform(context.TODO(), options)
 	if err != nil {
 		return nil, err
 	}
-	tf, err := tfexec.NewTerraform(workingDirectory, execPath)
+	logrus.Infof("using %s: %s", executable, executable.Path)
+	tf, err := tfexec.NewTerraform(workingDirectory, executable.Path)
 	if err != nil {
 		return nil, err
 	}
+	var env map[string]string
+	if options.PluginMirror != "" || len(options.DevOverrides) != 0 {
+		filename, err := writeCLIConfig(workingDirectory, executable.Flavor, options)
+		if err != nil {
+			return nil, err
+		}
+		env = cliConfigEnv(filename)
+		if err := tf.SetEnv(env); err != nil {
+			return nil, err
+		}
+	}
 
 	t := &Terraform{
-		exec:       tf,
-		LogEnabled: logEnabled,
+		exec:             tf,
+		LogEnabled:       logEnabled,
+		Executable:       executable,
+		workingDirectory: workingDirectory,
+		env:              env,
 	}
 	t.SetLogEnabled(true)
-	logPath := path.Join(workingDirectory, "log.txt")
-	_ = os.RemoveAll(logPath)
-	err = t.exec.SetLogPath(logPath)
+	t.logPath = path.Join(workingDirectory, "log.txt")
+	_ = os.RemoveAll(t.logPath)
+	err = t.exec.SetLogPath(t.logPath)
 	if err != nil {
 		return nil, err
 	}
@@ -81,12 +111,74 @@ func (t *Terraform) Plan() (*tfjson.Plan, error) {
 	return p, err
 }
 
+// Apply applies the changes, the errors are returned as *DiagnosticsError if terraform reports them in the JSON UI output
 func (t *Terraform) Apply() error {
-	return t.exec.Apply(context.TODO())
+	return t.runWithJSONUI(context.TODO(), "apply", "-auto-approve", "-input=false", "-lock=true", "-parallelism=10", "-refresh=true")
 }
 
+// Destroy destroys the resources, the errors are returned as *DiagnosticsError if terraform reports them in the JSON UI output
 func (t *Terraform) Destroy() error {
-	return t.exec.Destroy(context.TODO())
+	return t.runWithJSONUI(context.TODO(), "destroy", "-auto-approve", "-input=false", "-lock=true", "-parallelism=10", "-refresh=true")
+}
+
+// runWithJSONUI runs the terraform command with the machine-readable UI output, so the errors are mapped to the resource addresses.
+// The terraform-exec of this version doesn't support the -json flag of the apply and destroy commands, so the command is built
+// with the same arguments and environment as the terraform-exec ones.
+func (t *Terraform) runWithJSONUI(ctx context.Context, args ...string) error {
+	var stream, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, t.Executable.Path, append(args, "-json")...)
+	cmd.Dir = t.workingDirectory
+	cmd.Env = t.commandEnv()
+	cmd.Stdout = &stream
+	cmd.Stderr = &stderr
+	if t.LogEnabled {
+		cmd.Stdout = io.MultiWriter(&stream, &uiWriter{w: os.Stdout})
+		cmd.Stderr = io.MultiWriter(&stderr, os.Stderr)
+		logrus.Infof("running %s command: %s", t.Executable.Flavor, strings.Join(cmd.Args, " "))
+	}
+
+	err := cmd.Run()
+	if err != nil && stderr.Len() != 0 {
+		err = fmt.Errorf("%w\n\n%s", err, strings.TrimSpace(stderr.String()))
+	}
+	if err == nil {
+		return nil
+	}
+	diagnostics := ParseJSONUI(&stream)
+	for _, d := range diagnostics {
+		if d.Severity == DiagnosticSeverityError {
+			return &DiagnosticsError{Diagnostics: diagnostics, Err: err}
+		}
+	}
+	return err
+}
+
+// commandEnv returns the environment of the commands which are not run by terraform-exec, the same as the terraform-exec one
+func (t *Terraform) commandEnv() []string {
+	env := make(map[string]string)
+	if t.env == nil {
+		for _, kv := range os.Environ() {
+			if k, v, ok := strings.Cut(kv, "="); ok {
+				env[k] = v
+			}
+		}
+	} else {
+		for k, v := range t.env {
+			env[k] = v
+		}
+	}
+	env["TF_LOG_PATH"] = t.logPath
+	env["TF_LOG"] = "TRACE"
+	if t.logPath == "" {
+		env["TF_LOG"] = ""
+	}
+	env["TF_IN_AUTOMATION"] = "1"
+	env["TF_WORKSPACE"] = ""
+	out := make([]string, 0, len(env))
+	for k, v := range env {
+		out = append(out, k+"="+v)
+	}
+	return out
 }
 
 func (t *Terraform) Validate() (*tfjson.ValidateOutput, error) {
@@ -2,46 +2,187 @@ package tf
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
+	"strings"
 
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/terraform-exec/tfinstall"
+	"github.com/sirupsen/logrus"
 )
 
-const terraformBinary = "terraform"
+const (
+	FlavorTerraform = "terraform"
+	FlavorOpenTofu  = "tofu"
+)
+
+// the environment variables which configure the executable, they're used when the options are not specified
+const (
+	FlavorEnvVar   = "ARMSTRONG_TERRAFORM_FLAVOR"
+	VersionEnvVar  = "ARMSTRONG_TERRAFORM_VERSION"
+	ExecPathEnvVar = "ARMSTRONG_TERRAFORM_PATH"
+	// PluginMirrorEnvVar and DevOverridesEnvVar configure how the providers are installed, the dev overrides are separated by comma
+	PluginMirrorEnvVar = "ARMSTRONG_PLUGIN_MIRROR"
+	DevOverridesEnvVar = "ARMSTRONG_PROVIDER_DEV_OVERRIDES"
+)
+
+// the minimum versions which support the features used by the generated azapi configurations, e.g., the dynamic body and the JSON UI output
+var minVersions = map[string]*version.Version{
+	FlavorTerraform: version.Must(version.NewVersion("1.3.0")),
+	FlavorOpenTofu:  version.Must(version.NewVersion("1.6.0")),
+}
 
-// FindTerraform finds the path to the terraform executable whose version meets the min/max version constraint.
-// It first tries to find from the local OS PATH. If there is no match, it will then download the release of the minVersion from hashicorp to the tfDir.
-func FindTerraform(ctx context.Context) (string, error) {
+// matches the first line of the version command output, e.g., Terraform v1.9.5 and OpenTofu v1.8.1
+var versionOutputRegex = regexp.MustCompile(`(?m)^(Terraform|OpenTofu) v(\S+)`)
+
+// Options configures which executable is used to run the tests
+type Options struct {
+	// Flavor is terraform or tofu, default is terraform
+	Flavor string
+	// Version is the version constraint, e.g., 1.9.5 or ">= 1.5.0, < 2.0.0"
+	Version string
+	// ExecPath is the path to the executable, the executable is not searched or downloaded if it's specified
+	ExecPath string
+	// PluginMirror is the filesystem mirror directory, the providers are installed from it instead of the registry
+	PluginMirror string
+	// DevOverrides are the locally built providers in the format of <provider>=<directory>, e.g., azapi=/path/to/azapi
+	DevOverrides []string
+}
+
+// OptionsFromEnv returns the options configured by the environment variables
+func OptionsFromEnv() Options {
+	options := Options{
+		Flavor:       os.Getenv(FlavorEnvVar),
+		Version:      os.Getenv(VersionEnvVar),
+		ExecPath:     os.Getenv(ExecPathEnvVar),
+		PluginMirror: os.Getenv(PluginMirrorEnvVar),
+	}
+	for _, value := range strings.Split(os.Getenv(DevOverridesEnvVar), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			options.DevOverrides = append(options.DevOverrides, value)
+		}
+	}
+	return options
+}
+
+// Merge returns the options whose empty fields are filled by the other options
+func (o Options) Merge(other Options) Options {
+	if o.Flavor == "" {
+		o.Flavor = other.Flavor
+	}
+	if o.Version == "" {
+		o.Version = other.Version
+	}
+	if o.ExecPath == "" {
+		o.ExecPath = other.ExecPath
+	}
+	if o.PluginMirror == "" {
+		o.PluginMirror = other.PluginMirror
+	}
+	if len(o.DevOverrides) == 0 {
+		o.DevOverrides = other.DevOverrides
+	}
+	return o
+}
+
+// Validate checks the flavor, the version constraint and the provider installation options
+func (o Options) Validate() error {
+	switch o.Flavor {
+	case "", FlavorTerraform, FlavorOpenTofu:
+	default:
+		return fmt.Errorf("flavor %q is not supported, supported flavors are %s and %s", o.Flavor, FlavorTerraform, FlavorOpenTofu)
+	}
+	if o.Version != "" {
+		if _, err := version.NewConstraint(o.Version); err != nil {
+			return fmt.Errorf("version constraint %q is invalid: %+v", o.Version, err)
+		}
+	}
+	if o.PluginMirror != "" {
+		if info, err := os.Stat(o.PluginMirror); err != nil || !info.IsDir() {
+			return fmt.Errorf("plugin mirror %q is not a directory", o.PluginMirror)
+		}
+	}
+	if _, err := ParseProviderPairs(o.Flavor, o.DevOverrides); err != nil {
+		return fmt.Errorf("dev overrides are invalid: %+v", err)
+	}
+	return nil
+}
+
+// Executable is the terraform or tofu executable which is found
+type Executable struct {
+	Path    string
+	Flavor  string
+	Version *version.Version
+}
+
+func (e Executable) String() string {
+	return fmt.Sprintf("%s v%s", e.Flavor, e.Version)
+}
+
+// FindTerraform finds the path to the executable which meets the options, the candidates are checked in order:
+// 1. The executable path in the options, no other candidates are checked if it's specified.
+// 2. The executable in the armstrong cache directory.
+// 3. The executable in the local OS PATH.
+// 4. For terraform, the release downloaded from hashicorp to the cache directory, it's the exact version if the constraint is a version, otherwise the latest version.
+// Each candidate must match the flavor, the version constraint and the minimum version which supports the azapi features armstrong generates.
+func FindTerraform(ctx context.Context, options Options) (*Executable, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	flavor := options.Flavor
+	if flavor == "" {
+		flavor = FlavorTerraform
+	}
+
+	if options.ExecPath != "" {
+		execPath, err := filepath.Abs(options.ExecPath)
+		if err != nil {
+			return nil, fmt.Errorf("executable path %q is invalid: %+v", options.ExecPath, err)
+		}
+		executable, err := checkExecutable(ctx, execPath, options)
+		if err != nil {
+			return nil, err
+		}
+		return executable, nil
+	}
 
 	// Initialize the workspace
 	cacheDir, err := os.UserCacheDir()
 	if err != nil {
-		return "", fmt.Errorf("error finding the user cache directory: %w", err)
+		return nil, fmt.Errorf("error finding the user cache directory: %w", err)
 	}
 	rootDir := filepath.Join(cacheDir, "armstrong")
 	if err := os.MkdirAll(rootDir, 0755); err != nil {
-		return "", fmt.Errorf("creating workspace root %q: %w", rootDir, err)
+		return nil, fmt.Errorf("creating workspace root %q: %w", rootDir, err)
 	}
-	tfDir := filepath.Join(rootDir, "terraform")
+	tfDir := filepath.Join(rootDir, flavor)
 	if err := os.MkdirAll(tfDir, 0755); err != nil {
-		return "", fmt.Errorf("creating terraform cache dir %q: %w", tfDir, err)
+		return nil, fmt.Errorf("creating %s cache dir %q: %w", flavor, tfDir, err)
 	}
 
-	var terraformPath string
 	opts := []tfinstall.ExecPathFinder{
-		tfinstall.ExactPath(filepath.Join(tfDir, terraformBinary)),
-		tfinstall.LookPath(),
-		tfinstall.LatestVersion(tfDir, false),
+		tfinstall.ExactPath(filepath.Join(tfDir, flavor)),
+		lookPath(flavor),
+	}
+	if flavor == FlavorTerraform {
+		if v, err := version.NewVersion(options.Version); err == nil {
+			opts = append(opts, tfinstall.ExactVersion(v.String(), tfDir))
+		} else {
+			opts = append(opts, tfinstall.LatestVersion(tfDir, false))
+		}
 	}
 
 	// go through the options in order
-	// until a valid terraform executable is found
+	// until a valid executable is found
+	reasons := make([]string, 0)
 	for _, opt := range opts {
 		p, err := opt.ExecPath(ctx)
 		if err != nil {
-			return "", fmt.Errorf("unexpected error: %w", err)
+			return nil, fmt.Errorf("unexpected error: %w", err)
 		}
 
 		if p == "" {
@@ -49,13 +190,95 @@ func FindTerraform(ctx context.Context) (string, error) {
 			continue
 		}
 
-		terraformPath = p
-		break
+		executable, err := checkExecutable(ctx, p, options)
+		if err != nil {
+			logrus.Debugf("skip %s: %+v", p, err)
+			reasons = append(reasons, err.Error())
+			continue
+		}
+		return executable, nil
 	}
 
-	if terraformPath == "" {
-		return "", fmt.Errorf("could not find terraform executable")
+	if len(reasons) != 0 {
+		return nil, fmt.Errorf("could not find %s executable which meets the requirements:\n%s", flavor, strings.Join(reasons, "\n"))
 	}
+	if flavor == FlavorOpenTofu {
+		return nil, fmt.Errorf("could not find %s executable, please install it or specify the executable path", flavor)
+	}
+	return nil, fmt.Errorf("could not find %s executable", flavor)
+}
 
-	return terraformPath, nil
+// checkExecutable returns the executable if its flavor and version meet the options
+func checkExecutable(ctx context.Context, execPath string, options Options) (*Executable, error) {
+	executable, err := detectExecutable(ctx, execPath)
+	if err != nil {
+		return nil, err
+	}
+	if options.Flavor != "" && options.Flavor != executable.Flavor {
+		return nil, fmt.Errorf("%s is %s, but %s is required", execPath, executable, options.Flavor)
+	}
+	if minVersion := minVersions[executable.Flavor]; executable.Version.LessThan(minVersion) {
+		return nil, fmt.Errorf("%s is %s, but the azapi configurations generated by armstrong require %s v%s or later", execPath, executable, executable.Flavor, minVersion)
+	}
+	if options.Version != "" {
+		constraints, err := version.NewConstraint(options.Version)
+		if err != nil {
+			return nil, fmt.Errorf("version constraint %q is invalid: %+v", options.Version, err)
+		}
+		if !constraints.Check(executable.Version) {
+			return nil, fmt.Errorf("%s is %s, which doesn't meet the version constraint %q", execPath, executable, options.Version)
+		}
+	}
+	return executable, nil
+}
+
+// detectExecutable runs the version command to detect the flavor and version of the executable
+func detectExecutable(ctx context.Context, execPath string) (*Executable, error) {
+	output, err := exec.CommandContext(ctx, execPath, "version").Output()
+	if err != nil {
+		return nil, fmt.Errorf("running %s version: %+v", execPath, err)
+	}
+	return ParseVersionOutput(execPath, string(output))
+}
+
+// ParseVersionOutput parses the output of the version command, e.g., Terraform v1.9.5 and OpenTofu v1.8.1
+func ParseVersionOutput(execPath string, output string) (*Executable, error) {
+	matches := versionOutputRegex.FindStringSubmatch(output)
+	if matches == nil {
+		return nil, fmt.Errorf("unexpected version output of %s: %s", execPath, output)
+	}
+	v, err := version.NewVersion(matches[2])
+	if err != nil {
+		return nil, fmt.Errorf("parsing version of %s: %+v", execPath, err)
+	}
+	flavor := FlavorTerraform
+	if matches[1] == "OpenTofu" {
+		flavor = FlavorOpenTofu
+	}
+	return &Executable{
+		Path:    execPath,
+		Flavor:  flavor,
+		Version: v,
+	}, nil
+}
+
+// lookPathOption finds the executable in the local OS PATH, the tfinstall.LookPath only supports terraform
+type lookPathOption struct {
+	binary string
+}
+
+func lookPath(binary string) *lookPathOption {
+	return &lookPathOption{binary: binary}
+}
+
+func (opt *lookPathOption) ExecPath(context.Context) (string, error) {
+	p, err := exec.LookPath(opt.binary)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			logrus.Debugf("%s not found in PATH", opt.binary)
+			return "", nil
+		}
+		return "", err
+	}
+	return p, nil
 }
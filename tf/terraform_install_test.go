@@ -0,0 +1,109 @@
+package tf_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/tf"
+)
+
+func Test_ParseVersionOutput(t *testing.T) {
+	testcases := []struct {
+		Output  string
+		Flavor  string
+		Version string
+	}{
+		{
+			Output:  "Terraform v1.9.5\non linux_amd64\n+ provider registry.terraform.io/azure/azapi v2.0.1\n",
+			Flavor:  tf.FlavorTerraform,
+			Version: "1.9.5",
+		},
+		{
+			Output:  "OpenTofu v1.8.1\non linux_amd64\n",
+			Flavor:  tf.FlavorOpenTofu,
+			Version: "1.8.1",
+		},
+	}
+	for _, testcase := range testcases {
+		actual, err := tf.ParseVersionOutput("terraform", testcase.Output)
+		if err != nil {
+			t.Fatalf("unexpected error: %+v", err)
+		}
+		if actual.Flavor != testcase.Flavor || actual.Version.String() != testcase.Version {
+			t.Errorf("expect %s v%s, but got %s", testcase.Flavor, testcase.Version, actual)
+		}
+	}
+
+	if _, err := tf.ParseVersionOutput("terraform", "unknown"); err == nil {
+		t.Errorf("expect error for the unknown output")
+	}
+}
+
+func Test_FindTerraform_ExecPath(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake executable is a shell script")
+	}
+	fakeExecutable := func(output string) string {
+		p := filepath.Join(t.TempDir(), "fake")
+		if err := os.WriteFile(p, []byte("#!/bin/sh\necho '"+output+"'\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	testcases := []struct {
+		Output  string
+		Options tf.Options
+		Error   string
+	}{
+		{
+			Output:  "OpenTofu v1.8.1",
+			Options: tf.Options{Flavor: tf.FlavorOpenTofu, Version: ">= 1.8.0"},
+		},
+		{
+			Output:  "Terraform v1.9.5",
+			Options: tf.Options{Version: "1.9.5"},
+		},
+		{
+			Output:  "Terraform v1.9.5",
+			Options: tf.Options{Flavor: tf.FlavorOpenTofu},
+			Error:   "tofu is required",
+		},
+		{
+			Output:  "Terraform v1.9.5",
+			Options: tf.Options{Version: "< 1.9.0"},
+			Error:   "doesn't meet the version constraint",
+		},
+		{
+			Output:  "Terraform v1.2.9",
+			Options: tf.Options{},
+			Error:   "v1.3.0 or later",
+		},
+		{
+			Output:  "Terraform v1.9.5",
+			Options: tf.Options{Flavor: "unknown"},
+			Error:   "is not supported",
+		},
+	}
+	for _, testcase := range testcases {
+		testcase.Options.ExecPath = fakeExecutable(testcase.Output)
+		actual, err := tf.FindTerraform(context.TODO(), testcase.Options)
+		if testcase.Error != "" {
+			if err == nil || !strings.Contains(err.Error(), testcase.Error) {
+				t.Errorf("expect error containing %q for %s, but got %v", testcase.Error, testcase.Output, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("unexpected error for %s: %+v", testcase.Output, err)
+			continue
+		}
+		if actual.Path != testcase.Options.ExecPath {
+			t.Errorf("expect path %s, but got %s", testcase.Options.ExecPath, actual.Path)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package tf_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/azure/armstrong/tf"
+)
+
+func Test_ApplyDestroy_JSONUI(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake executable is a shell script")
+	}
+	output, err := filepath.Abs(filepath.Join("testdata", "apply_errored.jsonl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args.txt")
+	fakeExecutable := filepath.Join(t.TempDir(), "fake")
+	script := `#!/bin/sh
+case "$1" in
+version) echo 'Terraform v1.9.5' ;;
+*) echo "$@" > '` + argsFile + `'; cat '` + output + `'; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(fakeExecutable, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	terraform, err := tf.NewTerraform(dir, false, tf.Options{ExecPath: fakeExecutable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	testcases := []struct {
+		Run  func() error
+		Args string
+	}{
+		{
+			Run:  terraform.Apply,
+			Args: "apply -auto-approve -input=false -lock=true -parallelism=10 -refresh=true -json",
+		},
+		{
+			Run:  terraform.Destroy,
+			Args: "destroy -auto-approve -input=false -lock=true -parallelism=10 -refresh=true -json",
+		},
+	}
+	for _, tc := range testcases {
+		err := tc.Run()
+		var diagnosticsError *tf.DiagnosticsError
+		if !errors.As(err, &diagnosticsError) {
+			t.Fatalf("expect *DiagnosticsError, but got %+v", err)
+		}
+		args, err := os.ReadFile(argsFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.TrimSpace(string(args)) != tc.Args {
+			t.Errorf("expect the arguments %q, but got %q", tc.Args, strings.TrimSpace(string(args)))
+		}
+	}
+}
@@ -2,6 +2,7 @@ package tf
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"regexp"
 	"strings"
@@ -9,6 +10,8 @@ import (
 	"github.com/azure/armstrong/coverage"
 	"github.com/azure/armstrong/types"
 	"github.com/azure/armstrong/utils"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	tfjson "github.com/hashicorp/terraform-json"
 	paltypes "github.com/ms-henglu/pal/types"
 	"github.com/sirupsen/logrus"
@@ -127,7 +130,9 @@ func NewPassReportFromState(state *tfjson.State) types.PassReport {
 		if v, ok := res.AttributeValues["type"]; ok {
 			resourceType = v.(string)
 		}
+		id, _ := res.AttributeValues["id"].(string)
 		out.Resources = append(out.Resources, types.Resource{
+			Id:      id,
 			Type:    resourceType,
 			Address: res.Address,
 		})
@@ -155,7 +160,9 @@ func NewPassReport(plan *tfjson.Plan) types.PassReport {
 			if !beforeMapOk {
 				continue
 			}
+			id, _ := beforeMap["id"].(string)
 			out.Resources = append(out.Resources, types.Resource{
+				Id:      id,
 				Type:    beforeMap["type"].(string),
 				Address: resourceChange.Address,
 			})
@@ -324,6 +331,9 @@ func expandIdentity(input []interface{}) map[string]interface{} {
 	return config
 }
 
+// resourceIdRegex matches the resource id and api version in the azapi error details
+var resourceIdRegex = regexp.MustCompile(`ResourceId\s+\\?"([^\\"]+)\\?"\s+/\s+Api Version \\?"([^\\"]+)\\?"\)`)
+
 func NewErrorReport(applyErr error, logs []paltypes.RequestTrace) types.ErrorReport {
 	out := types.ErrorReport{
 		Errors: make([]types.Error, 0),
@@ -332,6 +342,11 @@ func NewErrorReport(applyErr error, logs []paltypes.RequestTrace) types.ErrorRep
 	if applyErr == nil {
 		return out
 	}
+	var diagnosticsErr *DiagnosticsError
+	if errors.As(applyErr, &diagnosticsErr) {
+		out.Errors = newErrorsFromDiagnostics(diagnosticsErr.Diagnostics)
+		return out
+	}
 	var res []string
 	if strings.Contains(applyErr.Error(), "Error: Failed to create/update resource") {
 		res = strings.Split(applyErr.Error(), "Error: Failed to create/update resource")
@@ -372,6 +387,15 @@ func NewCleanupErrorReport(applyErr error, logs []paltypes.RequestTrace) types.E
 	if applyErr == nil {
 		return out
 	}
+	var diagnosticsErr *DiagnosticsError
+	if errors.As(applyErr, &diagnosticsErr) {
+		out.Errors = newErrorsFromDiagnostics(diagnosticsErr.Diagnostics)
+		// the cleanup reports are labeled by the resource addresses
+		for i := range out.Errors {
+			out.Errors[i].Label = out.Errors[i].Address
+		}
+		return out
+	}
 	var res []string
 	if strings.Contains(applyErr.Error(), "Error: Failed to delete resource") {
 		res = strings.Split(applyErr.Error(), "Error: Failed to delete resource")
@@ -400,6 +424,73 @@ func NewCleanupErrorReport(applyErr error, logs []paltypes.RequestTrace) types.E
 	return out
 }
 
+// newErrorsFromDiagnostics returns one error for each resource which has error diagnostics, the diagnostics which are not related to any resource are skipped.
+// The resource id and api version are parsed from the azapi error details, the other resources are reported with their resource types.
+func newErrorsFromDiagnostics(diagnostics []Diagnostic) []types.Error {
+	out := make([]types.Error, 0)
+	indexMap := make(map[string]int)
+	for _, d := range diagnostics {
+		if d.Severity != DiagnosticSeverityError {
+			continue
+		}
+		if d.Address == "" {
+			logrus.Warnf("skip the error which is not related to any resource: %s", d.Summary)
+			continue
+		}
+		message := strings.TrimSpace(fmt.Sprintf("%s\n\n%s", d.Summary, d.Detail))
+		if index, ok := indexMap[d.Address]; ok {
+			out[index].Message += "\n\n" + message
+			continue
+		}
+
+		resourceType, label, err := parseResourceAddress(d.Address)
+		if err != nil {
+			logrus.Warnf("parsing the resource address %s: %+v", d.Address, err)
+		}
+		e := types.Error{
+			Type:    resourceType,
+			Label:   label,
+			Address: d.Address,
+			Message: message,
+		}
+		if matches := resourceIdRegex.FindStringSubmatch(strings.Join(strings.Fields(d.Detail), " ")); matches != nil {
+			e.Id = matches[1]
+			e.Type = fmt.Sprintf("%s@%s", utils.ResourceTypeOfResourceId(e.Id), matches[2])
+		}
+		indexMap[d.Address] = len(out)
+		out = append(out, e)
+	}
+	return out
+}
+
+// parseResourceAddress returns the resource type and the name of the resource address, e.g., azapi_resource and x of module.m.data.azapi_resource.x["a.b"]
+func parseResourceAddress(address string) (string, string, error) {
+	traversal, diags := hclsyntax.ParseTraversalAbs([]byte(address), "", hcl.InitialPos)
+	if diags.HasErrors() {
+		return "", "", diags
+	}
+	names := make([]string, 0)
+	for _, step := range traversal {
+		switch v := step.(type) {
+		case hcl.TraverseRoot:
+			names = append(names, v.Name)
+		case hcl.TraverseAttr:
+			names = append(names, v.Name)
+		}
+	}
+	// the instance keys, e.g., [0] and ["a.b"], are not names, the module calls, e.g., module.m, are skipped
+	for len(names) >= 2 && names[0] == "module" {
+		names = names[2:]
+	}
+	if len(names) != 0 && names[0] == "data" {
+		names = names[1:]
+	}
+	if len(names) != 2 {
+		return "", "", fmt.Errorf("expect the address in the format of [module.<name>.][data.]<type>.<name>")
+	}
+	return names[0], names[1], nil
+}
+
 func NewIdAddressFromState(state *tfjson.State) map[string]string {
 	out := map[string]string{}
 	if state == nil || state.Values == nil || state.Values.RootModule == nil || state.Values.RootModule.Resources == nil {
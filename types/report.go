@@ -1,12 +1,19 @@
 package types
 
-import paltypes "github.com/ms-henglu/pal/types"
+import (
+	"time"
+
+	paltypes "github.com/ms-henglu/pal/types"
+)
 
 type PassReport struct {
 	Resources []Resource
+	// TerraformVersion is the terraform or tofu version which runs the tests, e.g., terraform v1.9.5
+	TerraformVersion string
 }
 
 type Resource struct {
+	Id      string
 	Type    string
 	Address string
 }
@@ -21,6 +28,9 @@ type Diff struct {
 	Type    string
 	Address string
 	Change  Change
+	// OperationId and Permalink are the swagger operation which returns the response and the link to the model property which differs, they're empty if not resolved
+	OperationId string
+	Permalink   string
 }
 
 type Change struct {
@@ -31,11 +41,94 @@ type Change struct {
 type ErrorReport struct {
 	Errors []Error
 	Logs   []paltypes.RequestTrace
+	// TerraformVersion is the terraform or tofu version which runs the tests, e.g., terraform v1.9.5
+	TerraformVersion string
 }
 
 type Error struct {
 	Id      string
 	Type    string
 	Label   string
+	Address string
+	Message string
+	// OperationId and Permalink are the swagger operation of the failed request and the link to it, they're empty if not resolved
+	OperationId string
+	Permalink   string
+	// Code is the error code returned by the failed request
+	Code string
+	// Fixes are the likely causes and fixes of the error, they're matched from the known errors
+	Fixes []KnownFix
+}
+
+// KnownFix is the likely cause and fix of a known error
+type KnownFix struct {
+	Rule  string
+	Cause string
+	Fix   string
+	// Patch is the suggested HCL config which fixes the error, it's empty if the fix can't be expressed as config
+	Patch string
+	// Reference is the link to the document which explains the error
+	Reference string
+}
+
+// RunSummary is the overview of a test run, it's written as the index of the report directory
+type RunSummary struct {
+	// CommandLine is the full command line which starts the run
+	CommandLine      string
+	ToolVersion      string
+	TerraformVersion string
+	StartTime        time.Time
+	EndTime          time.Time
+	Phases           []PhaseSummary
+	Resources        []ResourceSummary
+	Coverages        []CoverageSummary
+	// SwaggerIndexCommit is the commit of the azure-rest-api-specs repo which the swagger index used by the coverage is built on
+	SwaggerIndexCommit string
+	// Artifacts are the files in the report directory, the paths are relative to it
+	Artifacts []string
+	// Suppressed are the findings which are not reported because they match the suppressions
+	Suppressed []SuppressedFinding
+}
+
+// SuppressedFinding is a finding which is not reported because it matches a suppression, it's listed in the appendix of the reports
+type SuppressedFinding struct {
+	// Report is the report which the finding belongs to, e.g., diff, error, coverage, credscan and accuracy
+	Report string
+	Rule   string
+	// Target describes where the finding is, e.g., the resource type, the operation and the property
+	Target  string
 	Message string
+	Reason  string
+	// Expires is the date when the suppression expires, it's empty if the suppression never expires
+	Expires string
+}
+
+const (
+	StatusPassed  = "passed"
+	StatusFailed  = "failed"
+	StatusDiff    = "API issue"
+	StatusSkipped = "skipped"
+)
+
+type PhaseSummary struct {
+	Name   string
+	Status string
+	Detail string
+}
+
+type ResourceSummary struct {
+	Id      string
+	Address string
+	Type    string
+	Status  string
+	// Reports and Traces are the error/diff reports and the trace files of the resource, the paths are relative to the report directory
+	Reports []string
+	Traces  []string
+}
+
+type CoverageSummary struct {
+	DisplayName  string
+	ApiPath      string
+	CoveredCount int
+	TotalCount   int
 }
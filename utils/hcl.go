@@ -19,3 +19,27 @@ func AttributeValue(attribute *hclwrite.Attribute) string {
 	value = strings.Trim(value, ` "`)
 	return value
 }
+
+// RenameReferences renames all the references which start with the search prefix to the replacement prefix in the body,
+// e.g., search: ["azapi_resource", "test"], replacement: ["azapi_resource", "test_1"].
+// Only the real traversals are renamed, including the ones in nested blocks and interpolated strings, the string literals are kept as is.
+func RenameReferences(body *hclwrite.Body, search, replacement []string) {
+	if body == nil {
+		return
+	}
+	for _, attr := range body.Attributes() {
+		attr.Expr().RenameVariablePrefix(search, replacement)
+	}
+	for _, block := range body.Blocks() {
+		RenameReferences(block.Body(), search, replacement)
+	}
+}
+
+// BlockAddress returns the traversal names used to refer to the resource or data block, e.g., ["data", "azapi_resource", "test"]
+func BlockAddress(blockType string, labels []string) []string {
+	out := make([]string, 0)
+	if blockType == "data" {
+		out = append(out, "data")
+	}
+	return append(out, labels...)
+}
@@ -152,3 +152,73 @@ data "azapi_resource_list" "test" {
 		}
 	}
 }
+
+func Test_RenameReferences(t *testing.T) {
+	testcases := []struct {
+		Input       string
+		Search      []string
+		Replacement []string
+		Expected    string
+	}{
+		{
+			Input: `resource "azapi_resource" "app" {
+  parent_id = azapi_resource.test.id
+  name      = "azapi_resource.test.name"
+  body = {
+    properties = {
+      sourceId = "${azapi_resource.test.id}/child"
+      otherId  = azapi_resource.test_1.id
+    }
+  }
+  depends_on = [azapi_resource.test]
+}
+`,
+			Search:      []string{"azapi_resource", "test"},
+			Replacement: []string{"azapi_resource", "test_2"},
+			Expected: `resource "azapi_resource" "app" {
+  parent_id = azapi_resource.test_2.id
+  name      = "azapi_resource.test.name"
+  body = {
+    properties = {
+      sourceId = "${azapi_resource.test_2.id}/child"
+      otherId  = azapi_resource.test_1.id
+    }
+  }
+  depends_on = [azapi_resource.test_2]
+}
+`,
+		},
+		{
+			Input: `resource "azapi_resource" "app" {
+  parent_id = data.azapi_resource.test.id
+  name      = azapi_resource.test.name
+  nested {
+    id = data.azapi_resource.test.id
+  }
+}
+`,
+			Search:      []string{"data", "azapi_resource", "test"},
+			Replacement: []string{"data", "azapi_resource", "test_1"},
+			Expected: `resource "azapi_resource" "app" {
+  parent_id = data.azapi_resource.test_1.id
+  name      = azapi_resource.test.name
+  nested {
+    id = data.azapi_resource.test_1.id
+  }
+}
+`,
+		},
+	}
+
+	for _, tc := range testcases {
+		file, diags := hclwrite.ParseConfig([]byte(tc.Input), "", hcl.InitialPos)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		utils.RenameReferences(file.Body(), tc.Search, tc.Replacement)
+		actual := string(hclwrite.Format(file.Bytes()))
+		if actual != tc.Expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", tc.Expected, actual)
+		}
+	}
+}
@@ -117,3 +117,20 @@ func ResourceTypeOfResourceId(input string) string {
 	}
 	return resourceType
 }
+
+// ResourceIdOf returns the resource id of the resource with the given parent id, resource type and name,
+// e.g., the child resource is under its parent resource, and the extension resource is under the `providers` segment of its scope.
+func ResourceIdOf(parentId string, resourceType string, name string) string {
+	parentId = strings.TrimSuffix(parentId, "/")
+	parentType := ResourceTypeOfResourceId(parentId)
+	switch {
+	case strings.EqualFold(resourceType, arm.ResourceGroupResourceType.String()):
+		return fmt.Sprintf("%s/resourceGroups/%s", parentId, name)
+	case strings.EqualFold(resourceType, arm.SubscriptionResourceType.String()):
+		return fmt.Sprintf("/subscriptions/%s", name)
+	case parentType != "" && strings.HasPrefix(strings.ToLower(resourceType), strings.ToLower(parentType)+"/") &&
+		!strings.Contains(resourceType[len(parentType)+1:], "/"):
+		return fmt.Sprintf("%s/%s/%s", parentId, resourceType[len(parentType)+1:], name)
+	}
+	return fmt.Sprintf("%s/providers/%s/%s", parentId, resourceType, name)
+}
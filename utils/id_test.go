@@ -267,3 +267,51 @@ func Test_PrentIdOfResourceId(t *testing.T) {
 		}
 	}
 }
+
+func Test_ResourceIdOf(t *testing.T) {
+	testcases := []struct {
+		ParentId     string
+		ResourceType string
+		Name         string
+		Expect       string
+	}{
+		{
+			ParentId:     "/subscriptions/00000000-0000-0000-0000-000000000000",
+			ResourceType: "Microsoft.Resources/resourceGroups",
+			Name:         "rg",
+			Expect:       "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg",
+		},
+		{
+			ParentId:     "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg",
+			ResourceType: "Microsoft.Automation/automationAccounts",
+			Name:         "aa",
+			Expect:       "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa",
+		},
+		{
+			ParentId:     "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa",
+			ResourceType: "Microsoft.Automation/automationAccounts/runbooks",
+			Name:         "rb",
+			Expect:       "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa/runbooks/rb",
+		},
+		{
+			ParentId:     "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa",
+			ResourceType: "Microsoft.Authorization/locks",
+			Name:         "lock",
+			Expect:       "/subscriptions/00000000-0000-0000-0000-000000000000/resourceGroups/rg/providers/Microsoft.Automation/automationAccounts/aa/providers/Microsoft.Authorization/locks/lock",
+		},
+		{
+			ParentId:     "/",
+			ResourceType: "Microsoft.Management/managementGroups",
+			Name:         "mg",
+			Expect:       "/providers/Microsoft.Management/managementGroups/mg",
+		},
+	}
+
+	for _, testcase := range testcases {
+		t.Logf("[DEBUG] testcase: %s %s %s", testcase.ParentId, testcase.ResourceType, testcase.Name)
+		actual := utils.ResourceIdOf(testcase.ParentId, testcase.ResourceType, testcase.Name)
+		if actual != testcase.Expect {
+			t.Fatalf("[ERROR] expect %s, actual %s", testcase.Expect, actual)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// JsonPointerLine returns the line number of the value referenced by the JSON pointer, e.g., /definitions/Foo/properties/bar,
+// it returns 0 if the value is not found.
+func JsonPointerLine(data []byte, pointer string) int {
+	start, _ := JsonPointerRange(data, pointer)
+	return start
+}
+
+// JsonPointerRange returns the first and the last line numbers of the value referenced by the JSON pointer,
+// it returns 0, 0 if the value is not found.
+func JsonPointerRange(data []byte, pointer string) (int, int) {
+	target := make([]string, 0)
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		if token == "" {
+			continue
+		}
+		token = strings.ReplaceAll(token, "~1", "/")
+		token = strings.ReplaceAll(token, "~0", "~")
+		target = append(target, token)
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	offset := findJsonPointer(decoder, target, 0)
+	if offset < 0 {
+		return 0, 0
+	}
+	// the offset is the end of the previous token, skip the separators before the value
+	for offset < int64(len(data)) && strings.ContainsRune(" \t\r\n:,", rune(data[offset])) {
+		offset++
+	}
+	start := bytes.Count(data[:offset], []byte("\n")) + 1
+
+	valueDecoder := json.NewDecoder(bytes.NewReader(data[offset:]))
+	if err := skipJsonValue(valueDecoder); err != nil {
+		return start, start
+	}
+	end := offset + valueDecoder.InputOffset()
+	return start, bytes.Count(data[:end], []byte("\n")) + 1
+}
+
+// findJsonPointer returns the offset before the value referenced by the target path, it returns -1 if the value is not found
+func findJsonPointer(decoder *json.Decoder, target []string, depth int) int64 {
+	if depth == len(target) {
+		return decoder.InputOffset()
+	}
+	token, err := decoder.Token()
+	if err != nil {
+		return -1
+	}
+	delim, ok := token.(json.Delim)
+	if !ok {
+		return -1
+	}
+	switch delim {
+	case '{':
+		for decoder.More() {
+			keyToken, err := decoder.Token()
+			if err != nil {
+				return -1
+			}
+			if key, ok := keyToken.(string); ok && key == target[depth] {
+				return findJsonPointer(decoder, target, depth+1)
+			}
+			if err := skipJsonValue(decoder); err != nil {
+				return -1
+			}
+		}
+	case '[':
+		index, err := strconv.Atoi(target[depth])
+		if err != nil {
+			return -1
+		}
+		for i := 0; decoder.More(); i++ {
+			if i == index {
+				return findJsonPointer(decoder, target, depth+1)
+			}
+			if err := skipJsonValue(decoder); err != nil {
+				return -1
+			}
+		}
+	}
+	return -1
+}
+
+func skipJsonValue(decoder *json.Decoder) error {
+	var v json.RawMessage
+	return decoder.Decode(&v)
+}
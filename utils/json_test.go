@@ -0,0 +1,72 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/azure/armstrong/utils"
+)
+
+const jsonPointerTestData = `{
+  "swagger": "2.0",
+  "paths": {
+    "/providers/Microsoft.Foo/operations": {
+      "get": {
+        "operationId": "Operations_List"
+      }
+    }
+  },
+  "definitions": {
+    "Foo": {
+      "required": [
+        "name",
+        "location"
+      ],
+      "properties": {
+        "a/b": {
+          "type": "string"
+        }
+      }
+    }
+  }
+}`
+
+func Test_JsonPointerLine(t *testing.T) {
+	data := []byte(jsonPointerTestData)
+	testcases := []struct {
+		Pointer  string
+		Expected int
+	}{
+		{Pointer: "", Expected: 1},
+		{Pointer: "/swagger", Expected: 2},
+		{Pointer: "/paths/~1providers~1Microsoft.Foo~1operations/get", Expected: 5},
+		{Pointer: "/definitions/Foo/required/1", Expected: 14},
+		{Pointer: "/definitions/Foo/properties/a~1b/type", Expected: 18},
+		{Pointer: "/definitions/Bar", Expected: 0},
+		{Pointer: "/definitions/Foo/required/5", Expected: 0},
+	}
+	for _, testcase := range testcases {
+		if actual := utils.JsonPointerLine(data, testcase.Pointer); actual != testcase.Expected {
+			t.Errorf("expected line %d for %q, got %d", testcase.Expected, testcase.Pointer, actual)
+		}
+	}
+}
+
+func Test_JsonPointerRange(t *testing.T) {
+	data := []byte(jsonPointerTestData)
+	testcases := []struct {
+		Pointer string
+		Start   int
+		End     int
+	}{
+		{Pointer: "/swagger", Start: 2, End: 2},
+		{Pointer: "/paths/~1providers~1Microsoft.Foo~1operations/get", Start: 5, End: 7},
+		{Pointer: "/definitions/Foo", Start: 11, End: 21},
+		{Pointer: "/definitions/Bar", Start: 0, End: 0},
+	}
+	for _, testcase := range testcases {
+		start, end := utils.JsonPointerRange(data, testcase.Pointer)
+		if start != testcase.Start || end != testcase.End {
+			t.Errorf("expected lines %d-%d for %q, got %d-%d", testcase.Start, testcase.End, testcase.Pointer, start, end)
+		}
+	}
+}